@@ -0,0 +1,452 @@
+// Package cache provides a bounded, concurrency-safe generic cache shared by
+// the pattern examples. Entries are evicted by an LRU, LFU or TinyLFU policy
+// when the cache exceeds its entry or cost limit, and may expire after a TTL.
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Policy selects which entry is evicted when the cache is full.
+type Policy int
+
+const (
+	// LRU evicts the least recently used entry.
+	LRU Policy = iota
+	// LFU evicts the least frequently used entry, oldest first on ties.
+	LFU
+	// TinyLFU keeps LRU order but only admits a new key when it has been
+	// looked up more often than the entry it would evict, or that entry has
+	// never been looked up. Frequencies are
+	// estimated with a count-min sketch that also remembers recent misses,
+	// so a scan of one-off keys cannot flush the working set.
+	TinyLFU
+)
+
+func (p Policy) String() string {
+	switch p {
+	case LRU:
+		return "LRU"
+	case LFU:
+		return "LFU"
+	case TinyLFU:
+		return "TinyLFU"
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// EvictionReason tells an eviction callback why an entry left the cache.
+type EvictionReason int
+
+const (
+	// ReasonCapacity means the entry was evicted, or refused, to stay within
+	// the entry or cost limit.
+	ReasonCapacity EvictionReason = iota
+	// ReasonExpired means the entry's TTL passed.
+	ReasonExpired
+	// ReasonDeleted means Delete or Clear removed the entry.
+	ReasonDeleted
+	// ReasonReplaced means Set stored a new value under the same key.
+	ReasonReplaced
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	}
+	return fmt.Sprintf("EvictionReason(%d)", int(r))
+}
+
+// Stats counts cache lookups and removals since creation or ResetStats.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64 // entries removed or refused for capacity
+	Expirations uint64 // entries removed because their TTL passed
+}
+
+// HitRatio returns hits as a fraction of all lookups, or 0 before any.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type options struct {
+	policy     Policy
+	maxEntries int
+	maxCost    int64
+	ttl        time.Duration
+	now        func() time.Time
+	cost       any // func(K, V) int64
+	onEvict    any // func(K, V, EvictionReason)
+}
+
+// Option configures a Cache.
+type Option func(*options)
+
+// WithPolicy sets the eviction policy. The default is LRU.
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithMaxEntries limits the number of entries. Zero means no limit.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithMaxCost limits the total cost of all entries, as reported by WithCost.
+// Zero means no limit.
+func WithMaxCost(max int64) Option {
+	return func(o *options) {
+		o.maxCost = max
+	}
+}
+
+// WithCost sets the function that weighs each entry against WithMaxCost,
+// such as its size in bytes. Without it every entry costs 1. The key and
+// value types must match the cache's, or New panics.
+func WithCost[K comparable, V any](fn func(key K, value V) int64) Option {
+	return func(o *options) {
+		o.cost = fn
+	}
+}
+
+// WithTTL sets the default time to live for entries stored with Set.
+// Zero, the default, means entries do not expire.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithEvictionCallback registers fn to be called whenever an entry leaves
+// the cache. It runs after the cache's lock is released, so it may call back
+// into the cache. The key and value types must match the cache's, or New
+// panics.
+func WithEvictionCallback[K comparable, V any](fn func(key K, value V, reason EvictionReason)) Option {
+	return func(o *options) {
+		o.onEvict = fn
+	}
+}
+
+// entry is a cached value plus the bookkeeping every policy needs.
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	cost    int64
+	expires time.Time
+
+	node  *node[K, V] // LRU and TinyLFU recency list
+	index int         // LFU heap position
+	freq  uint64      // LFU use count
+	seq   uint64      // LFU last use, to break ties
+}
+
+// removal is an entry waiting for the eviction callback.
+type removal[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// Cache is a bounded, concurrency-safe cache. Create one with New.
+type Cache[K comparable, V any] struct {
+	maxEntries int
+	maxCost    int64
+	ttl        time.Duration
+	now        func() time.Time
+	costFn     func(K, V) int64
+	onEvict    func(K, V, EvictionReason)
+
+	mu     sync.Mutex
+	items  map[K]*entry[K, V]
+	policy policy[K, V]
+	cost   int64
+	stats  Stats
+}
+
+// DefaultMaxEntries is a general-purpose limit for caches that must be
+// bounded but have no better figure to go on.
+const DefaultMaxEntries = 1000
+
+// New creates a cache. With no limit options the cache is unbounded.
+func New[K comparable, V any](opts ...Option) *Cache[K, V] {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Cache[K, V]{
+		maxEntries: o.maxEntries,
+		maxCost:    o.maxCost,
+		ttl:        o.ttl,
+		now:        o.now,
+		items:      make(map[K]*entry[K, V]),
+	}
+	if o.cost != nil {
+		fn, ok := o.cost.(func(K, V) int64)
+		if !ok {
+			panic(fmt.Sprintf("cache: WithCost function %T does not match Cache[%v, %v]", o.cost, reflect.TypeFor[K](), reflect.TypeFor[V]()))
+		}
+		c.costFn = fn
+	}
+	if o.onEvict != nil {
+		fn, ok := o.onEvict.(func(K, V, EvictionReason))
+		if !ok {
+			panic(fmt.Sprintf("cache: WithEvictionCallback function %T does not match Cache[%v, %v]", o.onEvict, reflect.TypeFor[K](), reflect.TypeFor[V]()))
+		}
+		c.onEvict = fn
+	}
+
+	switch o.policy {
+	case LFU:
+		c.policy = &lfuPolicy[K, V]{}
+	case TinyLFU:
+		c.policy = newTinyLFUPolicy[K, V](o.maxEntries)
+	default:
+		c.policy = newLRUPolicy[K, V]()
+	}
+	return c
+}
+
+// NewBounded creates a cache limited to DefaultMaxEntries. opts are applied
+// after that limit, so WithMaxEntries in opts replaces it.
+func NewBounded[K comparable, V any](opts ...Option) *Cache[K, V] {
+	return New[K, V](append([]Option{WithMaxEntries(DefaultMaxEntries)}, opts...)...)
+}
+
+// Get returns the value stored under key and records a hit or a miss.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	c.policy.record(key)
+	e, ok := c.items[key]
+	if ok && c.expired(e) {
+		c.stats.Expirations++
+		removed := c.removeLocked(e, ReasonExpired)
+		ok = false
+		defer c.notify(removed)
+	}
+	if !ok {
+		c.stats.Misses++
+		c.mu.Unlock()
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.policy.touch(e)
+	value := e.value
+	c.mu.Unlock()
+	return value, true
+}
+
+// Peek returns the value stored under key without counting a lookup or
+// changing its position in the eviction order.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok || c.expired(e) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value under key with the cache's default TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores value under key for ttl; zero means no expiry. The
+// entry may evict others to fit. It is refused if it alone exceeds the cost
+// limit, or if TinyLFU judges it less valuable than the entry it would
+// evict; the eviction callback then sees it with ReasonCapacity, and any
+// value already stored under key is kept.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	cost := int64(1)
+	if c.costFn != nil {
+		cost = c.costFn(key, value)
+	}
+
+	c.mu.Lock()
+	var removed []removal[K, V]
+	defer func() {
+		c.mu.Unlock()
+		c.notify(removed)
+	}()
+
+	if c.maxCost > 0 && cost > c.maxCost {
+		c.stats.Evictions++
+		removed = append(removed, removal[K, V]{key, value, ReasonCapacity})
+		return
+	}
+
+	freq := uint64(1)
+	resident := false
+	if old, ok := c.items[key]; ok {
+		// The key keeps its place in line: no admission check, and LFU
+		// carries its count over.
+		freq, resident = old.freq+1, true
+		removed = append(removed, c.removeLocked(old, ReasonReplaced)...)
+	}
+
+	for c.overflows(1, cost) {
+		victim := c.policy.victim()
+		if victim == nil {
+			break
+		}
+		if c.expired(victim) {
+			c.stats.Expirations++
+			removed = append(removed, c.removeLocked(victim, ReasonExpired)...)
+			continue
+		}
+		if !resident && !c.policy.admit(key, victim) {
+			c.stats.Evictions++
+			removed = append(removed, removal[K, V]{key, value, ReasonCapacity})
+			return
+		}
+		c.stats.Evictions++
+		removed = append(removed, c.removeLocked(victim, ReasonCapacity)...)
+	}
+
+	e := &entry[K, V]{key: key, value: value, cost: cost, freq: freq}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+	c.items[key] = e
+	c.cost += cost
+	c.policy.add(e)
+}
+
+// Delete removes key and reports whether it was present.
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	e, ok := c.items[key]
+	if !ok {
+		c.mu.Unlock()
+		return false
+	}
+	removed := c.removeLocked(e, ReasonDeleted)
+	c.mu.Unlock()
+	c.notify(removed)
+	return true
+}
+
+// DeleteExpired removes every expired entry and returns how many there were.
+// Expired entries are otherwise removed lazily, when looked up or when they
+// come up for eviction.
+func (c *Cache[K, V]) DeleteExpired() int {
+	c.mu.Lock()
+	// removed only collects entries when there is an eviction callback, so
+	// it cannot be used for the count.
+	var removed []removal[K, V]
+	n := 0
+	for _, e := range c.items {
+		if c.expired(e) {
+			c.stats.Expirations++
+			removed = append(removed, c.removeLocked(e, ReasonExpired)...)
+			n++
+		}
+	}
+	c.mu.Unlock()
+	c.notify(removed)
+	return n
+}
+
+// Clear removes every entry. Statistics are kept; see ResetStats.
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	var removed []removal[K, V]
+	if c.onEvict != nil {
+		for _, e := range c.items {
+			removed = append(removed, removal[K, V]{e.key, e.value, ReasonDeleted})
+		}
+	}
+	c.items = make(map[K]*entry[K, V])
+	c.cost = 0
+	c.policy.clear()
+	c.mu.Unlock()
+	c.notify(removed)
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Cost returns the total cost of all entries.
+func (c *Cache[K, V]) Cost() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cost
+}
+
+// Stats returns a snapshot of the cache's counters.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// ResetStats zeroes the counters.
+func (c *Cache[K, V]) ResetStats() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = Stats{}
+}
+
+func (c *Cache[K, V]) expired(e *entry[K, V]) bool {
+	return !e.expires.IsZero() && !c.now().Before(e.expires)
+}
+
+// overflows reports whether adding entries and cost would exceed a limit.
+func (c *Cache[K, V]) overflows(entries int, cost int64) bool {
+	return (c.maxEntries > 0 && len(c.items)+entries > c.maxEntries) ||
+		(c.maxCost > 0 && c.cost+cost > c.maxCost)
+}
+
+// removeLocked drops e and returns it for the callback, if there is one.
+func (c *Cache[K, V]) removeLocked(e *entry[K, V], reason EvictionReason) []removal[K, V] {
+	delete(c.items, e.key)
+	c.cost -= e.cost
+	c.policy.remove(e)
+	if c.onEvict == nil {
+		return nil
+	}
+	return []removal[K, V]{{e.key, e.value, reason}}
+}
+
+func (c *Cache[K, V]) notify(removed []removal[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, r := range removed {
+		c.onEvict(r.key, r.value, r.reason)
+	}
+}
@@ -0,0 +1,382 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type evictionLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *evictionLog) record(key string, value int, reason EvictionReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf("%s=%d %s", key, value, reason))
+}
+
+func (l *evictionLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.entries)
+}
+
+// TestCache_LRU tests that the least recently used entry goes first.
+func TestCache_LRU(t *testing.T) {
+	var log evictionLog
+	c := New[string, int](WithMaxEntries(2), WithEvictionCallback(log.record))
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // b is now the least recently used
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+	if got, want := log.list(), []string{"b=2 capacity"}; !slices.Equal(got, want) {
+		t.Errorf("evictions = %v, want %v", got, want)
+	}
+	if s := c.Stats(); s.Hits != 3 || s.Misses != 1 || s.Evictions != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+// TestCache_LFU tests that the least frequently used entry goes first.
+func TestCache_LFU(t *testing.T) {
+	c := New[string, int](WithPolicy(LFU), WithMaxEntries(3))
+
+	c.Set("hot", 1)
+	c.Set("warm", 2)
+	c.Set("cold", 3)
+	for range 5 {
+		c.Get("hot")
+	}
+	c.Get("warm")
+	c.Set("hot", 10) // replacing keeps the count
+	c.Set("new", 4)
+
+	if _, ok := c.Peek("cold"); ok {
+		t.Error("expected cold to be evicted")
+	}
+	c.Set("newer", 5) // new has the lowest count now
+	if _, ok := c.Peek("new"); ok {
+		t.Error("expected new to be evicted")
+	}
+	if v, ok := c.Peek("hot"); !ok || v != 10 {
+		t.Errorf("hot = %d, %v; want 10, true", v, ok)
+	}
+}
+
+// TestNewBounded tests that NewBounded stops at DefaultMaxEntries unless
+// the options set another limit.
+func TestNewBounded(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache *Cache[int, int]
+		limit int
+	}{
+		{"default", NewBounded[int, int](), DefaultMaxEntries},
+		{"configured", NewBounded[int, int](WithMaxEntries(3)), 3},
+	}
+	for _, tt := range tests {
+		for i := 0; i <= tt.limit; i++ {
+			tt.cache.Set(i, i)
+		}
+		if got := tt.cache.Len(); got != tt.limit {
+			t.Errorf("%s: expected %d entries, got %d", tt.name, tt.limit, got)
+		}
+		if _, ok := tt.cache.Peek(0); ok {
+			t.Errorf("%s: expected the oldest entry to be evicted", tt.name)
+		}
+	}
+}
+
+// TestCache_TinyLFUResistsScans tests that one-off keys do not flush popular ones.
+func TestCache_TinyLFUResistsScans(t *testing.T) {
+	const size = 100
+	for _, policy := range []Policy{LRU, TinyLFU} {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := New[string, int](WithPolicy(policy), WithMaxEntries(size))
+			for round := range 3 {
+				for i := range size {
+					key := fmt.Sprintf("hot-%d", i)
+					if _, ok := c.Get(key); !ok {
+						c.Set(key, round)
+					}
+				}
+			}
+			for i := range 2 * size {
+				key := fmt.Sprintf("scan-%d", i)
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, i)
+				}
+			}
+
+			kept := 0
+			for i := range size {
+				if _, ok := c.Peek(fmt.Sprintf("hot-%d", i)); ok {
+					kept++
+				}
+			}
+			if policy == TinyLFU && kept < size*9/10 {
+				t.Errorf("TinyLFU kept %d of %d hot keys after a scan", kept, size)
+			}
+			if policy == LRU && kept != 0 {
+				t.Errorf("LRU kept %d hot keys; the scan should flush them all", kept)
+			}
+			if c.Len() > size {
+				t.Errorf("Len = %d, want at most %d", c.Len(), size)
+			}
+		})
+	}
+}
+
+// TestCache_TinyLFUSetOnly tests that a cache filled only through Set keeps
+// taking new keys once it is full.
+func TestCache_TinyLFUSetOnly(t *testing.T) {
+	const size = 10
+	c := New[int, int](WithPolicy(TinyLFU), WithMaxEntries(size))
+	for i := range 3 * size {
+		c.Set(i, i)
+	}
+	if c.Len() != size {
+		t.Errorf("Len = %d, want %d", c.Len(), size)
+	}
+	for i := 2 * size; i < 3*size; i++ {
+		if _, ok := c.Peek(i); !ok {
+			t.Errorf("Expected the recent key %d to be cached", i)
+		}
+	}
+}
+
+// TestCache_DeleteExpiredWithoutCallback tests the count when removals are
+// not collected for a callback.
+func TestCache_DeleteExpiredWithoutCallback(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := New[string, int](WithTTL(time.Minute), WithClock(func() time.Time { return now }))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.SetWithTTL("c", 3, 0)
+
+	now = now.Add(2 * time.Minute)
+	if n := c.DeleteExpired(); n != 2 {
+		t.Errorf("DeleteExpired = %d, want 2", n)
+	}
+	if n := c.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
+
+// TestCache_TTL tests default and per-entry expiry.
+func TestCache_TTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	var log evictionLog
+	c := New[string, int](WithTTL(time.Minute), WithClock(func() time.Time { return now }),
+		WithEvictionCallback(log.record))
+
+	c.Set("short", 1)
+	c.SetWithTTL("long", 2, time.Hour)
+	c.SetWithTTL("forever", 3, 0)
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("short"); ok {
+		t.Error("expected short to expire")
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Error("expected long to be cached")
+	}
+
+	now = now.Add(24 * time.Hour)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("DeleteExpired = %d, want 1", n)
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Error("expected forever to be cached")
+	}
+
+	if got, want := log.list(), []string{"short=1 expired", "long=2 expired"}; !slices.Equal(got, want) {
+		t.Errorf("evictions = %v, want %v", got, want)
+	}
+	if s := c.Stats(); s.Expirations != 2 || s.Misses != 1 || s.Hits != 2 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+// TestCache_Cost tests cost-based limits and callback reasons.
+func TestCache_Cost(t *testing.T) {
+	var log evictionLog
+	c := New[string, int](
+		WithMaxCost(10),
+		WithCost(func(key string, value int) int64 { return int64(value) }),
+		WithEvictionCallback(log.record),
+	)
+
+	c.Set("a", 4)
+	c.Set("b", 4)
+	c.Set("a", 5)    // replaced: cost 9
+	c.Set("c", 3)    // evicts b, the least recent
+	c.Set("big", 11) // refused outright
+	c.Set("a", 12)   // refused, and the old a stays
+	c.Delete("c")
+
+	if v, ok := c.Peek("a"); !ok || v != 5 {
+		t.Errorf("Peek(a) = %d, %v; want the old value to survive an oversized replacement", v, ok)
+	}
+	if c.Cost() != 5 || c.Len() != 1 {
+		t.Errorf("Cost = %d, Len = %d; want 5, 1", c.Cost(), c.Len())
+	}
+	want := []string{"a=4 replaced", "b=4 capacity", "big=11 capacity", "a=12 capacity", "c=3 deleted"}
+	if got := log.list(); !slices.Equal(got, want) {
+		t.Errorf("evictions = %v, want %v", got, want)
+	}
+
+	c.Clear()
+	if c.Len() != 0 || c.Cost() != 0 {
+		t.Errorf("after Clear: Len = %d, Cost = %d", c.Len(), c.Cost())
+	}
+	if got := log.list(); got[len(got)-1] != "a=5 deleted" {
+		t.Errorf("expected Clear to report a, got %v", got)
+	}
+}
+
+// TestCache_MismatchedOptionPanics tests that typed options must match the cache.
+func TestCache_MismatchedOptionPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a callback of the wrong type")
+		}
+	}()
+	New[int, string](WithEvictionCallback(func(string, int, EvictionReason) {}))
+}
+
+// TestCache_Concurrent tests the cache under concurrent use with each policy.
+func TestCache_Concurrent(t *testing.T) {
+	for _, policy := range []Policy{LRU, LFU, TinyLFU} {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := New[int, int](WithPolicy(policy), WithMaxEntries(64))
+			var wg sync.WaitGroup
+			for g := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range 2000 {
+						key := (g*7 + i) % 200
+						if _, ok := c.Get(key); !ok {
+							c.Set(key, i)
+						}
+						if i%50 == 0 {
+							c.Delete(key)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if c.Len() > 64 {
+				t.Errorf("Len = %d, want at most 64", c.Len())
+			}
+			if s := c.Stats(); s.Hits+s.Misses != 8*2000 {
+				t.Errorf("lookups = %d, want %d", s.Hits+s.Misses, 8*2000)
+			}
+		})
+	}
+}
+
+// waitForWaiters waits until n callers are waiting on the call for key.
+func waitForWaiters[V any](g *Group[string, V], key string, n int) {
+	for {
+		g.mu.Lock()
+		c := g.calls[key]
+		joined := c != nil && c.dups == n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestGroup_Do tests that concurrent calls for one key share a single run.
+func TestGroup_Do(t *testing.T) {
+	var g Group[string, int]
+	var runs atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do("k", func() (int, error) {
+				runs.Add(1)
+				<-release
+				return 42, nil
+			})
+			if v != 42 || err != nil {
+				t.Errorf("Do = %d, %v", v, err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	waitForWaiters(&g, "k", 9)
+	close(release)
+	wg.Wait()
+
+	if runs.Load() != 1 {
+		t.Errorf("fn ran %d times, want 1", runs.Load())
+	}
+	if sharedCount.Load() != 10 {
+		t.Errorf("%d callers saw shared, want 10", sharedCount.Load())
+	}
+
+	wantErr := errors.New("boom")
+	if _, err, _ := g.Do("k", func() (int, error) { return 0, wantErr }); err != wantErr {
+		t.Errorf("expected a fresh run after the first finished, got %v", err)
+	}
+}
+
+// TestGroup_DoPanic tests that a panic reaches every caller as an error.
+func TestGroup_DoPanic(t *testing.T) {
+	var g Group[string, int]
+	release := make(chan struct{})
+	cause := errors.New("cause")
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err, _ := g.Do("k", func() (int, error) {
+				<-release
+				panic(cause)
+			})
+			errs <- err
+		}()
+	}
+	waitForWaiters(&g, "k", 1)
+	close(release)
+
+	for range 2 {
+		err := <-errs
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) || !errors.Is(err, cause) || len(panicErr.Stack) == 0 {
+			t.Errorf("expected a *PanicError wrapping the cause, got %v", err)
+		}
+	}
+	if g.InFlight("k") {
+		t.Error("expected the call to be finished")
+	}
+}
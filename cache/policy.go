@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"container/heap"
+	"hash/maphash"
+)
+
+// policy orders entries for eviction. The cache calls it with its lock held.
+type policy[K comparable, V any] interface {
+	// add starts tracking a new entry.
+	add(e *entry[K, V])
+	// touch records a hit on e.
+	touch(e *entry[K, V])
+	// remove stops tracking e.
+	remove(e *entry[K, V])
+	// victim returns the entry to evict next, or nil if there is none.
+	victim() *entry[K, V]
+	// record notes a lookup of key, whether or not it is cached.
+	record(key K)
+	// admit reports whether a new key should displace victim.
+	admit(candidate K, victim *entry[K, V]) bool
+	// clear forgets every entry.
+	clear()
+}
+
+// node is an element of the recency list. container/list would need a type
+// assertion on every access.
+type node[K comparable, V any] struct {
+	entry      *entry[K, V]
+	prev, next *node[K, V]
+}
+
+// lruPolicy keeps entries in a doubly linked list, most recent first.
+type lruPolicy[K comparable, V any] struct {
+	root node[K, V] // sentinel: root.next is the front, root.prev the back
+}
+
+func newLRUPolicy[K comparable, V any]() *lruPolicy[K, V] {
+	p := &lruPolicy[K, V]{}
+	p.clear()
+	return p
+}
+
+func (p *lruPolicy[K, V]) add(e *entry[K, V]) {
+	e.node = &node[K, V]{entry: e}
+	p.pushFront(e.node)
+}
+
+func (p *lruPolicy[K, V]) touch(e *entry[K, V]) {
+	p.unlink(e.node)
+	p.pushFront(e.node)
+}
+
+func (p *lruPolicy[K, V]) remove(e *entry[K, V]) {
+	p.unlink(e.node)
+	e.node = nil
+}
+
+func (p *lruPolicy[K, V]) victim() *entry[K, V] {
+	if p.root.prev == &p.root {
+		return nil
+	}
+	return p.root.prev.entry
+}
+
+func (p *lruPolicy[K, V]) record(K) {}
+
+func (p *lruPolicy[K, V]) admit(K, *entry[K, V]) bool { return true }
+
+func (p *lruPolicy[K, V]) clear() {
+	p.root.next, p.root.prev = &p.root, &p.root
+}
+
+func (p *lruPolicy[K, V]) pushFront(n *node[K, V]) {
+	n.prev, n.next = &p.root, p.root.next
+	p.root.next.prev = n
+	p.root.next = n
+}
+
+func (p *lruPolicy[K, V]) unlink(n *node[K, V]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+}
+
+// lfuPolicy keeps entries in a min-heap by use count, then by last use.
+type lfuPolicy[K comparable, V any] struct {
+	entries lfuHeap[K, V]
+	clock   uint64
+}
+
+func (p *lfuPolicy[K, V]) add(e *entry[K, V]) {
+	p.clock++
+	e.seq = p.clock
+	heap.Push(&p.entries, e)
+}
+
+func (p *lfuPolicy[K, V]) touch(e *entry[K, V]) {
+	p.clock++
+	e.freq++
+	e.seq = p.clock
+	heap.Fix(&p.entries, e.index)
+}
+
+func (p *lfuPolicy[K, V]) remove(e *entry[K, V]) {
+	heap.Remove(&p.entries, e.index)
+}
+
+func (p *lfuPolicy[K, V]) victim() *entry[K, V] {
+	if len(p.entries) == 0 {
+		return nil
+	}
+	return p.entries[0]
+}
+
+func (p *lfuPolicy[K, V]) record(K) {}
+
+func (p *lfuPolicy[K, V]) admit(K, *entry[K, V]) bool { return true }
+
+func (p *lfuPolicy[K, V]) clear() {
+	p.entries = nil
+}
+
+type lfuHeap[K comparable, V any] []*entry[K, V]
+
+func (h lfuHeap[K, V]) Len() int { return len(h) }
+
+func (h lfuHeap[K, V]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// tinyLFUPolicy evicts in LRU order but guards admission with a frequency
+// sketch of recent accesses.
+type tinyLFUPolicy[K comparable, V any] struct {
+	*lruPolicy[K, V]
+	seed   maphash.Seed
+	sketch *sketch
+}
+
+func newTinyLFUPolicy[K comparable, V any](maxEntries int) *tinyLFUPolicy[K, V] {
+	if maxEntries <= 0 {
+		maxEntries = 1024
+	}
+	return &tinyLFUPolicy[K, V]{
+		lruPolicy: newLRUPolicy[K, V](),
+		seed:      maphash.MakeSeed(),
+		sketch:    newSketch(max(maxEntries, 16)),
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) record(key K) {
+	p.sketch.increment(maphash.Comparable(p.seed, key))
+}
+
+// admit lets candidate in if it has been looked up more often than victim,
+// or if victim has never been looked up at all. Without the second case a
+// cache filled only through Set would refuse every new key, since both
+// estimates are zero, and keep its first entries forever.
+func (p *tinyLFUPolicy[K, V]) admit(candidate K, victim *entry[K, V]) bool {
+	victimFreq := p.sketch.estimate(maphash.Comparable(p.seed, victim.key))
+	return victimFreq == 0 || p.sketch.estimate(maphash.Comparable(p.seed, candidate)) > victimFreq
+}
+
+func (p *tinyLFUPolicy[K, V]) clear() {
+	p.lruPolicy.clear()
+	p.sketch.reset()
+}
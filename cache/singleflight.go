@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// errGoexit is returned to callers sharing a call whose fn called
+// runtime.Goexit, as t.FailNow does, instead of returning.
+var errGoexit = errors.New("cache: Group.Do function did not return")
+
+// PanicError is the error Group.Do returns to every caller when fn panics.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("cache: Group.Do function panicked: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// call is an in-flight or completed Group.Do call.
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	dups  int
+}
+
+// Group suppresses duplicate work: concurrent Do calls for the same key
+// share one execution of fn. It is typically placed in front of a cache's
+// loader so that a burst of misses for one key reaches the backend once.
+// The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+// Do runs fn once for key among concurrent callers and returns its result
+// to all of them. shared reports whether the result went to more than one
+// caller. A later Do for the same key, after fn returns, runs fn again.
+//
+// If fn panics, the panic is recovered and every caller gets a *PanicError,
+// so a panic in a background refresh cannot take the process down.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (value V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.value, c.err, true
+	}
+	c := &call[V]{done: make(chan struct{}), err: errGoexit}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		shared = c.dups > 0
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		c.value, c.err = fn()
+	}()
+	return c.value, c.err, false
+}
+
+// InFlight reports whether a call for key is running.
+func (g *Group[K, V]) InFlight(key K) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.calls[key]
+	return ok
+}
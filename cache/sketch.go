@@ -0,0 +1,75 @@
+package cache
+
+import "math/bits"
+
+const (
+	sketchDepth      = 4
+	sketchMaxCounter = 15
+)
+
+// sketch is a count-min sketch of key frequencies. It has four counters per
+// cached entry in each row to keep collisions rare. Counters saturate at 15
+// and are all halved after ten increments per cached entry, so old
+// popularity fades and the estimate follows recent traffic.
+type sketch struct {
+	rows    [sketchDepth][]uint8
+	mask    uint64
+	adds    int
+	resetAt int
+}
+
+func newSketch(entries int) *sketch {
+	size := 1 << bits.Len(uint(4*entries-1)) // next power of two
+	s := &sketch{mask: uint64(size - 1), resetAt: 10 * entries}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, size)
+	}
+	return s
+}
+
+// indexes derives one counter per row from a single 64-bit hash.
+func (s *sketch) indexes(h uint64) [sketchDepth]uint64 {
+	var idx [sketchDepth]uint64
+	lo, hi := h, h>>32|h<<32
+	for i := range idx {
+		idx[i] = (lo + uint64(i)*hi) & s.mask
+	}
+	return idx
+}
+
+func (s *sketch) increment(h uint64) {
+	for i, j := range s.indexes(h) {
+		if s.rows[i][j] < sketchMaxCounter {
+			s.rows[i][j]++
+		}
+	}
+	s.adds++
+	if s.adds >= s.resetAt {
+		s.age()
+	}
+}
+
+func (s *sketch) estimate(h uint64) uint8 {
+	est := uint8(sketchMaxCounter)
+	for i, j := range s.indexes(h) {
+		est = min(est, s.rows[i][j])
+	}
+	return est
+}
+
+// age halves every counter.
+func (s *sketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.adds /= 2
+}
+
+func (s *sketch) reset() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	s.adds = 0
+}
@@ -5,6 +5,8 @@ package builder
 
 import (
 	"fmt"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 )
@@ -14,15 +16,19 @@ type HTTPRequest struct {
 	url             string
 	method          string
 	headers         map[string]string
+	query           url.Values
 	body            string
 	timeout         time.Duration
 	retryCount      int
 	followRedirects bool
+	multipart       []multipartPart
 }
 
 // HTTPRequestBuilder builds HTTPRequest objects step by step.
 type HTTPRequestBuilder struct {
-	request HTTPRequest
+	request   HTTPRequest
+	multipart []multipartPart
+	err       error
 }
 
 // NewHTTPRequestBuilder creates a new builder with sensible defaults.
@@ -31,6 +37,7 @@ func NewHTTPRequestBuilder() *HTTPRequestBuilder {
 		request: HTTPRequest{
 			method:          "GET",
 			headers:         make(map[string]string),
+			query:           make(url.Values),
 			timeout:         30 * time.Second,
 			retryCount:      3,
 			followRedirects: true,
@@ -59,6 +66,7 @@ func (b *HTTPRequestBuilder) Header(key, value string) *HTTPRequestBuilder {
 // Body sets the request body.
 func (b *HTTPRequestBuilder) Body(body string) *HTTPRequestBuilder {
 	b.request.body = body
+	b.multipart = nil
 	return b
 }
 
@@ -83,6 +91,9 @@ func (b *HTTPRequestBuilder) FollowRedirects(follow bool) *HTTPRequestBuilder {
 // Build constructs and validates the final HTTPRequest.
 func (b *HTTPRequestBuilder) Build() (*HTTPRequest, error) {
 	// Validation
+	if b.err != nil {
+		return nil, b.err
+	}
 	if b.request.url == "" {
 		return nil, fmt.Errorf("URL is required")
 	}
@@ -92,15 +103,21 @@ func (b *HTTPRequestBuilder) Build() (*HTTPRequest, error) {
 	for k, v := range b.request.headers {
 		headers[k] = v
 	}
+	query := make(url.Values, len(b.request.query))
+	for k, v := range b.request.query {
+		query[k] = append([]string{}, v...)
+	}
 
 	return &HTTPRequest{
 		url:             b.request.url,
 		method:          b.request.method,
 		headers:         headers,
+		query:           query,
 		body:            b.request.body,
 		timeout:         b.request.timeout,
 		retryCount:      b.request.retryCount,
 		followRedirects: b.request.followRedirects,
+		multipart:       slices.Clone(b.multipart),
 	}, nil
 }
 
@@ -117,7 +134,9 @@ func (r *HTTPRequest) String() string {
 			sb.WriteString(fmt.Sprintf("  %s: %s\n", k, v))
 		}
 	}
-	if r.body != "" {
+	if len(r.multipart) > 0 {
+		sb.WriteString(fmt.Sprintf("Body: multipart form, %d parts\n", len(r.multipart)))
+	} else if r.body != "" {
 		sb.WriteString(fmt.Sprintf("Body: %s\n", r.body))
 	}
 	return sb.String()
@@ -128,11 +147,14 @@ type SQLQuery struct {
 	queryType string // SELECT, INSERT, UPDATE, DELETE
 	table     string
 	columns   []string
-	where     []string
+	where     []Predicate
 	orderBy   string
 	limit     int
 	offset    int
-	joins     []string
+	joins     []joinClause
+	groupBy   []string
+	having    []Predicate
+	dialect   Dialect
 }
 
 // SQLQueryBuilder builds SQL queries step by step.
@@ -145,8 +167,8 @@ func NewQueryBuilder() *SQLQueryBuilder {
 	return &SQLQueryBuilder{
 		query: SQLQuery{
 			columns: make([]string, 0),
-			where:   make([]string, 0),
-			joins:   make([]string, 0),
+			where:   make([]Predicate, 0),
+			joins:   make([]joinClause, 0),
 		},
 	}
 }
@@ -164,13 +186,22 @@ func (b *SQLQueryBuilder) From(table string) *SQLQueryBuilder {
 	return b
 }
 
-// Where adds a WHERE condition.
+// Where adds a raw WHERE condition. The condition is copied into the query
+// verbatim, so it must never contain user input; use WhereExpr instead.
 func (b *SQLQueryBuilder) Where(condition string) *SQLQueryBuilder {
-	b.query.where = append(b.query.where, condition)
+	b.query.where = append(b.query.where, rawCondition(condition))
+	return b
+}
+
+// WhereExpr adds typed WHERE predicates whose values are bound as parameters.
+func (b *SQLQueryBuilder) WhereExpr(predicates ...Predicate) *SQLQueryBuilder {
+	b.query.where = append(b.query.where, predicates...)
 	return b
 }
 
-// OrderBy sets the ORDER BY clause.
+// OrderBy sets the ORDER BY clause: comma-separated column names, each
+// optionally followed by ASC or DESC, such as "name ASC, id". The columns are
+// quoted like any other identifier; ToSQL fails on anything else.
 func (b *SQLQueryBuilder) OrderBy(orderBy string) *SQLQueryBuilder {
 	b.query.orderBy = orderBy
 	return b
@@ -188,57 +219,121 @@ func (b *SQLQueryBuilder) Offset(offset int) *SQLQueryBuilder {
 	return b
 }
 
-// Join adds a JOIN clause.
+// Join adds a raw JOIN clause. Like Where, it is copied verbatim.
 func (b *SQLQueryBuilder) Join(join string) *SQLQueryBuilder {
-	b.query.joins = append(b.query.joins, join)
+	b.query.joins = append(b.query.joins, joinClause{raw: join})
+	return b
+}
+
+// InnerJoin adds an INNER JOIN on the given predicate, typically EqCol.
+func (b *SQLQueryBuilder) InnerJoin(table string, on Predicate) *SQLQueryBuilder {
+	b.query.joins = append(b.query.joins, joinClause{kind: "INNER JOIN", table: table, on: on})
+	return b
+}
+
+// LeftJoin adds a LEFT JOIN on the given predicate, typically EqCol.
+func (b *SQLQueryBuilder) LeftJoin(table string, on Predicate) *SQLQueryBuilder {
+	b.query.joins = append(b.query.joins, joinClause{kind: "LEFT JOIN", table: table, on: on})
+	return b
+}
+
+// GroupBy sets the GROUP BY columns.
+func (b *SQLQueryBuilder) GroupBy(columns ...string) *SQLQueryBuilder {
+	b.query.groupBy = append(b.query.groupBy, columns...)
+	return b
+}
+
+// Having adds HAVING predicates.
+func (b *SQLQueryBuilder) Having(predicates ...Predicate) *SQLQueryBuilder {
+	b.query.having = append(b.query.having, predicates...)
+	return b
+}
+
+// Dialect sets the SQL dialect used for placeholders and identifier quoting.
+// Without a dialect, identifiers are left unquoted and placeholders are "?".
+func (b *SQLQueryBuilder) Dialect(dialect Dialect) *SQLQueryBuilder {
+	b.query.dialect = dialect
 	return b
 }
 
 // Build constructs the final SQL query string.
+// It fails if the query has bound parameters; use ToSQL to get them.
 func (b *SQLQueryBuilder) Build() (string, error) {
+	query, args, err := b.ToSQL()
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		return "", fmt.Errorf("query has %d bound parameters; use ToSQL", len(args))
+	}
+	return query, nil
+}
+
+// ToSQL constructs the SQL query and the arguments for its placeholders.
+func (b *SQLQueryBuilder) ToSQL() (string, []any, error) {
 	if b.query.table == "" {
-		return "", fmt.Errorf("table name is required")
+		return "", nil, fmt.Errorf("table name is required")
 	}
 
-	var sb strings.Builder
+	w := newSQLWriter(b.query.dialect)
 
 	// SELECT clause
-	sb.WriteString("SELECT ")
+	w.WriteString("SELECT ")
 	if len(b.query.columns) == 0 {
-		sb.WriteString("*")
+		w.WriteString("*")
 	} else {
-		sb.WriteString(strings.Join(b.query.columns, ", "))
+		w.writeIdents(b.query.columns)
 	}
 
 	// FROM clause
-	sb.WriteString(fmt.Sprintf(" FROM %s", b.query.table))
+	w.WriteString(" FROM ")
+	w.writeIdent(b.query.table)
 
 	// JOIN clauses
 	for _, join := range b.query.joins {
-		sb.WriteString(fmt.Sprintf(" %s", join))
+		w.WriteString(" ")
+		if err := join.writeSQL(w); err != nil {
+			return "", nil, err
+		}
 	}
 
 	// WHERE clause
-	if len(b.query.where) > 0 {
-		sb.WriteString(fmt.Sprintf(" WHERE %s", strings.Join(b.query.where, " AND ")))
+	if err := w.writeClause(" WHERE ", b.query.where); err != nil {
+		return "", nil, err
+	}
+
+	// GROUP BY / HAVING clauses
+	if len(b.query.groupBy) > 0 {
+		w.WriteString(" GROUP BY ")
+		w.writeIdents(b.query.groupBy)
+	}
+	if len(b.query.having) > 0 && len(b.query.groupBy) == 0 {
+		return "", nil, fmt.Errorf("HAVING requires GROUP BY")
+	}
+	if err := w.writeClause(" HAVING ", b.query.having); err != nil {
+		return "", nil, err
 	}
 
 	// ORDER BY clause
 	if b.query.orderBy != "" {
-		sb.WriteString(fmt.Sprintf(" ORDER BY %s", b.query.orderBy))
+		terms, err := parseOrderBy(b.query.orderBy)
+		if err != nil {
+			return "", nil, err
+		}
+		w.writeOrderBy(terms)
 	}
 
 	// LIMIT clause
 	if b.query.limit > 0 {
-		sb.WriteString(fmt.Sprintf(" LIMIT %d", b.query.limit))
+		w.WriteString(fmt.Sprintf(" LIMIT %d", b.query.limit))
 	}
 
 	// OFFSET clause
 	if b.query.offset > 0 {
-		sb.WriteString(fmt.Sprintf(" OFFSET %d", b.query.offset))
+		w.WriteString(fmt.Sprintf(" OFFSET %d", b.query.offset))
 	}
 
-	return sb.String(), nil
+	return w.String(), w.args, nil
 }
 
 // EmailMessage represents a complex email message.
@@ -340,6 +435,18 @@ func (b *EmailBuilder) Build() (*EmailMessage, error) {
 	if b.email.body == "" && b.email.htmlBody == "" {
 		return nil, fmt.Errorf("body or HTML body is required")
 	}
+	if err := validateAddresses("from", b.email.from); err != nil {
+		return nil, err
+	}
+	if err := validateAddresses("to", b.email.to...); err != nil {
+		return nil, err
+	}
+	if err := validateAddresses("cc", b.email.cc...); err != nil {
+		return nil, err
+	}
+	if err := validateAddresses("bcc", b.email.bcc...); err != nil {
+		return nil, err
+	}
 
 	// Return copy
 	return &EmailMessage{
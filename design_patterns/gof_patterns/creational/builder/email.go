@@ -0,0 +1,381 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// priorityHeaders maps EmailBuilder priorities to X-Priority and Importance values.
+var priorityHeaders = map[string][2]string{
+	"high":   {"1 (Highest)", "High"},
+	"urgent": {"1 (Highest)", "High"},
+	"normal": {"3 (Normal)", "Normal"},
+	"low":    {"5 (Lowest)", "Low"},
+}
+
+// SMTPConfig holds the settings needed to deliver an EmailMessage.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+
+	// TLSConfig is used for STARTTLS. If nil, a config for Host is used.
+	TLSConfig *tls.Config
+	// DisableStartTLS sends in plain text even if the server offers STARTTLS.
+	DisableStartTLS bool
+	// LocalName is sent in HELO/EHLO. Defaults to "localhost".
+	LocalName string
+}
+
+// validateAddresses parses every address so malformed ones fail at Build time.
+func validateAddresses(field string, addresses ...string) error {
+	for _, addr := range addresses {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid %s address %q: %w", field, addr, err)
+		}
+	}
+	return nil
+}
+
+// formatAddressList renders addresses for a header, encoding display names.
+func formatAddressList(addresses []string) string {
+	formatted := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			continue
+		}
+		formatted = append(formatted, parsed.String())
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// envelopeAddress returns the bare address used in SMTP MAIL and RCPT commands.
+func envelopeAddress(addr string) (string, error) {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Address, nil
+}
+
+// WriteTo writes the message in RFC 5322 / MIME format. Plain and HTML bodies
+// are sent as multipart/alternative; attachments wrap that in multipart/mixed.
+// BCC recipients are never written to the headers.
+func (e *EmailMessage) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	if err := e.writeMessage(bw); err != nil {
+		return cw.n, err
+	}
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+func (e *EmailMessage) writeMessage(w io.Writer) error {
+	headers := []string{
+		"From: " + formatAddressList([]string{e.from}),
+		"To: " + formatAddressList(e.to),
+	}
+	if len(e.cc) > 0 {
+		headers = append(headers, "Cc: "+formatAddressList(e.cc))
+	}
+	headers = append(headers,
+		"Subject: "+mime.QEncoding.Encode("utf-8", e.subject),
+		"Date: "+time.Now().Format(time.RFC1123Z),
+		"Message-ID: "+newMessageID(e.from),
+		"MIME-Version: 1.0",
+	)
+	if values, ok := priorityHeaders[strings.ToLower(e.priority)]; ok {
+		headers = append(headers, "X-Priority: "+values[0], "Importance: "+values[1])
+	}
+	for _, h := range headers {
+		if _, err := io.WriteString(w, h+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	body := e.bodyPart()
+	if len(e.attachments) == 0 {
+		if err := writePartHeader(w, body.header); err != nil {
+			return err
+		}
+		return body.write(w)
+	}
+
+	mixed := multipart.NewWriter(w)
+	if err := writePartHeader(w, textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()})},
+	}); err != nil {
+		return err
+	}
+
+	part, err := mixed.CreatePart(body.header)
+	if err != nil {
+		return err
+	}
+	if err := body.write(part); err != nil {
+		return err
+	}
+
+	for _, path := range e.attachments {
+		if err := writeAttachment(mixed, path); err != nil {
+			return err
+		}
+	}
+	return mixed.Close()
+}
+
+// mimePart is a MIME entity: its headers and a function that writes its content.
+type mimePart struct {
+	header textproto.MIMEHeader
+	write  func(w io.Writer) error
+}
+
+// bodyPart returns the message body: multipart/alternative when both plain and
+// HTML bodies are set, otherwise a single quoted-printable text part.
+func (e *EmailMessage) bodyPart() mimePart {
+	switch {
+	case e.body != "" && e.htmlBody != "":
+		boundary := multipart.NewWriter(io.Discard).Boundary()
+		return mimePart{
+			header: textproto.MIMEHeader{
+				"Content-Type": {mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": boundary})},
+			},
+			write: func(w io.Writer) error {
+				alt := multipart.NewWriter(w)
+				if err := alt.SetBoundary(boundary); err != nil {
+					return err
+				}
+				for _, text := range []mimePart{textPart("text/plain", e.body), textPart("text/html", e.htmlBody)} {
+					part, err := alt.CreatePart(text.header)
+					if err != nil {
+						return err
+					}
+					if err := text.write(part); err != nil {
+						return err
+					}
+				}
+				return alt.Close()
+			},
+		}
+	case e.htmlBody != "":
+		return textPart("text/html", e.htmlBody)
+	default:
+		return textPart("text/plain", e.body)
+	}
+}
+
+// textPart returns a UTF-8, quoted-printable text entity.
+func textPart(contentType, text string) mimePart {
+	return mimePart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		write: func(w io.Writer) error {
+			qp := quotedprintable.NewWriter(w)
+			if _, err := io.WriteString(qp, text); err != nil {
+				return err
+			}
+			return qp.Close()
+		},
+	}
+}
+
+// writePartHeader writes MIME headers in a stable order followed by a blank line.
+func writePartHeader(w io.Writer, header textproto.MIMEHeader) error {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			if _, err := fmt.Fprintf(w, "%s: %s\r\n", key, value); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+// writeAttachment reads the file at path and adds it as a base64 part.
+func writeAttachment(mw *multipart.Writer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read attachment: %w", err)
+	}
+
+	name := filepath.Base(path)
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": name})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	// RFC 2045 limits encoded lines to 76 characters.
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if addr, err := envelopeAddress(from); err == nil {
+		if at := strings.LastIndex(addr, "@"); at >= 0 {
+			domain = addr[at+1:]
+		}
+	}
+	var buf [12]byte
+	rand.Read(buf[:])
+	return fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(buf[:]), time.Now().UnixNano(), domain)
+}
+
+// countingWriter counts bytes written so WriteTo can report them.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Send delivers the message over SMTP to every To, CC and BCC recipient.
+// STARTTLS is used when the server offers it unless disabled in config.
+// Once the server accepts the message data Send reports success, even if
+// ctx ends or QUIT fails afterwards: the message is on its way, and a
+// retry would deliver it twice.
+func (e *EmailMessage) Send(ctx context.Context, config SMTPConfig) error {
+	// Render first so attachment errors surface before talking to the server.
+	var msg bytes.Buffer
+	if _, err := e.WriteTo(&msg); err != nil {
+		return err
+	}
+
+	from, err := envelopeAddress(e.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+	recipients := make([]string, 0, len(e.to)+len(e.cc)+len(e.bcc))
+	for _, addr := range append(append(append([]string{}, e.to...), e.cc...), e.bcc...) {
+		rcpt, err := envelopeAddress(addr)
+		if err != nil {
+			return fmt.Errorf("invalid recipient: %w", err)
+		}
+		recipients = append(recipients, rcpt)
+	}
+
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connect to SMTP server: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	localName := config.LocalName
+	if localName == "" {
+		localName = "localhost"
+	}
+	if err := client.Hello(localName); err != nil {
+		return fmt.Errorf("smtp hello: %w", err)
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !config.DisableStartTLS {
+		tlsConfig := config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: config.Host}
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	if config.Username != "" {
+		auth := smtp.PlainAuth("", config.Username, config.Password, config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", rcpt, err)
+		}
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := msg.WriteTo(data); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+
+	// The server has accepted the message; a failed QUIT only means the
+	// session did not close cleanly.
+	client.Quit()
+	return nil
+}
@@ -0,0 +1,241 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailMessage_WriteTo(t *testing.T) {
+	attachment := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(attachment, []byte("a,b\n1,2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	email, err := NewEmailBuilder().
+		From("Reports <reports@example.com>").
+		To("alice@example.com").
+		CC("bob@example.com").
+		BCC("audit@example.com").
+		Subject("Monthly Report").
+		Body("Plain body").
+		HTMLBody("<p>HTML body</p>").
+		Attachment(attachment).
+		Priority("high").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := email.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if strings.Contains(buf.String(), "audit@example.com") {
+		t.Error("BCC recipient leaked into the message")
+	}
+
+	msg, err := mail.ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if got := msg.Header.Get("X-Priority"); got != "1 (Highest)" {
+		t.Errorf("X-Priority = %q", got)
+	}
+	if got := msg.Header.Get("Cc"); got != "<bob@example.com>" {
+		t.Errorf("Cc = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("body part: %v", err)
+	}
+	mediaType, params, _ = mime.ParseMediaType(body.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("body Content-Type = %q", mediaType)
+	}
+	alt := multipart.NewReader(body, params["boundary"])
+	for _, want := range []string{"Plain body", "<p>HTML body</p>"} {
+		part, err := alt.NextRawPart()
+		if err != nil {
+			t.Fatalf("alternative part: %v", err)
+		}
+		got, _ := io.ReadAll(quotedprintable.NewReader(part))
+		if string(got) != want {
+			t.Errorf("alternative part = %q, want %q", got, want)
+		}
+	}
+
+	file, err := mixed.NextRawPart()
+	if err != nil {
+		t.Fatalf("attachment part: %v", err)
+	}
+	if file.FileName() != "report.csv" {
+		t.Errorf("attachment filename = %q", file.FileName())
+	}
+	content, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, file))
+	if string(content) != "a,b\n1,2\n" {
+		t.Errorf("attachment content = %q", content)
+	}
+}
+
+func TestEmailBuilder_InvalidAddress(t *testing.T) {
+	_, err := NewEmailBuilder().
+		From("sender@example.com").
+		To("not an address").
+		Subject("Test").
+		Body("Test body").
+		Build()
+	if err == nil {
+		t.Error("expected error for malformed recipient")
+	}
+}
+
+// fakeSMTPServer accepts one session and records the envelope and message.
+// With dropQuit it hangs up on QUIT instead of replying.
+type fakeSMTPServer struct {
+	listener   net.Listener
+	from       string
+	recipients []string
+	data       string
+	done       chan struct{}
+}
+
+func startFakeSMTPServer(t *testing.T, dropQuit bool) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		defer close(server.done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				server.from = line[len("MAIL FROM:"):]
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				server.recipients = append(server.recipients, line[len("RCPT TO:"):])
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				server.data = data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				if !dropQuit {
+					reply("221 bye")
+				}
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return server
+}
+
+func TestEmailMessage_Send(t *testing.T) {
+	server := startFakeSMTPServer(t, false)
+	addr := server.listener.Addr().(*net.TCPAddr)
+
+	email, err := NewEmailBuilder().
+		From("sender@example.com").
+		To("alice@example.com").
+		CC("bob@example.com").
+		BCC("audit@example.com").
+		Subject("Hello").
+		Body("Hi there").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := email.Send(ctx, SMTPConfig{Host: "127.0.0.1", Port: addr.Port}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if server.from != "<sender@example.com>" {
+		t.Errorf("MAIL FROM = %q", server.from)
+	}
+	want := []string{"<alice@example.com>", "<bob@example.com>", "<audit@example.com>"}
+	if strings.Join(server.recipients, ",") != strings.Join(want, ",") {
+		t.Errorf("RCPT TO = %v, want %v", server.recipients, want)
+	}
+	if !strings.Contains(server.data, "Subject: Hello") || strings.Contains(server.data, "audit@example.com") {
+		t.Errorf("unexpected message data:\n%s", server.data)
+	}
+}
+
+// TestEmailMessage_SendQuitFails tests that a message the server accepted
+// is reported as sent even if the session then fails.
+func TestEmailMessage_SendQuitFails(t *testing.T) {
+	server := startFakeSMTPServer(t, true)
+	addr := server.listener.Addr().(*net.TCPAddr)
+
+	email, err := NewEmailBuilder().From("sender@example.com").To("alice@example.com").Subject("Hi").Body("x").Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := email.Send(ctx, SMTPConfig{Host: "127.0.0.1", Port: addr.Port}); err != nil {
+		t.Errorf("Expected a sent message to be reported as sent, got %v", err)
+	}
+	<-server.done
+	if !strings.Contains(server.data, "Subject: Hi") {
+		t.Errorf("unexpected message data:\n%s", server.data)
+	}
+}
@@ -165,7 +165,8 @@ func Example4_ComputerBuilder() {
 func Example5_FluentInterface() {
 	fmt.Println("\n=== Example 5: Fluent Interface Demonstration ===")
 
-	fmt.Println("\nFluent interface enables readable, self-documenting code:\n")
+	fmt.Println("\nFluent interface enables readable, self-documenting code:")
+	fmt.Println()
 
 	// Show the fluent interface in action
 	fmt.Println("Building an HTTP request with fluent interface:")
@@ -199,7 +200,8 @@ func Example5_FluentInterface() {
 func Example6_ValidationInBuilder() {
 	fmt.Println("\n=== Example 6: Validation in Builder ===")
 
-	fmt.Println("\nBuilders validate before creating objects:\n")
+	fmt.Println("\nBuilders validate before creating objects:")
+	fmt.Println()
 
 	// Test various validation scenarios
 	testCases := []struct {
@@ -316,7 +318,8 @@ func Example7_BuilderPatternBenefits() {
 
 	// Demonstrate
 	fmt.Println("\n\nPRACTICAL EXAMPLE:")
-	fmt.Println("Building a complex email in one fluent chain:\n")
+	fmt.Println("Building a complex email in one fluent chain:")
+	fmt.Println()
 
 	email, _ := NewEmailBuilder().
 		From("system@company.com").
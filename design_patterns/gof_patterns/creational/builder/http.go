@@ -0,0 +1,122 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	httpbuilder "github.com/jumaniyozov/design_patterns/tier1/builder"
+)
+
+// multipartPart is a form field or file queued for a multipart body.
+type multipartPart struct {
+	field    string
+	filename string
+	content  []byte
+}
+
+// QueryParam adds a URL-encoded query parameter.
+func (b *HTTPRequestBuilder) QueryParam(key, value string) *HTTPRequestBuilder {
+	b.request.query.Add(key, value)
+	return b
+}
+
+// JSON encodes v as the request body and sets the JSON content type.
+func (b *HTTPRequestBuilder) JSON(v any) *HTTPRequestBuilder {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.err = fmt.Errorf("encode JSON body: %w", err)
+		return b
+	}
+	b.Body(string(data))
+	b.request.headers["Content-Type"] = "application/json"
+	return b
+}
+
+// Form encodes values as an application/x-www-form-urlencoded body.
+func (b *HTTPRequestBuilder) Form(values url.Values) *HTTPRequestBuilder {
+	b.Body(values.Encode())
+	b.request.headers["Content-Type"] = "application/x-www-form-urlencoded"
+	return b
+}
+
+// IdempotencyKey sets the Idempotency-Key header, which also lets Execute
+// retry a POST or PATCH.
+func (b *HTTPRequestBuilder) IdempotencyKey(key string) *HTTPRequestBuilder {
+	return b.Header("Idempotency-Key", key)
+}
+
+// MultipartField adds a form field to a multipart/form-data body.
+func (b *HTTPRequestBuilder) MultipartField(name, value string) *HTTPRequestBuilder {
+	b.multipart = append(b.multipart, multipartPart{field: name, content: []byte(value)})
+	return b
+}
+
+// MultipartFile adds a file to a multipart/form-data body.
+func (b *HTTPRequestBuilder) MultipartFile(field, filename string, content []byte) *HTTPRequestBuilder {
+	b.multipart = append(b.multipart, multipartPart{field: field, filename: filename, content: content})
+	return b
+}
+
+// BuildStd constructs the request and converts it to a standard *http.Request.
+func (b *HTTPRequestBuilder) BuildStd(ctx context.Context) (*http.Request, error) {
+	request, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return request.NewRequest(ctx)
+}
+
+// NewRequest converts the HTTPRequest into a standard *http.Request.
+func (r *HTTPRequest) NewRequest(ctx context.Context) (*http.Request, error) {
+	request, err := r.std()
+	if err != nil {
+		return nil, err
+	}
+	return request.NewRequest(ctx)
+}
+
+// Execute sends the request, applying the timeout and redirect policy.
+// GET, HEAD, OPTIONS, PUT and DELETE requests, and any request with an
+// idempotency key, are retried up to retryCount times on network errors and
+// 5xx or 429 responses.
+func (r *HTTPRequest) Execute(ctx context.Context, client *http.Client) (*http.Response, error) {
+	request, err := r.std()
+	if err != nil {
+		return nil, err
+	}
+	return request.Execute(ctx, client)
+}
+
+// std converts r to the production request type in tier1/builder, which
+// encodes, sends and retries requests for both builders.
+func (r *HTTPRequest) std() (*httpbuilder.HTTPRequest, error) {
+	b := httpbuilder.NewHTTPRequestBuilder().
+		WithMethod(r.method).
+		WithURL(r.url).
+		WithHeaders(r.headers).
+		WithTimeout(r.timeout).
+		WithRetries(r.retryCount).
+		WithFollowRedirects(r.followRedirects)
+	for key, values := range r.query {
+		for _, value := range values {
+			b.WithQueryParam(key, value)
+		}
+	}
+	if key := r.headers["Idempotency-Key"]; key != "" {
+		b.WithIdempotencyKey(key)
+	}
+	if r.body != "" {
+		b.WithBody([]byte(r.body))
+	}
+	for _, part := range r.multipart {
+		if part.filename != "" {
+			b.WithMultipartFile(part.field, part.filename, part.content)
+		} else {
+			b.WithMultipartField(part.field, string(part.content))
+		}
+	}
+	return b.Build()
+}
@@ -0,0 +1,132 @@
+package builder
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPRequestBuilder_BuildStd(t *testing.T) {
+	ctx := context.Background()
+
+	req, err := NewHTTPRequestBuilder().
+		URL("https://api.example.com/users?page=1").
+		Method("post").
+		QueryParam("sort", "name").
+		JSON(map[string]string{"name": "Ann"}).
+		BuildStd(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "POST" {
+		t.Errorf("Expected POST, got %s", req.Method)
+	}
+	if got := req.URL.Query().Encode(); got != "page=1&sort=name" {
+		t.Errorf("Expected merged query page=1&sort=name, got %s", got)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %q", ct)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"name":"Ann"}` {
+		t.Errorf("Expected JSON body, got %s", body)
+	}
+
+	req, err = NewHTTPRequestBuilder().
+		URL("https://api.example.com/login").
+		Method("POST").
+		Form(url.Values{"user": {"ann"}}).
+		BuildStd(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "user=ann" {
+		t.Errorf("Expected form body, got %s", body)
+	}
+
+	if _, err := NewHTTPRequestBuilder().URL("https://api.example.com").JSON(func() {}).BuildStd(ctx); err == nil {
+		t.Error("Expected an error for a value JSON cannot encode")
+	}
+}
+
+func TestHTTPRequestBuilder_Multipart(t *testing.T) {
+	request, err := NewHTTPRequestBuilder().
+		URL("https://api.example.com/upload").
+		Method("POST").
+		Body("replaced").
+		MultipartField("title", "report").
+		MultipartFile("file", "report.csv", []byte("a,b\n1,2\n")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(request.String(), "multipart form, 2 parts") {
+		t.Errorf("Expected String to describe the multipart body, got:\n%s", request)
+	}
+
+	req, err := request.NewRequest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("Expected multipart/form-data, got %q (%v)", mediaType, err)
+	}
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.FormValue("title"); got != "report" {
+		t.Errorf("Expected title=report, got %q", got)
+	}
+	if files := req.MultipartForm.File["file"]; len(files) != 1 || files[0].Filename != "report.csv" {
+		t.Errorf("Expected report.csv upload, got %v", files)
+	}
+}
+
+func TestHTTPRequest_ExecuteRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name, method, key string
+		want              int32
+	}{
+		{"PUT", "PUT", "", 3},
+		{"POST", "POST", "", 1},
+		{"POST with idempotency key", "POST", "order-42", 3},
+	}
+	for _, tt := range tests {
+		calls.Store(0)
+		builder := NewHTTPRequestBuilder().
+			URL(server.URL).
+			Method(tt.method).
+			Body("payload").
+			RetryCount(2)
+		if tt.key != "" {
+			builder.IdempotencyKey(tt.key)
+		}
+		request, err := builder.Build()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp, err := request.Execute(context.Background(), server.Client())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503, got %d", tt.name, resp.StatusCode)
+		}
+		if got := calls.Load(); got != tt.want {
+			t.Errorf("%s: expected %d attempts, got %d", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,611 @@
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes how a database spells placeholders and quotes identifiers.
+type Dialect interface {
+	// Placeholder returns the placeholder for the n-th argument, starting at 1.
+	Placeholder(n int) string
+	// QuoteIdent quotes a single identifier part (no dots).
+	QuoteIdent(name string) string
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Placeholder(int) string { return "?" }
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(int) string { return "?" }
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Supported dialects.
+var (
+	Postgres Dialect = postgresDialect{}
+	MySQL    Dialect = mysqlDialect{}
+	SQLite   Dialect = sqliteDialect{}
+)
+
+// identPattern matches plain, optionally qualified identifiers such as
+// "users" or "u.id", with an optional alias ("users u", "users AS u").
+// Anything else (expressions, "*") is written as-is.
+var identPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)(?:\s+(?:(?i:AS)\s+)?([A-Za-z_][A-Za-z0-9_]*))?$`)
+
+// sqlWriter accumulates SQL text and bound arguments for one statement.
+type sqlWriter struct {
+	strings.Builder
+	dialect Dialect
+	args    []any
+}
+
+func newSQLWriter(dialect Dialect) *sqlWriter {
+	return &sqlWriter{dialect: dialect}
+}
+
+// bind records an argument and writes its placeholder.
+func (w *sqlWriter) bind(value any) {
+	w.args = append(w.args, value)
+	if w.dialect == nil {
+		w.WriteString("?")
+		return
+	}
+	w.WriteString(w.dialect.Placeholder(len(w.args)))
+}
+
+// writeIdent writes an identifier, quoting it when a dialect is set.
+func (w *sqlWriter) writeIdent(name string) {
+	match := identPattern.FindStringSubmatch(name)
+	if w.dialect == nil || match == nil {
+		w.WriteString(name)
+		return
+	}
+	for i, part := range strings.Split(match[1], ".") {
+		if i > 0 {
+			w.WriteString(".")
+		}
+		w.WriteString(w.dialect.QuoteIdent(part))
+	}
+	if match[2] != "" {
+		w.WriteString(" " + w.dialect.QuoteIdent(match[2]))
+	}
+}
+
+func (w *sqlWriter) writeIdents(names []string) {
+	for i, name := range names {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.writeIdent(name)
+	}
+}
+
+// writeClause writes keyword followed by predicates joined with AND.
+func (w *sqlWriter) writeClause(keyword string, predicates []Predicate) error {
+	if len(predicates) == 0 {
+		return nil
+	}
+	w.WriteString(keyword)
+	return writeJoined(w, " AND ", predicates)
+}
+
+// writeJoined writes predicates separated by sep, parenthesising nested
+// groups, and raw SQL when there is more than one predicate, so an OR inside
+// it cannot bind to its neighbours.
+func writeJoined(w *sqlWriter, sep string, predicates []Predicate) error {
+	for i, p := range predicates {
+		if i > 0 {
+			w.WriteString(sep)
+		}
+		var parens bool
+		switch p := p.(type) {
+		case group:
+			parens = len(p.predicates) > 1
+		case rawCondition, rawPredicate:
+			parens = len(predicates) > 1
+		}
+		if parens {
+			w.WriteString("(")
+			if err := p.writeSQL(w); err != nil {
+				return err
+			}
+			w.WriteString(")")
+			continue
+		}
+		if err := p.writeSQL(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Predicate is a condition that renders to SQL with bound arguments.
+type Predicate interface {
+	writeSQL(w *sqlWriter) error
+}
+
+// rawCondition is a legacy, verbatim condition added through Where.
+type rawCondition string
+
+func (c rawCondition) writeSQL(w *sqlWriter) error {
+	w.WriteString(string(c))
+	return nil
+}
+
+// comparison compares a column to a bound value.
+type comparison struct {
+	column string
+	op     string
+	value  any
+}
+
+func (c comparison) writeSQL(w *sqlWriter) error {
+	w.writeIdent(c.column)
+	w.WriteString(" " + c.op + " ")
+	w.bind(c.value)
+	return nil
+}
+
+// Eq matches rows where column equals value.
+func Eq(column string, value any) Predicate { return comparison{column, "=", value} }
+
+// NotEq matches rows where column differs from value.
+func NotEq(column string, value any) Predicate { return comparison{column, "<>", value} }
+
+// Lt matches rows where column is less than value.
+func Lt(column string, value any) Predicate { return comparison{column, "<", value} }
+
+// Lte matches rows where column is at most value.
+func Lte(column string, value any) Predicate { return comparison{column, "<=", value} }
+
+// Gt matches rows where column is greater than value.
+func Gt(column string, value any) Predicate { return comparison{column, ">", value} }
+
+// Gte matches rows where column is at least value.
+func Gte(column string, value any) Predicate { return comparison{column, ">=", value} }
+
+// Like matches rows where column matches a LIKE pattern.
+func Like(column string, pattern string) Predicate { return comparison{column, "LIKE", pattern} }
+
+type inPredicate struct {
+	column string
+	values []any
+}
+
+func (p inPredicate) writeSQL(w *sqlWriter) error {
+	if len(p.values) == 0 {
+		// IN () is invalid SQL; an empty set matches nothing.
+		w.WriteString("1 = 0")
+		return nil
+	}
+	w.writeIdent(p.column)
+	w.WriteString(" IN (")
+	for i, v := range p.values {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.bind(v)
+	}
+	w.WriteString(")")
+	return nil
+}
+
+// In matches rows where column is one of values.
+func In(column string, values ...any) Predicate { return inPredicate{column, values} }
+
+type betweenPredicate struct {
+	column    string
+	low, high any
+}
+
+func (p betweenPredicate) writeSQL(w *sqlWriter) error {
+	w.writeIdent(p.column)
+	w.WriteString(" BETWEEN ")
+	w.bind(p.low)
+	w.WriteString(" AND ")
+	w.bind(p.high)
+	return nil
+}
+
+// Between matches rows where column is within [low, high].
+func Between(column string, low, high any) Predicate { return betweenPredicate{column, low, high} }
+
+type nullPredicate struct {
+	column string
+	not    bool
+}
+
+func (p nullPredicate) writeSQL(w *sqlWriter) error {
+	w.writeIdent(p.column)
+	if p.not {
+		w.WriteString(" IS NOT NULL")
+	} else {
+		w.WriteString(" IS NULL")
+	}
+	return nil
+}
+
+// IsNull matches rows where column is NULL.
+func IsNull(column string) Predicate { return nullPredicate{column: column} }
+
+// IsNotNull matches rows where column is not NULL.
+func IsNotNull(column string) Predicate { return nullPredicate{column: column, not: true} }
+
+type columnComparison struct {
+	left, right string
+}
+
+func (c columnComparison) writeSQL(w *sqlWriter) error {
+	w.writeIdent(c.left)
+	w.WriteString(" = ")
+	w.writeIdent(c.right)
+	return nil
+}
+
+// EqCol compares two columns, e.g. for JOIN conditions.
+func EqCol(left, right string) Predicate { return columnComparison{left, right} }
+
+// group combines predicates with AND or OR.
+type group struct {
+	op         string
+	predicates []Predicate
+}
+
+func (g group) writeSQL(w *sqlWriter) error {
+	if len(g.predicates) == 0 {
+		return fmt.Errorf("empty %s group", strings.TrimSpace(g.op))
+	}
+	return writeJoined(w, g.op, g.predicates)
+}
+
+// And matches rows satisfying all predicates.
+func And(predicates ...Predicate) Predicate { return group{" AND ", predicates} }
+
+// Or matches rows satisfying any predicate.
+func Or(predicates ...Predicate) Predicate { return group{" OR ", predicates} }
+
+type notPredicate struct {
+	inner Predicate
+}
+
+func (p notPredicate) writeSQL(w *sqlWriter) error {
+	w.WriteString("NOT (")
+	if err := p.inner.writeSQL(w); err != nil {
+		return err
+	}
+	w.WriteString(")")
+	return nil
+}
+
+// Not negates a predicate.
+func Not(predicate Predicate) Predicate { return notPredicate{predicate} }
+
+type rawPredicate struct {
+	sql  string
+	args []any
+}
+
+func (p rawPredicate) writeSQL(w *sqlWriter) error {
+	placeholders := rawPlaceholders(p.sql)
+	if n := len(placeholders); n != len(p.args) {
+		return fmt.Errorf("raw predicate %q has %d placeholders but %d args", p.sql, n, len(p.args))
+	}
+	last := 0
+	for i, at := range placeholders {
+		w.WriteString(p.sql[last:at])
+		w.bind(p.args[i])
+		last = at + 1
+	}
+	w.WriteString(p.sql[last:])
+	return nil
+}
+
+// rawPlaceholders returns the offsets of the "?" placeholders in sql,
+// skipping any inside quoted strings and identifiers. A doubled quote
+// inside a quoted run leaves and re-enters it, so it needs no special case.
+func rawPlaceholders(sql string) []int {
+	var offsets []int
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
+
+// Raw is an escape hatch for SQL the typed predicates cannot express.
+// Each "?" in sql, outside quoted strings and identifiers, is replaced with
+// the dialect's placeholder for the matching arg.
+func Raw(sql string, args ...any) Predicate { return rawPredicate{sql, args} }
+
+// orderTerm is one column of an ORDER BY clause.
+type orderTerm struct {
+	column    string
+	direction string // "ASC", "DESC" or empty
+}
+
+// columnPattern matches a plain, optionally qualified column name.
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// parseOrderBy splits "name ASC, created_at DESC" into terms. Each term is
+// a column name and an optional direction; anything else is refused, since
+// the column is written into the query.
+func parseOrderBy(orderBy string) ([]orderTerm, error) {
+	var terms []orderTerm
+	for _, part := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 || len(fields) > 2 || !columnPattern.MatchString(fields[0]) {
+			return nil, fmt.Errorf("invalid ORDER BY term %q", strings.TrimSpace(part))
+		}
+		term := orderTerm{column: fields[0]}
+		if len(fields) == 2 {
+			term.direction = strings.ToUpper(fields[1])
+			if term.direction != "ASC" && term.direction != "DESC" {
+				return nil, fmt.Errorf("invalid ORDER BY direction %q", fields[1])
+			}
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+func (w *sqlWriter) writeOrderBy(terms []orderTerm) {
+	w.WriteString(" ORDER BY ")
+	for i, term := range terms {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.writeIdent(term.column)
+		if term.direction != "" {
+			w.WriteString(" " + term.direction)
+		}
+	}
+}
+
+// joinClause is either a legacy raw JOIN or a typed join.
+type joinClause struct {
+	raw   string
+	kind  string
+	table string
+	on    Predicate
+}
+
+func (j joinClause) writeSQL(w *sqlWriter) error {
+	if j.raw != "" {
+		w.WriteString(j.raw)
+		return nil
+	}
+	w.WriteString(j.kind + " ")
+	w.writeIdent(j.table)
+	w.WriteString(" ON ")
+	return writeJoined(w, " AND ", []Predicate{j.on})
+}
+
+// InsertBuilder builds parameterized INSERT statements.
+type InsertBuilder struct {
+	table     string
+	columns   []string
+	rows      [][]any
+	returning []string
+	dialect   Dialect
+}
+
+// NewInsertBuilder creates a new INSERT builder.
+func NewInsertBuilder() *InsertBuilder {
+	return &InsertBuilder{}
+}
+
+// Into sets the target table.
+func (b *InsertBuilder) Into(table string) *InsertBuilder {
+	b.table = table
+	return b
+}
+
+// Columns sets the inserted columns.
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// Values adds a row of values, one per column.
+func (b *InsertBuilder) Values(values ...any) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// Returning adds a RETURNING clause (Postgres and SQLite).
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Dialect sets the SQL dialect.
+func (b *InsertBuilder) Dialect(dialect Dialect) *InsertBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// ToSQL constructs the INSERT statement and its arguments.
+func (b *InsertBuilder) ToSQL() (string, []any, error) {
+	if b.table == "" {
+		return "", nil, fmt.Errorf("table name is required")
+	}
+	if len(b.columns) == 0 {
+		return "", nil, fmt.Errorf("at least one column is required")
+	}
+	if len(b.rows) == 0 {
+		return "", nil, fmt.Errorf("at least one row of values is required")
+	}
+
+	w := newSQLWriter(b.dialect)
+	w.WriteString("INSERT INTO ")
+	w.writeIdent(b.table)
+	w.WriteString(" (")
+	w.writeIdents(b.columns)
+	w.WriteString(") VALUES ")
+
+	for i, row := range b.rows {
+		if len(row) != len(b.columns) {
+			return "", nil, fmt.Errorf("row %d has %d values, expected %d", i+1, len(row), len(b.columns))
+		}
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString("(")
+		for j, v := range row {
+			if j > 0 {
+				w.WriteString(", ")
+			}
+			w.bind(v)
+		}
+		w.WriteString(")")
+	}
+
+	if len(b.returning) > 0 {
+		w.WriteString(" RETURNING ")
+		w.writeIdents(b.returning)
+	}
+
+	return w.String(), w.args, nil
+}
+
+// assignment is a single "column = value" in an UPDATE.
+type assignment struct {
+	column string
+	value  any
+}
+
+// UpdateBuilder builds parameterized UPDATE statements.
+type UpdateBuilder struct {
+	table   string
+	sets    []assignment
+	where   []Predicate
+	dialect Dialect
+}
+
+// NewUpdateBuilder creates a new UPDATE builder.
+func NewUpdateBuilder() *UpdateBuilder {
+	return &UpdateBuilder{}
+}
+
+// Table sets the target table.
+func (b *UpdateBuilder) Table(table string) *UpdateBuilder {
+	b.table = table
+	return b
+}
+
+// Set assigns value to column.
+func (b *UpdateBuilder) Set(column string, value any) *UpdateBuilder {
+	b.sets = append(b.sets, assignment{column, value})
+	return b
+}
+
+// Where adds WHERE predicates.
+func (b *UpdateBuilder) Where(predicates ...Predicate) *UpdateBuilder {
+	b.where = append(b.where, predicates...)
+	return b
+}
+
+// Dialect sets the SQL dialect.
+func (b *UpdateBuilder) Dialect(dialect Dialect) *UpdateBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// ToSQL constructs the UPDATE statement and its arguments.
+func (b *UpdateBuilder) ToSQL() (string, []any, error) {
+	if b.table == "" {
+		return "", nil, fmt.Errorf("table name is required")
+	}
+	if len(b.sets) == 0 {
+		return "", nil, fmt.Errorf("at least one SET is required")
+	}
+
+	w := newSQLWriter(b.dialect)
+	w.WriteString("UPDATE ")
+	w.writeIdent(b.table)
+	w.WriteString(" SET ")
+	for i, set := range b.sets {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.writeIdent(set.column)
+		w.WriteString(" = ")
+		w.bind(set.value)
+	}
+	if err := w.writeClause(" WHERE ", b.where); err != nil {
+		return "", nil, err
+	}
+
+	return w.String(), w.args, nil
+}
+
+// DeleteBuilder builds parameterized DELETE statements.
+type DeleteBuilder struct {
+	table   string
+	where   []Predicate
+	dialect Dialect
+}
+
+// NewDeleteBuilder creates a new DELETE builder.
+func NewDeleteBuilder() *DeleteBuilder {
+	return &DeleteBuilder{}
+}
+
+// From sets the target table.
+func (b *DeleteBuilder) From(table string) *DeleteBuilder {
+	b.table = table
+	return b
+}
+
+// Where adds WHERE predicates.
+func (b *DeleteBuilder) Where(predicates ...Predicate) *DeleteBuilder {
+	b.where = append(b.where, predicates...)
+	return b
+}
+
+// Dialect sets the SQL dialect.
+func (b *DeleteBuilder) Dialect(dialect Dialect) *DeleteBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// ToSQL constructs the DELETE statement and its arguments.
+func (b *DeleteBuilder) ToSQL() (string, []any, error) {
+	if b.table == "" {
+		return "", nil, fmt.Errorf("table name is required")
+	}
+
+	w := newSQLWriter(b.dialect)
+	w.WriteString("DELETE FROM ")
+	w.writeIdent(b.table)
+	if err := w.writeClause(" WHERE ", b.where); err != nil {
+		return "", nil, err
+	}
+
+	return w.String(), w.args, nil
+}
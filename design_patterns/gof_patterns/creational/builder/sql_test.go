@@ -0,0 +1,226 @@
+package builder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func checkSQL(t *testing.T, name, gotSQL string, gotArgs []any, err error, wantSQL string, wantArgs ...any) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("%s: ToSQL: %v", name, err)
+		return
+	}
+	if gotSQL != wantSQL {
+		t.Errorf("%s:\n got %s\nwant %s", name, gotSQL, wantSQL)
+	}
+	if len(gotArgs) != 0 || len(wantArgs) != 0 {
+		if !reflect.DeepEqual(gotArgs, wantArgs) {
+			t.Errorf("%s: args = %v, want %v", name, gotArgs, wantArgs)
+		}
+	}
+}
+
+func TestSQLQueryBuilder_Dialects(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"none", nil, "SELECT u.id, u.name FROM users AS u WHERE u.id = ? AND u.role IN (?, ?) ORDER BY u.name DESC LIMIT 10"},
+		{"postgres", Postgres, `SELECT "u"."id", "u"."name" FROM "users" "u" WHERE "u"."id" = $1 AND "u"."role" IN ($2, $3) ORDER BY "u"."name" DESC LIMIT 10`},
+		{"mysql", MySQL, "SELECT `u`.`id`, `u`.`name` FROM `users` `u` WHERE `u`.`id` = ? AND `u`.`role` IN (?, ?) ORDER BY `u`.`name` DESC LIMIT 10"},
+		{"sqlite", SQLite, `SELECT "u"."id", "u"."name" FROM "users" "u" WHERE "u"."id" = ? AND "u"."role" IN (?, ?) ORDER BY "u"."name" DESC LIMIT 10`},
+	}
+	for _, tt := range tests {
+		query, args, err := NewQueryBuilder().
+			Select("u.id", "u.name").
+			From("users AS u").
+			WhereExpr(Eq("u.id", 7), In("u.role", "admin", "ops")).
+			OrderBy("u.name desc").
+			Limit(10).
+			Dialect(tt.dialect).
+			ToSQL()
+		checkSQL(t, tt.name, query, args, err, tt.want, 7, "admin", "ops")
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		predicate Predicate
+		want      string
+		args      []any
+	}{
+		{"Eq", Eq("a", 1), `"a" = $1`, []any{1}},
+		{"NotEq", NotEq("a", 1), `"a" <> $1`, []any{1}},
+		{"Lt", Lt("a", 1), `"a" < $1`, []any{1}},
+		{"Lte", Lte("a", 1), `"a" <= $1`, []any{1}},
+		{"Gt", Gt("a", 1), `"a" > $1`, []any{1}},
+		{"Gte", Gte("a", 1), `"a" >= $1`, []any{1}},
+		{"Like", Like("name", "J%"), `"name" LIKE $1`, []any{"J%"}},
+		{"In", In("id", 1, 2, 3), `"id" IN ($1, $2, $3)`, []any{1, 2, 3}},
+		{"empty In", In("id"), `1 = 0`, nil},
+		{"Between", Between("age", 18, 30), `"age" BETWEEN $1 AND $2`, []any{18, 30}},
+		{"IsNull", IsNull("deleted_at"), `"deleted_at" IS NULL`, nil},
+		{"IsNotNull", IsNotNull("deleted_at"), `"deleted_at" IS NOT NULL`, nil},
+		{"EqCol", EqCol("o.user_id", "u.id"), `"o"."user_id" = "u"."id"`, nil},
+		{"And", And(Eq("a", 1), Eq("b", 2)), `("a" = $1 AND "b" = $2)`, []any{1, 2}},
+		{"Or", Or(Eq("a", 1), And(Eq("b", 2), Eq("c", 3))), `("a" = $1 OR ("b" = $2 AND "c" = $3))`, []any{1, 2, 3}},
+		{"Not", Not(Or(Eq("a", 1), Eq("b", 2))), `NOT ("a" = $1 OR "b" = $2)`, []any{1, 2}},
+		{"Raw", Raw("lower(email) = ?", "a@b.c"), `lower(email) = $1`, []any{"a@b.c"}},
+		{"Raw quoted", Raw(`name = '?' AND "?" = ? AND note = 'it''s ?'`, 1), `name = '?' AND "?" = $1 AND note = 'it''s ?'`, []any{1}},
+	}
+	for _, tt := range tests {
+		query, args, err := NewQueryBuilder().From("t").WhereExpr(tt.predicate).Dialect(Postgres).ToSQL()
+		checkSQL(t, tt.name, query, args, err, `SELECT * FROM "t" WHERE `+tt.want, tt.args...)
+	}
+
+	for name, p := range map[string]Predicate{
+		"empty And":       And(),
+		"empty Or":        Or(),
+		"too many args":   Raw("a = ?", 1, 2),
+		"quoted ? is not": Raw("a = '?'", 1),
+	} {
+		if _, _, err := NewQueryBuilder().From("t").WhereExpr(p).ToSQL(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSQLQueryBuilder_RawConditionsAreParenthesised(t *testing.T) {
+	query, args, err := NewQueryBuilder().
+		From("t").
+		Where("a = 1 OR b = 2").
+		WhereExpr(Eq("tenant", 5), Raw("c = ? OR d = ?", 3, 4)).
+		Dialect(Postgres).
+		ToSQL()
+	checkSQL(t, "mixed", query, args, err,
+		`SELECT * FROM "t" WHERE (a = 1 OR b = 2) AND "tenant" = $1 AND (c = $2 OR d = $3)`, 5, 3, 4)
+
+	query, _, err = NewQueryBuilder().From("t").Where("a = 1 OR b = 2").ToSQL()
+	checkSQL(t, "alone", query, nil, err, "SELECT * FROM t WHERE a = 1 OR b = 2")
+}
+
+func TestSQLQueryBuilder_OrderBy(t *testing.T) {
+	query, _, err := NewQueryBuilder().From("t").OrderBy("name ASC, t.created_at desc, id").Dialect(MySQL).ToSQL()
+	checkSQL(t, "order", query, nil, err, "SELECT * FROM `t` ORDER BY `name` ASC, `t`.`created_at` DESC, `id`")
+
+	for _, orderBy := range []string{
+		"name; DROP TABLE users",
+		"name ASC NULLS FIRST",
+		"(SELECT 1)",
+		"name SIDEWAYS",
+		"name,",
+	} {
+		if _, _, err := NewQueryBuilder().From("t").OrderBy(orderBy).ToSQL(); err == nil {
+			t.Errorf("OrderBy(%q): expected an error", orderBy)
+		}
+	}
+}
+
+func TestSQLQueryBuilder_GroupByHaving(t *testing.T) {
+	query, args, err := NewQueryBuilder().
+		Select("u.id", "COUNT(*)").
+		From("users u").
+		InnerJoin("orders o", EqCol("o.user_id", "u.id")).
+		WhereExpr(Eq("u.active", true)).
+		GroupBy("u.id").
+		Having(Raw("COUNT(*) > ?", 5), Lt("SUM(o.total)", 100)).
+		Dialect(Postgres).
+		ToSQL()
+	checkSQL(t, "group", query, args, err,
+		`SELECT "u"."id", COUNT(*) FROM "users" "u" INNER JOIN "orders" "o" ON "o"."user_id" = "u"."id"`+
+			` WHERE "u"."active" = $1 GROUP BY "u"."id" HAVING (COUNT(*) > $2) AND SUM(o.total) < $3`,
+		true, 5, 100)
+
+	if _, _, err := NewQueryBuilder().From("t").Having(Gt("n", 1)).ToSQL(); err == nil ||
+		!strings.Contains(err.Error(), "GROUP BY") {
+		t.Errorf("Expected HAVING without GROUP BY to fail, got %v", err)
+	}
+}
+
+func TestInsertBuilder_ToSQL(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Postgres, `INSERT INTO "users" ("name", "email") VALUES ($1, $2), ($3, $4) RETURNING "id"`},
+		{MySQL, "INSERT INTO `users` (`name`, `email`) VALUES (?, ?), (?, ?) RETURNING `id`"},
+		{SQLite, `INSERT INTO "users" ("name", "email") VALUES (?, ?), (?, ?) RETURNING "id"`},
+	}
+	for _, tt := range tests {
+		query, args, err := NewInsertBuilder().
+			Into("users").
+			Columns("name", "email").
+			Values("Ann", "ann@example.com").
+			Values("Bob", "bob@example.com").
+			Returning("id").
+			Dialect(tt.dialect).
+			ToSQL()
+		checkSQL(t, "insert", query, args, err, tt.want, "Ann", "ann@example.com", "Bob", "bob@example.com")
+	}
+
+	for name, b := range map[string]*InsertBuilder{
+		"no table":   NewInsertBuilder().Columns("a").Values(1),
+		"no columns": NewInsertBuilder().Into("t").Values(1),
+		"no rows":    NewInsertBuilder().Into("t").Columns("a"),
+		"short row":  NewInsertBuilder().Into("t").Columns("a", "b").Values(1),
+	} {
+		if _, _, err := b.ToSQL(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestUpdateBuilder_ToSQL(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Postgres, `UPDATE "users" SET "email" = $1, "active" = $2 WHERE "id" = $3`},
+		{MySQL, "UPDATE `users` SET `email` = ?, `active` = ? WHERE `id` = ?"},
+		{SQLite, `UPDATE "users" SET "email" = ?, "active" = ? WHERE "id" = ?`},
+	}
+	for _, tt := range tests {
+		query, args, err := NewUpdateBuilder().
+			Table("users").
+			Set("email", "new@example.com").
+			Set("active", false).
+			Where(Eq("id", 9)).
+			Dialect(tt.dialect).
+			ToSQL()
+		checkSQL(t, "update", query, args, err, tt.want, "new@example.com", false, 9)
+	}
+
+	if _, _, err := NewUpdateBuilder().Set("a", 1).ToSQL(); err == nil {
+		t.Error("Expected an error without a table")
+	}
+	if _, _, err := NewUpdateBuilder().Table("t").ToSQL(); err == nil {
+		t.Error("Expected an error without SET")
+	}
+}
+
+func TestDeleteBuilder_ToSQL(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Postgres, `DELETE FROM "sessions" WHERE ("expires_at" < $1 OR "revoked" = $2)`},
+		{MySQL, "DELETE FROM `sessions` WHERE (`expires_at` < ? OR `revoked` = ?)"},
+		{SQLite, `DELETE FROM "sessions" WHERE ("expires_at" < ? OR "revoked" = ?)`},
+	}
+	for _, tt := range tests {
+		query, args, err := NewDeleteBuilder().
+			From("sessions").
+			Where(Or(Lt("expires_at", 100), Eq("revoked", true))).
+			Dialect(tt.dialect).
+			ToSQL()
+		checkSQL(t, "delete", query, args, err, tt.want, 100, true)
+	}
+
+	if _, _, err := NewDeleteBuilder().ToSQL(); err == nil {
+		t.Error("Expected an error without a table")
+	}
+}
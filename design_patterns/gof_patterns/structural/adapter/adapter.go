@@ -5,14 +5,15 @@ package adapter
 
 import (
 	"fmt"
-	"strings"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
 // PaymentProcessor is our target interface that all payment gateways should implement.
 type PaymentProcessor interface {
-	ProcessPayment(amount float64, currency string) (string, error)
-	RefundPayment(transactionID string, amount float64) error
+	ProcessPayment(amount payment.Money) (string, error)
+	RefundPayment(transactionID string, amount payment.Money) error
 	GetProviderName() string
 }
 
@@ -22,14 +23,14 @@ type StripeAPI struct {
 }
 
 // Charge is Stripe's method (different name and parameters).
-func (s *StripeAPI) Charge(amountCents int, curr string, token string) (string, error) {
+func (s *StripeAPI) Charge(amountCents int64, curr string, token string) (string, error) {
 	txID := fmt.Sprintf("stripe_tx_%d", time.Now().Unix())
 	fmt.Printf("[Stripe] Charging %d cents in %s\n", amountCents, curr)
 	return txID, nil
 }
 
 // CreateRefund is Stripe's refund method.
-func (s *StripeAPI) CreateRefund(chargeID string, amountCents int) error {
+func (s *StripeAPI) CreateRefund(chargeID string, amountCents int64) error {
 	fmt.Printf("[Stripe] Refunding %d cents for charge %s\n", amountCents, chargeID)
 	return nil
 }
@@ -48,15 +49,13 @@ func NewStripeAdapter(apiKey string) PaymentProcessor {
 	}
 }
 
-func (s *StripeAdapter) ProcessPayment(amount float64, currency string) (string, error) {
-	// Adapt: convert dollars to cents, call Stripe's Charge method
-	amountCents := int(amount * 100)
-	return s.stripe.Charge(amountCents, strings.ToUpper(currency), s.token)
+func (s *StripeAdapter) ProcessPayment(amount payment.Money) (string, error) {
+	// Adapt: Stripe wants minor units, which Money already holds
+	return s.stripe.Charge(amount.Amount(), amount.Currency().Code, s.token)
 }
 
-func (s *StripeAdapter) RefundPayment(transactionID string, amount float64) error {
-	amountCents := int(amount * 100)
-	return s.stripe.CreateRefund(transactionID, amountCents)
+func (s *StripeAdapter) RefundPayment(transactionID string, amount payment.Money) error {
+	return s.stripe.CreateRefund(transactionID, amount.Amount())
 }
 
 func (s *StripeAdapter) GetProviderName() string {
@@ -96,17 +95,17 @@ func NewPayPalAdapter(clientID string) PaymentProcessor {
 	}
 }
 
-func (p *PayPalAdapter) ProcessPayment(amount float64, currency string) (string, error) {
+func (p *PayPalAdapter) ProcessPayment(amount payment.Money) (string, error) {
 	// Adapt: call PayPal's MakePayment and extract transaction ID
-	result, err := p.paypal.MakePayment(amount, currency, "paypal_account")
+	result, err := p.paypal.MakePayment(amount.Float64(), amount.Currency().Code, "paypal_account")
 	if err != nil {
 		return "", err
 	}
 	return result["transaction_id"].(string), nil
 }
 
-func (p *PayPalAdapter) RefundPayment(transactionID string, amount float64) error {
-	return p.paypal.IssueRefund(transactionID, amount)
+func (p *PayPalAdapter) RefundPayment(transactionID string, amount payment.Money) error {
+	return p.paypal.IssueRefund(transactionID, amount.Float64())
 }
 
 func (p *PayPalAdapter) GetProviderName() string {
@@ -120,7 +119,7 @@ type SquareAPI struct {
 
 // CreatePayment is Square's payment method (yet another different interface).
 func (s *SquareAPI) CreatePayment(amountMoney map[string]interface{}, sourceID string) (string, error) {
-	amount := amountMoney["amount"].(int)
+	amount := amountMoney["amount"].(int64)
 	currency := amountMoney["currency"].(string)
 	txID := fmt.Sprintf("square_tx_%d", time.Now().Unix())
 	fmt.Printf("[Square] Creating payment of %d %s\n", amount, currency)
@@ -129,41 +128,79 @@ func (s *SquareAPI) CreatePayment(amountMoney map[string]interface{}, sourceID s
 
 // RefundTransaction is Square's refund method.
 func (s *SquareAPI) RefundTransaction(paymentID string, amountMoney map[string]interface{}) error {
-	amount := amountMoney["amount"].(int)
+	amount := amountMoney["amount"].(int64)
 	currency := amountMoney["currency"].(string)
 	fmt.Printf("[Square] Refunding %d %s for payment %s\n", amount, currency, paymentID)
 	return nil
 }
 
 // SquareAdapter adapts SquareAPI to PaymentProcessor interface.
+// A Square location only charges in its own currency, so the adapter converts
+// other currencies using its rate provider.
 type SquareAdapter struct {
 	square   *SquareAPI
 	sourceID string
+	currency string
+	rates    payment.RateProvider
 }
 
-// NewSquareAdapter creates a new Square adapter.
+// NewSquareAdapter creates a new Square adapter for a USD location.
 func NewSquareAdapter(locationID string) PaymentProcessor {
+	return NewSquareAdapterWithRates(locationID, "USD", nil)
+}
+
+// NewSquareAdapterWithRates creates a Square adapter for a location charging
+// in currency. Payments in other currencies are converted with rates; with
+// nil rates they are rejected.
+func NewSquareAdapterWithRates(locationID, currency string, rates payment.RateProvider) PaymentProcessor {
 	return &SquareAdapter{
 		square:   &SquareAPI{locationID: locationID},
 		sourceID: "cnon:card-nonce-ok",
+		currency: currency,
+		rates:    rates,
+	}
+}
+
+func (s *SquareAdapter) ProcessPayment(amount payment.Money) (string, error) {
+	// Adapt: convert to the location's currency and create the money map Square expects
+	local, err := s.toLocal(amount)
+	if err != nil {
+		return "", err
 	}
+	return s.square.CreatePayment(squareMoney(local), s.sourceID)
 }
 
-func (s *SquareAdapter) ProcessPayment(amount float64, currency string) (string, error) {
-	// Adapt: create the money map Square expects
-	amountMoney := map[string]interface{}{
-		"amount":   int(amount * 100), // Square uses cents
-		"currency": strings.ToUpper(currency),
+// RefundPayment refunds amount, converted at the current rate if needed.
+func (s *SquareAdapter) RefundPayment(transactionID string, amount payment.Money) error {
+	local, err := s.toLocal(amount)
+	if err != nil {
+		return err
+	}
+	return s.square.RefundTransaction(transactionID, squareMoney(local))
+}
+
+// toLocal converts amount to the location's currency.
+func (s *SquareAdapter) toLocal(amount payment.Money) (payment.Money, error) {
+	if amount.Currency().Code == s.currency {
+		return amount, nil
 	}
-	return s.square.CreatePayment(amountMoney, s.sourceID)
+	if s.rates == nil {
+		return payment.Money{}, fmt.Errorf("square: location only accepts %s, got %s", s.currency, amount.Currency().Code)
+	}
+	rate, err := s.rates.Rate(amount.Currency().Code, s.currency)
+	if err != nil {
+		return payment.Money{}, fmt.Errorf("square: %w", err)
+	}
+	fmt.Printf("[Square] Converting %s at %s\n", amount, rate)
+	return rate.Convert(amount)
 }
 
-func (s *SquareAdapter) RefundPayment(transactionID string, amount float64) error {
-	amountMoney := map[string]interface{}{
-		"amount":   int(amount * 100),
-		"currency": "USD",
+// squareMoney converts amount to Square's money map (minor units plus currency).
+func squareMoney(amount payment.Money) map[string]interface{} {
+	return map[string]interface{}{
+		"amount":   amount.Amount(),
+		"currency": amount.Currency().Code,
 	}
-	return s.square.RefundTransaction(transactionID, amountMoney)
 }
 
 func (s *SquareAdapter) GetProviderName() string {
@@ -271,11 +308,11 @@ func NewMockPaymentProcessor() PaymentProcessor {
 	return &MockPaymentProcessor{}
 }
 
-func (m *MockPaymentProcessor) ProcessPayment(amount float64, currency string) (string, error) {
+func (m *MockPaymentProcessor) ProcessPayment(amount payment.Money) (string, error) {
 	return "mock_transaction_12345", nil
 }
 
-func (m *MockPaymentProcessor) RefundPayment(transactionID string, amount float64) error {
+func (m *MockPaymentProcessor) RefundPayment(transactionID string, amount payment.Money) error {
 	return nil
 }
 
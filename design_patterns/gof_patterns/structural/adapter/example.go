@@ -1,6 +1,10 @@
 package adapter
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
 
 // Example1_PaymentProcessors demonstrates adapting multiple payment gateways.
 func Example1_PaymentProcessors() {
@@ -18,7 +22,7 @@ func Example1_PaymentProcessors() {
 	for _, processor := range processors {
 		fmt.Printf("\n%s:\n", processor.GetProviderName())
 
-		txID, err := processor.ProcessPayment(50.00, "USD")
+		txID, err := processor.ProcessPayment(payment.MustParseMoney("50.00", "USD"))
 		if err != nil {
 			fmt.Printf("  Error: %v\n", err)
 			continue
@@ -27,7 +31,7 @@ func Example1_PaymentProcessors() {
 		fmt.Printf("  Transaction successful: %s\n", txID)
 
 		// Process a refund
-		err = processor.RefundPayment(txID, 10.00)
+		err = processor.RefundPayment(txID, payment.MustParseMoney("10.00", "USD"))
 		if err != nil {
 			fmt.Printf("  Refund error: %v\n", err)
 			continue
@@ -42,10 +46,10 @@ func Example2_UniformInterface() {
 	fmt.Println("\n=== Example 2: Uniform Interface Benefits ===")
 
 	// Function that works with any PaymentProcessor
-	processOrder := func(processor PaymentProcessor, amount float64) {
+	processOrder := func(processor PaymentProcessor, amount payment.Money) {
 		fmt.Printf("\nProcessing order with %s\n", processor.GetProviderName())
 
-		txID, err := processor.ProcessPayment(amount, "USD")
+		txID, err := processor.ProcessPayment(amount)
 		if err != nil {
 			fmt.Printf("Payment failed: %v\n", err)
 			return
@@ -57,9 +61,9 @@ func Example2_UniformInterface() {
 
 	// Same function works with all adapters
 	fmt.Println("\nProcessing multiple orders:")
-	processOrder(NewStripeAdapter("key1"), 99.99)
-	processOrder(NewPayPalAdapter("key2"), 149.99)
-	processOrder(NewSquareAdapter("key3"), 79.99)
+	processOrder(NewStripeAdapter("key1"), payment.MustParseMoney("99.99", "USD"))
+	processOrder(NewPayPalAdapter("key2"), payment.MustParseMoney("149.99", "USD"))
+	processOrder(NewSquareAdapter("key3"), payment.MustParseMoney("79.99", "USD"))
 
 	fmt.Println("\nNotice: The processOrder function doesn't know or care")
 	fmt.Println("which payment gateway is being used. It just uses the interface!")
@@ -96,7 +100,7 @@ func Example3_RuntimeSelection() {
 		processor := createProcessor(preferredProvider)
 
 		fmt.Printf("\n%s (prefers %s):\n", user, preferredProvider)
-		txID, err := processor.ProcessPayment(25.00, "USD")
+		txID, err := processor.ProcessPayment(payment.MustParseMoney("25.00", "USD"))
 
 		if err != nil {
 			fmt.Printf("  Error: %v\n", err)
@@ -143,10 +147,10 @@ func Example5_AdapterComposition() {
 		logger    Logger
 	}
 
-	processOrder := func(service OrderService, amount float64) {
-		service.logger.Info(fmt.Sprintf("Processing order for $%.2f", amount))
+	processOrder := func(service OrderService, amount payment.Money) {
+		service.logger.Info(fmt.Sprintf("Processing order for %s", amount.Format()))
 
-		txID, err := service.processor.ProcessPayment(amount, "USD")
+		txID, err := service.processor.ProcessPayment(amount)
 		if err != nil {
 			service.logger.Error(fmt.Sprintf("Payment failed: %v", err))
 			return
@@ -171,7 +175,7 @@ func Example5_AdapterComposition() {
 	fmt.Println("\nDifferent service configurations:")
 	for i, service := range services {
 		fmt.Printf("\nConfiguration %d:\n", i+1)
-		processOrder(service, 100.00)
+		processOrder(service, payment.MustParseMoney("100.00", "USD"))
 	}
 
 	fmt.Println("\nThis demonstrates how adapters enable flexible composition")
@@ -219,7 +223,7 @@ func Example6_AdapterBenefits() {
 
 	for _, p := range processors {
 		fmt.Printf("\nUsing %s:\n", p.GetProviderName())
-		txID, _ := p.ProcessPayment(10.00, "USD")
+		txID, _ := p.ProcessPayment(payment.MustParseMoney("10.00", "USD"))
 		fmt.Printf("  Transaction: %s\n", txID)
 	}
 
@@ -0,0 +1,301 @@
+// Package yamlsubset reads the small subset of YAML that the policy files
+// and layered settings files in this repository need: block mappings and
+// sequences, "- key: value" items, flow sequences of scalars such as
+// [select, insert], quoted and plain scalars, and comments. Anchors, tags,
+// flow mappings and multi-line scalars are rejected rather than misread.
+package yamlsubset
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scalar is a scalar as written in the document, with quotes removed.
+type Scalar struct {
+	Text   string
+	Quoted bool
+}
+
+// Value resolves a plain scalar to true, false, nil or a float64 where it
+// reads as one, and returns every other scalar as a string.
+func (s Scalar) Value() any {
+	if s.Quoted {
+		return s.Text
+	}
+	switch s.Text {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "~":
+		return nil
+	}
+	if f, err := strconv.ParseFloat(s.Text, 64); err == nil {
+		return f
+	}
+	return s.Text
+}
+
+// Parse decodes data into map[string]any, []any and Scalar values, leaving
+// callers to decide how scalars are typed. A key with nothing after it and
+// nothing nested under it is nil. An empty document is an empty mapping.
+func Parse(data []byte) (any, error) {
+	lines, err := splitLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+	p := &parser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+// Decode parses data like Parse and resolves every scalar with
+// Scalar.Value, so the result is ready to re-encode as JSON.
+func Decode(data []byte) (any, error) {
+	v, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return resolve(v), nil
+}
+
+func resolve(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = resolve(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = resolve(item)
+		}
+	case Scalar:
+		return v.Value()
+	}
+	return v
+}
+
+type srcLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+func splitLines(src string) ([]srcLine, error) {
+	var lines []srcLine
+	for i, raw := range strings.Split(src, "\n") {
+		text := strings.TrimRight(StripComment(raw), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("yaml line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, srcLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	return lines, nil
+}
+
+// StripComment cuts a # comment that starts a line or follows a space,
+// unless it is inside quotes. The TOML settings reader shares it.
+func StripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+type parser struct {
+	lines []srcLine
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	num := p.lines[len(p.lines)-1].num
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	}
+	return fmt.Errorf("yaml line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or sequence whose lines start at indent.
+func (p *parser) block(indent int) (any, error) {
+	if isSequenceItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+// nested parses the block indented under a key or "-" at parent, or returns
+// nil if there is none.
+func (p *parser) nested(parent int) (any, error) {
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > parent {
+		return p.block(p.lines[p.pos].indent)
+	}
+	return nil, nil
+}
+
+func (p *parser) sequence(indent int) ([]any, error) {
+	seq := []any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].text) {
+		line := &p.lines[p.pos]
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+
+		var v any
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			v, err = p.nested(indent)
+		case hasKey(rest):
+			// "- key: value" opens a mapping whose keys line up with key;
+			// reparse this line as its first entry.
+			line.indent += len(line.text) - len(rest)
+			line.text = rest
+			v, err = p.mapping(line.indent)
+		default:
+			v, err = p.scalar(rest)
+			p.pos++
+		}
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+	}
+	return seq, nil
+}
+
+func (p *parser) mapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		key, rest, ok := splitKey(line.text)
+		if !ok {
+			return nil, p.errorf("expected \"key: value\", got %q", line.text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		var v any
+		var err error
+		if rest != "" {
+			v, err = p.scalar(rest)
+			p.pos++
+		} else {
+			p.pos++
+			v, err = p.value(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return m, nil
+}
+
+// value parses what follows a "key:" with nothing after it at indent.
+func (p *parser) value(indent int) (any, error) {
+	// A sequence may sit at the same indentation as its key.
+	if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.nested(indent)
+}
+
+func hasKey(text string) bool {
+	_, _, ok := splitKey(text)
+	return ok
+}
+
+// splitKey splits "key: value" or "key:" into its key and value.
+func splitKey(text string) (key, rest string, ok bool) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, after := text[1:end+1], text[end+2:]
+		if after != ":" && !strings.HasPrefix(after, ": ") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(after[1:]), true
+	}
+	if text[0] == '[' {
+		return "", "", false
+	}
+	i := strings.Index(text+" ", ": ")
+	if i <= 0 {
+		return "", "", false
+	}
+	return text[:i], strings.TrimSpace(text[i+1:]), true
+}
+
+// scalar parses a value on the current line.
+func (p *parser) scalar(s string) (any, error) {
+	switch s[0] {
+	case '[':
+		if !strings.HasSuffix(s, "]") {
+			return nil, p.errorf("unterminated flow sequence")
+		}
+		items := []any{}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" || item[0] == '[' {
+				return nil, p.errorf("unsupported flow sequence %s", s)
+			}
+			v, err := p.scalar(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case '{', '|', '>', '&', '*', '!':
+		return nil, p.errorf("unsupported YAML syntax %q (quote it if it is a string)", s)
+	case '"':
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, p.errorf("bad double-quoted string %s", s)
+		}
+		return Scalar{Text: v, Quoted: true}, nil
+	case '\'':
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, p.errorf("bad single-quoted string %s", s)
+		}
+		return Scalar{Text: strings.ReplaceAll(s[1:len(s)-1], "''", "'"), Quoted: true}, nil
+	}
+	return Scalar{Text: s}, nil
+}
@@ -0,0 +1,78 @@
+package yamlsubset
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `# settings
+server:
+  port: 8080 # inline comment
+  host: "127.0.0.1"
+  name: 'it''s'
+tags: [a, "b"]
+empty:
+rules:
+  - actions: [select]
+    resources: ['#1']
+  - plain
+`
+	got, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"server": map[string]any{
+			"port": Scalar{Text: "8080"},
+			"host": Scalar{Text: "127.0.0.1", Quoted: true},
+			"name": Scalar{Text: "it's", Quoted: true},
+		},
+		"tags":  []any{Scalar{Text: "a"}, Scalar{Text: "b", Quoted: true}},
+		"empty": nil,
+		"rules": []any{
+			map[string]any{
+				"actions":   []any{Scalar{Text: "select"}},
+				"resources": []any{Scalar{Text: "#1", Quoted: true}},
+			},
+			Scalar{Text: "plain"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	got, err := Decode([]byte("a: true\nb: 1.5\nc: ~\nd: \"true\"\ne: text\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"a": true, "b": 1.5, "c": nil, "d": "true", "e": "text"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name, src, errText string
+	}{
+		{"tab indentation", "a:\n\tb: 1\n", "line 2: tabs"},
+		{"alias", "a: *ref\n", "quote it"},
+		{"flow mapping", "a: {b: 1}\n", "unsupported YAML syntax"},
+		{"duplicate key", "a: 1\na: 2\n", `duplicate key "a"`},
+		{"bad indentation", "a:\n    b: 1\n  c: 2\n", "line 3"},
+		{"not a mapping", "a: 1\nplain\n", `expected "key: value"`},
+		{"unterminated quote", "a: 'b\n", "bad single-quoted string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("Expected an error containing %q, got %v", tt.errText, err)
+			}
+		})
+	}
+}
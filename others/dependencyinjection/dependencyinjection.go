@@ -6,6 +6,8 @@ package dependencyinjection
 import (
 	"errors"
 	"fmt"
+
+	"github.com/jumaniyozov/design_patterns/cache"
 )
 
 // Database interface abstracts data storage
@@ -95,20 +97,20 @@ func (c *ConsoleLogger) Debug(message string) {
 
 // MemoryCache is an in-memory cache implementation
 type MemoryCache struct {
-	data map[string]interface{}
+	data *cache.Cache[string, interface{}]
 }
 
-func NewMemoryCache() *MemoryCache {
-	return &MemoryCache{data: make(map[string]interface{})}
+// NewMemoryCache creates a bounded cache to inject where a Cache is needed.
+func NewMemoryCache(opts ...cache.Option) *MemoryCache {
+	return &MemoryCache{data: cache.NewBounded[string, interface{}](opts...)}
 }
 
 func (m *MemoryCache) Get(key string) (interface{}, bool) {
-	val, exists := m.data[key]
-	return val, exists
+	return m.data.Get(key)
 }
 
 func (m *MemoryCache) Set(key string, value interface{}) {
-	m.data[key] = value
+	m.data.Set(key, value)
 }
 
 // UserService demonstrates constructor injection
@@ -0,0 +1,39 @@
+package dependencyinjection
+
+import "testing"
+
+// countingDB is a Database stand-in that counts queries.
+type countingDB struct{ queries int }
+
+func (d *countingDB) Query(query string) ([]map[string]interface{}, error) {
+	d.queries++
+	return []map[string]interface{}{{"id": 1, "name": "John Doe"}}, nil
+}
+
+func (d *countingDB) Execute(command string) error { return nil }
+
+type nopLogger struct{}
+
+func (nopLogger) Info(message string)  {}
+func (nopLogger) Error(message string) {}
+func (nopLogger) Debug(message string) {}
+
+// TestUserService_GetUser tests that UserService answers a repeated lookup
+// from the injected cache instead of the injected database.
+func TestUserService_GetUser(t *testing.T) {
+	db := &countingDB{}
+	service := NewUserService(db, nopLogger{}, NewMemoryCache())
+
+	for range 2 {
+		user, err := service.GetUser("1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user["name"] != "John Doe" {
+			t.Errorf("Expected John Doe, got %v", user["name"])
+		}
+	}
+	if db.queries != 1 {
+		t.Errorf("Expected 1 database query, got %d", db.queries)
+	}
+}
@@ -4,9 +4,14 @@
 package interceptingfilter
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/ratelimit"
 )
 
 // Request represents a request
@@ -114,28 +119,50 @@ func (c *CompressionFilter) Execute(req *Request, res *Response, chain *FilterCh
 	}
 }
 
-// RateLimitFilter limits request rate
+// RateLimitFilter limits request rate per client
 type RateLimitFilter struct {
-	requestCount int
-	maxRequests  int
+	limiter ratelimit.Limiter
+	key     func(req *Request) string
 }
 
+// NewRateLimitFilter allows each client maxRequests requests per minute
 func NewRateLimitFilter(maxRequests int) *RateLimitFilter {
-	return &RateLimitFilter{
-		requestCount: 0,
-		maxRequests:  maxRequests,
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerMinute(maxRequests))
+	if err != nil {
+		panic(err)
 	}
+	return NewRateLimitFilterWith(limiter, ClientKey)
+}
+
+// NewRateLimitFilterWith limits requests with any limiter, under the key
+// that key returns for each request
+func NewRateLimitFilterWith(limiter ratelimit.Limiter, key func(req *Request) string) *RateLimitFilter {
+	return &RateLimitFilter{limiter: limiter, key: key}
+}
+
+// ClientKey identifies a client by its X-Client-ID header
+func ClientKey(req *Request) string {
+	return req.Headers["X-Client-ID"]
 }
 
 func (r *RateLimitFilter) Execute(req *Request, res *Response, chain *FilterChain) {
-	r.requestCount++
-	if r.requestCount > r.maxRequests {
+	d, err := ratelimit.Allow(context.Background(), r.limiter, r.key(req))
+	if err != nil {
+		res.StatusCode = 500
+		res.Body = "Internal Server Error"
+		fmt.Printf("[RateLimitFilter] Limiter failed: %v\n", err)
+		return
+	}
+	res.Headers["X-RateLimit-Limit"] = strconv.Itoa(d.Limit)
+	res.Headers["X-RateLimit-Remaining"] = strconv.Itoa(d.Remaining)
+	if !d.Allowed {
 		res.StatusCode = 429
 		res.Body = "Too Many Requests"
+		res.Headers["Retry-After"] = strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds())))
 		fmt.Println("[RateLimitFilter] Rate limit exceeded")
 		return
 	}
-	fmt.Printf("[RateLimitFilter] Request %d/%d\n", r.requestCount, r.maxRequests)
+	fmt.Printf("[RateLimitFilter] Request %d/%d\n", d.Limit-d.Remaining, d.Limit)
 	chain.Execute(req, res)
 }
 
@@ -0,0 +1,98 @@
+package interceptingfilter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jumaniyozov/design_patterns/ratelimit"
+)
+
+// okTarget answers 200 and keeps the headers filters set.
+type okTarget struct{ calls int }
+
+func (t *okTarget) Execute(req *Request, res *Response) {
+	t.calls++
+	res.StatusCode = 200
+}
+
+// failingLimiter always fails, like a limiter whose store is unreachable.
+type failingLimiter struct{}
+
+func (failingLimiter) AllowN(ctx context.Context, key string, n int) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("store unavailable")
+}
+
+func newRequest(client string) *Request {
+	return &Request{Method: "GET", Path: "/api", Headers: map[string]string{"X-Client-ID": client}}
+}
+
+func TestRateLimitFilter(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerMinute(2), ratelimit.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := &okTarget{}
+	manager := NewFilterManager(target)
+	manager.AddFilter(NewRateLimitFilterWith(limiter, ClientKey))
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		res := manager.Process(newRequest("alice"))
+		if res.StatusCode != 200 {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, res.StatusCode)
+		}
+		if res.Headers["X-RateLimit-Limit"] != "2" || res.Headers["X-RateLimit-Remaining"] != wantRemaining {
+			t.Errorf("Request %d: expected limit 2 and remaining %s, got %v", i+1, wantRemaining, res.Headers)
+		}
+	}
+
+	res := manager.Process(newRequest("alice"))
+	if res.StatusCode != 429 {
+		t.Fatalf("Expected 429 once the limit is used, got %d", res.StatusCode)
+	}
+	if got := res.Headers["Retry-After"]; got != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", got)
+	}
+	if target.calls != 2 {
+		t.Errorf("Expected the target to run twice, got %d", target.calls)
+	}
+
+	if res := manager.Process(newRequest("bob")); res.StatusCode != 200 {
+		t.Errorf("Expected another client to have its own limit, got %d", res.StatusCode)
+	}
+
+	now = now.Add(30 * time.Second)
+	if res := manager.Process(newRequest("alice")); res.StatusCode != 200 {
+		t.Errorf("Expected a request after Retry-After to pass, got %d", res.StatusCode)
+	}
+}
+
+func TestRateLimitFilter_LimiterError(t *testing.T) {
+	target := &okTarget{}
+	manager := NewFilterManager(target)
+	manager.AddFilter(NewRateLimitFilterWith(failingLimiter{}, ClientKey))
+
+	res := manager.Process(newRequest("alice"))
+	if res.StatusCode != 500 {
+		t.Errorf("Expected 500 when the limiter fails, got %d", res.StatusCode)
+	}
+	if target.calls != 0 {
+		t.Errorf("Expected the target not to run, got %d calls", target.calls)
+	}
+}
+
+func TestNewRateLimitFilter(t *testing.T) {
+	manager := NewFilterManager(&okTarget{})
+	manager.AddFilter(NewRateLimitFilter(3))
+
+	for i := range 3 {
+		if res := manager.Process(newRequest("alice")); res.StatusCode != 200 {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, res.StatusCode)
+		}
+	}
+	if res := manager.Process(newRequest("alice")); res.StatusCode != 429 {
+		t.Errorf("Expected the fourth request in a minute to be limited, got %d", res.StatusCode)
+	}
+}
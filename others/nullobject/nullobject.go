@@ -3,7 +3,11 @@
 // and preventing nil pointer errors.
 package nullobject
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/jumaniyozov/design_patterns/cache"
+)
 
 // Logger interface
 type Logger interface {
@@ -41,26 +45,27 @@ type Cache interface {
 	Delete(key string)
 }
 
-// MemoryCache is a real cache implementation
+// MemoryCache is a real cache implementation. Unlike NullCache it holds on
+// to what it is given.
 type MemoryCache struct {
-	data map[string]interface{}
+	data *cache.Cache[string, interface{}]
 }
 
-func NewMemoryCache() *MemoryCache {
-	return &MemoryCache{data: make(map[string]interface{})}
+// NewMemoryCache creates a MemoryCache; opts are passed to cache.NewBounded.
+func NewMemoryCache(opts ...cache.Option) *MemoryCache {
+	return &MemoryCache{data: cache.NewBounded[string, interface{}](opts...)}
 }
 
 func (m *MemoryCache) Get(key string) (interface{}, bool) {
-	val, exists := m.data[key]
-	return val, exists
+	return m.data.Get(key)
 }
 
 func (m *MemoryCache) Set(key string, value interface{}) {
-	m.data[key] = value
+	m.data.Set(key, value)
 }
 
 func (m *MemoryCache) Delete(key string) {
-	delete(m.data, key)
+	m.data.Delete(key)
 }
 
 // NullCache is a no-op cache (null object)
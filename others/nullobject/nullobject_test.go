@@ -0,0 +1,26 @@
+package nullobject
+
+import "testing"
+
+// TestNewService_NullObjects tests that a Service given nil dependencies
+// falls back to null objects and still works, and that a MemoryCache
+// keeps what the NullCache drops.
+func TestNewService_NullObjects(t *testing.T) {
+	service := NewService(nil, nil)
+	if _, ok := service.cache.(*NullCache); !ok {
+		t.Fatalf("Expected a NullCache, got %T", service.cache)
+	}
+	if got := service.DoWork("a"); got != "Result for a" {
+		t.Errorf("Expected Result for a, got %q", got)
+	}
+	if _, ok := service.cache.Get("a"); ok {
+		t.Error("Expected NullCache to keep nothing")
+	}
+
+	memory := NewMemoryCache()
+	service = NewService(&NullLogger{}, memory)
+	service.DoWork("a")
+	if v, ok := memory.Get("a"); !ok || v != "Result for a" {
+		t.Errorf("Expected the result to be cached, got %v, %v", v, ok)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+
+	"github.com/jumaniyozov/design_patterns/cache"
 )
 
 // ServiceLocator is a central registry for services
@@ -94,21 +96,23 @@ func (m *MockDatabase) Query(sql string) ([]map[string]interface{}, error) {
 	}, nil
 }
 
+// MemoryCache is an in-memory cache service. A located service lives as
+// long as the program, so it is bounded.
 type MemoryCache struct {
-	data map[string]interface{}
+	data *cache.Cache[string, interface{}]
 }
 
-func NewMemoryCache() *MemoryCache {
-	return &MemoryCache{data: make(map[string]interface{})}
+// NewMemoryCache creates the cache service with cache.NewBounded.
+func NewMemoryCache(opts ...cache.Option) *MemoryCache {
+	return &MemoryCache{data: cache.NewBounded[string, interface{}](opts...)}
 }
 
 func (m *MemoryCache) Get(key string) (interface{}, bool) {
-	val, exists := m.data[key]
-	return val, exists
+	return m.data.Get(key)
 }
 
 func (m *MemoryCache) Set(key string, value interface{}) {
-	m.data[key] = value
+	m.data.Set(key, value)
 }
 
 // Global service locator instance (anti-pattern, but shown for completeness)
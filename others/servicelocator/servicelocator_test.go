@@ -0,0 +1,29 @@
+package servicelocator
+
+import "testing"
+
+type nopLogger struct{}
+
+func (nopLogger) Log(message string) {}
+
+// TestApplication_DoWork tests that Application finds its services through
+// the locator and fails when one is missing.
+func TestApplication_DoWork(t *testing.T) {
+	locator := NewServiceLocator()
+	locator.Register("logger", nopLogger{})
+	locator.Register("database", &MockDatabase{})
+	app := NewApplication(locator)
+
+	if err := app.DoWork(); err == nil {
+		t.Error("Expected an error without a cache service")
+	}
+
+	memory := NewMemoryCache()
+	locator.Register("cache", memory)
+	if err := app.DoWork(); err != nil {
+		t.Fatal(err)
+	}
+	if results, ok := memory.Get("last_query"); !ok || len(results.([]map[string]interface{})) != 1 {
+		t.Errorf("Expected the query results in the located cache, got %v, %v", results, ok)
+	}
+}
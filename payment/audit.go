@@ -0,0 +1,94 @@
+package payment
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Status is the outcome recorded in an audit entry.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusRejected marks requests the ledger refused before reaching the
+	// provider, such as over-refunds.
+	StatusRejected Status = "rejected"
+	// StatusReplayed marks retries answered from the idempotency store.
+	StatusReplayed Status = "replayed"
+)
+
+// AuditEntry records one charge or refund attempt.
+type AuditEntry struct {
+	Sequence       int64     `json:"sequence"`
+	Time           time.Time `json:"time"`
+	Operation      Operation `json:"operation"`
+	Status         Status    `json:"status"`
+	Processor      string    `json:"processor,omitempty"`
+	TransactionID  string    `json:"transaction_id,omitempty"`
+	Amount         Money     `json:"amount"`
+	IdempotencyKey string    `json:"idempotency_key,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// AuditLog is an append-only record of payment activity. Implementations
+// assign the entry's Sequence.
+type AuditLog interface {
+	Append(entry AuditEntry) error
+}
+
+// MemoryAuditLog keeps audit entries in memory.
+type MemoryAuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// NewMemoryAuditLog creates an empty in-memory audit log.
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+// Append implements AuditLog.
+func (l *MemoryAuditLog) Append(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Sequence = int64(len(l.entries)) + 1
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries returns a copy of the entries in append order.
+func (l *MemoryAuditLog) Entries() []AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]AuditEntry(nil), l.entries...)
+}
+
+// JSONAuditLog writes each entry as a JSON line, e.g. to an append-only file.
+type JSONAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	seq int64
+}
+
+// NewJSONAuditLog creates an audit log that writes to w.
+func NewJSONAuditLog(w io.Writer) *JSONAuditLog {
+	return &JSONAuditLog{enc: json.NewEncoder(w)}
+}
+
+// Append implements AuditLog.
+func (l *JSONAuditLog) Append(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Sequence = l.seq + 1
+	if err := l.enc.Encode(entry); err != nil {
+		return err
+	}
+	l.seq++
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package payment holds the types shared by the payment examples in
+// tier1/factory, tier1/strategy and the adapter packages: the Money value
+// type, idempotency keys, an append-only audit log and a ledger that
+// validates refunds against the original charge.
+package payment
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRequestInProgress is returned when a request with the same
+	// idempotency key is still being processed.
+	ErrRequestInProgress = errors.New("request with this idempotency key is in progress")
+	// ErrKeyReused is returned when an idempotency key is reused for a
+	// different operation or amount.
+	ErrKeyReused = errors.New("idempotency key reused with different parameters")
+)
+
+// Operation identifies what an idempotency record or audit entry describes.
+type Operation string
+
+const (
+	OperationCharge Operation = "charge"
+	OperationRefund Operation = "refund"
+)
+
+// Record is the stored outcome of a completed request.
+type Record struct {
+	Key           string
+	Operation     Operation
+	TransactionID string
+	Amount        Money
+	CreatedAt     time.Time
+}
+
+// matches reports whether a retry carries the same parameters as the original.
+func (r Record) matches(op Operation, transactionID string, amount Money) bool {
+	if r.Operation != op || !r.Amount.Equal(amount) {
+		return false
+	}
+	// Charges learn their transaction ID on completion; refunds name it up front.
+	return op == OperationCharge || r.TransactionID == transactionID
+}
+
+// IdempotencyStore persists idempotency keys. Implementations must make
+// Begin atomic so only one caller can own a key at a time.
+type IdempotencyStore interface {
+	// Begin reserves key. If the key already completed it returns the stored
+	// record and true. If another caller holds the reservation it returns
+	// ErrRequestInProgress.
+	Begin(key string) (Record, bool, error)
+	// Complete stores the outcome for a reserved key.
+	Complete(record Record) error
+	// Abort releases a reservation so the request can be retried.
+	Abort(key string) error
+}
+
+// MemoryStore is an in-process IdempotencyStore and TransactionStore.
+type MemoryStore struct {
+	mu           sync.Mutex
+	records      map[string]Record
+	inflight     map[string]struct{}
+	transactions map[string]Transaction
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records:      make(map[string]Record),
+		inflight:     make(map[string]struct{}),
+		transactions: make(map[string]Transaction),
+	}
+}
+
+// Begin implements IdempotencyStore.
+func (s *MemoryStore) Begin(key string) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok {
+		return record, true, nil
+	}
+	if _, ok := s.inflight[key]; ok {
+		return Record{}, false, ErrRequestInProgress
+	}
+	s.inflight[key] = struct{}{}
+	return Record{}, false, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *MemoryStore) Complete(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inflight, record.Key)
+	s.records[record.Key] = record
+	return nil
+}
+
+// Abort implements IdempotencyStore.
+func (s *MemoryStore) Abort(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inflight, key)
+	return nil
+}
@@ -0,0 +1,270 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInvalidAmount is returned for non-positive charge or refund amounts.
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrUnknownTransaction is returned when refunding a transaction the
+	// ledger did not record.
+	ErrUnknownTransaction = errors.New("unknown transaction")
+	// ErrOverRefund is returned when a refund exceeds the amount still refundable.
+	ErrOverRefund = errors.New("refund exceeds remaining charge amount")
+	// ErrAlreadyRefunded is returned when refunding a fully refunded transaction.
+	ErrAlreadyRefunded = errors.New("transaction already fully refunded")
+)
+
+// Ledger wraps charges and refunds with idempotency keys, refund validation
+// and an audit trail. It is safe for concurrent use.
+type Ledger struct {
+	store        IdempotencyStore
+	transactions TransactionStore
+	audit        AuditLog
+	now          func() time.Time
+}
+
+// LedgerOption configures a Ledger.
+type LedgerOption func(*Ledger)
+
+// WithStore sets the idempotency store. Defaults to a MemoryStore. If store
+// is also a TransactionStore and WithTransactionStore is not given, the
+// ledger keeps its transactions there as well.
+func WithStore(store IdempotencyStore) LedgerOption {
+	return func(l *Ledger) {
+		l.store = store
+	}
+}
+
+// WithTransactionStore sets where the ledger keeps the transactions it
+// validates refunds against. Without it the idempotency store is used if it
+// can hold transactions, and a new MemoryStore otherwise.
+func WithTransactionStore(store TransactionStore) LedgerOption {
+	return func(l *Ledger) {
+		l.transactions = store
+	}
+}
+
+// WithAuditLog sets the audit log. Defaults to a MemoryAuditLog.
+func WithAuditLog(audit AuditLog) LedgerOption {
+	return func(l *Ledger) {
+		l.audit = audit
+	}
+}
+
+// NewLedger creates a ledger with in-memory storage unless configured otherwise.
+func NewLedger(opts ...LedgerOption) *Ledger {
+	l := &Ledger{
+		store: NewMemoryStore(),
+		audit: NewMemoryAuditLog(),
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.transactions == nil {
+		if store, ok := l.store.(TransactionStore); ok {
+			l.transactions = store
+		} else {
+			l.transactions = NewMemoryStore()
+		}
+	}
+	return l
+}
+
+// AuditLog returns the ledger's audit log.
+func (l *Ledger) AuditLog() AuditLog {
+	return l.audit
+}
+
+// Charge runs charge at most once per idempotency key. A retry with a key
+// that already succeeded returns the original transaction ID without calling
+// charge again. An empty key disables deduplication. Every attempt is audited.
+func (l *Ledger) Charge(key, processor string, amount Money, charge func() (string, error)) (string, error) {
+	entry := AuditEntry{Operation: OperationCharge, Processor: processor, Amount: amount, IdempotencyKey: key}
+	if !amount.IsPositive() {
+		return "", l.reject(entry, ErrInvalidAmount)
+	}
+
+	if key != "" {
+		record, done, err := l.store.Begin(key)
+		if err != nil {
+			return "", l.reject(entry, err)
+		}
+		if done {
+			if !record.matches(OperationCharge, "", amount) {
+				return "", l.reject(entry, ErrKeyReused)
+			}
+			entry.TransactionID = record.TransactionID
+			entry.Status = StatusReplayed
+			return record.TransactionID, l.append(entry)
+		}
+	}
+
+	transactionID, err := charge()
+	if err != nil {
+		if key != "" {
+			l.store.Abort(key)
+		}
+		entry.Status = StatusFailed
+		entry.Error = err.Error()
+		l.append(entry)
+		return "", err
+	}
+
+	// The charge went through, so its key is completed even if the
+	// transaction cannot be stored; releasing it would let a retry charge
+	// twice.
+	txErr := l.transactions.Create(Transaction{
+		ID:        transactionID,
+		Processor: processor,
+		Amount:    amount,
+		Refunded:  FromMinor(0, amount.Currency()),
+		Pending:   FromMinor(0, amount.Currency()),
+	})
+
+	if key != "" {
+		if err := l.store.Complete(Record{
+			Key:           key,
+			Operation:     OperationCharge,
+			TransactionID: transactionID,
+			Amount:        amount,
+			CreatedAt:     l.now(),
+		}); err != nil {
+			return transactionID, fmt.Errorf("store idempotency key: %w", err)
+		}
+	}
+	if txErr != nil {
+		return transactionID, fmt.Errorf("store transaction: %w", txErr)
+	}
+
+	entry.TransactionID = transactionID
+	entry.Status = StatusSucceeded
+	return transactionID, l.append(entry)
+}
+
+// Refund validates the refund against the original charge and runs refund.
+// Refunds of unknown transactions, refunds larger than the remaining amount
+// and refunds of fully refunded transactions are rejected without calling
+// refund. A non-empty key makes retries of the same refund a no-op.
+func (l *Ledger) Refund(key, transactionID string, amount Money, refund func() error) error {
+	entry := AuditEntry{Operation: OperationRefund, TransactionID: transactionID, Amount: amount, IdempotencyKey: key}
+	if !amount.IsPositive() {
+		return l.reject(entry, ErrInvalidAmount)
+	}
+
+	if key != "" {
+		record, done, err := l.store.Begin(key)
+		if err != nil {
+			return l.reject(entry, err)
+		}
+		if done {
+			if !record.matches(OperationRefund, transactionID, amount) {
+				return l.reject(entry, ErrKeyReused)
+			}
+			entry.Status = StatusReplayed
+			return l.append(entry)
+		}
+	}
+
+	tx, err := l.reserveRefund(transactionID, amount)
+	if err != nil {
+		if key != "" {
+			l.store.Abort(key)
+		}
+		return l.reject(entry, err)
+	}
+	entry.Processor = tx.Processor
+
+	err = refund()
+
+	// Same currency as the reservation, and bounded by the charge, so the
+	// arithmetic cannot fail; the store still can.
+	_, storeErr := l.transactions.Update(transactionID, func(tx *Transaction) error {
+		tx.Pending, _ = tx.Pending.Sub(amount)
+		if err == nil {
+			tx.Refunded, _ = tx.Refunded.Add(amount)
+		}
+		return nil
+	})
+	if err == nil && storeErr != nil {
+		err = fmt.Errorf("store transaction: %w", storeErr)
+	}
+
+	if err != nil {
+		if key != "" {
+			l.store.Abort(key)
+		}
+		entry.Status = StatusFailed
+		entry.Error = err.Error()
+		l.append(entry)
+		return err
+	}
+
+	if key != "" {
+		if err := l.store.Complete(Record{
+			Key:           key,
+			Operation:     OperationRefund,
+			TransactionID: transactionID,
+			Amount:        amount,
+			CreatedAt:     l.now(),
+		}); err != nil {
+			return fmt.Errorf("store idempotency key: %w", err)
+		}
+	}
+
+	entry.Status = StatusSucceeded
+	return l.append(entry)
+}
+
+// reserveRefund checks the refund against the stored transaction and marks
+// the amount as pending.
+func (l *Ledger) reserveRefund(transactionID string, amount Money) (Transaction, error) {
+	return l.transactions.Update(transactionID, func(tx *Transaction) error {
+		if tx.Refunded.Equal(tx.Amount) {
+			return fmt.Errorf("%w: %s", ErrAlreadyRefunded, transactionID)
+		}
+		remaining, err := tx.Remaining()
+		if err != nil {
+			return err
+		}
+		if cmp, err := amount.Cmp(remaining); err != nil {
+			return err
+		} else if cmp > 0 {
+			return fmt.Errorf("%w: %s requested, %s remaining", ErrOverRefund, amount, remaining)
+		}
+		tx.Pending, _ = tx.Pending.Add(amount)
+		return nil
+	})
+}
+
+// Refundable returns how much of a recorded transaction can still be
+// refunded. It reports false if the transaction is unknown or the store
+// cannot be read.
+func (l *Ledger) Refundable(transactionID string) (Money, bool) {
+	tx, ok, err := l.transactions.Get(transactionID)
+	if err != nil || !ok {
+		return Money{}, false
+	}
+	remaining, _ := tx.Remaining()
+	return remaining, true
+}
+
+// reject audits a request refused before reaching the provider and returns err.
+func (l *Ledger) reject(entry AuditEntry, err error) error {
+	entry.Status = StatusRejected
+	entry.Error = err.Error()
+	l.append(entry)
+	return err
+}
+
+func (l *Ledger) append(entry AuditEntry) error {
+	entry.Time = l.now()
+	if err := l.audit.Append(entry); err != nil {
+		return fmt.Errorf("append audit entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func usd(amount string) Money {
+	return MustParseMoney(amount, "USD")
+}
+
+// countingCharge returns a charge func that issues sequential transaction IDs.
+func countingCharge(calls *atomic.Int32) func() (string, error) {
+	return func() (string, error) {
+		return fmt.Sprintf("TX-%d", calls.Add(1)), nil
+	}
+}
+
+func TestLedger_ChargeIdempotency(t *testing.T) {
+	audit := NewMemoryAuditLog()
+	ledger := NewLedger(WithAuditLog(audit))
+	var calls atomic.Int32
+
+	first, err := ledger.Charge("order-1", "card", usd("50"), countingCharge(&calls))
+	if err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+	second, err := ledger.Charge("order-1", "card", usd("50"), countingCharge(&calls))
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if first != second || calls.Load() != 1 {
+		t.Errorf("expected one charge and the same ID, got %s/%s after %d calls", first, second, calls.Load())
+	}
+
+	if _, err := ledger.Charge("order-1", "card", usd("75"), countingCharge(&calls)); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("expected ErrKeyReused for a different amount, got %v", err)
+	}
+	if _, err := ledger.Charge("order-1", "card", MustParseMoney("50", "EUR"), countingCharge(&calls)); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("expected ErrKeyReused for a different currency, got %v", err)
+	}
+
+	// A failed charge releases the key so the client can retry it.
+	failing := func() (string, error) { return "", errors.New("declined") }
+	if _, err := ledger.Charge("order-2", "card", usd("10"), failing); err == nil {
+		t.Fatal("expected declined charge to fail")
+	}
+	if _, err := ledger.Charge("order-2", "card", usd("10"), countingCharge(&calls)); err != nil {
+		t.Errorf("expected retry after failure to succeed, got %v", err)
+	}
+
+	var statuses []Status
+	for _, entry := range audit.Entries() {
+		statuses = append(statuses, entry.Status)
+	}
+	want := []Status{StatusSucceeded, StatusReplayed, StatusRejected, StatusRejected, StatusFailed, StatusSucceeded}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("audit statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestLedger_ConcurrentChargesWithSameKey(t *testing.T) {
+	ledger := NewLedger()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	charge := func() (string, error) {
+		<-release
+		return countingCharge(&calls)()
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ledger.Charge("order-1", "card", usd("20"), charge)
+			errs <- err
+		}()
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	if calls.Load() != 1 {
+		t.Errorf("expected exactly one charge, got %d", calls.Load())
+	}
+	for err := range errs {
+		if err != nil && !errors.Is(err, ErrRequestInProgress) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestLedger_RefundValidation(t *testing.T) {
+	ledger := NewLedger()
+	var calls atomic.Int32
+	txID, err := ledger.Charge("", "card", usd("100"), countingCharge(&calls))
+	if err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+
+	var refunds int
+	refund := func() error { refunds++; return nil }
+
+	if err := ledger.Refund("", "TX-unknown", usd("10"), refund); !errors.Is(err, ErrUnknownTransaction) {
+		t.Errorf("expected ErrUnknownTransaction, got %v", err)
+	}
+	if err := ledger.Refund("", txID, usd("150"), refund); !errors.Is(err, ErrOverRefund) {
+		t.Errorf("expected ErrOverRefund, got %v", err)
+	}
+	if err := ledger.Refund("", txID, MustParseMoney("10", "EUR"), refund); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+	}
+	if err := ledger.Refund("refund-1", txID, usd("60"), refund); err != nil {
+		t.Fatalf("partial refund: %v", err)
+	}
+	if err := ledger.Refund("refund-1", txID, usd("60"), refund); err != nil {
+		t.Errorf("expected retried refund to be a no-op, got %v", err)
+	}
+	if err := ledger.Refund("", txID, usd("50"), refund); !errors.Is(err, ErrOverRefund) {
+		t.Errorf("expected ErrOverRefund for 50 of 40 remaining, got %v", err)
+	}
+	if err := ledger.Refund("", txID, usd("40"), refund); err != nil {
+		t.Fatalf("final refund: %v", err)
+	}
+	if err := ledger.Refund("", txID, usd("40"), refund); !errors.Is(err, ErrAlreadyRefunded) {
+		t.Errorf("expected ErrAlreadyRefunded, got %v", err)
+	}
+
+	if refunds != 2 {
+		t.Errorf("expected 2 refunds to reach the provider, got %d", refunds)
+	}
+	if remaining, _ := ledger.Refundable(txID); !remaining.IsZero() {
+		t.Errorf("expected nothing left to refund, got %s", remaining)
+	}
+}
+
+// TestLedger_SharedStore tests that refund validation survives a restart
+// and holds across ledgers sharing one store.
+func TestLedger_SharedStore(t *testing.T) {
+	store := NewMemoryStore()
+	var calls atomic.Int32
+	id, err := NewLedger(WithStore(store)).Charge("order-1", "card", usd("30"), countingCharge(&calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new ledger over the same store, as after a restart.
+	restarted := NewLedger(WithStore(store))
+	if replayed, err := restarted.Charge("order-1", "card", usd("30"), countingCharge(&calls)); err != nil || replayed != id {
+		t.Fatalf("replay = %q, %v; want %q", replayed, err, id)
+	}
+	if err := restarted.Refund("refund-1", id, usd("20"), func() error { return nil }); err != nil {
+		t.Fatalf("Refund after restart: %v", err)
+	}
+
+	other := NewLedger(WithStore(store))
+	if err := other.Refund("refund-2", id, usd("20"), func() error { return nil }); !errors.Is(err, ErrOverRefund) {
+		t.Errorf("expected ErrOverRefund from another ledger, got %v", err)
+	}
+	if remaining, ok := other.Refundable(id); !ok || !remaining.Equal(usd("10")) {
+		t.Errorf("Refundable = %v, %v; want 10.00 USD", remaining, ok)
+	}
+
+	// Transactions can live in their own store.
+	transactions := NewMemoryStore()
+	ledger := NewLedger(WithStore(NewMemoryStore()), WithTransactionStore(transactions))
+	id, _ = ledger.Charge("", "card", usd("5"), countingCharge(&calls))
+	if _, ok, _ := transactions.Get(id); !ok {
+		t.Errorf("expected %s in the transaction store", id)
+	}
+}
+
+func TestJSONAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewJSONAuditLog(&buf)
+	ledger := NewLedger(WithAuditLog(log))
+	var calls atomic.Int32
+
+	txID, _ := ledger.Charge("k", "card", usd("5"), countingCharge(&calls))
+	ledger.Refund("", txID, usd("5"), func() error { return nil })
+
+	decoder := json.NewDecoder(&buf)
+	for i, op := range []Operation{OperationCharge, OperationRefund} {
+		var entry AuditEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatalf("decode entry %d: %v", i, err)
+		}
+		if entry.Sequence != int64(i+1) || entry.Operation != op || entry.TransactionID != txID || !entry.Amount.Equal(usd("5")) {
+			t.Errorf("entry %d = %+v", i, entry)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownCurrency is returned for currency codes not in the table.
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrOverflow is returned when arithmetic exceeds the int64 range of minor units.
+	ErrOverflow = errors.New("money amount overflow")
+)
+
+// Currency describes an ISO 4217 currency: its code and the number of digits
+// after the decimal point in its minor unit (2 for USD cents, 0 for JPY).
+type Currency struct {
+	Code        string
+	MinorDigits int
+	Symbol      string
+}
+
+// Common currencies. BTC is not ISO 4217 but is listed with its satoshi
+// precision for the crypto payment examples.
+var (
+	USD = Currency{Code: "USD", MinorDigits: 2, Symbol: "$"}
+	EUR = Currency{Code: "EUR", MinorDigits: 2, Symbol: "€"}
+	GBP = Currency{Code: "GBP", MinorDigits: 2, Symbol: "£"}
+	JPY = Currency{Code: "JPY", MinorDigits: 0, Symbol: "¥"}
+	BTC = Currency{Code: "BTC", MinorDigits: 8, Symbol: "₿"}
+)
+
+var currencies = map[string]Currency{
+	"USD": USD,
+	"EUR": EUR,
+	"GBP": GBP,
+	"JPY": JPY,
+	"BTC": BTC,
+	"AUD": {Code: "AUD", MinorDigits: 2},
+	"CAD": {Code: "CAD", MinorDigits: 2},
+	"CHF": {Code: "CHF", MinorDigits: 2},
+	"CNY": {Code: "CNY", MinorDigits: 2},
+	"INR": {Code: "INR", MinorDigits: 2},
+	"MXN": {Code: "MXN", MinorDigits: 2},
+	"SEK": {Code: "SEK", MinorDigits: 2},
+	"KRW": {Code: "KRW", MinorDigits: 0},
+	"BHD": {Code: "BHD", MinorDigits: 3},
+	"KWD": {Code: "KWD", MinorDigits: 3},
+}
+
+// LookupCurrency returns the currency for an ISO 4217 code, case-insensitively.
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// Money is an amount in integer minor units of a currency. The zero value
+// has no currency and is only useful as "no amount".
+type Money struct {
+	amount   int64
+	currency Currency
+}
+
+// FromMinor returns amount minor units (e.g. cents) of currency.
+func FromMinor(amount int64, currency Currency) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+// NewMoney returns amount minor units of the currency with the given code.
+func NewMoney(amount int64, code string) (Money, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return FromMinor(amount, currency), nil
+}
+
+// ParseMoney parses a decimal string such as "12.34", "+3" or "-0.5", with at
+// most one leading sign. Amounts with more fractional digits than the
+// currency allows are rejected rather than rounded.
+func ParseMoney(s, code string) (Money, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	str := strings.TrimSpace(s)
+	negative := strings.HasPrefix(str, "-")
+	if negative || strings.HasPrefix(str, "+") {
+		str = str[1:]
+	}
+	whole, frac, _ := strings.Cut(str, ".")
+	if whole == "" && frac == "" {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > currency.MinorDigits {
+		return Money{}, fmt.Errorf("invalid amount %q: %s allows %d decimal places", s, currency.Code, currency.MinorDigits)
+	}
+	digits := whole + frac + strings.Repeat("0", currency.MinorDigits-len(frac))
+	if strings.ContainsFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, ErrOverflow)
+	}
+	if negative {
+		amount = -amount
+	}
+	return FromMinor(amount, currency), nil
+}
+
+// MustParseMoney is like ParseMoney but panics on error. It is intended for
+// constants in tests and examples.
+func MustParseMoney(s, code string) Money {
+	m, err := ParseMoney(s, code)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Amount returns the amount in minor units.
+func (m Money) Amount() int64 { return m.amount }
+
+// Currency returns the currency.
+func (m Money) Currency() Currency { return m.currency }
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool { return m.amount == 0 }
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool { return m.amount > 0 }
+
+// IsNegative reports whether the amount is less than zero.
+func (m Money) IsNegative() bool { return m.amount < 0 }
+
+// Float64 returns the amount in major units. It is lossy and meant only for
+// APIs that take floats; do arithmetic on Money instead.
+func (m Money) Float64() float64 {
+	return float64(m.amount) / math.Pow10(m.currency.MinorDigits)
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.currency.Code != other.currency.Code {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency.Code, other.currency.Code)
+	}
+	return nil
+}
+
+// Add returns m + other.
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	a, b := m.amount, other.amount
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return Money{}, ErrOverflow
+	}
+	return FromMinor(a+b, m.currency), nil
+}
+
+// Sub returns m - other.
+func (m Money) Sub(other Money) (Money, error) {
+	if other.amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(FromMinor(-other.amount, other.currency))
+}
+
+// Mul returns m multiplied by n.
+func (m Money) Mul(n int64) (Money, error) {
+	product := m.amount * n
+	if m.amount != 0 && (product/m.amount != n || (m.amount == -1 && n == math.MinInt64)) {
+		return Money{}, ErrOverflow
+	}
+	return FromMinor(product, m.currency), nil
+}
+
+// Scale multiplies m by factor, e.g. 29/1000 for a 2.9% fee, rounding half
+// away from zero to the nearest minor unit.
+func (m Money) Scale(factor *big.Rat) (Money, error) {
+	v := new(big.Rat).Mul(new(big.Rat).SetInt64(m.amount), factor)
+	rounded := roundRat(v)
+	if !rounded.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return FromMinor(rounded.Int64(), m.currency), nil
+}
+
+// Cmp compares m and other, returning -1, 0 or +1.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.amount < other.amount:
+		return -1, nil
+	case m.amount > other.amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Equal reports whether m and other have the same amount and currency.
+func (m Money) Equal(other Money) bool {
+	return m.amount == other.amount && m.currency.Code == other.currency.Code
+}
+
+// Allocate splits m in proportion to ratios without losing minor units:
+// the remainder left by integer division goes one unit at a time to the
+// first shares. Allocate(1, 1, 1) of $1.00 gives $0.34, $0.33, $0.33.
+func (m Money) Allocate(ratios ...int) ([]Money, error) {
+	if len(ratios) == 0 {
+		return nil, errors.New("allocate: at least one ratio is required")
+	}
+	var total int64
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, errors.New("allocate: ratios must not be negative")
+		}
+		total += int64(r)
+	}
+	if total == 0 {
+		return nil, errors.New("allocate: ratios must not all be zero")
+	}
+
+	shares := make([]Money, len(ratios))
+	remainder := m.amount
+	amount, divisor := big.NewInt(m.amount), big.NewInt(total)
+	for i, r := range ratios {
+		// amount*r fits in big.Int; the quotient never exceeds |amount|.
+		share := new(big.Int).Mul(amount, big.NewInt(int64(r)))
+		share.Quo(share, divisor)
+		shares[i] = FromMinor(share.Int64(), m.currency)
+		remainder -= share.Int64()
+	}
+
+	unit := int64(1)
+	if remainder < 0 {
+		unit = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(shares) {
+		if ratios[i] == 0 {
+			continue
+		}
+		shares[i].amount += unit
+		remainder -= unit
+	}
+	return shares, nil
+}
+
+// Split divides m into n shares that differ by at most one minor unit.
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, errors.New("split: n must be positive")
+	}
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+// Decimal returns the amount in major units with exactly MinorDigits
+// decimal places, e.g. "-12.30".
+func (m Money) Decimal() string {
+	sign := ""
+	abs := uint64(m.amount)
+	if m.amount < 0 {
+		sign = "-"
+		abs = uint64(-m.amount)
+	}
+	digits := strconv.FormatUint(abs, 10)
+	if m.currency.MinorDigits == 0 {
+		return sign + digits
+	}
+	if pad := m.currency.MinorDigits + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	cut := len(digits) - m.currency.MinorDigits
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// String returns the amount followed by the currency code, e.g. "12.30 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency.Code
+}
+
+// Format returns the amount for display with the currency symbol and
+// thousands separators, e.g. "$1,234.50". Currencies without a symbol are
+// prefixed with their code.
+func (m Money) Format() string {
+	decimal := strings.TrimPrefix(m.Decimal(), "-")
+	whole, frac, hasFrac := strings.Cut(decimal, ".")
+
+	var grouped strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(r)
+	}
+	if hasFrac {
+		grouped.WriteString("." + frac)
+	}
+
+	prefix := m.currency.Symbol
+	if prefix == "" {
+		prefix = m.currency.Code + " "
+	}
+	if m.amount < 0 {
+		prefix = "-" + prefix
+	}
+	return prefix + grouped.String()
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes Money as {"amount": "12.30", "currency": "USD"}. The
+// amount is a string so no precision is lost to JSON numbers.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.currency.Code})
+}
+
+// UnmarshalJSON decodes the format written by MarshalJSON.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,149 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input, code string
+		minor       int64
+		decimal     string
+	}{
+		{"12.34", "USD", 1234, "12.34"},
+		{"12.3", "usd", 1230, "12.30"},
+		{"-0.05", "EUR", -5, "-0.05"},
+		{"+3", "USD", 300, "3.00"},
+		{".5", "GBP", 50, "0.50"},
+		{"1500", "JPY", 1500, "1500"},
+		{"1.234", "KWD", 1234, "1.234"},
+		{"0.00000001", "BTC", 1, "0.00000001"},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.input, tt.code)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %q): %v", tt.input, tt.code, err)
+			continue
+		}
+		if m.Amount() != tt.minor || m.Decimal() != tt.decimal {
+			t.Errorf("ParseMoney(%q, %q) = %d (%s), want %d (%s)", tt.input, tt.code, m.Amount(), m.Decimal(), tt.minor, tt.decimal)
+		}
+	}
+
+	for _, bad := range []struct{ input, code string }{
+		{"1.234", "USD"}, // too many decimals
+		{"1.5", "JPY"},
+		{"abc", "USD"},
+		{"", "USD"},
+		{"-+5", "USD"}, // at most one sign
+		{"+-5", "USD"},
+		{"--5", "USD"},
+		{"-", "USD"},
+		{"1.00", "XXX"},
+		{"99999999999999999999", "USD"},
+	} {
+		if _, err := ParseMoney(bad.input, bad.code); err == nil {
+			t.Errorf("ParseMoney(%q, %q) succeeded, want error", bad.input, bad.code)
+		}
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a, b := usd("10.25"), usd("0.75")
+	sum, err := a.Add(b)
+	if err != nil || !sum.Equal(usd("11.00")) {
+		t.Errorf("Add = %s, %v", sum, err)
+	}
+	diff, err := b.Sub(a)
+	if err != nil || !diff.Equal(usd("-9.50")) {
+		t.Errorf("Sub = %s, %v", diff, err)
+	}
+	product, err := b.Mul(3)
+	if err != nil || !product.Equal(usd("2.25")) {
+		t.Errorf("Mul = %s, %v", product, err)
+	}
+
+	if _, err := a.Add(MustParseMoney("1", "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+	}
+	big := FromMinor(math.MaxInt64, USD)
+	if _, err := big.Add(FromMinor(1, USD)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow from Add, got %v", err)
+	}
+	if _, err := big.Mul(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow from Mul, got %v", err)
+	}
+	if cmp, _ := a.Cmp(b); cmp != 1 {
+		t.Errorf("Cmp = %d, want 1", cmp)
+	}
+}
+
+func TestMoney_AllocateAndSplit(t *testing.T) {
+	shares, err := usd("1.00").Split(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0.34", "0.33", "0.33"}
+	for i, share := range shares {
+		if share.Decimal() != want[i] {
+			t.Errorf("Split share %d = %s, want %s", i, share.Decimal(), want[i])
+		}
+	}
+
+	shares, err = usd("-0.05").Allocate(70, 30, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := FromMinor(0, USD)
+	for _, share := range shares {
+		total, _ = total.Add(share)
+	}
+	if !total.Equal(usd("-0.05")) || !shares[2].IsZero() {
+		t.Errorf("Allocate lost or misplaced units: %v", shares)
+	}
+
+	if _, err := usd("1").Allocate(0, 0); err == nil {
+		t.Error("expected error for all-zero ratios")
+	}
+}
+
+func TestMoney_Scale(t *testing.T) {
+	fee, err := usd("10.00").Scale(big.NewRat(29, 1000))
+	if err != nil || fee.String() != "0.29 USD" {
+		t.Errorf("Scale = %s, %v; want 0.29 USD", fee, err)
+	}
+	if half, _ := usd("0.05").Scale(big.NewRat(1, 2)); half.String() != "0.03 USD" {
+		t.Errorf("Scale(1/2) of 0.05 = %s, want 0.03 USD", half)
+	}
+}
+
+func TestMoney_Formatting(t *testing.T) {
+	tests := []struct {
+		money  Money
+		str    string
+		format string
+	}{
+		{usd("1234567.5"), "1234567.50 USD", "$1,234,567.50"},
+		{usd("-12"), "-12.00 USD", "-$12.00"},
+		{MustParseMoney("5000", "JPY"), "5000 JPY", "¥5,000"},
+		{MustParseMoney("99.9", "CHF"), "99.90 CHF", "CHF 99.90"},
+	}
+	for _, tt := range tests {
+		if tt.money.String() != tt.str || tt.money.Format() != tt.format {
+			t.Errorf("got %q / %q, want %q / %q", tt.money.String(), tt.money.Format(), tt.str, tt.format)
+		}
+	}
+
+	data, err := json.Marshal(usd("19.99"))
+	if err != nil || string(data) != `{"amount":"19.99","currency":"USD"}` {
+		t.Errorf("MarshalJSON = %s, %v", data, err)
+	}
+	var decoded Money
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Equal(usd("19.99")) {
+		t.Errorf("UnmarshalJSON = %s, %v", decoded, err)
+	}
+}
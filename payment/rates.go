@@ -0,0 +1,240 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateUnavailable is returned when a provider has no rate for a currency pair.
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// ExchangeRate converts amounts from one currency to another. Rate is the
+// number of major units of To per major unit of From.
+type ExchangeRate struct {
+	From Currency
+	To   Currency
+	Rate *big.Rat
+	// AsOf is when the provider's table was loaded.
+	AsOf time.Time
+}
+
+// IsIdentity reports whether the rate converts a currency to itself.
+func (r ExchangeRate) IsIdentity() bool {
+	return r.From.Code == r.To.Code
+}
+
+// String returns the rate as "1 EUR = 1.085 USD".
+func (r ExchangeRate) String() string {
+	return fmt.Sprintf("1 %s = %s %s", r.From.Code, trimRat(r.Rate), r.To.Code)
+}
+
+// Convert returns m in the target currency, rounded half away from zero to
+// the target's minor unit.
+func (r ExchangeRate) Convert(m Money) (Money, error) {
+	if m.currency.Code != r.From.Code {
+		return Money{}, fmt.Errorf("%w: converting %s with a %s rate", ErrCurrencyMismatch, m.currency.Code, r.From.Code)
+	}
+	if r.IsIdentity() {
+		return m, nil
+	}
+
+	// minor_to = minor_from * rate * 10^(toDigits - fromDigits)
+	factor := new(big.Rat).Set(r.Rate)
+	scale := new(big.Rat).SetInt(pow10(abs(r.To.MinorDigits - r.From.MinorDigits)))
+	if r.To.MinorDigits >= r.From.MinorDigits {
+		factor.Mul(factor, scale)
+	} else {
+		factor.Quo(factor, scale)
+	}
+
+	converted, err := m.Scale(factor)
+	if err != nil {
+		return Money{}, fmt.Errorf("convert %s to %s: %w", m, r.To.Code, err)
+	}
+	return FromMinor(converted.amount, r.To), nil
+}
+
+// Inverse returns the rate converting To back to From.
+func (r ExchangeRate) Inverse() ExchangeRate {
+	return ExchangeRate{From: r.To, To: r.From, Rate: new(big.Rat).Inv(r.Rate), AsOf: r.AsOf}
+}
+
+// RateProvider looks up exchange rates. Implementations must return an
+// identity rate when from and to are the same currency.
+type RateProvider interface {
+	Rate(from, to string) (ExchangeRate, error)
+}
+
+// StaticRates is a fixed rate table quoted against a base currency. Cross
+// rates between two non-base currencies go through the base.
+type StaticRates struct {
+	base  Currency
+	rates map[string]*big.Rat
+	asOf  time.Time
+}
+
+// NewStaticRates builds a table from decimal rates such as
+// {"EUR": "0.92"}, each meaning units of that currency per one unit of base.
+func NewStaticRates(base string, rates map[string]string) (*StaticRates, error) {
+	baseCurrency, err := LookupCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &StaticRates{
+		base:  baseCurrency,
+		rates: map[string]*big.Rat{baseCurrency.Code: big.NewRat(1, 1)},
+		asOf:  time.Now(),
+	}
+	for code, value := range rates {
+		currency, err := LookupCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", value, currency.Code)
+		}
+		t.rates[currency.Code] = rate
+	}
+	return t, nil
+}
+
+// Base returns the currency the table is quoted against.
+func (t *StaticRates) Base() Currency {
+	return t.base
+}
+
+// Rate implements RateProvider.
+func (t *StaticRates) Rate(from, to string) (ExchangeRate, error) {
+	fromCurrency, err := LookupCurrency(from)
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+	toCurrency, err := LookupCurrency(to)
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+	if fromCurrency.Code == toCurrency.Code {
+		return ExchangeRate{From: fromCurrency, To: toCurrency, Rate: big.NewRat(1, 1), AsOf: t.asOf}, nil
+	}
+
+	fromRate, ok := t.rates[fromCurrency.Code]
+	if !ok {
+		return ExchangeRate{}, fmt.Errorf("%w: %s/%s", ErrRateUnavailable, fromCurrency.Code, toCurrency.Code)
+	}
+	toRate, ok := t.rates[toCurrency.Code]
+	if !ok {
+		return ExchangeRate{}, fmt.Errorf("%w: %s/%s", ErrRateUnavailable, fromCurrency.Code, toCurrency.Code)
+	}
+	return ExchangeRate{
+		From: fromCurrency,
+		To:   toCurrency,
+		Rate: new(big.Rat).Quo(toRate, fromRate),
+		AsOf: t.asOf,
+	}, nil
+}
+
+// ratesFile is the on-disk format read by FileRates:
+//
+//	{"base": "USD", "rates": {"EUR": "0.92", "GBP": 0.79}}
+//
+// Rates may be JSON strings or numbers; strings avoid float rounding.
+type ratesFile struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// FileRates is a rate table loaded from a JSON file. Reload re-reads the file,
+// so a job that refreshes the file can update rates without a restart.
+type FileRates struct {
+	path string
+
+	mu    sync.RWMutex
+	table *StaticRates
+}
+
+// NewFileRates loads rates from path.
+func NewFileRates(path string) (*FileRates, error) {
+	f := &FileRates{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload re-reads the file. On error the previously loaded rates stay in use.
+func (f *FileRates) Reload() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("load rates: %w", err)
+	}
+
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("load rates %s: %w", f.path, err)
+	}
+	rates := make(map[string]string, len(file.Rates))
+	for code, value := range file.Rates {
+		rates[code] = value.String()
+	}
+	table, err := NewStaticRates(file.Base, rates)
+	if err != nil {
+		return fmt.Errorf("load rates %s: %w", f.path, err)
+	}
+	if info, err := os.Stat(f.path); err == nil {
+		table.asOf = info.ModTime()
+	}
+
+	f.mu.Lock()
+	f.table = table
+	f.mu.Unlock()
+	return nil
+}
+
+// Rate implements RateProvider.
+func (f *FileRates) Rate(from, to string) (ExchangeRate, error) {
+	f.mu.RLock()
+	table := f.table
+	f.mu.RUnlock()
+	return table.Rate(from, to)
+}
+
+// roundRat rounds v to the nearest integer, halves away from zero.
+func roundRat(v *big.Rat) *big.Int {
+	num, den := new(big.Int).Abs(v.Num()), v.Denom()
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Lsh(r, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if v.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// trimRat formats r with up to 8 decimal places and no trailing zeros.
+func trimRat(r *big.Rat) string {
+	s := r.FloatString(8)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package payment
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticRates(t *testing.T) {
+	rates, err := NewStaticRates("USD", map[string]string{"EUR": "0.92", "GBP": "0.79", "JPY": "150"})
+	if err != nil {
+		t.Fatalf("NewStaticRates: %v", err)
+	}
+
+	tests := []struct {
+		amount Money
+		to     string
+		want   string
+	}{
+		{usd("100.00"), "EUR", "92.00 EUR"},
+		{MustParseMoney("92.00", "EUR"), "USD", "100.00 USD"},
+		{usd("10.00"), "JPY", "1500 JPY"},
+		{MustParseMoney("1", "JPY"), "USD", "0.01 USD"},       // 0.00666… rounds up
+		{MustParseMoney("10.00", "EUR"), "GBP", "8.59 GBP"},   // cross rate via USD: 8.5869…
+		{MustParseMoney("-10.00", "EUR"), "GBP", "-8.59 GBP"}, // rounding is symmetric
+		{usd("12.34"), "USD", "12.34 USD"},                    // identity
+	}
+	for _, tt := range tests {
+		rate, err := rates.Rate(tt.amount.Currency().Code, tt.to)
+		if err != nil {
+			t.Fatalf("Rate(%s, %s): %v", tt.amount.Currency().Code, tt.to, err)
+		}
+		got, err := rate.Convert(tt.amount)
+		if err != nil {
+			t.Fatalf("Convert(%s): %v", tt.amount, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s -> %s = %s, want %s", tt.amount, tt.to, got, tt.want)
+		}
+	}
+
+	if _, err := rates.Rate("USD", "CHF"); !errors.Is(err, ErrRateUnavailable) {
+		t.Errorf("expected ErrRateUnavailable, got %v", err)
+	}
+	rate, _ := rates.Rate("USD", "EUR")
+	if _, err := rate.Convert(MustParseMoney("1", "GBP")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+	}
+	if got := rate.String(); got != "1 USD = 0.92 EUR" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := rate.Inverse().String(); got != "1 EUR = 1.08695652 USD" {
+		t.Errorf("Inverse().String() = %q", got)
+	}
+
+	if _, err := NewStaticRates("USD", map[string]string{"EUR": "-1"}); err == nil {
+		t.Error("expected an error for a negative rate")
+	}
+}
+
+func TestFileRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"base": "EUR", "rates": {"USD": "1.10", "GBP": 0.85}}`)
+	rates, err := NewFileRates(path)
+	if err != nil {
+		t.Fatalf("NewFileRates: %v", err)
+	}
+	rate, err := rates.Rate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("Rate: %v", err)
+	}
+	if got, _ := rate.Convert(MustParseMoney("10", "EUR")); got.String() != "11.00 USD" {
+		t.Errorf("Convert = %s, want 11.00 USD", got)
+	}
+
+	write(`{"base": "EUR", "rates": {"USD": "1.20"}}`)
+	if err := rates.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	rate, _ = rates.Rate("EUR", "USD")
+	if got, _ := rate.Convert(MustParseMoney("10", "EUR")); got.String() != "12.00 USD" {
+		t.Errorf("Convert after reload = %s, want 12.00 USD", got)
+	}
+
+	write(`{"base": "EUR", "rates": {"XXX": "1"}}`)
+	if err := rates.Reload(); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("expected ErrUnknownCurrency, got %v", err)
+	}
+	if _, err := rates.Rate("EUR", "USD"); err != nil {
+		t.Errorf("expected previous rates to stay loaded, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package payment
+
+import "fmt"
+
+// Transaction is a recorded charge and how much of it has been refunded.
+// Pending holds refunds sent to the provider but not yet confirmed, so
+// concurrent refunds cannot together exceed the charge.
+type Transaction struct {
+	ID        string
+	Processor string
+	Amount    Money
+	Refunded  Money
+	Pending   Money
+}
+
+// Remaining returns the amount neither refunded nor pending refund.
+func (tx Transaction) Remaining() (Money, error) {
+	remaining, err := tx.Amount.Sub(tx.Refunded)
+	if err != nil {
+		return Money{}, err
+	}
+	return remaining.Sub(tx.Pending)
+}
+
+// TransactionStore persists the transactions a Ledger validates refunds
+// against. Keep it next to the IdempotencyStore: a charge replayed from a
+// stored idempotency key can only be refunded if its transaction survived
+// too. Update must apply fn atomically with respect to other updates of the
+// same transaction; a store shared between processes would use a
+// transaction or compare-and-swap and retry fn on conflict, so fn must be
+// free of side effects.
+type TransactionStore interface {
+	// Create records a new transaction.
+	Create(tx Transaction) error
+	// Get returns the transaction with id and whether it exists.
+	Get(id string) (Transaction, bool, error)
+	// Update replaces the transaction with id by what fn makes of it and
+	// returns the result. It returns ErrUnknownTransaction if there is no
+	// such transaction, and fn's error unchanged if fn fails.
+	Update(id string, fn func(tx *Transaction) error) (Transaction, error)
+}
+
+// Create implements TransactionStore.
+func (s *MemoryStore) Create(tx Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transactions[tx.ID] = tx
+	return nil
+}
+
+// Get implements TransactionStore.
+func (s *MemoryStore) Get(id string) (Transaction, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, ok := s.transactions[id]
+	return tx, ok, nil
+}
+
+// Update implements TransactionStore.
+func (s *MemoryStore) Update(id string, fn func(tx *Transaction) error) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, ok := s.transactions[id]
+	if !ok {
+		return Transaction{}, fmt.Errorf("%w: %s", ErrUnknownTransaction, id)
+	}
+	if err := fn(&tx); err != nil {
+		return Transaction{}, err
+	}
+	s.transactions[id] = tx
+	return tx, nil
+}
@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditEntry records one authorization decision.
+type AuditEntry struct {
+	Sequence int64     `json:"sequence"`
+	Time     time.Time `json:"time"`
+	Subject  string    `json:"subject"`
+	Roles    []string  `json:"roles"`
+	Action   string    `json:"action"`
+	Resource string    `json:"resource"`
+	Allowed  bool      `json:"allowed"`
+	Reason   string    `json:"reason"`
+}
+
+// AuditLog is an append-only record of decisions. Implementations assign
+// the entry's Sequence.
+type AuditLog interface {
+	Append(entry AuditEntry) error
+}
+
+// MemoryAuditLog keeps audit entries in memory.
+type MemoryAuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// NewMemoryAuditLog creates an empty in-memory audit log.
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+// Append implements AuditLog.
+func (l *MemoryAuditLog) Append(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Sequence = int64(len(l.entries)) + 1
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries returns a copy of the entries in append order.
+func (l *MemoryAuditLog) Entries() []AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]AuditEntry(nil), l.entries...)
+}
+
+// JSONAuditLog writes each entry as a JSON line.
+type JSONAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	seq int64
+}
+
+// NewJSONAuditLog creates an audit log that writes to w.
+func NewJSONAuditLog(w io.Writer) *JSONAuditLog {
+	return &JSONAuditLog{enc: json.NewEncoder(w)}
+}
+
+// Append implements AuditLog.
+func (l *JSONAuditLog) Append(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Sequence = l.seq + 1
+	if err := l.enc.Encode(entry); err != nil {
+		return err
+	}
+	l.seq++
+	return nil
+}
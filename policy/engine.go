@@ -0,0 +1,206 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jumaniyozov/design_patterns/internal/yamlsubset"
+)
+
+// Format is the encoding of a policy document.
+type Format int
+
+const (
+	JSON Format = iota
+	YAML
+)
+
+// FormatOf picks the format from a file name's extension: .yaml and .yml
+// are YAML, anything else JSON.
+func FormatOf(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return YAML
+	default:
+		return JSON
+	}
+}
+
+// Parse decodes a policy document. Unknown fields are an error, so a
+// misspelled key does not silently drop a rule.
+func Parse(data []byte, format Format) (Document, error) {
+	if format == YAML {
+		v, err := yamlsubset.Decode(data)
+		if err != nil {
+			return Document{}, fmt.Errorf("policy: %w", err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return Document{}, fmt.Errorf("policy: %w", err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		return Document{}, fmt.Errorf("policy: %w", err)
+	}
+	return doc, nil
+}
+
+type options struct {
+	audit         AuditLog
+	now           func() time.Time
+	onReloadError func(error)
+}
+
+// Option configures an Engine.
+type Option func(*options)
+
+// WithAuditLog records every decision in log. If recording fails, the
+// request is denied: an access that cannot be audited is not allowed.
+func WithAuditLog(log AuditLog) Option {
+	return func(o *options) {
+		o.audit = log
+	}
+}
+
+// WithClock replaces time.Now for audit entries, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithReloadErrorHandler is called by Watch when a changed file fails to
+// load. The engine keeps the policy it had.
+func WithReloadErrorHandler(fn func(error)) Option {
+	return func(o *options) {
+		o.onReloadError = fn
+	}
+}
+
+// Engine answers authorization requests against a policy that can be
+// replaced at any time. It is safe for concurrent use; each request sees
+// either the old policy or the new one, never a mix.
+type Engine struct {
+	opts    options
+	current atomic.Pointer[compiled]
+
+	// path and loaded are only set for engines created by LoadFile.
+	path   string
+	mu     sync.Mutex
+	loaded []byte
+}
+
+// New creates an engine for doc.
+func New(doc Document, opts ...Option) (*Engine, error) {
+	e := newEngine(opts)
+	if err := e.Replace(doc); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// LoadFile creates an engine from a JSON or YAML file, which Reload and
+// Watch re-read.
+func LoadFile(path string, opts ...Option) (*Engine, error) {
+	e := newEngine(opts)
+	e.path = path
+	if _, err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func newEngine(opts []Option) *Engine {
+	o := options{now: time.Now, onReloadError: func(error) {}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Engine{opts: o}
+}
+
+// Replace swaps in a new policy. An invalid doc leaves the current policy
+// in place.
+func (e *Engine) Replace(doc Document) error {
+	c, err := compile(doc)
+	if err != nil {
+		return err
+	}
+	e.current.Store(c)
+	return nil
+}
+
+// Reload re-reads the engine's file and replaces the policy if the file has
+// changed. It reports whether it did.
+func (e *Engine) Reload() (bool, error) {
+	if e.path == "" {
+		return false, errors.New("policy: engine was not loaded from a file")
+	}
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, fmt.Errorf("policy: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.loaded != nil && bytes.Equal(data, e.loaded) {
+		return false, nil
+	}
+	doc, err := Parse(data, FormatOf(e.path))
+	if err != nil {
+		return false, fmt.Errorf("%w (in %s)", err, e.path)
+	}
+	if err := e.Replace(doc); err != nil {
+		return false, fmt.Errorf("%w (in %s)", err, e.path)
+	}
+	e.loaded = data
+	return true, nil
+}
+
+// Watch calls Reload every interval until ctx is done, passing failures to
+// the WithReloadErrorHandler handler. Run it in its own goroutine.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := e.Reload(); err != nil {
+				e.opts.onReloadError(err)
+			}
+		}
+	}
+}
+
+// Authorize decides req and records the decision in the audit log.
+func (e *Engine) Authorize(req Request) Decision {
+	d := e.current.Load().decide(req)
+	if e.opts.audit == nil {
+		return d
+	}
+	entry := AuditEntry{
+		Time:     e.opts.now(),
+		Subject:  req.Subject.Name,
+		Roles:    req.Subject.Roles,
+		Action:   req.Action,
+		Resource: req.Resource,
+		Allowed:  d.Allowed,
+		Reason:   d.Reason,
+	}
+	if err := e.opts.audit.Append(entry); err != nil {
+		d.Allowed, d.Reason = false, "audit log failed: "+err.Error()
+	}
+	return d
+}
@@ -0,0 +1,196 @@
+// Package policy is a role-based authorization engine shared by the
+// access-control examples. A policy document defines roles; each role can
+// inherit other roles and has allow and deny rules matching an action (such
+// as a SQL verb or HTTP method) and a resource (such as a table or path).
+// Documents load from JSON or YAML, can be reloaded while in use, and every
+// decision can be written to an audit log.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ErrDenied matches every error returned by Decision.Err.
+var ErrDenied = errors.New("access denied")
+
+// Document is a policy as written in JSON or YAML.
+type Document struct {
+	Roles map[string]RoleSpec `json:"roles"`
+}
+
+// RoleSpec defines one role. A role has the rules of every role it
+// inherits, transitively.
+type RoleSpec struct {
+	Inherits []string   `json:"inherits,omitempty"`
+	Allow    []RuleSpec `json:"allow,omitempty"`
+	Deny     []RuleSpec `json:"deny,omitempty"`
+}
+
+// RuleSpec matches requests whose action is one of Actions and whose
+// resource matches one of Resources. Actions compare case-insensitively and
+// "*" matches any action. Resources are path.Match patterns, so "*" matches
+// any table and "/api/*" any path one level under /api.
+type RuleSpec struct {
+	Actions   []string `json:"actions"`
+	Resources []string `json:"resources"`
+}
+
+// Subject is who a request is made for.
+type Subject struct {
+	Name  string
+	Roles []string
+}
+
+// Request asks whether Subject may perform Action on Resource.
+type Request struct {
+	Subject  Subject
+	Action   string
+	Resource string
+}
+
+// Decision is the outcome of a Request.
+type Decision struct {
+	Request Request
+	Allowed bool
+	// Reason says which role's rule decided, or that none matched.
+	Reason string
+}
+
+// Err returns nil if the request was allowed, or a *DeniedError.
+func (d Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+	return &DeniedError{Request: d.Request, Reason: d.Reason}
+}
+
+// DeniedError reports a denied request.
+type DeniedError struct {
+	Request Request
+	Reason  string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("access denied: %s may not %s %s: %s",
+		e.Request.Subject.Name, e.Request.Action, e.Request.Resource, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrDenied) true.
+func (e *DeniedError) Is(target error) bool { return target == ErrDenied }
+
+// rule is a compiled RuleSpec, remembering the role that defined it.
+type rule struct {
+	role      string
+	actions   []string
+	resources []string
+}
+
+func (r rule) matches(action, resource string) bool {
+	actionOK := slices.ContainsFunc(r.actions, func(a string) bool {
+		return a == "*" || strings.EqualFold(a, action)
+	})
+	return actionOK && slices.ContainsFunc(r.resources, func(pattern string) bool {
+		ok, _ := path.Match(pattern, resource)
+		return ok
+	})
+}
+
+// compiled holds each role's rules with inheritance resolved.
+type compiled struct {
+	allow map[string][]rule
+	deny  map[string][]rule
+}
+
+// compile validates doc and resolves inheritance.
+func compile(doc Document) (*compiled, error) {
+	c := &compiled{allow: make(map[string][]rule), deny: make(map[string][]rule)}
+	own := make(map[string][2][]rule, len(doc.Roles))
+	for name, spec := range doc.Roles {
+		var rules [2][]rule
+		for i, specs := range [2][]RuleSpec{spec.Allow, spec.Deny} {
+			for _, rs := range specs {
+				r, err := compileRule(name, rs)
+				if err != nil {
+					return nil, err
+				}
+				rules[i] = append(rules[i], r)
+			}
+		}
+		own[name] = rules
+	}
+
+	// visiting detects inheritance cycles during the depth-first walk.
+	visiting, resolved := make(map[string]bool), make(map[string]bool)
+	var resolve func(name string) error
+	resolve = func(name string) error {
+		if resolved[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("policy: role %q inherits itself", name)
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		allow, deny := slices.Clone(own[name][0]), slices.Clone(own[name][1])
+		for _, parent := range doc.Roles[name].Inherits {
+			if _, ok := doc.Roles[parent]; !ok {
+				return fmt.Errorf("policy: role %q inherits unknown role %q", name, parent)
+			}
+			if err := resolve(parent); err != nil {
+				return err
+			}
+			allow = append(allow, c.allow[parent]...)
+			deny = append(deny, c.deny[parent]...)
+		}
+		c.allow[name], c.deny[name] = allow, deny
+		resolved[name] = true
+		return nil
+	}
+	for name := range doc.Roles {
+		if err := resolve(name); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func compileRule(role string, rs RuleSpec) (rule, error) {
+	if len(rs.Actions) == 0 || len(rs.Resources) == 0 {
+		return rule{}, fmt.Errorf("policy: role %q has a rule without actions or resources", role)
+	}
+	for _, pattern := range rs.Resources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return rule{}, fmt.Errorf("policy: role %q: resource %q: %w", role, pattern, err)
+		}
+	}
+	return rule{role: role, actions: rs.Actions, resources: rs.Resources}, nil
+}
+
+// decide applies the policy to req. A matching deny rule from any of the
+// subject's roles wins over every allow rule; with no matching rule the
+// request is denied.
+func (c *compiled) decide(req Request) Decision {
+	d := Decision{Request: req, Reason: "no rule allows it"}
+	for _, role := range req.Subject.Roles {
+		for _, r := range c.deny[role] {
+			if r.matches(req.Action, req.Resource) {
+				d.Reason = "denied by role " + r.role
+				return d
+			}
+		}
+	}
+	for _, role := range req.Subject.Roles {
+		for _, r := range c.allow[role] {
+			if r.matches(req.Action, req.Resource) {
+				d.Allowed, d.Reason = true, "allowed by role "+r.role
+				return d
+			}
+		}
+	}
+	return d
+}
@@ -0,0 +1,235 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const yamlPolicy = `# Database roles.
+roles:
+  reader:
+    allow:
+      - actions: [select]
+        resources: ["*"]
+  writer:
+    inherits: [reader]
+    allow:
+    - actions: [insert, update]
+      resources:
+        - orders
+        - 'order_*'
+  admin:
+    inherits:
+      - writer
+    allow:
+      - actions: ["*"]
+        resources: ["*"]
+    deny:
+      - actions: [drop, truncate]   # never, even for admins
+        resources: [audit_log]
+`
+
+const jsonPolicy = `{
+  "roles": {
+    "reader": {"allow": [{"actions": ["select"], "resources": ["*"]}]},
+    "writer": {
+      "inherits": ["reader"],
+      "allow": [{"actions": ["insert", "update"], "resources": ["orders", "order_*"]}]
+    },
+    "admin": {
+      "inherits": ["writer"],
+      "allow": [{"actions": ["*"], "resources": ["*"]}],
+      "deny": [{"actions": ["drop", "truncate"], "resources": ["audit_log"]}]
+    }
+  }
+}`
+
+func mustParse(t *testing.T, src string, format Format) Document {
+	t.Helper()
+	doc, err := Parse([]byte(src), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// TestParse_YAMLMatchesJSON tests that the same policy reads the same from
+// both formats.
+func TestParse_YAMLMatchesJSON(t *testing.T) {
+	fromYAML, fromJSON := mustParse(t, yamlPolicy, YAML), mustParse(t, jsonPolicy, JSON)
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("YAML and JSON differ:\n%+v\n%+v", fromYAML, fromJSON)
+	}
+}
+
+// TestEngine_Authorize tests inheritance, pattern matching and deny rules
+// winning over allow rules.
+func TestEngine_Authorize(t *testing.T) {
+	e, err := New(mustParse(t, yamlPolicy, YAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		roles    []string
+		action   string
+		resource string
+		allowed  bool
+		reason   string
+	}{
+		{[]string{"reader"}, "SELECT", "users", true, "allowed by role reader"},
+		{[]string{"reader"}, "UPDATE", "orders", false, "no rule allows it"},
+		{[]string{"writer"}, "select", "users", true, "allowed by role reader"},
+		{[]string{"writer"}, "UPDATE", "order_items", true, "allowed by role writer"},
+		{[]string{"writer"}, "UPDATE", "users", false, "no rule allows it"},
+		{[]string{"admin"}, "DELETE", "users", true, "allowed by role admin"},
+		{[]string{"admin"}, "DROP", "audit_log", false, "denied by role admin"},
+		{[]string{"reader", "admin"}, "TRUNCATE", "audit_log", false, "denied by role admin"},
+		{[]string{"unknown"}, "SELECT", "users", false, "no rule allows it"},
+		{nil, "SELECT", "users", false, "no rule allows it"},
+	}
+	for _, tt := range tests {
+		req := Request{Subject: Subject{Name: "alice", Roles: tt.roles}, Action: tt.action, Resource: tt.resource}
+		d := e.Authorize(req)
+		if d.Allowed != tt.allowed || d.Reason != tt.reason {
+			t.Errorf("%v %s %s: got %v (%s), want %v (%s)",
+				tt.roles, tt.action, tt.resource, d.Allowed, d.Reason, tt.allowed, tt.reason)
+		}
+		if err := d.Err(); (err == nil) != tt.allowed || (err != nil && !errors.Is(err, ErrDenied)) {
+			t.Errorf("%v %s %s: Err() = %v", tt.roles, tt.action, tt.resource, err)
+		}
+	}
+}
+
+// TestNew_Invalid tests that broken policies are rejected.
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name, src, errText string
+	}{
+		{"cycle", "roles:\n  a:\n    inherits: [b]\n  b:\n    inherits: [a]\n", "inherits itself"},
+		{"unknown parent", "roles:\n  a:\n    inherits: [b]\n", `unknown role "b"`},
+		{"empty rule", "roles:\n  a:\n    allow:\n      - actions: [select]\n", "without actions or resources"},
+		{"bad pattern", "roles:\n  a:\n    allow:\n      - actions: [select]\n        resources: ['[']\n", "syntax error in pattern"},
+		{"unknown field", "roles:\n  a:\n    alow: []\n", `unknown field "alow"`},
+		{"unquoted star", "roles:\n  a:\n    allow:\n      - actions: *\n", "quote it"},
+		{"bad indentation", "roles:\n  a:\n    inherits: [b]\n   b: {}\n", "line 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.src), YAML)
+			if err == nil {
+				_, err = New(doc)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("expected an error containing %q, got %v", tt.errText, err)
+			}
+		})
+	}
+}
+
+// TestEngine_Reload tests that a changed file replaces the policy and a
+// broken one keeps the previous policy.
+func TestEngine_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	write := func(src string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("roles:\n  reader:\n    allow:\n      - actions: [select]\n        resources: [users]\n")
+
+	reloadErrs := make(chan error, 1)
+	e, err := LoadFile(path, WithReloadErrorHandler(func(err error) {
+		select {
+		case reloadErrs <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := Request{Subject: Subject{Name: "bob", Roles: []string{"reader"}}, Action: "SELECT", Resource: "orders"}
+	if e.Authorize(req).Allowed {
+		t.Fatal("expected orders to be denied before the reload")
+	}
+	if changed, err := e.Reload(); changed || err != nil {
+		t.Errorf("Reload of an unchanged file = %v, %v", changed, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Watch(ctx, 5*time.Millisecond)
+
+	write("roles:\n  reader:\n    allow:\n      - actions: [select]\n        resources: [users, orders]\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for !e.Authorize(req).Allowed {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch did not pick up the new policy")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	write("roles:\n  reader:\n    inherits: [missing]\n")
+	select {
+	case err := <-reloadErrs:
+		if !strings.Contains(err.Error(), "missing") {
+			t.Errorf("unexpected reload error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a reload error")
+	}
+	if !e.Authorize(req).Allowed {
+		t.Error("a broken file should leave the previous policy in place")
+	}
+}
+
+type failingAuditLog struct{}
+
+func (failingAuditLog) Append(AuditEntry) error { return errors.New("disk full") }
+
+// TestEngine_Audit tests that decisions are recorded and that an audit
+// failure denies the request.
+func TestEngine_Audit(t *testing.T) {
+	doc := mustParse(t, jsonPolicy, JSON)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	e, err := New(doc, WithAuditLog(NewJSONAuditLog(&buf)), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Authorize(Request{Subject: Subject{Name: "alice", Roles: []string{"reader"}}, Action: "SELECT", Resource: "users"})
+	e.Authorize(Request{Subject: Subject{Name: "alice", Roles: []string{"reader"}}, Action: "DELETE", Resource: "users"})
+
+	var entries []AuditEntry
+	for dec := json.NewDecoder(&buf); dec.More(); {
+		var entry AuditEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	want := []AuditEntry{
+		{Sequence: 1, Time: now, Subject: "alice", Roles: []string{"reader"}, Action: "SELECT", Resource: "users", Allowed: true, Reason: "allowed by role reader"},
+		{Sequence: 2, Time: now, Subject: "alice", Roles: []string{"reader"}, Action: "DELETE", Resource: "users", Reason: "no rule allows it"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("audit entries = %+v, want %+v", entries, want)
+	}
+
+	e, err = New(doc, WithAuditLog(failingAuditLog{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := e.Authorize(Request{Subject: Subject{Name: "alice", Roles: []string{"admin"}}, Action: "SELECT", Resource: "users"})
+	if d.Allowed || !strings.Contains(d.Reason, "disk full") {
+		t.Errorf("expected an unauditable request to be denied, got %+v", d)
+	}
+}
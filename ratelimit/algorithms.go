@@ -0,0 +1,225 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// TokenBucket refills Rate tokens per Period up to Burst, and each request
+// takes one token. State per key is the token count and the time it was
+// last refilled.
+type TokenBucket struct{ limiter }
+
+// NewTokenBucket creates a token bucket limiter.
+func NewTokenBucket(limit Limit, opts ...Option) (*TokenBucket, error) {
+	l, err := newLimiter("tb:", limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenBucket{l}, nil
+}
+
+// AllowN implements Limiter.
+func (tb *TokenBucket) AllowN(ctx context.Context, key string, n int) (Decision, error) {
+	burst := tb.limit.burst()
+	if err := checkN(n, burst); err != nil {
+		return Decision{}, err
+	}
+	perToken := float64(tb.limit.interval())
+	return tb.update(ctx, key, func(state []byte, now time.Time) ([]byte, time.Duration, Decision) {
+		tokens := float64(burst)
+		if len(state) == 16 {
+			last := readTime(state)
+			saved := math.Float64frombits(binary.BigEndian.Uint64(state[8:]))
+			tokens = min(float64(burst), saved+float64(now.Sub(last))/perToken)
+		}
+
+		d := Decision{Limit: burst}
+		if tokens >= float64(n) {
+			tokens -= float64(n)
+			d.Allowed = true
+		} else {
+			d.RetryAfter = time.Duration(math.Ceil((float64(n) - tokens) * perToken))
+		}
+		d.Remaining = int(tokens)
+		d.ResetAfter = time.Duration(math.Ceil((float64(burst) - tokens) * perToken))
+
+		next := binary.BigEndian.AppendUint64(appendTime(nil, now), math.Float64bits(tokens))
+		return next, max(d.ResetAfter, time.Nanosecond), d
+	})
+}
+
+// GCRA is the generic cell rate algorithm: a token bucket that stores only
+// the theoretical arrival time (TAT) of the next request, which makes it the
+// cheapest algorithm to keep in a shared store.
+type GCRA struct{ limiter }
+
+// NewGCRA creates a GCRA limiter.
+func NewGCRA(limit Limit, opts ...Option) (*GCRA, error) {
+	l, err := newLimiter("gcra:", limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &GCRA{l}, nil
+}
+
+// AllowN implements Limiter.
+func (g *GCRA) AllowN(ctx context.Context, key string, n int) (Decision, error) {
+	burst := g.limit.burst()
+	if err := checkN(n, burst); err != nil {
+		return Decision{}, err
+	}
+	interval := g.limit.interval()
+	tolerance := interval * time.Duration(burst)
+	return g.update(ctx, key, func(state []byte, now time.Time) ([]byte, time.Duration, Decision) {
+		tat := now
+		if len(state) == 8 {
+			if saved := readTime(state); saved.After(now) {
+				tat = saved
+			}
+		}
+
+		d := Decision{Limit: burst}
+		newTAT := tat.Add(interval * time.Duration(n))
+		if allowAt := newTAT.Add(-tolerance); now.Before(allowAt) {
+			d.RetryAfter = allowAt.Sub(now)
+			d.Remaining = int(tolerance-tat.Sub(now)) / int(interval)
+			d.ResetAfter = tat.Sub(now)
+			return state, d.ResetAfter, d
+		}
+		d.Allowed = true
+		d.Remaining = int(tolerance-newTAT.Sub(now)) / int(interval)
+		d.ResetAfter = newTAT.Sub(now)
+		return appendTime(nil, newTAT), d.ResetAfter, d
+	})
+}
+
+// SlidingWindowLog remembers the time of every allowed request in the last
+// Period and allows at most Rate of them. It is exact, at the cost of
+// storing up to Rate timestamps per key.
+type SlidingWindowLog struct{ limiter }
+
+// NewSlidingWindowLog creates a sliding-window log limiter. Limit.Burst is
+// ignored.
+func NewSlidingWindowLog(limit Limit, opts ...Option) (*SlidingWindowLog, error) {
+	l, err := newLimiter("swl:", limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SlidingWindowLog{l}, nil
+}
+
+// AllowN implements Limiter.
+func (s *SlidingWindowLog) AllowN(ctx context.Context, key string, n int) (Decision, error) {
+	rate, period := s.limit.Rate, s.limit.Period
+	if err := checkN(n, rate); err != nil {
+		return Decision{}, err
+	}
+	return s.update(ctx, key, func(state []byte, now time.Time) ([]byte, time.Duration, Decision) {
+		// The log is oldest first; drop what has left the window.
+		var log []time.Time
+		for i := 0; i+8 <= len(state); i += 8 {
+			if t := readTime(state[i:]); now.Sub(t) < period {
+				log = append(log, t)
+			}
+		}
+
+		d := Decision{Limit: rate}
+		if len(log)+n <= rate {
+			d.Allowed = true
+			for range n {
+				log = append(log, now)
+			}
+		} else {
+			// Enough of the oldest entries must expire to make room for n.
+			d.RetryAfter = log[len(log)+n-rate-1].Add(period).Sub(now)
+		}
+		d.Remaining = rate - len(log)
+		d.ResetAfter = log[len(log)-1].Add(period).Sub(now)
+
+		next := make([]byte, 0, 8*len(log))
+		for _, t := range log {
+			next = appendTime(next, t)
+		}
+		return next, d.ResetAfter, d
+	})
+}
+
+// SlidingWindowCounter counts requests in fixed windows of Period and
+// estimates the sliding window by weighting the previous window's count by
+// how much of it still overlaps. It stores three numbers per key whatever
+// the rate, and is approximate: it assumes the previous window's requests
+// were evenly spread.
+type SlidingWindowCounter struct{ limiter }
+
+// NewSlidingWindowCounter creates a sliding-window counter limiter.
+// Limit.Burst is ignored.
+func NewSlidingWindowCounter(limit Limit, opts ...Option) (*SlidingWindowCounter, error) {
+	l, err := newLimiter("swc:", limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SlidingWindowCounter{l}, nil
+}
+
+// AllowN implements Limiter.
+func (s *SlidingWindowCounter) AllowN(ctx context.Context, key string, n int) (Decision, error) {
+	rate, period := s.limit.Rate, s.limit.Period
+	if err := checkN(n, rate); err != nil {
+		return Decision{}, err
+	}
+	return s.update(ctx, key, func(state []byte, now time.Time) ([]byte, time.Duration, Decision) {
+		window := now.Truncate(period)
+		var prev, curr uint64
+		if len(state) == 24 {
+			switch start := readTime(state); {
+			case start.Equal(window):
+				prev, curr = binary.BigEndian.Uint64(state[8:]), binary.BigEndian.Uint64(state[16:])
+			case start.Add(period).Equal(window):
+				prev = binary.BigEndian.Uint64(state[16:])
+			}
+		}
+
+		elapsed := now.Sub(window)
+		overlap := 1 - float64(elapsed)/float64(period)
+		estimate := float64(prev)*overlap + float64(curr)
+
+		d := Decision{Limit: rate}
+		if estimate+float64(n) <= float64(rate) {
+			d.Allowed = true
+			curr += uint64(n)
+			estimate += float64(n)
+		} else {
+			d.RetryAfter = s.retryAfter(prev, curr, n, elapsed)
+		}
+		d.Remaining = max(0, int(float64(rate)-estimate))
+		switch {
+		case curr > 0:
+			d.ResetAfter = 2*period - elapsed
+		case prev > 0:
+			d.ResetAfter = period - elapsed
+		}
+
+		next := appendTime(nil, window)
+		next = binary.BigEndian.AppendUint64(next, prev)
+		next = binary.BigEndian.AppendUint64(next, curr)
+		return next, 2*period - elapsed, d
+	})
+}
+
+// retryAfter finds when the weighted estimate leaves room for n: later in
+// this window if the current count alone fits, otherwise in the next one.
+func (s *SlidingWindowCounter) retryAfter(prev, curr uint64, n int, elapsed time.Duration) time.Duration {
+	rate, period := float64(s.limit.Rate), s.limit.Period
+	// at is how far into a window count, weighted by the part of its own
+	// window still overlapping, fits in room.
+	at := func(count uint64, room float64) time.Duration {
+		return time.Duration(math.Ceil((1 - room/float64(count)) * float64(period)))
+	}
+	if room := rate - float64(curr) - float64(n); room >= 0 {
+		return at(prev, room) - elapsed
+	}
+	return period - elapsed + at(curr, rate-float64(n))
+}
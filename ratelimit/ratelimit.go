@@ -0,0 +1,176 @@
+// Package ratelimit provides per-key rate limiters shared by the proxy,
+// chain-of-responsibility and intercepting-filter examples. Four algorithms
+// are available: token bucket, GCRA, sliding-window log and sliding-window
+// counter. Each keeps its per-key state in a Store, so limits can be shared
+// by every process that uses the same store. MemoryStore is the in-process
+// implementation.
+package ratelimit
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLimited matches every error returned by Decision.Err.
+var ErrLimited = errors.New("rate limit exceeded")
+
+// Limit is Rate requests per Period. Burst is how many requests the token
+// bucket and GCRA accept at once; zero means Rate.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// PerSecond returns a limit of n requests per second.
+func PerSecond(n int) Limit { return Limit{Rate: n, Period: time.Second} }
+
+// PerMinute returns a limit of n requests per minute.
+func PerMinute(n int) Limit { return Limit{Rate: n, Period: time.Minute} }
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// interval is the time one request's worth of capacity takes to return.
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Rate)
+}
+
+func (l Limit) validate() error {
+	if l.Rate <= 0 || l.Period <= 0 || l.Burst < 0 {
+		return fmt.Errorf("ratelimit: invalid limit %+v", l)
+	}
+	return nil
+}
+
+// Decision is the outcome of a request for capacity.
+type Decision struct {
+	Allowed bool
+	// Limit is the most requests that can be allowed at once.
+	Limit int
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int
+	// RetryAfter is how long to wait before the same request would be
+	// allowed. It is zero when Allowed is true.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the key is back to full capacity.
+	ResetAfter time.Duration
+}
+
+// Err returns nil if the request was allowed, or an *ExceededError.
+func (d Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+	return &ExceededError{Limit: d.Limit, RetryAfter: d.RetryAfter}
+}
+
+// ExceededError reports a denied request and when to retry.
+type ExceededError struct {
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: retry after %v", e.RetryAfter.Round(time.Millisecond))
+}
+
+// Is makes errors.Is(err, ErrLimited) true.
+func (e *ExceededError) Is(target error) bool { return target == ErrLimited }
+
+// Limiter decides whether requests for a key are within its limit.
+type Limiter interface {
+	// AllowN asks for n units of capacity for key at once. A denied request
+	// consumes nothing. Errors report store failures, or an n that the
+	// limit could never allow; a denial is not an error.
+	AllowN(ctx context.Context, key string, n int) (Decision, error)
+}
+
+// Allow asks l for one unit of capacity for key.
+func Allow(ctx context.Context, l Limiter, key string) (Decision, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+type options struct {
+	store Store
+	now   func() time.Time
+}
+
+// Option configures a limiter.
+type Option func(*options)
+
+// WithStore keeps limiter state in s instead of a new MemoryStore. Limiters
+// sharing a store must use different algorithms or the same limit, since
+// each algorithm owns its keys in the store.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithClock replaces time.Now, for tests. The default MemoryStore uses the
+// same clock to expire state.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// limiter is the state every algorithm shares.
+type limiter struct {
+	limit  Limit
+	store  Store
+	now    func() time.Time
+	prefix string
+}
+
+func newLimiter(prefix string, limit Limit, opts []Option) (limiter, error) {
+	if err := limit.validate(); err != nil {
+		return limiter{}, err
+	}
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore(WithClock(o.now))
+	}
+	return limiter{limit: limit, store: o.store, now: o.now, prefix: prefix}, nil
+}
+
+// update runs step on the key's state in the store and returns its decision.
+func (l *limiter) update(ctx context.Context, key string, step func(state []byte, now time.Time) ([]byte, time.Duration, Decision)) (Decision, error) {
+	var d Decision
+	err := l.store.Update(ctx, l.prefix+key, func(state []byte) ([]byte, time.Duration, error) {
+		next, ttl, decision := step(state, l.now())
+		d = decision
+		return next, ttl, nil
+	})
+	if err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: %w", err)
+	}
+	return d, nil
+}
+
+// checkN rejects requests that could never be allowed under max.
+func checkN(n, max int) error {
+	if n <= 0 || n > max {
+		return fmt.Errorf("ratelimit: cannot allow %d at once with a limit of %d", n, max)
+	}
+	return nil
+}
+
+func appendTime(b []byte, t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(t.UnixNano()))
+}
+
+func readTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
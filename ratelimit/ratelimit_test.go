@@ -0,0 +1,269 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock shared by a limiter under test.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1_700_000_000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var algorithms = []struct {
+	name string
+	new  func(Limit, ...Option) (Limiter, error)
+}{
+	{"token bucket", func(l Limit, o ...Option) (Limiter, error) { return NewTokenBucket(l, o...) }},
+	{"GCRA", func(l Limit, o ...Option) (Limiter, error) { return NewGCRA(l, o...) }},
+	{"sliding window log", func(l Limit, o ...Option) (Limiter, error) { return NewSlidingWindowLog(l, o...) }},
+	{"sliding window counter", func(l Limit, o ...Option) (Limiter, error) { return NewSlidingWindowCounter(l, o...) }},
+}
+
+func allow(t *testing.T, l Limiter, key string) Decision {
+	t.Helper()
+	d, err := Allow(context.Background(), l, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// TestLimiters tests that every algorithm allows the limit, denies the
+// next request with a RetryAfter that is honoured, and keeps keys apart.
+func TestLimiters(t *testing.T) {
+	for _, alg := range algorithms {
+		t.Run(alg.name, func(t *testing.T) {
+			clock := newFakeClock()
+			l, err := alg.new(Limit{Rate: 3, Period: time.Second}, WithClock(clock.Now))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range 3 {
+				d := allow(t, l, "alice")
+				if !d.Allowed || d.Remaining != 2-i || d.Limit != 3 {
+					t.Fatalf("request %d: got %+v", i+1, d)
+				}
+			}
+			// The counter may have to wait into the next window for the
+			// previous one's weight to fall, so allow up to two periods.
+			d := allow(t, l, "alice")
+			if d.Allowed || d.RetryAfter <= 0 || d.RetryAfter > 2*time.Second {
+				t.Fatalf("4th request: got %+v", d)
+			}
+			err = d.Err()
+			var exceeded *ExceededError
+			if !errors.Is(err, ErrLimited) || !errors.As(err, &exceeded) || exceeded.RetryAfter != d.RetryAfter {
+				t.Errorf("Err() = %v", err)
+			}
+
+			if d := allow(t, l, "bob"); !d.Allowed {
+				t.Errorf("expected bob to have a separate limit, got %+v", d)
+			}
+
+			clock.Advance(d.RetryAfter - time.Millisecond)
+			if d := allow(t, l, "alice"); d.Allowed {
+				t.Errorf("allowed %v before RetryAfter", time.Millisecond)
+			}
+			clock.Advance(time.Millisecond)
+			if d := allow(t, l, "alice"); !d.Allowed {
+				t.Errorf("denied at RetryAfter: %+v", d)
+			}
+		})
+	}
+}
+
+// TestLimiters_AllowN tests requests for several units at once.
+func TestLimiters_AllowN(t *testing.T) {
+	ctx := context.Background()
+	for _, alg := range algorithms {
+		t.Run(alg.name, func(t *testing.T) {
+			l, err := alg.new(PerSecond(4), WithClock(newFakeClock().Now))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d, _ := l.AllowN(ctx, "k", 3); !d.Allowed || d.Remaining != 1 {
+				t.Errorf("AllowN(3) = %+v", d)
+			}
+			if d, _ := l.AllowN(ctx, "k", 2); d.Allowed {
+				t.Errorf("AllowN(2) with 1 left = %+v", d)
+			}
+			if d, _ := l.AllowN(ctx, "k", 1); !d.Allowed {
+				t.Errorf("a denied AllowN should consume nothing, got %+v", d)
+			}
+			if _, err := l.AllowN(ctx, "k", 5); err == nil {
+				t.Error("expected an error for more than the limit at once")
+			}
+		})
+	}
+}
+
+// TestBurst tests that Burst sets the bucket size apart from the rate.
+func TestBurst(t *testing.T) {
+	for _, alg := range algorithms[:2] {
+		t.Run(alg.name, func(t *testing.T) {
+			clock := newFakeClock()
+			l, err := alg.new(Limit{Rate: 1, Period: time.Second, Burst: 5}, WithClock(clock.Now))
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed := 0
+			for range 10 {
+				if allow(t, l, "k").Allowed {
+					allowed++
+				}
+			}
+			if allowed != 5 {
+				t.Errorf("allowed %d of a burst of 5", allowed)
+			}
+			clock.Advance(time.Second)
+			if d := allow(t, l, "k"); !d.Allowed || d.Remaining != 0 {
+				t.Errorf("after 1s, expected 1 refilled request, got %+v", d)
+			}
+		})
+	}
+}
+
+// TestSlidingWindowCounter_Weighting tests that the previous window counts
+// in proportion to its overlap with the sliding window.
+func TestSlidingWindowCounter_Weighting(t *testing.T) {
+	clock := newFakeClock()
+	l, err := NewSlidingWindowCounter(PerSecond(10), WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake clock starts on a window boundary.
+	for range 10 {
+		allow(t, l, "k")
+	}
+
+	// A quarter into the next window, 75% of the previous 10 still count.
+	clock.Advance(time.Second + time.Second/4)
+	allowed := 0
+	for range 5 {
+		if allow(t, l, "k").Allowed {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("allowed %d, want 2", allowed)
+	}
+}
+
+// TestMemoryStore_Expiry tests that keys are dropped once their TTL passes.
+func TestMemoryStore_Expiry(t *testing.T) {
+	clock := newFakeClock()
+	s := NewMemoryStore(WithClock(clock.Now))
+	ctx := context.Background()
+
+	set := func(state []byte) ([]byte, time.Duration, error) { return []byte("x"), time.Second, nil }
+	if err := s.Update(ctx, "k", set); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	if err := s.Update(ctx, "k", func(state []byte) ([]byte, time.Duration, error) {
+		if state != nil {
+			t.Errorf("expected expired state to read as nil, got %q", state)
+		}
+		return nil, 0, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", s.Len())
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Update(cancelled, "k", set); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// TestLimiter_DefaultStoreUsesClock tests that a limiter's own store expires
+// state by the limiter's clock rather than the wall clock.
+func TestLimiter_DefaultStoreUsesClock(t *testing.T) {
+	clock := newFakeClock()
+	g, err := NewGCRA(PerSecond(1), WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := Allow(ctx, g, "k"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	err = g.store.Update(ctx, g.prefix+"k", func(state []byte) ([]byte, time.Duration, error) {
+		if state != nil {
+			t.Errorf("expected state to expire with the limiter's clock, got %q", state)
+		}
+		return nil, 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLimiters_Concurrent tests that concurrent requests never exceed the
+// limit, with limiters of different algorithms sharing one store.
+func TestLimiters_Concurrent(t *testing.T) {
+	store := NewMemoryStore()
+	clock := newFakeClock()
+	for _, alg := range algorithms {
+		t.Run(alg.name, func(t *testing.T) {
+			l, err := alg.new(PerMinute(50), WithStore(store), WithClock(clock.Now))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var allowed atomic.Int64
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Go(func() {
+					for range 20 {
+						if d, err := Allow(context.Background(), l, "shared"); err == nil && d.Allowed {
+							allowed.Add(1)
+						}
+					}
+				})
+			}
+			wg.Wait()
+			if got := allowed.Load(); got != 50 {
+				t.Errorf("allowed %d of 160 requests, want 50", got)
+			}
+		})
+	}
+}
+
+// TestNew_InvalidLimit tests that limits without a rate or period are
+// rejected.
+func TestNew_InvalidLimit(t *testing.T) {
+	for _, limit := range []Limit{{}, {Rate: 1}, {Period: time.Second}, {Rate: 1, Period: time.Second, Burst: -1}} {
+		for _, alg := range algorithms {
+			if _, err := alg.new(limit); err == nil {
+				t.Errorf("%s: expected an error for %+v", alg.name, limit)
+			}
+		}
+	}
+}
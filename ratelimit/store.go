@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store holds limiter state by key. Update must apply fn atomically with
+// respect to other updates of the same key: a store shared between
+// processes would use a transaction or compare-and-swap and retry fn on
+// conflict, so fn must be free of side effects.
+type Store interface {
+	// Update calls fn with the key's current state, or nil if there is none
+	// or it has expired, and stores what fn returns for ttl. A nil state
+	// deletes the key.
+	Update(ctx context.Context, key string, fn func(state []byte) (next []byte, ttl time.Duration, err error)) error
+}
+
+// MemoryStore is an in-process Store. Expired keys are dropped when touched
+// and swept periodically, so idle keys do not accumulate.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	updates int
+}
+
+type memoryEntry struct {
+	state   []byte
+	expires time.Time
+}
+
+// sweepEvery is how many updates pass between sweeps of expired keys.
+const sweepEvery = 1024
+
+// NewMemoryStore creates an empty MemoryStore. Of the options only WithClock
+// applies; a limiter given the store with WithStore should use the same clock,
+// or entries expire by a different time than the limiter computes with.
+func NewMemoryStore(opts ...Option) *MemoryStore {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &MemoryStore{now: o.now, entries: make(map[string]memoryEntry)}
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(state []byte) ([]byte, time.Duration, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.updates++
+	if s.updates%sweepEvery == 0 {
+		for k, e := range s.entries {
+			if !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	var state []byte
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		state = e.state
+	}
+	next, ttl, err := fn(state)
+	if err != nil {
+		return err
+	}
+	if next == nil || ttl <= 0 {
+		delete(s.entries, key)
+		return nil
+	}
+	s.entries[key] = memoryEntry{state: next, expires: now.Add(ttl)}
+	return nil
+}
+
+// Len returns the number of keys held, including expired ones not yet swept.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
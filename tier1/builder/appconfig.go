@@ -0,0 +1,347 @@
+package builder
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sources reported by AppConfig.Source for values that do not come from a
+// file, environment variable or flag.
+const (
+	SourceDefault = "default"
+	SourceBuilder = "builder"
+)
+
+// environmentPattern restricts environment names, which become part of
+// overlay file paths, to a single safe path segment.
+var environmentPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// appConfigField describes one AppConfig setting that can be loaded from
+// files, environment variables and flags.
+type appConfigField struct {
+	key   string
+	usage string
+	set   func(c *AppConfig, value string) error
+	get   func(c *AppConfig) string
+}
+
+// appConfigFields lists every loadable setting, keyed as in config files.
+// Environment variables and flags use the same names in their own casing:
+// server_port, APP_SERVER_PORT and -server-port.
+var appConfigFields = []appConfigField{
+	{
+		key:   "server_port",
+		usage: "server port",
+		set:   func(c *AppConfig, v string) error { return parseInt(v, &c.serverPort) },
+		get:   func(c *AppConfig) string { return strconv.Itoa(c.serverPort) },
+	},
+	{
+		key:   "server_host",
+		usage: "server host",
+		set:   func(c *AppConfig, v string) error { c.serverHost = v; return nil },
+		get:   func(c *AppConfig) string { return c.serverHost },
+	},
+	{
+		key:   "database_url",
+		usage: "database connection URL",
+		set:   func(c *AppConfig, v string) error { c.databaseURL = v; return nil },
+		get:   func(c *AppConfig) string { return c.databaseURL },
+	},
+	{
+		key:   "log_level",
+		usage: "log level (debug, info, warn, error)",
+		set:   func(c *AppConfig, v string) error { c.logLevel = v; return nil },
+		get:   func(c *AppConfig) string { return c.logLevel },
+	},
+	{
+		key:   "max_workers",
+		usage: "maximum number of workers",
+		set:   func(c *AppConfig, v string) error { return parseInt(v, &c.maxWorkers) },
+		get:   func(c *AppConfig) string { return strconv.Itoa(c.maxWorkers) },
+	},
+	{
+		key:   "enable_metrics",
+		usage: "enable metrics collection",
+		set:   func(c *AppConfig, v string) error { return parseBool(v, &c.enableMetrics) },
+		get:   func(c *AppConfig) string { return strconv.FormatBool(c.enableMetrics) },
+	},
+	{
+		key:   "enable_profiling",
+		usage: "enable profiling",
+		set:   func(c *AppConfig, v string) error { return parseBool(v, &c.enableProfiling) },
+		get:   func(c *AppConfig) string { return strconv.FormatBool(c.enableProfiling) },
+	},
+	{
+		key:   "enable_tracing",
+		usage: "enable distributed tracing",
+		set:   func(c *AppConfig, v string) error { return parseBool(v, &c.enableTracing) },
+		get:   func(c *AppConfig) string { return strconv.FormatBool(c.enableTracing) },
+	},
+	{
+		key:   "shutdown_timeout",
+		usage: "graceful shutdown timeout (e.g. 30s)",
+		set: func(c *AppConfig, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.shutdownTimeout = d
+			return nil
+		},
+		get: func(c *AppConfig) string { return c.shutdownTimeout.String() },
+	},
+	{
+		key:   "environment",
+		usage: "environment (development, staging, production)",
+		set:   func(c *AppConfig, v string) error { c.environment = v; return nil },
+		get:   func(c *AppConfig) string { return c.environment },
+	},
+}
+
+func parseInt(v string, dst *int) error {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return err
+	}
+	*dst = n
+	return nil
+}
+
+func parseBool(v string, dst *bool) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return err
+	}
+	*dst = b
+	return nil
+}
+
+func lookupAppConfigField(key string) (appConfigField, bool) {
+	for _, field := range appConfigFields {
+		if field.key == key {
+			return field, true
+		}
+	}
+	return appConfigField{}, false
+}
+
+// normalizeConfigKey maps file keys, flag names and environment variable
+// suffixes onto field keys: "Server-Port" and "server.port" become "server_port".
+func normalizeConfigKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return strings.NewReplacer("-", "_", ".", "_").Replace(key)
+}
+
+// configValue is a raw setting together with where it came from.
+type configValue struct {
+	value  string
+	source string
+}
+
+// configLayer is one set of raw settings, keyed by field key.
+type configLayer map[string]configValue
+
+// FromFile loads settings from a JSON, YAML or TOML file, chosen by extension.
+// Nested keys are joined with underscores, so {"server": {"port": 80}} sets
+// server_port. If a sibling file named after the environment exists (for
+// example config.production.yaml next to config.yaml) it is applied on top.
+// Files are read at Build; later files override earlier ones.
+func (b *AppConfigBuilder) FromFile(path string) *AppConfigBuilder {
+	b.files = append(b.files, path)
+	return b
+}
+
+// FromEnv loads settings from environment variables named prefix_KEY, such
+// as APP_SERVER_PORT for prefix "APP". Variables are read at Build.
+func (b *AppConfigBuilder) FromEnv(prefix string) *AppConfigBuilder {
+	b.envPrefixes = append(b.envPrefixes, prefix)
+	return b
+}
+
+// FromFlags loads settings from flags that were set on flagSet. Flags are matched
+// by name (server-port or server_port) and flagSet must be parsed before Build.
+// RegisterAppConfigFlags defines a flag for every setting.
+func (b *AppConfigBuilder) FromFlags(flagSet *flag.FlagSet) *AppConfigBuilder {
+	b.flagSets = append(b.flagSets, flagSet)
+	return b
+}
+
+// RegisterAppConfigFlags defines a string flag on flagSet for every AppConfig
+// setting, named like -server-port, for use with FromFlags.
+func RegisterAppConfigFlags(flagSet *flag.FlagSet) {
+	for _, field := range appConfigFields {
+		flagSet.String(strings.ReplaceAll(field.key, "_", "-"), "", field.usage)
+	}
+}
+
+// merge applies every layer on top of the defaults in precedence order.
+func (b *AppConfigBuilder) merge() (*AppConfig, error) {
+	var files []configLayer
+	for _, path := range b.files {
+		layer, err := loadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, layer)
+	}
+	env := b.envLayer()
+	flags := b.flagLayer()
+	explicit := make(configLayer, len(b.explicit))
+	for key := range b.explicit {
+		field, _ := lookupAppConfigField(key)
+		explicit[key] = configValue{value: field.get(b.config), source: SourceBuilder}
+	}
+
+	// The environment picks the overlay files, so resolve it from the
+	// regular layers before loading them.
+	environment := configValue{value: defaultAppConfig().environment, source: SourceDefault}
+	for _, layer := range slices.Concat(files, []configLayer{env, flags, explicit}) {
+		if v, ok := layer["environment"]; ok {
+			environment = v
+		}
+	}
+	if !environmentPattern.MatchString(environment.value) {
+		return nil, fmt.Errorf("invalid environment from %s: %q must match %s",
+			environment.source, environment.value, environmentPattern)
+	}
+
+	var overlays []configLayer
+	for _, path := range b.files {
+		layer, err := loadConfigFile(overlayPath(path, environment.value))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, layer)
+	}
+
+	config := defaultAppConfig()
+	config.sources = make(map[string]string, len(appConfigFields))
+	for _, field := range appConfigFields {
+		config.sources[field.key] = SourceDefault
+	}
+
+	layers := slices.Concat(files, overlays, []configLayer{env, flags, explicit})
+	for _, layer := range layers {
+		keys := make([]string, 0, len(layer))
+		for key := range layer {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := layer[key]
+			field, _ := lookupAppConfigField(key)
+			if err := field.set(config, value.value); err != nil {
+				return nil, fmt.Errorf("invalid %s from %s: %w", key, value.source, err)
+			}
+			config.sources[key] = value.source
+		}
+	}
+	return config, nil
+}
+
+// envLayer collects settings from the environment. Unrelated variables that
+// share the prefix are ignored.
+func (b *AppConfigBuilder) envLayer() configLayer {
+	layer := make(configLayer)
+	for _, prefix := range b.envPrefixes {
+		if prefix != "" && !strings.HasSuffix(prefix, "_") {
+			prefix += "_"
+		}
+		for _, kv := range os.Environ() {
+			name, value, _ := strings.Cut(kv, "=")
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			key := normalizeConfigKey(strings.TrimPrefix(name, prefix))
+			if _, ok := lookupAppConfigField(key); ok {
+				layer[key] = configValue{value: value, source: "env:" + name}
+			}
+		}
+	}
+	return layer
+}
+
+// flagLayer collects settings from flags that were explicitly set.
+func (b *AppConfigBuilder) flagLayer() configLayer {
+	layer := make(configLayer)
+	for _, flagSet := range b.flagSets {
+		flagSet.Visit(func(f *flag.Flag) {
+			key := normalizeConfigKey(f.Name)
+			if _, ok := lookupAppConfigField(key); ok {
+				layer[key] = configValue{value: f.Value.String(), source: "flag:-" + f.Name}
+			}
+		})
+	}
+	return layer
+}
+
+// overlayPath returns the environment-specific sibling of path:
+// config.yaml becomes config.production.yaml. environment must match
+// environmentPattern so the result stays in the same directory.
+func overlayPath(path, environment string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + environment + ext
+}
+
+// loadConfigFile parses a config file into a layer. Unknown keys are an
+// error so typos do not silently fall back to defaults.
+func loadConfigFile(path string) (configLayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		values, err = parseJSONConfig(data)
+	case ".yaml", ".yml":
+		values, err = parseYAMLConfig(data)
+	case ".toml":
+		values, err = parseTOMLConfig(data)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	layer := make(configLayer, len(values))
+	for key, value := range values {
+		if _, ok := lookupAppConfigField(key); !ok {
+			return nil, fmt.Errorf("unknown config key %q in %s", key, path)
+		}
+		layer[key] = configValue{value: value, source: "file:" + path}
+	}
+	return layer, nil
+}
+
+// Source reports where a setting's value came from: "default", "builder",
+// "file:<path>", "env:<NAME>" or "flag:-<name>". Keys are the file key names,
+// such as "server_port". It returns "" for unknown keys.
+func (c *AppConfig) Source(key string) string {
+	return c.sources[normalizeConfigKey(key)]
+}
+
+// Sources returns the source of every setting, keyed like Source.
+func (c *AppConfig) Sources() map[string]string {
+	sources := make(map[string]string, len(c.sources))
+	for key, source := range c.sources {
+		sources[key] = source
+	}
+	return sources
+}
@@ -0,0 +1,187 @@
+package builder
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestAppConfigBuilder_FileFormats tests JSON, YAML and TOML loading.
+func TestAppConfigBuilder_FileFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"server": {"port": 9000, "host": "127.0.0.1"}, "log_level": "debug", "enable_metrics": true, "shutdown_timeout": "5s"}`,
+		"config.yaml": `
+# application settings
+server:
+  port: 9000
+  host: "127.0.0.1"
+log_level: debug # inline comment
+enable_metrics: true
+shutdown_timeout: 5s
+`,
+		"config.toml": `
+log_level = "debug"
+enable_metrics = true
+shutdown_timeout = "5s"
+
+[server]
+port = 9000
+host = "127.0.0.1"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, dir, name, content)
+			config, err := NewAppConfigBuilder().FromFile(path).Build()
+			if err != nil {
+				t.Fatalf("Expected successful build, got error: %v", err)
+			}
+
+			if config.serverPort != 9000 || config.serverHost != "127.0.0.1" {
+				t.Errorf("Expected server 127.0.0.1:9000, got %s:%d", config.serverHost, config.serverPort)
+			}
+			if config.logLevel != "debug" || !config.enableMetrics || config.shutdownTimeout != 5*time.Second {
+				t.Errorf("Unexpected config: %s", config)
+			}
+			if got := config.Source("server_port"); got != "file:"+path {
+				t.Errorf("Expected server_port from file, got %q", got)
+			}
+			if got := config.Source("max_workers"); got != SourceDefault {
+				t.Errorf("Expected max_workers from default, got %q", got)
+			}
+		})
+	}
+}
+
+// TestAppConfigBuilder_Precedence tests that layers override each other in
+// the documented order regardless of call order.
+func TestAppConfigBuilder_Precedence(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "config.yaml", "server_port: 7000\nmax_workers: 2\nlog_level: warn\nserver_host: file-host\n")
+	t.Setenv("APP_SERVER_PORT", "7100")
+	t.Setenv("APP_MAX_WORKERS", "6")
+	t.Setenv("APP_UNRELATED", "ignored")
+
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	RegisterAppConfigFlags(flags)
+	if err := flags.Parse([]string{"-server-port", "7200"}); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := NewAppConfigBuilder().
+		WithLogLevel("error").
+		FromFlags(flags).
+		FromEnv("APP").
+		FromFile(path).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+
+	expected := map[string]struct {
+		value  any
+		source string
+	}{
+		"server_host": {config.serverHost, "file:" + path},
+		"max_workers": {config.maxWorkers, "env:APP_MAX_WORKERS"},
+		"server_port": {config.serverPort, "flag:-server-port"},
+		"log_level":   {config.logLevel, SourceBuilder},
+	}
+	for key, want := range expected {
+		if got := config.Source(key); got != want.source {
+			t.Errorf("Expected %s from %s, got %s (value %v)", key, want.source, got, want.value)
+		}
+	}
+	if config.serverPort != 7200 || config.maxWorkers != 6 || config.logLevel != "error" {
+		t.Errorf("Unexpected merged config: %s", config)
+	}
+}
+
+// TestAppConfigBuilder_EnvironmentOverlay tests environment-specific overlay files.
+func TestAppConfigBuilder_EnvironmentOverlay(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.json", `{"log_level": "debug", "max_workers": 2}`)
+	overlay := writeConfigFile(t, dir, "config.production.json", `{"log_level": "warn"}`)
+
+	config, err := NewAppConfigBuilder().
+		FromFile(path).
+		WithEnvironment("production").
+		Build()
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+	if config.logLevel != "warn" || config.Source("log_level") != "file:"+overlay {
+		t.Errorf("Expected overlay log level, got %s from %s", config.logLevel, config.Source("log_level"))
+	}
+	if config.maxWorkers != 2 {
+		t.Errorf("Expected base file max workers, got %d", config.maxWorkers)
+	}
+
+	config, err = NewAppConfigBuilder().FromFile(path).Build()
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+	if config.logLevel != "debug" {
+		t.Errorf("Expected no overlay for development, got log level %s", config.logLevel)
+	}
+
+	// The environment names a file, so it must not escape the directory.
+	writeConfigFile(t, dir, "x.json", `{"log_level": "error"}`)
+	for _, environment := range []string{"/../x", "../../etc/passwd", "prod.x", ""} {
+		_, err := NewAppConfigBuilder().FromFile(path).WithEnvironment(environment).Build()
+		if err == nil || !strings.Contains(err.Error(), "invalid environment") {
+			t.Errorf("Expected environment %q to be rejected, got %v", environment, err)
+		}
+	}
+	t.Setenv("APP_ENVIRONMENT", "../x")
+	if _, err := NewAppConfigBuilder().FromFile(path).FromEnv("APP").Build(); err == nil ||
+		!strings.Contains(err.Error(), "env:APP_ENVIRONMENT") {
+		t.Errorf("Expected environment from APP_ENVIRONMENT to be rejected, got %v", err)
+	}
+}
+
+// TestAppConfigBuilder_ValidationAfterMerge tests that invalid layered values
+// fail validation and that explicit values can fix them.
+func TestAppConfigBuilder_ValidationAfterMerge(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.toml", "max_workers = 0\n")
+
+	if _, err := NewAppConfigBuilder().FromFile(path).Build(); err == nil {
+		t.Error("Expected validation error for max_workers from file, got nil")
+	}
+	if _, err := NewAppConfigBuilder().FromFile(path).WithMaxWorkers(3).Build(); err != nil {
+		t.Errorf("Expected explicit value to override invalid file value, got: %v", err)
+	}
+
+	badType := writeConfigFile(t, dir, "bad.yaml", "server_port: eighty\n")
+	if _, err := NewAppConfigBuilder().FromFile(badType).Build(); err == nil {
+		t.Error("Expected parse error for non-numeric port, got nil")
+	}
+
+	sequence := writeConfigFile(t, dir, "list.yaml", "server_host: [a, b]\n")
+	if _, err := NewAppConfigBuilder().FromFile(sequence).Build(); err == nil || !strings.Contains(err.Error(), "sequences are not supported") {
+		t.Errorf("Expected a sequence to be rejected, got %v", err)
+	}
+
+	unknown := writeConfigFile(t, dir, "typo.json", `{"sever_port": 80}`)
+	if _, err := NewAppConfigBuilder().FromFile(unknown).Build(); err == nil {
+		t.Error("Expected error for unknown config key, got nil")
+	}
+
+	if _, err := NewAppConfigBuilder().FromFile(filepath.Join(dir, "missing.yaml")).Build(); err == nil {
+		t.Error("Expected error for missing config file, got nil")
+	}
+}
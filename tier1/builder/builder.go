@@ -9,8 +9,12 @@
 package builder
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"time"
 )
 
@@ -21,6 +25,9 @@ import (
 // DBConnectionPool represents a configured database connection pool.
 // This object has many optional configuration parameters, making it
 // an ideal candidate for the Builder pattern.
+//
+// When a Dialer is configured the pool is live: Build pre-warms
+// minConnections and Acquire hands out connections up to maxConnections.
 type DBConnectionPool struct {
 	host               string
 	port               int
@@ -36,6 +43,13 @@ type DBConnectionPool struct {
 	retryAttempts      int
 	enableCompression  bool
 	enableQueryLogging bool
+	acquireTimeout     time.Duration
+	maxLifetime        time.Duration
+	maxIdleTime        time.Duration
+	dialer             Dialer[io.Closer]
+	healthCheck        func(ctx context.Context, conn io.Closer) error
+
+	conns *Pool[io.Closer]
 }
 
 // DBConnectionPoolBuilder builds DBConnectionPool instances step by step.
@@ -58,6 +72,7 @@ func NewDBConnectionPoolBuilder() *DBConnectionPoolBuilder {
 			writeTimeout:      5 * time.Second,
 			sslMode:           "prefer",
 			retryAttempts:     3,
+			acquireTimeout:    30 * time.Second,
 		},
 		errs: []error{},
 	}
@@ -182,8 +197,51 @@ func (b *DBConnectionPoolBuilder) WithQueryLogging(enabled bool) *DBConnectionPo
 	return b
 }
 
+// WithDialer sets the dialer used to open pool connections.
+func (b *DBConnectionPoolBuilder) WithDialer(dialer Dialer[io.Closer]) *DBConnectionPoolBuilder {
+	if dialer == nil {
+		b.errs = append(b.errs, errors.New("dialer cannot be nil"))
+	}
+	b.pool.dialer = dialer
+	return b
+}
+
+// WithAcquireTimeout sets how long Acquire waits for a free connection.
+func (b *DBConnectionPoolBuilder) WithAcquireTimeout(timeout time.Duration) *DBConnectionPoolBuilder {
+	if timeout <= 0 {
+		b.errs = append(b.errs, errors.New("acquire timeout must be positive"))
+	}
+	b.pool.acquireTimeout = timeout
+	return b
+}
+
+// WithMaxLifetime sets the maximum age of a connection before it is closed.
+func (b *DBConnectionPoolBuilder) WithMaxLifetime(lifetime time.Duration) *DBConnectionPoolBuilder {
+	if lifetime < 0 {
+		b.errs = append(b.errs, errors.New("max lifetime cannot be negative"))
+	}
+	b.pool.maxLifetime = lifetime
+	return b
+}
+
+// WithMaxIdleTime sets how long a connection may sit idle before it is reaped.
+func (b *DBConnectionPoolBuilder) WithMaxIdleTime(idle time.Duration) *DBConnectionPoolBuilder {
+	if idle < 0 {
+		b.errs = append(b.errs, errors.New("max idle time cannot be negative"))
+	}
+	b.pool.maxIdleTime = idle
+	return b
+}
+
+// WithHealthCheck sets a check that idle connections must pass before reuse.
+func (b *DBConnectionPoolBuilder) WithHealthCheck(check func(ctx context.Context, conn io.Closer) error) *DBConnectionPoolBuilder {
+	b.pool.healthCheck = check
+	return b
+}
+
 // Build validates all settings and returns the final DBConnectionPool.
 // Returns an error if any validation failed during construction.
+// If a dialer is configured, Build also opens minConnections connections.
 func (b *DBConnectionPoolBuilder) Build() (*DBConnectionPool, error) {
 	// Check for accumulated errors
 	if len(b.errs) > 0 {
@@ -204,9 +262,55 @@ func (b *DBConnectionPoolBuilder) Build() (*DBConnectionPool, error) {
 		return nil, errors.New("username is required")
 	}
 
+	if b.pool.dialer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), b.pool.connectionTimeout)
+		defer cancel()
+
+		conns, err := NewPool(ctx, b.pool.dialer, PoolConfig[io.Closer]{
+			MaxConnections: b.pool.maxConnections,
+			MinConnections: b.pool.minConnections,
+			AcquireTimeout: b.pool.acquireTimeout,
+			DialTimeout:    b.pool.connectionTimeout,
+			RetryAttempts:  b.pool.retryAttempts,
+			RetryDelay:     100 * time.Millisecond,
+			MaxLifetime:    b.pool.maxLifetime,
+			MaxIdleTime:    b.pool.maxIdleTime,
+			HealthCheck:    b.pool.healthCheck,
+		})
+		if err != nil {
+			return nil, err
+		}
+		b.pool.conns = conns
+	}
+
 	return b.pool, nil
 }
 
+// Acquire checks a connection out of the pool.
+// The caller must Release or Discard it when done.
+func (p *DBConnectionPool) Acquire(ctx context.Context) (*PooledConn[io.Closer], error) {
+	if p.conns == nil {
+		return nil, ErrNoDialer
+	}
+	return p.conns.Acquire(ctx)
+}
+
+// Stats returns a snapshot of pool activity.
+func (p *DBConnectionPool) Stats() PoolStats {
+	if p.conns == nil {
+		return PoolStats{MaxConnections: p.maxConnections}
+	}
+	return p.conns.Stats()
+}
+
+// Close closes all pooled connections.
+func (p *DBConnectionPool) Close() error {
+	if p.conns == nil {
+		return nil
+	}
+	return p.conns.Close()
+}
+
 // String provides a readable representation of the connection pool config.
 func (p *DBConnectionPool) String() string {
 	return fmt.Sprintf(
@@ -223,27 +327,34 @@ func (p *DBConnectionPool) String() string {
 // HTTPRequest represents a configured HTTP request.
 // Demonstrates builder pattern for complex request configuration.
 type HTTPRequest struct {
-	method  string
-	url     string
-	headers map[string]string
-	body    []byte
-	timeout time.Duration
-	retries int
+	method          string
+	url             string
+	headers         map[string]string
+	query           url.Values
+	body            []byte
+	timeout         time.Duration
+	retries         int
+	idempotencyKey  string
+	followRedirects bool
 }
 
 // HTTPRequestBuilder builds HTTPRequest instances.
 type HTTPRequestBuilder struct {
-	request *HTTPRequest
+	request   *HTTPRequest
+	multipart []multipartPart
+	errs      []error
 }
 
 // NewHTTPRequestBuilder creates a new HTTP request builder with defaults.
 func NewHTTPRequestBuilder() *HTTPRequestBuilder {
 	return &HTTPRequestBuilder{
 		request: &HTTPRequest{
-			method:  "GET",
-			headers: make(map[string]string),
-			timeout: 30 * time.Second,
-			retries: 0,
+			method:          "GET",
+			headers:         make(map[string]string),
+			query:           make(url.Values),
+			timeout:         30 * time.Second,
+			retries:         0,
+			followRedirects: true,
 		},
 	}
 }
@@ -277,6 +388,7 @@ func (b *HTTPRequestBuilder) WithHeaders(headers map[string]string) *HTTPRequest
 // WithBody sets the request body.
 func (b *HTTPRequestBuilder) WithBody(body []byte) *HTTPRequestBuilder {
 	b.request.body = body
+	b.multipart = nil
 	return b
 }
 
@@ -292,8 +404,20 @@ func (b *HTTPRequestBuilder) WithRetries(retries int) *HTTPRequestBuilder {
 	return b
 }
 
+// WithFollowRedirects sets whether Execute follows redirects.
+func (b *HTTPRequestBuilder) WithFollowRedirects(follow bool) *HTTPRequestBuilder {
+	b.request.followRedirects = follow
+	return b
+}
+
 // Build validates and returns the final HTTPRequest.
 func (b *HTTPRequestBuilder) Build() (*HTTPRequest, error) {
+	if len(b.errs) > 0 {
+		return nil, fmt.Errorf("builder validation failed: %v", b.errs)
+	}
+	if err := b.encodeMultipart(); err != nil {
+		return nil, err
+	}
 	if b.request.url == "" {
 		return nil, errors.New("URL is required")
 	}
@@ -320,106 +444,141 @@ func (r *HTTPRequest) String() string {
 
 // AppConfig represents application configuration with many optional settings.
 type AppConfig struct {
-	serverPort     int
-	serverHost     string
-	databaseURL    string
-	logLevel       string
-	maxWorkers     int
-	enableMetrics  bool
+	serverPort      int
+	serverHost      string
+	databaseURL     string
+	logLevel        string
+	maxWorkers      int
+	enableMetrics   bool
 	enableProfiling bool
-	enableTracing  bool
+	enableTracing   bool
 	shutdownTimeout time.Duration
-	environment    string
+	environment     string
+
+	// sources records which layer supplied each setting.
+	sources map[string]string
 }
 
 // AppConfigBuilder builds AppConfig instances.
+//
+// Settings can come from several layers, merged at Build in increasing
+// precedence: defaults, config files, environment overlay files, environment
+// variables, command-line flags and finally explicit With* calls.
 type AppConfigBuilder struct {
-	config *AppConfig
+	config   *AppConfig
+	explicit map[string]bool
+
+	files       []string
+	envPrefixes []string
+	flagSets    []*flag.FlagSet
 }
 
 // NewAppConfigBuilder creates a new application config builder with defaults.
 func NewAppConfigBuilder() *AppConfigBuilder {
 	return &AppConfigBuilder{
-		config: &AppConfig{
-			serverPort:      8080,
-			serverHost:      "0.0.0.0",
-			logLevel:        "info",
-			maxWorkers:      4,
-			enableMetrics:   false,
-			enableProfiling: false,
-			enableTracing:   false,
-			shutdownTimeout: 30 * time.Second,
-			environment:     "development",
-		},
+		config:   defaultAppConfig(),
+		explicit: make(map[string]bool),
+	}
+}
+
+// defaultAppConfig returns the configuration used when no layer sets a value.
+func defaultAppConfig() *AppConfig {
+	return &AppConfig{
+		serverPort:      8080,
+		serverHost:      "0.0.0.0",
+		logLevel:        "info",
+		maxWorkers:      4,
+		enableMetrics:   false,
+		enableProfiling: false,
+		enableTracing:   false,
+		shutdownTimeout: 30 * time.Second,
+		environment:     "development",
 	}
 }
 
 // WithServerPort sets the server port.
 func (b *AppConfigBuilder) WithServerPort(port int) *AppConfigBuilder {
 	b.config.serverPort = port
+	b.explicit["server_port"] = true
 	return b
 }
 
 // WithServerHost sets the server host.
 func (b *AppConfigBuilder) WithServerHost(host string) *AppConfigBuilder {
 	b.config.serverHost = host
+	b.explicit["server_host"] = true
 	return b
 }
 
 // WithDatabaseURL sets the database connection URL.
 func (b *AppConfigBuilder) WithDatabaseURL(url string) *AppConfigBuilder {
 	b.config.databaseURL = url
+	b.explicit["database_url"] = true
 	return b
 }
 
 // WithLogLevel sets the logging level (debug, info, warn, error).
 func (b *AppConfigBuilder) WithLogLevel(level string) *AppConfigBuilder {
 	b.config.logLevel = level
+	b.explicit["log_level"] = true
 	return b
 }
 
 // WithMaxWorkers sets the maximum number of worker goroutines.
 func (b *AppConfigBuilder) WithMaxWorkers(workers int) *AppConfigBuilder {
 	b.config.maxWorkers = workers
+	b.explicit["max_workers"] = true
 	return b
 }
 
 // WithMetrics enables or disables metrics collection.
 func (b *AppConfigBuilder) WithMetrics(enabled bool) *AppConfigBuilder {
 	b.config.enableMetrics = enabled
+	b.explicit["enable_metrics"] = true
 	return b
 }
 
 // WithProfiling enables or disables profiling.
 func (b *AppConfigBuilder) WithProfiling(enabled bool) *AppConfigBuilder {
 	b.config.enableProfiling = enabled
+	b.explicit["enable_profiling"] = true
 	return b
 }
 
 // WithTracing enables or disables distributed tracing.
 func (b *AppConfigBuilder) WithTracing(enabled bool) *AppConfigBuilder {
 	b.config.enableTracing = enabled
+	b.explicit["enable_tracing"] = true
 	return b
 }
 
 // WithShutdownTimeout sets the graceful shutdown timeout.
 func (b *AppConfigBuilder) WithShutdownTimeout(timeout time.Duration) *AppConfigBuilder {
 	b.config.shutdownTimeout = timeout
+	b.explicit["shutdown_timeout"] = true
 	return b
 }
 
 // WithEnvironment sets the environment (development, staging, production).
+// It also selects the overlay file loaded next to each FromFile path, e.g.
+// config.production.yaml for config.yaml.
 func (b *AppConfigBuilder) WithEnvironment(env string) *AppConfigBuilder {
 	b.config.environment = env
+	b.explicit["environment"] = true
 	return b
 }
 
-// Build validates and returns the final AppConfig.
+// Build merges all configured layers, validates the result and returns it.
 func (b *AppConfigBuilder) Build() (*AppConfig, error) {
-	if b.config.serverPort <= 0 || b.config.serverPort > 65535 {
-		return nil, fmt.Errorf("invalid server port: %d", b.config.serverPort)
+	config, err := b.merge()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.serverPort <= 0 || config.serverPort > 65535 {
+		return nil, fmt.Errorf("invalid server port: %d", config.serverPort)
 	}
-	if b.config.maxWorkers <= 0 {
+	if config.maxWorkers <= 0 {
 		return nil, errors.New("max workers must be positive")
 	}
 	validLogLevels := map[string]bool{
@@ -428,10 +587,10 @@ func (b *AppConfigBuilder) Build() (*AppConfig, error) {
 		"warn":  true,
 		"error": true,
 	}
-	if !validLogLevels[b.config.logLevel] {
-		return nil, fmt.Errorf("invalid log level: %s", b.config.logLevel)
+	if !validLogLevels[config.logLevel] {
+		return nil, fmt.Errorf("invalid log level: %s", config.logLevel)
 	}
-	return b.config, nil
+	return config, nil
 }
 
 // String provides a readable representation of the application config.
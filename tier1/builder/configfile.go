@@ -0,0 +1,148 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jumaniyozov/design_patterns/internal/yamlsubset"
+)
+
+// The parsers below cover the subset of JSON, YAML and TOML that a flat
+// settings file needs: nested tables or mappings of scalar values. Nested
+// keys are flattened with underscores and all values are returned as strings
+// for the field setters to parse.
+
+// parseJSONConfig flattens a JSON object of scalars and nested objects.
+func parseJSONConfig(data []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var root map[string]any
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if err := flattenJSON("", root, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenJSON(prefix string, object map[string]any, values map[string]string) error {
+	for key, value := range object {
+		key = prefix + normalizeConfigKey(key)
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flattenJSON(key+"_", v, values); err != nil {
+				return err
+			}
+		case string:
+			values[key] = v
+		case json.Number:
+			values[key] = v.String()
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		case nil:
+			// null leaves the setting to lower layers.
+		default:
+			return fmt.Errorf("key %q: unsupported value %v", key, value)
+		}
+	}
+	return nil
+}
+
+// parseYAMLConfig flattens block mappings of scalars, nested by
+// indentation. Sequences are not supported.
+func parseYAMLConfig(data []byte) (map[string]string, error) {
+	root, err := yamlsubset.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a mapping at the top level")
+	}
+
+	values := make(map[string]string)
+	if err := flattenYAML("", object, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenYAML(prefix string, object map[string]any, values map[string]string) error {
+	for key, value := range object {
+		key = prefix + normalizeConfigKey(key)
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flattenYAML(key+"_", v, values); err != nil {
+				return err
+			}
+		case yamlsubset.Scalar:
+			if !v.Quoted && v.Value() == nil {
+				continue // null leaves the setting to lower layers.
+			}
+			values[key] = v.Text
+		case nil:
+			// A key with nothing under it leaves the setting to lower layers.
+		default:
+			return fmt.Errorf("key %q: sequences are not supported", key)
+		}
+	}
+	return nil
+}
+
+// parseTOMLConfig reads key = value pairs and [table] headers.
+// Arrays and inline tables are not supported.
+func parseTOMLConfig(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	prefix := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(yamlsubset.StripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header", n+1)
+			}
+			prefix = normalizeConfigKey(strings.Trim(line, "[]")) + "_"
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+			return nil, fmt.Errorf("line %d: arrays and inline tables are not supported", n+1)
+		}
+		values[prefix+normalizeConfigKey(unquote(strings.TrimSpace(key)))] = unquote(value)
+	}
+	return values, nil
+}
+
+// unquote strips matching double or single quotes, interpreting escapes in
+// double-quoted strings.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+		return s[1 : len(s)-1]
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,226 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// multipartPart is a form field or file queued for a multipart body.
+type multipartPart struct {
+	field    string
+	filename string
+	content  []byte
+}
+
+// WithQueryParam adds a query parameter. Values are URL-encoded and merged
+// with any query string already present in the URL.
+func (b *HTTPRequestBuilder) WithQueryParam(key, value string) *HTTPRequestBuilder {
+	b.request.query.Add(key, value)
+	return b
+}
+
+// WithJSONBody encodes v as the JSON request body.
+func (b *HTTPRequestBuilder) WithJSONBody(v any) *HTTPRequestBuilder {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("encode JSON body: %w", err))
+		return b
+	}
+	b.WithBody(data)
+	b.request.headers["Content-Type"] = "application/json"
+	return b
+}
+
+// WithFormBody encodes values as an application/x-www-form-urlencoded body.
+func (b *HTTPRequestBuilder) WithFormBody(values url.Values) *HTTPRequestBuilder {
+	b.WithBody([]byte(values.Encode()))
+	b.request.headers["Content-Type"] = "application/x-www-form-urlencoded"
+	return b
+}
+
+// WithIdempotencyKey sends key in the Idempotency-Key header, which lets
+// Execute retry a POST or PATCH: a server that honours the header applies
+// repeats of the same key once.
+func (b *HTTPRequestBuilder) WithIdempotencyKey(key string) *HTTPRequestBuilder {
+	if key == "" {
+		b.errs = append(b.errs, errors.New("idempotency key cannot be empty"))
+	}
+	b.request.headers["Idempotency-Key"] = key
+	b.request.idempotencyKey = key
+	return b
+}
+
+// WithMultipartField adds a form field to a multipart/form-data body.
+func (b *HTTPRequestBuilder) WithMultipartField(name, value string) *HTTPRequestBuilder {
+	b.multipart = append(b.multipart, multipartPart{field: name, content: []byte(value)})
+	return b
+}
+
+// WithMultipartFile adds a file to a multipart/form-data body.
+func (b *HTTPRequestBuilder) WithMultipartFile(field, filename string, content []byte) *HTTPRequestBuilder {
+	if filename == "" {
+		b.errs = append(b.errs, errors.New("multipart filename cannot be empty"))
+	}
+	b.multipart = append(b.multipart, multipartPart{field: field, filename: filename, content: content})
+	return b
+}
+
+// encodeMultipart renders queued multipart parts into the request body.
+func (b *HTTPRequestBuilder) encodeMultipart() error {
+	if len(b.multipart) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, part := range b.multipart {
+		var (
+			w   io.Writer
+			err error
+		)
+		if part.filename != "" {
+			w, err = writer.CreateFormFile(part.field, part.filename)
+		} else {
+			w, err = writer.CreateFormField(part.field)
+		}
+		if err != nil {
+			return fmt.Errorf("encode multipart body: %w", err)
+		}
+		if _, err := w.Write(part.content); err != nil {
+			return fmt.Errorf("encode multipart body: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("encode multipart body: %w", err)
+	}
+
+	b.request.body = buf.Bytes()
+	b.request.headers["Content-Type"] = writer.FormDataContentType()
+	b.multipart = nil
+	return nil
+}
+
+// BuildStd validates the configuration and returns a standard *http.Request.
+func (b *HTTPRequestBuilder) BuildStd(ctx context.Context) (*http.Request, error) {
+	request, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return request.NewRequest(ctx)
+}
+
+// NewRequest converts the HTTPRequest into a standard *http.Request.
+// The body is replayable, so the request can be retried.
+func (r *HTTPRequest) NewRequest(ctx context.Context) (*http.Request, error) {
+	target, err := url.Parse(r.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if len(r.query) > 0 {
+		query := target.Query()
+		for key, values := range r.query {
+			for _, value := range values {
+				query.Add(key, value)
+			}
+		}
+		target.RawQuery = query.Encode()
+	}
+
+	var body io.Reader
+	if len(r.body) > 0 {
+		body = bytes.NewReader(r.body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range r.headers {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+// Execute sends the request with client, applying the configured timeout and
+// redirect policy. Requests with an idempotent method (GET, HEAD, OPTIONS,
+// PUT, DELETE) or an idempotency key are retried up to the configured number
+// of times on network errors and 5xx or 429 responses; others are sent once,
+// since a repeat could apply them twice. A nil client uses
+// http.DefaultClient as the base.
+func (r *HTTPRequest) Execute(ctx context.Context, client *http.Client) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	configured := *client
+	configured.Timeout = r.timeout
+	if !r.followRedirects {
+		configured.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	attempts := 1
+	if isIdempotentMethod(r.method) || r.idempotencyKey != "" {
+		attempts += max(r.retries, 0)
+	}
+
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		req, err := r.NewRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := configured.Do(req)
+		if attempt == attempts || !isRetryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isIdempotentMethod reports whether a request can be safely retried.
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryable reports whether an attempt failed transiently: a network
+// error, or a 429 or 5xx response. Other errors, such as an unsupported URL
+// scheme or a refused redirect, would only fail again.
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// *url.Error is itself a net.Error, so look at what it wraps.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
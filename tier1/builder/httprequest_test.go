@@ -0,0 +1,260 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHTTPRequestBuilder_BuildStd tests conversion to *http.Request.
+func TestHTTPRequestBuilder_BuildStd(t *testing.T) {
+	req, err := NewHTTPRequestBuilder().
+		WithMethod("PUT").
+		WithURL("https://example.com/items?page=1").
+		WithQueryParam("q", "a&b c").
+		WithHeader("X-Trace", "123").
+		WithJSONBody(map[string]string{"name": "widget"}).
+		BuildStd(context.Background())
+
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+
+	if req.Method != "PUT" {
+		t.Errorf("Expected method PUT, got %s", req.Method)
+	}
+	if got := req.URL.Query().Get("q"); got != "a&b c" {
+		t.Errorf("Expected encoded query param to round-trip, got %q", got)
+	}
+	if got := req.URL.Query().Get("page"); got != "1" {
+		t.Errorf("Expected existing query param to be kept, got %q", got)
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", req.Header.Get("Content-Type"))
+	}
+	if req.Header.Get("X-Trace") != "123" {
+		t.Error("Expected custom header to be set")
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"name":"widget"}` {
+		t.Errorf("Unexpected body: %s", body)
+	}
+}
+
+// TestHTTPRequestBuilder_FormBody tests URL-encoded form bodies.
+func TestHTTPRequestBuilder_FormBody(t *testing.T) {
+	req, err := NewHTTPRequestBuilder().
+		WithMethod("POST").
+		WithURL("https://example.com/login").
+		WithFormBody(url.Values{"user": {"a b"}, "pass": {"x=y"}}).
+		BuildStd(context.Background())
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+
+	if err := req.ParseForm(); err != nil {
+		t.Fatalf("ParseForm failed: %v", err)
+	}
+	if req.PostForm.Get("user") != "a b" || req.PostForm.Get("pass") != "x=y" {
+		t.Errorf("Unexpected form values: %v", req.PostForm)
+	}
+}
+
+// TestHTTPRequestBuilder_MultipartBody tests multipart/form-data bodies.
+func TestHTTPRequestBuilder_MultipartBody(t *testing.T) {
+	req, err := NewHTTPRequestBuilder().
+		WithMethod("POST").
+		WithURL("https://example.com/upload").
+		WithMultipartField("title", "report").
+		WithMultipartFile("file", "report.csv", []byte("a,b\n1,2\n")).
+		BuildStd(context.Background())
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("ParseMultipartForm failed: %v", err)
+	}
+	if req.FormValue("title") != "report" {
+		t.Errorf("Expected title field, got %q", req.FormValue("title"))
+	}
+
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		t.Fatalf("Expected file part: %v", err)
+	}
+	defer file.Close()
+	content, _ := io.ReadAll(file)
+	if header.Filename != "report.csv" || string(content) != "a,b\n1,2\n" {
+		t.Errorf("Unexpected file part %q: %q", header.Filename, content)
+	}
+}
+
+// TestHTTPRequestBuilder_InvalidJSONBody tests that encoding errors surface at Build.
+func TestHTTPRequestBuilder_InvalidJSONBody(t *testing.T) {
+	_, err := NewHTTPRequestBuilder().
+		WithURL("https://example.com").
+		WithJSONBody(make(chan int)).
+		Build()
+
+	if err == nil {
+		t.Error("Expected error for unencodable JSON body, got nil")
+	}
+}
+
+// TestHTTPRequest_ExecuteRetries tests that Execute retries idempotent requests.
+func TestHTTPRequest_ExecuteRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"v":1}` {
+			t.Errorf("Expected body on every attempt, got %q", body)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+	}))
+	defer server.Close()
+
+	request, err := NewHTTPRequestBuilder().
+		WithMethod("PUT").
+		WithURL(server.URL).
+		WithJSONBody(map[string]int{"v": 1}).
+		WithRetries(2).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+
+	resp, err := request.Execute(context.Background(), server.Client())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after retries, got %d", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+}
+
+// TestHTTPRequest_ExecuteRetryPolicy tests that only requests safe to repeat
+// are retried, and only on transient failures.
+func TestHTTPRequest_ExecuteRetryPolicy(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/drop" {
+			// Close the connection without a response.
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name, method, path, key string
+		want                    int32
+	}{
+		{"GET on 503", "GET", "/", "", 3},
+		{"GET on dropped connection", "GET", "/drop", "", 3},
+		{"POST on 503", "POST", "/", "", 1},
+		{"POST on dropped connection", "POST", "/drop", "", 1},
+		{"PATCH on dropped connection", "PATCH", "/drop", "", 1},
+		{"POST with idempotency key", "POST", "/drop", "order-42", 3},
+	}
+	// Without reused connections the transport makes no retries of its own.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	for _, tt := range tests {
+		calls.Store(0)
+		builder := NewHTTPRequestBuilder().
+			WithMethod(tt.method).
+			WithURL(server.URL + tt.path).
+			WithBody([]byte("payload")).
+			WithRetries(2)
+		if tt.key != "" {
+			builder.WithIdempotencyKey(tt.key)
+		}
+		request, err := builder.Build()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp, err := request.Execute(context.Background(), client); err == nil {
+			resp.Body.Close()
+		}
+		if got := calls.Load(); got != tt.want {
+			t.Errorf("%s: expected %d attempts, got %d", tt.name, tt.want, got)
+		}
+	}
+
+	// An error that would recur is not retried.
+	bad, _ := NewHTTPRequestBuilder().WithURL("ftp://example.com/file").WithRetries(2).Build()
+	if _, err := bad.Execute(context.Background(), nil); err == nil || isRetryable(nil, err) {
+		t.Errorf("Expected an unsupported scheme to fail without retrying, got %v", err)
+	}
+}
+
+// TestHTTPRequest_ExecuteRedirectsAndTimeout tests redirect policy and timeout.
+func TestHTTPRequest_ExecuteRedirectsAndTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	noFollow, _ := NewHTTPRequestBuilder().
+		WithURL(server.URL + "/old").
+		WithFollowRedirects(false).
+		Build()
+	resp, err := noFollow.Execute(context.Background(), server.Client())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("Expected redirect response 302, got %d", resp.StatusCode)
+	}
+
+	follow, _ := NewHTTPRequestBuilder().WithURL(server.URL + "/old").Build()
+	resp, err = follow.Execute(context.Background(), server.Client())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected redirect to be followed, got %d", resp.StatusCode)
+	}
+
+	slow, _ := NewHTTPRequestBuilder().
+		WithURL(server.URL + "/slow").
+		WithTimeout(50 * time.Millisecond).
+		Build()
+	if _, err := slow.Execute(context.Background(), server.Client()); err == nil {
+		t.Error("Expected timeout error, got nil")
+	}
+}
@@ -0,0 +1,434 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrPoolClosed is returned when acquiring from a closed pool.
+	ErrPoolClosed = errors.New("connection pool is closed")
+
+	// ErrAcquireTimeout is returned when no connection became available in time.
+	ErrAcquireTimeout = errors.New("timed out waiting for a connection")
+
+	// ErrNoDialer is returned when a pool is used without a Dialer.
+	ErrNoDialer = errors.New("connection pool has no dialer")
+)
+
+// Dialer opens new connections for a Pool.
+type Dialer[C io.Closer] interface {
+	Dial(ctx context.Context) (C, error)
+}
+
+// DialerFunc adapts an ordinary function to the Dialer interface.
+type DialerFunc[C io.Closer] func(ctx context.Context) (C, error)
+
+// Dial calls f(ctx).
+func (f DialerFunc[C]) Dial(ctx context.Context) (C, error) {
+	return f(ctx)
+}
+
+// PoolConfig holds the runtime settings of a Pool.
+type PoolConfig[C io.Closer] struct {
+	MaxConnections int
+	MinConnections int
+	AcquireTimeout time.Duration
+	DialTimeout    time.Duration
+	RetryAttempts  int
+	RetryDelay     time.Duration
+	MaxLifetime    time.Duration
+	MaxIdleTime    time.Duration
+	ReapInterval   time.Duration
+
+	// HealthCheck is run on idle connections before they are handed out.
+	// Connections that fail it are closed and replaced.
+	HealthCheck func(ctx context.Context, conn C) error
+}
+
+// PoolStats is a snapshot of pool activity.
+type PoolStats struct {
+	MaxConnections      int
+	Open                int
+	Idle                int
+	InUse               int
+	WaitCount           int64
+	WaitDuration        time.Duration
+	Dials               int64
+	DialErrors          int64
+	HealthCheckFailures int64
+	ClosedMaxLifetime   int64
+	ClosedMaxIdle       int64
+}
+
+// PooledConn is a connection checked out of a Pool.
+// Exactly one of Release or Discard must be called when done with it.
+type PooledConn[C io.Closer] struct {
+	Conn C
+
+	pool      *Pool[C]
+	createdAt time.Time
+	idleSince time.Time
+	done      atomic.Bool
+}
+
+// Release returns the connection to the pool for reuse.
+func (pc *PooledConn[C]) Release() {
+	if pc.done.Swap(true) {
+		return
+	}
+	pc.pool.put(pc)
+}
+
+// Discard closes the connection instead of returning it, e.g. after an I/O error.
+func (pc *PooledConn[C]) Discard() {
+	if pc.done.Swap(true) {
+		return
+	}
+	pc.pool.discard(pc)
+}
+
+// Pool is a bounded pool of connections created by a Dialer.
+//
+// Open connections are counted with the slots channel; idle connections wait
+// in the idle channel, which is sized so that releasing never blocks.
+type Pool[C io.Closer] struct {
+	dialer Dialer[C]
+	config PoolConfig[C]
+
+	slots chan struct{}
+	idle  chan *PooledConn[C]
+
+	// mu orders put against Close so no connection is parked after the drain.
+	mu       sync.RWMutex
+	closed   chan struct{}
+	isClosed bool
+	reaperWG sync.WaitGroup
+
+	waitCount           atomic.Int64
+	waitNanos           atomic.Int64
+	dials               atomic.Int64
+	dialErrors          atomic.Int64
+	healthCheckFailures atomic.Int64
+	closedMaxLifetime   atomic.Int64
+	closedMaxIdle       atomic.Int64
+}
+
+// NewPool creates a pool and pre-warms MinConnections connections.
+func NewPool[C io.Closer](ctx context.Context, dialer Dialer[C], config PoolConfig[C]) (*Pool[C], error) {
+	if dialer == nil {
+		return nil, ErrNoDialer
+	}
+	if config.MaxConnections <= 0 {
+		return nil, errors.New("max connections must be positive")
+	}
+	if config.MinConnections > config.MaxConnections {
+		return nil, errors.New("min connections cannot exceed max connections")
+	}
+
+	p := &Pool[C]{
+		dialer: dialer,
+		config: config,
+		slots:  make(chan struct{}, config.MaxConnections),
+		idle:   make(chan *PooledConn[C], config.MaxConnections),
+		closed: make(chan struct{}),
+	}
+
+	if err := p.fill(ctx); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("pre-warm pool: %w", err)
+	}
+
+	if interval := p.reapInterval(); interval > 0 {
+		p.reaperWG.Add(1)
+		go p.reaper(interval)
+	}
+
+	return p, nil
+}
+
+// Acquire returns an idle connection or dials a new one, waiting up to
+// AcquireTimeout when the pool is at MaxConnections.
+func (p *Pool[C]) Acquire(ctx context.Context) (*PooledConn[C], error) {
+	if p.config.AcquireTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.AcquireTimeout)
+		defer cancel()
+	}
+
+	waited := false
+	start := time.Now()
+	defer func() {
+		if waited {
+			p.waitCount.Add(1)
+			p.waitNanos.Add(int64(time.Since(start)))
+		}
+	}()
+
+	for {
+		select {
+		case <-p.closed:
+			return nil, ErrPoolClosed
+		default:
+		}
+
+		// Prefer an idle connection without blocking.
+		select {
+		case pc := <-p.idle:
+			if p.usable(ctx, pc) {
+				return p.checkout(pc), nil
+			}
+			continue
+		default:
+		}
+
+		waited = true
+		select {
+		case pc := <-p.idle:
+			if p.usable(ctx, pc) {
+				return p.checkout(pc), nil
+			}
+		case p.slots <- struct{}{}:
+			pc, err := p.dial(ctx)
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return pc, nil
+		case <-p.closed:
+			return nil, ErrPoolClosed
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, ErrAcquireTimeout
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Stats returns a snapshot of the pool's counters.
+func (p *Pool[C]) Stats() PoolStats {
+	open := len(p.slots)
+	idle := len(p.idle)
+	return PoolStats{
+		MaxConnections:      p.config.MaxConnections,
+		Open:                open,
+		Idle:                idle,
+		InUse:               max(open-idle, 0),
+		WaitCount:           p.waitCount.Load(),
+		WaitDuration:        time.Duration(p.waitNanos.Load()),
+		Dials:               p.dials.Load(),
+		DialErrors:          p.dialErrors.Load(),
+		HealthCheckFailures: p.healthCheckFailures.Load(),
+		ClosedMaxLifetime:   p.closedMaxLifetime.Load(),
+		ClosedMaxIdle:       p.closedMaxIdle.Load(),
+	}
+}
+
+// Close closes all idle connections and stops the reaper.
+// Connections still checked out are closed when they are released.
+func (p *Pool[C]) Close() error {
+	p.mu.Lock()
+	if p.isClosed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.isClosed = true
+	close(p.closed)
+	p.mu.Unlock()
+
+	p.reaperWG.Wait()
+
+	var errs []error
+	for {
+		select {
+		case pc := <-p.idle:
+			if err := p.closeConn(pc); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// put returns a connection to the idle set, or closes it if it should not be reused.
+func (p *Pool[C]) put(pc *PooledConn[C]) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.isClosed {
+		p.closeConn(pc)
+		return
+	}
+
+	if p.expired(pc, time.Now()) {
+		p.closedMaxLifetime.Add(1)
+		p.closeConn(pc)
+		return
+	}
+
+	pc.idleSince = time.Now()
+	p.idle <- pc
+}
+
+func (p *Pool[C]) discard(pc *PooledConn[C]) {
+	p.closeConn(pc)
+}
+
+// closeConn closes the underlying connection and frees its slot.
+func (p *Pool[C]) closeConn(pc *PooledConn[C]) error {
+	err := pc.Conn.Close()
+	<-p.slots
+	return err
+}
+
+// checkout hands an idle connection to a caller.
+func (p *Pool[C]) checkout(pc *PooledConn[C]) *PooledConn[C] {
+	return &PooledConn[C]{Conn: pc.Conn, pool: p, createdAt: pc.createdAt}
+}
+
+// usable validates an idle connection, closing it if it is expired or unhealthy.
+func (p *Pool[C]) usable(ctx context.Context, pc *PooledConn[C]) bool {
+	if p.expired(pc, time.Now()) {
+		p.closedMaxLifetime.Add(1)
+		p.closeConn(pc)
+		return false
+	}
+	if p.config.HealthCheck != nil {
+		if err := p.config.HealthCheck(ctx, pc.Conn); err != nil {
+			p.healthCheckFailures.Add(1)
+			p.closeConn(pc)
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Pool[C]) expired(pc *PooledConn[C], now time.Time) bool {
+	return p.config.MaxLifetime > 0 && now.Sub(pc.createdAt) >= p.config.MaxLifetime
+}
+
+// dial opens a new connection, retrying up to RetryAttempts times.
+// The caller must already hold a slot.
+func (p *Pool[C]) dial(ctx context.Context) (*PooledConn[C], error) {
+	var lastErr error
+	for attempt := 0; attempt <= p.config.RetryAttempts; attempt++ {
+		if attempt > 0 && p.config.RetryDelay > 0 {
+			select {
+			case <-time.After(p.config.RetryDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		dialCtx := ctx
+		cancel := func() {}
+		if p.config.DialTimeout > 0 {
+			dialCtx, cancel = context.WithTimeout(ctx, p.config.DialTimeout)
+		}
+		conn, err := p.dialer.Dial(dialCtx)
+		cancel()
+
+		p.dials.Add(1)
+		if err == nil {
+			return &PooledConn[C]{Conn: conn, pool: p, createdAt: time.Now()}, nil
+		}
+		p.dialErrors.Add(1)
+		lastErr = err
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("dial: %w", lastErr)
+}
+
+// fill dials connections until MinConnections are open.
+func (p *Pool[C]) fill(ctx context.Context) error {
+	for len(p.slots) < p.config.MinConnections {
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			return nil
+		}
+		pc, err := p.dial(ctx)
+		if err != nil {
+			<-p.slots
+			return err
+		}
+		pc.idleSince = time.Now()
+		p.idle <- pc
+	}
+	return nil
+}
+
+func (p *Pool[C]) reapInterval() time.Duration {
+	if p.config.ReapInterval > 0 {
+		return p.config.ReapInterval
+	}
+	interval := time.Duration(0)
+	for _, d := range []time.Duration{p.config.MaxIdleTime, p.config.MaxLifetime} {
+		if d > 0 && (interval == 0 || d/2 < interval) {
+			interval = d / 2
+		}
+	}
+	return interval
+}
+
+// reaper periodically closes idle connections that exceeded MaxIdleTime or
+// MaxLifetime, then tops the pool back up to MinConnections.
+func (p *Pool[C]) reaper(interval time.Duration) {
+	defer p.reaperWG.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.closed:
+			return
+		case <-ticker.C:
+			p.reap(time.Now())
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			p.fill(ctx)
+			cancel()
+		}
+	}
+}
+
+// reap inspects every connection that is idle right now.
+func (p *Pool[C]) reap(now time.Time) {
+	keep := make([]*PooledConn[C], 0, len(p.idle))
+
+drain:
+	for n := len(p.idle); n > 0; n-- {
+		var pc *PooledConn[C]
+		select {
+		case pc = <-p.idle:
+		default:
+			break drain
+		}
+
+		switch {
+		case p.expired(pc, now):
+			p.closedMaxLifetime.Add(1)
+			p.closeConn(pc)
+		case p.config.MaxIdleTime > 0 && now.Sub(pc.idleSince) >= p.config.MaxIdleTime &&
+			len(p.slots) > p.config.MinConnections:
+			p.closedMaxIdle.Add(1)
+			p.closeConn(pc)
+		default:
+			keep = append(keep, pc)
+		}
+	}
+
+	for _, pc := range keep {
+		p.idle <- pc
+	}
+}
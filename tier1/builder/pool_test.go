@@ -0,0 +1,288 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeConn is an in-memory connection used to exercise the pool.
+type fakeConn struct {
+	id      int
+	healthy atomic.Bool
+	closed  atomic.Bool
+}
+
+func (c *fakeConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// fakeDialer hands out fakeConns and can be told to fail.
+type fakeDialer struct {
+	mu    sync.Mutex
+	conns []*fakeConn
+	fail  error
+}
+
+func (d *fakeDialer) Dial(ctx context.Context) (io.Closer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.fail != nil {
+		return nil, d.fail
+	}
+	conn := &fakeConn{id: len(d.conns) + 1}
+	conn.healthy.Store(true)
+	d.conns = append(d.conns, conn)
+	return conn, nil
+}
+
+func (d *fakeDialer) dialed() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.conns)
+}
+
+func newTestPool(t *testing.T, dialer *fakeDialer, configure func(b *DBConnectionPoolBuilder)) *DBConnectionPool {
+	t.Helper()
+
+	b := NewDBConnectionPoolBuilder().
+		WithDatabase("testdb").
+		WithCredentials("user", "pass").
+		WithDialer(dialer)
+	if configure != nil {
+		configure(b)
+	}
+
+	pool, err := b.Build()
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+// TestPool_PreWarmsMinConnections tests that Build opens minConnections.
+func TestPool_PreWarmsMinConnections(t *testing.T) {
+	dialer := &fakeDialer{}
+	pool := newTestPool(t, dialer, func(b *DBConnectionPoolBuilder) {
+		b.WithMinConnections(3).WithMaxConnections(5)
+	})
+
+	if dialer.dialed() != 3 {
+		t.Errorf("Expected 3 pre-warmed connections, got %d", dialer.dialed())
+	}
+
+	stats := pool.Stats()
+	if stats.Open != 3 || stats.Idle != 3 {
+		t.Errorf("Expected 3 open and idle, got %+v", stats)
+	}
+}
+
+// TestPool_ReusesReleasedConnections tests that released connections are reused.
+func TestPool_ReusesReleasedConnections(t *testing.T) {
+	dialer := &fakeDialer{}
+	pool := newTestPool(t, dialer, func(b *DBConnectionPoolBuilder) {
+		b.WithMinConnections(0).WithMaxConnections(2)
+	})
+
+	ctx := context.Background()
+	first, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	first.Release()
+
+	second, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer second.Release()
+
+	if second.Conn != first.Conn {
+		t.Error("Expected released connection to be reused")
+	}
+	if dialer.dialed() != 1 {
+		t.Errorf("Expected 1 dial, got %d", dialer.dialed())
+	}
+	if stats := pool.Stats(); stats.InUse != 1 {
+		t.Errorf("Expected 1 in use, got %d", stats.InUse)
+	}
+}
+
+// TestPool_CapsAtMaxConnectionsAndTimesOut tests the acquire timeout.
+func TestPool_CapsAtMaxConnectionsAndTimesOut(t *testing.T) {
+	dialer := &fakeDialer{}
+	pool := newTestPool(t, dialer, func(b *DBConnectionPoolBuilder) {
+		b.WithMinConnections(0).WithMaxConnections(2).WithAcquireTimeout(50 * time.Millisecond)
+	})
+
+	ctx := context.Background()
+	a, _ := pool.Acquire(ctx)
+	b, _ := pool.Acquire(ctx)
+
+	start := time.Now()
+	_, err := pool.Acquire(ctx)
+	if !errors.Is(err, ErrAcquireTimeout) {
+		t.Fatalf("Expected ErrAcquireTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected Acquire to wait for the timeout, returned after %v", elapsed)
+	}
+	if dialer.dialed() != 2 {
+		t.Errorf("Expected pool to cap at 2 connections, dialed %d", dialer.dialed())
+	}
+
+	// A waiter is served as soon as a connection is released.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		a.Release()
+	}()
+	c, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Expected waiter to get released connection, got %v", err)
+	}
+	c.Release()
+	b.Release()
+
+	if stats := pool.Stats(); stats.WaitCount < 2 {
+		t.Errorf("Expected wait count to be recorded, got %d", stats.WaitCount)
+	}
+}
+
+// TestPool_HealthCheckReplacesBrokenConnections tests idle health checks.
+func TestPool_HealthCheckReplacesBrokenConnections(t *testing.T) {
+	dialer := &fakeDialer{}
+	pool := newTestPool(t, dialer, func(b *DBConnectionPoolBuilder) {
+		b.WithMinConnections(1).WithHealthCheck(func(ctx context.Context, conn io.Closer) error {
+			if !conn.(*fakeConn).healthy.Load() {
+				return errors.New("connection reset")
+			}
+			return nil
+		})
+	})
+
+	dialer.conns[0].healthy.Store(false)
+
+	conn, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer conn.Release()
+
+	if conn.Conn.(*fakeConn).id != 2 {
+		t.Error("Expected unhealthy connection to be replaced")
+	}
+	if !dialer.conns[0].closed.Load() {
+		t.Error("Expected unhealthy connection to be closed")
+	}
+	if pool.Stats().HealthCheckFailures != 1 {
+		t.Errorf("Expected 1 health check failure, got %d", pool.Stats().HealthCheckFailures)
+	}
+}
+
+// TestPool_MaxLifetime tests that old connections are not reused.
+func TestPool_MaxLifetime(t *testing.T) {
+	dialer := &fakeDialer{}
+	pool := newTestPool(t, dialer, func(b *DBConnectionPoolBuilder) {
+		b.WithMinConnections(0).WithMaxLifetime(20 * time.Millisecond)
+	})
+
+	conn, _ := pool.Acquire(context.Background())
+	time.Sleep(30 * time.Millisecond)
+	conn.Release()
+
+	if !dialer.conns[0].closed.Load() {
+		t.Error("Expected expired connection to be closed on release")
+	}
+	if stats := pool.Stats(); stats.Open != 0 || stats.ClosedMaxLifetime != 1 {
+		t.Errorf("Unexpected stats after expiry: %+v", stats)
+	}
+}
+
+// TestPool_ReapsIdleConnections tests idle reaping down to minConnections.
+func TestPool_ReapsIdleConnections(t *testing.T) {
+	dialer := &fakeDialer{}
+	pool := newTestPool(t, dialer, func(b *DBConnectionPoolBuilder) {
+		b.WithMinConnections(1).WithMaxConnections(3).WithMaxIdleTime(20 * time.Millisecond)
+	})
+
+	ctx := context.Background()
+	a, _ := pool.Acquire(ctx)
+	b, _ := pool.Acquire(ctx)
+	c, _ := pool.Acquire(ctx)
+	a.Release()
+	b.Release()
+	c.Release()
+
+	deadline := time.Now().Add(time.Second)
+	for pool.Stats().Open > 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stats := pool.Stats()
+	if stats.Open != 1 {
+		t.Errorf("Expected idle connections to be reaped down to 1, got %d", stats.Open)
+	}
+	if stats.ClosedMaxIdle != 2 {
+		t.Errorf("Expected 2 idle closures, got %d", stats.ClosedMaxIdle)
+	}
+}
+
+// TestPool_DialFailure tests that pre-warm failures fail the build.
+func TestPool_DialFailure(t *testing.T) {
+	dialer := &fakeDialer{fail: errors.New("connection refused")}
+
+	_, err := NewDBConnectionPoolBuilder().
+		WithDatabase("testdb").
+		WithCredentials("user", "pass").
+		WithRetryAttempts(0).
+		WithDialer(dialer).
+		Build()
+
+	if err == nil {
+		t.Fatal("Expected build to fail when the dialer fails")
+	}
+}
+
+// TestPool_Close tests that closing the pool closes idle connections.
+func TestPool_Close(t *testing.T) {
+	dialer := &fakeDialer{}
+	pool := newTestPool(t, dialer, func(b *DBConnectionPoolBuilder) {
+		b.WithMinConnections(2)
+	})
+
+	held, _ := pool.Acquire(context.Background())
+	pool.Close()
+
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed, got %v", err)
+	}
+
+	held.Release()
+	for _, conn := range dialer.conns {
+		if !conn.closed.Load() {
+			t.Errorf("Expected connection %d to be closed", conn.id)
+		}
+	}
+}
+
+// TestPool_WithoutDialer tests that a config-only pool reports ErrNoDialer.
+func TestPool_WithoutDialer(t *testing.T) {
+	pool, err := NewDBConnectionPoolBuilder().
+		WithDatabase("testdb").
+		WithCredentials("user", "pass").
+		Build()
+	if err != nil {
+		t.Fatalf("Expected successful build, got error: %v", err)
+	}
+
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrNoDialer) {
+		t.Errorf("Expected ErrNoDialer, got %v", err)
+	}
+}
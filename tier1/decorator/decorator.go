@@ -8,16 +8,16 @@ package decorator
 
 import (
 	"compress/gzip"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/cache"
 )
 
 // ============================================================================
@@ -198,20 +198,45 @@ func (cd *CompressionDecorator) compress(data string) (string, error) {
 // This demonstrates how decorators can add security features transparently.
 type EncryptionDecorator struct {
 	processor DataProcessor
-	key       []byte
+	keyring   *Keyring
+	envelope  bool
+}
+
+// EncryptionOption configures an EncryptionDecorator.
+type EncryptionOption func(*EncryptionDecorator)
+
+// WithEnvelope seals each result with its own data key, wrapped by the
+// keyring's active key, so rotation only has to rewrap the data keys.
+func WithEnvelope() EncryptionOption {
+	return func(ed *EncryptionDecorator) {
+		ed.envelope = true
+	}
 }
 
 // NewEncryptionDecorator wraps a processor with AES encryption.
 // The key must be 16, 24, or 32 bytes for AES-128, AES-192, or AES-256.
+// It is held in a keyring under LegacyKeyID; use NewKeyringEncryptionDecorator
+// to rotate keys.
 func NewEncryptionDecorator(processor DataProcessor, key []byte) (DataProcessor, error) {
-	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
-		return nil, fmt.Errorf("invalid key size: must be 16, 24, or 32 bytes")
+	keyring, err := NewKeyring(LegacyKeyID, key)
+	if err != nil {
+		return nil, err
 	}
+	return NewKeyringEncryptionDecorator(processor, keyring), nil
+}
 
-	return &EncryptionDecorator{
+// NewKeyringEncryptionDecorator wraps a processor with AES encryption under
+// the keyring's active key. Results are base64 encoded and name the key
+// they were sealed with, so they stay decryptable after rotation.
+func NewKeyringEncryptionDecorator(processor DataProcessor, keyring *Keyring, opts ...EncryptionOption) *EncryptionDecorator {
+	ed := &EncryptionDecorator{
 		processor: processor,
-		key:       key,
-	}, nil
+		keyring:   keyring,
+	}
+	for _, opt := range opts {
+		opt(ed)
+	}
+	return ed
 }
 
 // Process adds encryption after processing.
@@ -231,79 +256,208 @@ func (ed *EncryptionDecorator) Process(data string) (string, error) {
 }
 
 func (ed *EncryptionDecorator) encrypt(plaintext string) (string, error) {
-	block, err := aes.NewCipher(ed.key)
+	var ciphertext []byte
+	var err error
+	if ed.envelope {
+		ciphertext, err = ed.keyring.EncryptEnvelope([]byte(plaintext))
+	} else {
+		ciphertext, err = ed.keyring.Encrypt([]byte(plaintext))
+	}
 	if err != nil {
 		return "", err
 	}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+	// Return base64 encoded for safe string representation
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt reverses Process, returning the wrapped processor's output.
+func (ed *EncryptionDecorator) Decrypt(data string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("decryption error: %w: %w", ErrDecrypt, err)
 	}
-
-	// Generate nonce
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
+	plaintext, err := ed.keyring.Decrypt(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decryption error: %w", err)
 	}
+	return string(plaintext), nil
+}
 
-	// Encrypt and append nonce
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-
-	// Return base64 encoded for safe string representation
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+// ReEncrypt migrates a result of Process to the keyring's active key and
+// reports whether it changed.
+func (ed *EncryptionDecorator) ReEncrypt(data string) (string, bool, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", false, fmt.Errorf("re-encryption error: %w: %w", ErrDecrypt, err)
+	}
+	migrated, changed, err := ed.keyring.ReEncrypt(ciphertext)
+	if err != nil {
+		return "", false, fmt.Errorf("re-encryption error: %w", err)
+	}
+	if !changed {
+		return data, false, nil
+	}
+	return base64.StdEncoding.EncodeToString(migrated), true, nil
+}
+
+// ReEncryptAll migrates stored results of Process to the active key in
+// place, so retired keys can be removed afterwards. It returns how many
+// values changed; values that cannot be decrypted are left as they are and
+// their errors returned together.
+func (ed *EncryptionDecorator) ReEncryptAll(records map[string]string) (int, error) {
+	var errs []error
+	changed := 0
+	for name, data := range records {
+		migrated, ok, err := ed.ReEncrypt(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if ok {
+			records[name] = migrated
+			changed++
+		}
+	}
+	return changed, errors.Join(errs...)
 }
 
 // ============================================================================
 
+// cachingDecoratorMaxEntries is a CachingDecorator's default capacity; the
+// least recently used results are evicted beyond it.
+const cachingDecoratorMaxEntries = 1024
+
 // CachingDecorator adds caching functionality.
 // This demonstrates stateful decorators that maintain their own data.
+// It is safe for concurrent use.
 type CachingDecorator struct {
 	processor DataProcessor
-	cache     map[string]string
-	hits      int
-	misses    int
+	cache     *cache.Cache[string, string]
+	name      string
+	keyFuncs  []func(string) string
+}
+
+type cachingConfig struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// CachingOption configures a CachingDecorator.
+type CachingOption func(*CachingDecorator, *cachingConfig)
+
+// WithCacheCapacity sets how many results are kept before the least recently
+// used are evicted. Zero means no limit; the default is 1024.
+func WithCacheCapacity(n int) CachingOption {
+	return func(_ *CachingDecorator, c *cachingConfig) {
+		c.capacity = n
+	}
+}
+
+// WithCacheTTL makes cached results expire after ttl.
+func WithCacheTTL(ttl time.Duration) CachingOption {
+	return func(_ *CachingDecorator, c *cachingConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithCacheKey normalises inputs before they are used as cache keys, applying
+// fns in order. Inputs with the same normalised key share one cached result,
+// so WithCacheKey(strings.TrimSpace, strings.ToLower) suits processors that
+// ignore surrounding space and case. The processor still sees the input as
+// given.
+func WithCacheKey(fns ...func(string) string) CachingOption {
+	return func(cd *CachingDecorator, _ *cachingConfig) {
+		cd.keyFuncs = append(cd.keyFuncs, fns...)
+	}
+}
+
+// WithCacheName labels the decorator's metrics, to tell several caches apart.
+func WithCacheName(name string) CachingOption {
+	return func(cd *CachingDecorator, _ *cachingConfig) {
+		cd.name = name
+	}
+}
+
+// withCacheClock replaces time.Now, for tests.
+func withCacheClock(now func() time.Time) CachingOption {
+	return func(_ *CachingDecorator, c *cachingConfig) {
+		c.now = now
+	}
 }
 
 // NewCachingDecorator wraps a processor with caching capabilities.
-func NewCachingDecorator(processor DataProcessor) *CachingDecorator {
-	return &CachingDecorator{
-		processor: processor,
-		cache:     make(map[string]string),
+func NewCachingDecorator(processor DataProcessor, opts ...CachingOption) *CachingDecorator {
+	cd := &CachingDecorator{processor: processor}
+	config := cachingConfig{capacity: cachingDecoratorMaxEntries}
+	for _, opt := range opts {
+		opt(cd, &config)
 	}
+
+	cacheOpts := []cache.Option{cache.WithMaxEntries(config.capacity), cache.WithTTL(config.ttl)}
+	if config.now != nil {
+		cacheOpts = append(cacheOpts, cache.WithClock(config.now))
+	}
+	cd.cache = cache.New[string, string](cacheOpts...)
+	return cd
+}
+
+func (cd *CachingDecorator) key(data string) string {
+	for _, fn := range cd.keyFuncs {
+		data = fn(data)
+	}
+	return data
 }
 
 // Process checks cache before delegating to the wrapped processor.
 func (cd *CachingDecorator) Process(data string) (string, error) {
+	key := cd.key(data)
+
 	// Check cache first
-	if result, found := cd.cache[data]; found {
-		cd.hits++
+	if result, found := cd.cache.Get(key); found {
 		return result, nil
 	}
 
 	// Cache miss - delegate to wrapped processor
-	cd.misses++
 	result, err := cd.processor.Process(data)
 	if err != nil {
 		return "", err
 	}
 
 	// Store in cache
-	cd.cache[data] = result
+	cd.cache.Set(key, result)
 	return result, nil
 }
 
 // Stats returns cache statistics.
 func (cd *CachingDecorator) Stats() (hits, misses int) {
-	return cd.hits, cd.misses
+	stats := cd.cache.Stats()
+	return int(stats.Hits), int(stats.Misses)
+}
+
+// Len returns the number of cached results.
+func (cd *CachingDecorator) Len() int {
+	return cd.cache.Len()
 }
 
 // ClearCache clears the cache and resets statistics.
 func (cd *CachingDecorator) ClearCache() {
-	cd.cache = make(map[string]string)
-	cd.hits = 0
-	cd.misses = 0
+	cd.cache.Clear()
+	cd.cache.ResetStats()
+}
+
+// Metrics implements MetricsSource.
+func (cd *CachingDecorator) Metrics() []Metric {
+	stats := cd.cache.Stats()
+	labels := map[string]string{"cache": cd.name}
+	return []Metric{
+		{Name: "decorator_cache_hits_total", Help: "Lookups answered from the cache.", Kind: Counter, Labels: labels, Value: float64(stats.Hits)},
+		{Name: "decorator_cache_misses_total", Help: "Lookups passed to the wrapped processor.", Kind: Counter, Labels: labels, Value: float64(stats.Misses)},
+		{Name: "decorator_cache_evictions_total", Help: "Results evicted for capacity.", Kind: Counter, Labels: labels, Value: float64(stats.Evictions)},
+		{Name: "decorator_cache_expirations_total", Help: "Results removed because their TTL passed.", Kind: Counter, Labels: labels, Value: float64(stats.Expirations)},
+		{Name: "decorator_cache_entries", Help: "Results currently cached.", Kind: Gauge, Labels: labels, Value: float64(cd.cache.Len())},
+	}
 }
 
 // ============================================================================
@@ -371,34 +525,69 @@ func MetricsMiddleware(metrics *RequestMetrics) HTTPMiddleware {
 	}
 }
 
-// RequestMetrics holds metrics for HTTP requests.
+// RequestMetrics holds metrics for HTTP requests. It is safe for concurrent
+// use; read the fields directly only once no requests are in flight, or
+// use Snapshot.
 type RequestMetrics struct {
+	// Name labels the exported metrics, to tell several handlers apart.
+	Name string
+
 	TotalRequests int
 	TotalErrors   int
 	TotalDuration time.Duration
+
+	mu sync.Mutex
 }
 
 // IncrementRequests increments the request counter.
 func (rm *RequestMetrics) IncrementRequests() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
 	rm.TotalRequests++
 }
 
 // IncrementErrors increments the error counter.
 func (rm *RequestMetrics) IncrementErrors() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
 	rm.TotalErrors++
 }
 
 // RecordDuration adds to the total duration.
 func (rm *RequestMetrics) RecordDuration(d time.Duration) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
 	rm.TotalDuration += d
 }
 
+// Snapshot returns the counters as of now.
+func (rm *RequestMetrics) Snapshot() (requests, failures int, duration time.Duration) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.TotalRequests, rm.TotalErrors, rm.TotalDuration
+}
+
 // AverageDuration returns the average request duration.
+//
+// Deprecated: an average hides slow outliers. Use HTTPMetrics, which keeps
+// a latency histogram per route.
 func (rm *RequestMetrics) AverageDuration() time.Duration {
-	if rm.TotalRequests == 0 {
+	requests, _, duration := rm.Snapshot()
+	if requests == 0 {
 		return 0
 	}
-	return rm.TotalDuration / time.Duration(rm.TotalRequests)
+	return duration / time.Duration(requests)
+}
+
+// Metrics implements MetricsSource.
+func (rm *RequestMetrics) Metrics() []Metric {
+	requests, failures, duration := rm.Snapshot()
+	labels := map[string]string{"handler": rm.Name}
+	return []Metric{
+		{Name: "decorator_http_requests_total", Help: "HTTP requests handled.", Kind: Counter, Labels: labels, Value: float64(requests)},
+		{Name: "decorator_http_errors_total", Help: "HTTP requests answered with a 4xx or 5xx status.", Kind: Counter, Labels: labels, Value: float64(failures)},
+		{Name: "decorator_http_request_duration_seconds_total", Help: "Time spent handling HTTP requests.", Kind: Counter, Labels: labels, Value: duration.Seconds()},
+	}
 }
 
 // statusRecorder wraps http.ResponseWriter to capture the status code.
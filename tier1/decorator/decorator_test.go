@@ -5,7 +5,9 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -283,6 +285,69 @@ func TestCachingDecorator(t *testing.T) {
 	}
 }
 
+func TestCachingDecorator_CapacityAndTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewCachingDecorator(NewSimpleProcessor("Test"),
+		WithCacheCapacity(2), WithCacheTTL(time.Minute),
+		withCacheClock(func() time.Time { return now }))
+
+	cache.Process("a")
+	cache.Process("b")
+	cache.Process("a") // hit; "b" is now least recently used
+	cache.Process("c") // evicts "b"
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached results, got %d", cache.Len())
+	}
+	cache.Process("a")
+	cache.Process("b")
+	if hits, misses := cache.Stats(); hits != 2 || misses != 4 {
+		t.Errorf("Expected 2 hits and 4 misses, got %d hits and %d misses", hits, misses)
+	}
+
+	now = now.Add(2 * time.Minute)
+	cache.Process("b")
+	if hits, _ := cache.Stats(); hits != 2 {
+		t.Errorf("Expected expired result to miss, got %d hits", hits)
+	}
+}
+
+func TestCachingDecorator_KeyNormalisation(t *testing.T) {
+	cache := NewCachingDecorator(NewSimpleProcessor("Test"), WithCacheKey(strings.TrimSpace, strings.ToLower))
+
+	first, _ := cache.Process("Hello")
+	second, _ := cache.Process("  hello ")
+	if first != second || first != "[Test] Hello" {
+		t.Errorf("Expected the first result for both inputs, got %q and %q", first, second)
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d hits and %d misses", hits, misses)
+	}
+}
+
+func TestCachingDecorator_Concurrent(t *testing.T) {
+	cache := NewCachingDecorator(NewSimpleProcessor("Test"), WithCacheCapacity(8))
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 200 {
+				data := strconv.Itoa((i + j) % 16)
+				if result, err := cache.Process(data); err != nil || result != "[Test] "+data {
+					t.Errorf("Process(%q) = %q, %v", data, result, err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if hits, misses := cache.Stats(); hits+misses != 8*200 {
+		t.Errorf("Expected %d lookups, got %d", 8*200, hits+misses)
+	}
+	if cache.Len() > 8 {
+		t.Errorf("Expected at most 8 cached results, got %d", cache.Len())
+	}
+}
+
 // ============================================================================
 // Decorator Composition Tests
 // ============================================================================
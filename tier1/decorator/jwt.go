@@ -0,0 +1,221 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Bearer Token Authentication
+// ============================================================================
+
+// ErrInvalidToken matches every reason BearerAuth rejects a token.
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTConfig configures HMAC-signed JSON Web Tokens.
+type JWTConfig struct {
+	// Key is the shared HMAC secret. It must be at least as long as the
+	// algorithm's hash: 32 bytes for HS256, 48 for HS384 and 64 for HS512.
+	Key []byte
+	// Algorithm is HS256, HS384 or HS512; the default is HS256. Tokens
+	// signed with any other algorithm, including "none", are rejected.
+	Algorithm string
+	// Issuer and Audience, if set, must match the token's iss and aud.
+	Issuer   string
+	Audience string
+	// Leeway allows for clock skew when checking exp and nbf.
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+func (c JWTConfig) hash() (func() hash.Hash, string, error) {
+	var newHash func() hash.Hash
+	alg := c.Algorithm
+	switch alg {
+	case "", "HS256":
+		newHash, alg = sha256.New, "HS256"
+	case "HS384":
+		newHash = sha512.New384
+	case "HS512":
+		newHash = sha512.New
+	default:
+		return nil, "", fmt.Errorf("unsupported JWT algorithm %q", c.Algorithm)
+	}
+	if size := newHash().Size(); len(c.Key) < size {
+		return nil, "", fmt.Errorf("%s key must be at least %d bytes, got %d", alg, size, len(c.Key))
+	}
+	return newHash, alg, nil
+}
+
+// Claims are the registered JWT claims BearerAuth checks. Tokens must carry
+// exp; the other claims are optional.
+type Claims struct {
+	Subject   string   `json:"sub,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// Audience is the aud claim. It is sent as a string if it has one entry and
+// accepted as a string or an array.
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*a = Audience{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// Sign issues a token for claims. It fails if the algorithm is unsupported
+// or the key is too short.
+func (c JWTConfig) Sign(claims Claims) (string, error) {
+	newHash, alg, err := c.hash()
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	mac := hmac.New(newHash, c.Key)
+	mac.Write([]byte(signed))
+	return signed + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify checks a token's signature, algorithm and claims and returns the
+// claims. Errors about the token match ErrInvalidToken; an unsupported
+// algorithm or a short key is reported as is.
+func (c JWTConfig) Verify(token string) (*Claims, error) {
+	newHash, alg, err := c.hash()
+	if err != nil {
+		return nil, err
+	}
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, reason)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed")
+	}
+	signed := parts[0] + "." + parts[1]
+	enc := base64.RawURLEncoding
+
+	// Check the algorithm before the signature, so a token cannot pick a
+	// weaker one than configured.
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	raw, err := enc.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(raw, &header) != nil {
+		return nil, invalid("malformed header")
+	}
+	if header.Alg != alg {
+		return nil, invalid(fmt.Sprintf("algorithm %q not allowed", header.Alg))
+	}
+	got, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed signature")
+	}
+	mac := hmac.New(newHash, c.Key)
+	mac.Write([]byte(signed))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, invalid("bad signature")
+	}
+
+	var claims Claims
+	raw, err = enc.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(raw, &claims) != nil {
+		return nil, invalid("malformed claims")
+	}
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	t := now()
+	switch {
+	case claims.ExpiresAt == 0:
+		return nil, invalid("no expiry")
+	case t.After(time.Unix(claims.ExpiresAt, 0).Add(c.Leeway)):
+		return nil, invalid("expired")
+	case claims.NotBefore != 0 && t.Before(time.Unix(claims.NotBefore, 0).Add(-c.Leeway)):
+		return nil, invalid("not valid yet")
+	case c.Issuer != "" && claims.Issuer != c.Issuer:
+		return nil, invalid("wrong issuer")
+	case c.Audience != "" && !slices.Contains(claims.Audience, c.Audience):
+		return nil, invalid("wrong audience")
+	}
+	return &claims, nil
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the token BearerAuth accepted.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// BearerAuth requires an "Authorization: Bearer <token>" header carrying a
+// token that config verifies, and stores its claims in the request's
+// context. Other requests get 401 Unauthorized with a WWW-Authenticate
+// challenge. It panics if config's algorithm is unsupported or its key is
+// too short.
+func BearerAuth(config JWTConfig) Middleware {
+	if _, _, err := config.hash(); err != nil {
+		panic("decorator: BearerAuth: " + err.Error())
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			claims, err := config.Verify(strings.TrimSpace(token))
+			if err != nil {
+				reason := strings.TrimPrefix(err.Error(), ErrInvalidToken.Error()+": ")
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, reason))
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
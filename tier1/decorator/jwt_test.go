@@ -0,0 +1,131 @@
+package decorator
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Bearer Token Authentication Tests
+// ============================================================================
+
+func TestJWTConfig_Verify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	config := JWTConfig{
+		Key:      []byte("0123456789abcdef0123456789abcdef"),
+		Issuer:   "auth.example.com",
+		Audience: "api",
+		Leeway:   30 * time.Second,
+		now:      func() time.Time { return now },
+	}
+	valid := Claims{
+		Subject:   "user-1",
+		Issuer:    "auth.example.com",
+		Audience:  Audience{"web", "api"},
+		ExpiresAt: now.Add(time.Minute).Unix(),
+	}
+
+	token, err := config.Sign(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := config.Verify(token)
+	if err != nil || claims.Subject != "user-1" {
+		t.Fatalf("Verify = %+v, %v", claims, err)
+	}
+
+	sign := func(c JWTConfig, claims Claims) string {
+		token, err := c.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	modify := func(fn func(*Claims)) string {
+		c := valid
+		fn(&c)
+		return sign(config, c)
+	}
+	otherKey := config
+	otherKey.Key = []byte("another key of thirty-two bytes!")
+	hs512 := config
+	hs512.Algorithm = "HS512"
+	hs512.Key = []byte(strings.Repeat("0123456789abcdef", 4))
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		strings.Split(token, ".")[1] + "."
+
+	tests := map[string]string{
+		"malformed":       "not.a-token",
+		"wrong key":       sign(otherKey, valid),
+		"wrong algorithm": sign(hs512, valid),
+		"alg none":        unsigned,
+		"tampered":        token[:len(token)-2] + "AA",
+		"expired":         modify(func(c *Claims) { c.ExpiresAt = now.Add(-time.Minute).Unix() }),
+		"no expiry":       modify(func(c *Claims) { c.ExpiresAt = 0 }),
+		"not yet valid":   modify(func(c *Claims) { c.NotBefore = now.Add(time.Minute).Unix() }),
+		"wrong issuer":    modify(func(c *Claims) { c.Issuer = "evil" }),
+		"wrong audience":  modify(func(c *Claims) { c.Audience = Audience{"web"} }),
+	}
+	for name, token := range tests {
+		if _, err := config.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+
+	// Keys shorter than the hash are refused for signing and verifying.
+	for _, short := range []JWTConfig{{}, {Key: []byte("secret")}, {Key: config.Key, Algorithm: "HS384"}} {
+		if _, err := short.Sign(valid); err == nil {
+			t.Errorf("Expected Sign to refuse a %d byte %s key", len(short.Key), short.Algorithm)
+		}
+		if _, err := short.Verify(token); err == nil || errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected Verify to refuse a %d byte %s key, got %v", len(short.Key), short.Algorithm, err)
+		}
+	}
+
+	// Leeway tolerates small clock skew.
+	skewed := modify(func(c *Claims) { c.ExpiresAt = now.Add(-10 * time.Second).Unix() })
+	if _, err := config.Verify(skewed); err != nil {
+		t.Errorf("Expected a token expired within the leeway to pass, got %v", err)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	config := JWTConfig{Key: []byte("0123456789abcdef0123456789abcdef")}
+	h := BearerAuth(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		w.Write([]byte(claims.Subject))
+	}))
+	token, _ := config.Sign(Claims{Subject: "alice", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	request := func(authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		return serve(h, r)
+	}
+
+	if w := request("Bearer " + token); w.Code != http.StatusOK || w.Body.String() != "alice" {
+		t.Errorf("Expected the subject from a valid token, got %d %q", w.Code, w.Body.String())
+	}
+	if w := request(""); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("Expected a bare challenge without credentials, got %d %v", w.Code, w.Header())
+	}
+	w := request("Bearer " + token + "x")
+	if w.Code != http.StatusUnauthorized ||
+		w.Header().Get("WWW-Authenticate") != `Bearer error="invalid_token", error_description="bad signature"` {
+		t.Errorf("Expected an invalid_token challenge, got %d %v", w.Code, w.Header())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected BearerAuth to panic on an empty key")
+		}
+	}()
+	BearerAuth(JWTConfig{})
+}
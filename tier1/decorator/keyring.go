@@ -0,0 +1,371 @@
+package decorator
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// ============================================================================
+// Key Management
+// ============================================================================
+
+var (
+	// ErrUnknownKey is returned when decrypting data sealed with a key the
+	// keyring does not hold, for example one that was removed after rotation.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrDecrypt is returned when ciphertext is malformed or fails
+	// authentication.
+	ErrDecrypt = errors.New("decryption failed")
+)
+
+// LegacyKeyID is the ID of the key NewEncryptionDecorator was given. Data
+// encrypted before ciphertexts carried a key ID is decrypted with it.
+const LegacyKeyID = ""
+
+// PassphraseIterations is the PBKDF2-SHA256 iteration count DeriveKey uses.
+const PassphraseIterations = 600_000
+
+// Ciphertexts start with a header that names the key, which is also
+// authenticated as additional data:
+//
+//	magic 'K' | version | flags | len(id) | id
+//
+// Direct ciphertexts follow it with a nonce and the sealed data. Envelope
+// ciphertexts follow it with a nonce and a fresh data key sealed by the
+// named key, then a nonce and the data sealed by the data key, so rotating
+// the named key only means rewrapping the data key.
+const (
+	keyringMagic    = 'K'
+	keyringVersion  = 1
+	flagEnvelope    = 1
+	dataKeySize     = 32
+	minSaltSize     = 16
+	keyringHeaderSz = 4
+)
+
+// Keyring holds the keys an EncryptionDecorator encrypts and decrypts with.
+// New data is sealed with the active key; retired keys only decrypt, so
+// rotating keys never strands old ciphertexts. It is safe for concurrent
+// use.
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// NewKeyring creates a keyring whose active key is key, under id. The key
+// must be 16, 24, or 32 bytes for AES-128, AES-192, or AES-256.
+func NewKeyring(id string, key []byte) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string]cipher.AEAD)}
+	if err := kr.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("invalid key size: must be 16, 24, or 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Add adds a retired key, which decrypts but is never used to encrypt.
+func (kr *Keyring) Add(id string, key []byte) error {
+	return kr.add(id, key, false)
+}
+
+// Rotate adds key under id and makes it the active key. The previously
+// active key is retired.
+func (kr *Keyring) Rotate(id string, key []byte) error {
+	return kr.add(id, key, true)
+}
+
+func (kr *Keyring) add(id string, key []byte, activate bool) error {
+	if len(id) > 255 {
+		return fmt.Errorf("key id %q longer than 255 bytes", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, ok := kr.keys[id]; ok {
+		return fmt.Errorf("key %q already in keyring", id)
+	}
+	kr.keys[id] = aead
+	if activate {
+		kr.active = id
+	}
+	return nil
+}
+
+// Activate makes a key already in the keyring the active key.
+func (kr *Keyring) Activate(id string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, ok := kr.keys[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	kr.active = id
+	return nil
+}
+
+// Remove drops a retired key. Data still sealed with it can no longer be
+// decrypted, so remove keys only once that data has been re-encrypted.
+func (kr *Keyring) Remove(id string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, ok := kr.keys[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	if id == kr.active {
+		return fmt.Errorf("cannot remove active key %q", id)
+	}
+	delete(kr.keys, id)
+	return nil
+}
+
+// ActiveID returns the ID of the key new data is encrypted with.
+func (kr *Keyring) ActiveID() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.active
+}
+
+// KeyIDs returns the IDs of every key in the keyring, sorted.
+func (kr *Keyring) KeyIDs() []string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	ids := make([]string, 0, len(kr.keys))
+	for id := range kr.keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (kr *Keyring) activeKey() (string, cipher.AEAD) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.active, kr.keys[kr.active]
+}
+
+func (kr *Keyring) key(id string) (cipher.AEAD, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	aead, ok := kr.keys[id]
+	return aead, ok
+}
+
+// Encrypt seals plaintext with the active key.
+func (kr *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	id, aead := kr.activeKey()
+	header := keyringHeader(id, 0)
+	return seal(aead, header, plaintext, header)
+}
+
+// EncryptEnvelope seals plaintext with a fresh data key, and the data key
+// with the active key.
+func (kr *Keyring) EncryptEnvelope(plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	out, err := kr.wrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return seal(dataAEAD, out, plaintext, nil)
+}
+
+// wrap returns an envelope header and dataKey sealed with the active key.
+func (kr *Keyring) wrap(dataKey []byte) ([]byte, error) {
+	id, aead := kr.activeKey()
+	header := keyringHeader(id, flagEnvelope)
+	return seal(aead, header, dataKey, header)
+}
+
+// seal appends a random nonce and plaintext sealed with aead to dst.
+func seal(aead cipher.AEAD, dst, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	dst = append(dst, nonce...)
+	return aead.Seal(dst, nonce, plaintext, additionalData), nil
+}
+
+// open opens a nonce followed by sealed data of the given length, or of the
+// rest of data if length is negative, and returns what follows it.
+func open(aead cipher.AEAD, data []byte, length int, additionalData []byte) (plaintext, rest []byte, err error) {
+	n := aead.NonceSize()
+	if length < 0 {
+		length = len(data) - n
+	}
+	if length < aead.Overhead() || len(data) < n+length {
+		return nil, nil, ErrDecrypt
+	}
+	plaintext, err = aead.Open(nil, data[:n], data[n:n+length], additionalData)
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	return plaintext, data[n+length:], nil
+}
+
+func keyringHeader(id string, flags byte) []byte {
+	header := make([]byte, 0, keyringHeaderSz+len(id)+12+dataKeySize+16)
+	header = append(header, keyringMagic, keyringVersion, flags, byte(len(id)))
+	return append(header, id...)
+}
+
+// parsedCiphertext is a ciphertext split at its header.
+type parsedCiphertext struct {
+	header []byte
+	id     string
+	flags  byte
+	body   []byte
+}
+
+func parseCiphertext(ciphertext []byte) (parsedCiphertext, bool) {
+	if len(ciphertext) < keyringHeaderSz || ciphertext[0] != keyringMagic || ciphertext[1] != keyringVersion {
+		return parsedCiphertext{}, false
+	}
+	end := keyringHeaderSz + int(ciphertext[3])
+	if len(ciphertext) < end || ciphertext[2]&^flagEnvelope != 0 {
+		return parsedCiphertext{}, false
+	}
+	return parsedCiphertext{
+		header: ciphertext[:end],
+		id:     string(ciphertext[keyringHeaderSz:end]),
+		flags:  ciphertext[2],
+		body:   ciphertext[end:],
+	}, true
+}
+
+// KeyID returns the ID of the key ciphertext was sealed with. Ciphertexts
+// without a header are reported as LegacyKeyID.
+func (kr *Keyring) KeyID(ciphertext []byte) string {
+	if p, ok := parseCiphertext(ciphertext); ok {
+		return p.id
+	}
+	return LegacyKeyID
+}
+
+// Decrypt opens ciphertext from Encrypt or EncryptEnvelope with whichever
+// key it names, or headerless ciphertext with the LegacyKeyID key.
+func (kr *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	p, ok := parseCiphertext(ciphertext)
+	if ok {
+		plaintext, err := kr.decrypt(p)
+		if err == nil {
+			return plaintext, nil
+		}
+		// A legacy nonce can start like a header, so fall through.
+		if _, legacy := kr.key(LegacyKeyID); !legacy {
+			return nil, err
+		}
+	}
+	aead, legacy := kr.key(LegacyKeyID)
+	if !legacy {
+		return nil, ErrDecrypt
+	}
+	plaintext, _, err := open(aead, ciphertext, -1, nil)
+	return plaintext, err
+}
+
+func (kr *Keyring) decrypt(p parsedCiphertext) ([]byte, error) {
+	aead, ok := kr.key(p.id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, p.id)
+	}
+	if p.flags&flagEnvelope == 0 {
+		plaintext, _, err := open(aead, p.body, -1, p.header)
+		return plaintext, err
+	}
+	dataKey, rest, err := open(aead, p.body, dataKeySize+aead.Overhead(), p.header)
+	if err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, _, err := open(dataAEAD, rest, -1, nil)
+	return plaintext, err
+}
+
+// ReEncrypt migrates ciphertext to the active key and reports whether it
+// changed anything. Envelope ciphertexts keep their data and data key; only
+// the data key is rewrapped. Everything else is decrypted and encrypted
+// again in the same form.
+func (kr *Keyring) ReEncrypt(ciphertext []byte) ([]byte, bool, error) {
+	p, ok := parseCiphertext(ciphertext)
+	if ok && p.id == kr.ActiveID() {
+		if _, err := kr.decrypt(p); err == nil {
+			return ciphertext, false, nil
+		}
+	}
+	if ok && p.flags&flagEnvelope != 0 {
+		if aead, known := kr.key(p.id); known {
+			dataKey, rest, err := open(aead, p.body, dataKeySize+aead.Overhead(), p.header)
+			if err == nil {
+				out, err := kr.wrap(dataKey)
+				if err != nil {
+					return nil, false, err
+				}
+				return append(out, rest...), true, nil
+			}
+		}
+	}
+
+	plaintext, err := kr.Decrypt(ciphertext)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := kr.Encrypt(plaintext)
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// NewSalt returns a random salt for DeriveKey. Store it alongside the data;
+// the same passphrase and salt always derive the same key.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, minSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// DeriveKey derives an AES-256 key from a passphrase with PBKDF2-SHA256.
+// The salt must be at least 16 bytes.
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	if len(salt) < minSaltSize {
+		return nil, fmt.Errorf("salt must be at least %d bytes", minSaltSize)
+	}
+	return pbkdf2.Key(sha256.New, passphrase, salt, PassphraseIterations, 32)
+}
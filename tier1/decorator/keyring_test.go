@@ -0,0 +1,182 @@
+package decorator
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// ============================================================================
+// Key Management Tests
+// ============================================================================
+
+var (
+	testKey1 = []byte("11111111111111111111111111111111")
+	testKey2 = []byte("2222222222222222")
+	testKey3 = []byte("333333333333333333333333")
+)
+
+func TestKeyring_Rotation(t *testing.T) {
+	for _, envelope := range []bool{false, true} {
+		kr, err := NewKeyring("k1", testKey1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypt := kr.Encrypt
+		if envelope {
+			encrypt = kr.EncryptEnvelope
+		}
+
+		old, err := encrypt([]byte("old data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kr.Rotate("k2", testKey2); err != nil {
+			t.Fatal(err)
+		}
+		current, _ := encrypt([]byte("new data"))
+
+		if id := kr.KeyID(old); id != "k1" {
+			t.Errorf("envelope=%v: expected old data under k1, got %q", envelope, id)
+		}
+		if id := kr.KeyID(current); id != "k2" {
+			t.Errorf("envelope=%v: expected new data under k2, got %q", envelope, id)
+		}
+		for ciphertext, want := range map[string]string{string(old): "old data", string(current): "new data"} {
+			got, err := kr.Decrypt([]byte(ciphertext))
+			if err != nil || string(got) != want {
+				t.Errorf("envelope=%v: Decrypt = %q, %v; want %q", envelope, got, err, want)
+			}
+		}
+
+		// Once old data is migrated, the retired key can go.
+		migrated, changed, err := kr.ReEncrypt(old)
+		if err != nil || !changed {
+			t.Fatalf("envelope=%v: ReEncrypt = %v, %v", envelope, changed, err)
+		}
+		if _, changed, _ := kr.ReEncrypt(migrated); changed {
+			t.Errorf("envelope=%v: expected data under the active key to be left alone", envelope)
+		}
+		if err := kr.Remove("k1"); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := kr.Decrypt(migrated); err != nil || string(got) != "old data" {
+			t.Errorf("envelope=%v: Decrypt migrated = %q, %v", envelope, got, err)
+		}
+		if _, err := kr.Decrypt(old); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("envelope=%v: expected ErrUnknownKey after removal, got %v", envelope, err)
+		}
+	}
+}
+
+func TestKeyring_EnvelopeRewrapKeepsData(t *testing.T) {
+	kr, _ := NewKeyring("k1", testKey1)
+	ciphertext, _ := kr.EncryptEnvelope(bytes.Repeat([]byte("x"), 1000))
+	kr.Rotate("k2", testKey2)
+
+	migrated, _, err := kr.ReEncrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the header and wrapped data key change; the sealed data is reused.
+	tail := ciphertext[len(ciphertext)-1000:]
+	if !bytes.HasSuffix(migrated, tail) {
+		t.Error("Expected rewrapping to keep the sealed data")
+	}
+}
+
+func TestKeyring_Errors(t *testing.T) {
+	kr, _ := NewKeyring("k1", testKey1)
+
+	if err := kr.Add("k1", testKey2); err == nil {
+		t.Error("Expected error adding a duplicate key id")
+	}
+	if err := kr.Add("bad", []byte("short")); err == nil {
+		t.Error("Expected error for invalid key size")
+	}
+	if err := kr.Remove("k1"); err == nil {
+		t.Error("Expected error removing the active key")
+	}
+	if err := kr.Activate("missing"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+
+	ciphertext, _ := kr.Encrypt([]byte("data"))
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err := kr.Decrypt(ciphertext); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for tampered data, got %v", err)
+	}
+
+	// The key id is authenticated, so relabelling data as another key fails.
+	kr.Add("k3", testKey3)
+	ciphertext, _ = kr.Encrypt([]byte("data"))
+	relabelled := append(keyringHeader("k3", 0), ciphertext[len(keyringHeader("k1", 0)):]...)
+	if _, err := kr.Decrypt(relabelled); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for relabelled data, got %v", err)
+	}
+
+	if got := kr.KeyIDs(); len(got) != 2 || got[0] != "k1" || got[1] != "k3" {
+		t.Errorf("KeyIDs = %v", got)
+	}
+}
+
+// TestEncryptionDecorator_Legacy tests that output from before key ids were
+// added still decrypts and can be migrated to a rotated key.
+func TestEncryptionDecorator_Legacy(t *testing.T) {
+	block, _ := aes.NewCipher(testKey1)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	legacy := base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte("[Test] old"), nil))
+
+	kr, _ := NewKeyring(LegacyKeyID, testKey1)
+	ed := NewKeyringEncryptionDecorator(NewSimpleProcessor("Test"), kr, WithEnvelope())
+	if got, err := ed.Decrypt(legacy); err != nil || got != "[Test] old" {
+		t.Fatalf("Decrypt legacy = %q, %v", got, err)
+	}
+
+	if err := kr.Rotate("2026-10", testKey2); err != nil {
+		t.Fatal(err)
+	}
+	fresh, _ := ed.Process("new")
+	records := map[string]string{"a": legacy, "b": fresh, "c": "not base64!"}
+	changed, err := ed.ReEncryptAll(records)
+	if changed != 1 || !errors.Is(err, ErrDecrypt) {
+		t.Errorf("ReEncryptAll = %d, %v; want 1 and an error for c", changed, err)
+	}
+	if err := kr.Remove(LegacyKeyID); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a": "[Test] old", "b": "[Test] new"} {
+		if got, err := ed.Decrypt(records[name]); err != nil || got != want {
+			t.Errorf("%s: Decrypt = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DeriveKey("correct horse battery staple", salt)
+	if err != nil || len(key) != 32 {
+		t.Fatalf("DeriveKey = %d bytes, %v", len(key), err)
+	}
+	again, _ := DeriveKey("correct horse battery staple", salt)
+	if !bytes.Equal(key, again) {
+		t.Error("Expected the same passphrase and salt to derive the same key")
+	}
+	if _, err := NewKeyring("passphrase", key); err != nil {
+		t.Errorf("Expected a derived key to be usable, got %v", err)
+	}
+
+	if _, err := DeriveKey("", salt); err == nil {
+		t.Error("Expected error for empty passphrase")
+	}
+	if _, err := DeriveKey("pass", []byte("short")); err == nil {
+		t.Error("Expected error for short salt")
+	}
+}
@@ -0,0 +1,315 @@
+package decorator
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// Metrics Export
+// ============================================================================
+
+// MetricKind says how a metric's value behaves over time.
+type MetricKind int
+
+const (
+	// Counter only goes up, except when its source is reset.
+	Counter MetricKind = iota
+	// Gauge goes up and down.
+	Gauge
+	// Histogram counts observations into buckets. Its Value is the sum of
+	// all observations.
+	Histogram
+)
+
+func (k MetricKind) String() string {
+	switch k {
+	case Counter:
+		return "counter"
+	case Gauge:
+		return "gauge"
+	case Histogram:
+		return "histogram"
+	default:
+		return fmt.Sprintf("MetricKind(%d)", int(k))
+	}
+}
+
+// Metric is one value reported by a MetricsSource.
+type Metric struct {
+	Name   string
+	Help   string
+	Kind   MetricKind
+	Labels map[string]string
+	Value  float64
+
+	// Buckets and Count are set for histograms only.
+	Buckets []Bucket
+	Count   uint64
+}
+
+// Bucket counts the observations of a histogram at or below UpperBound,
+// including those counted by smaller buckets. The +Inf bucket is implied by
+// the histogram's Count.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// MetricsSource is implemented by decorators that keep statistics, such as
+// CachingDecorator and RequestMetrics, so one exporter can scrape them all.
+type MetricsSource interface {
+	Metrics() []Metric
+}
+
+// WriteMetrics writes the metrics of every source in the Prometheus text
+// exposition format. Metrics with the same name from several sources are
+// grouped under one HELP and TYPE line, and should differ in their labels.
+func WriteMetrics(w io.Writer, sources ...MetricsSource) error {
+	var names []string
+	byName := make(map[string][]Metric)
+	for _, source := range sources {
+		for _, m := range source.Metrics() {
+			if _, ok := byName[m.Name]; !ok {
+				names = append(names, m.Name)
+			}
+			byName[m.Name] = append(byName[m.Name], m)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		metrics := byName[name]
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, helpEscaper.Replace(metrics[0].Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, metrics[0].Kind)
+		for _, m := range metrics {
+			if m.Kind != Histogram {
+				writeSample(bw, name, m.Labels, "", m.Value)
+				continue
+			}
+			for _, b := range m.Buckets {
+				writeSample(bw, name+"_bucket", m.Labels, formatFloat(b.UpperBound), float64(b.Count))
+			}
+			writeSample(bw, name+"_bucket", m.Labels, "+Inf", float64(m.Count))
+			writeSample(bw, name+"_sum", m.Labels, "", m.Value)
+			writeSample(bw, name+"_count", m.Labels, "", float64(m.Count))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSample writes one sample line, with an le label for histogram
+// buckets if le is not empty.
+func writeSample(w *bufio.Writer, name string, labels map[string]string, le string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || le != "" {
+		w.WriteByte('{')
+		for i, name := range slices.Sorted(maps.Keys(labels)) {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, name, labelEscaper.Replace(labels[name]))
+		}
+		if le != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `le="%s"`, le)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// MetricsHandler serves the metrics of every source for a Prometheus
+// scraper.
+func MetricsHandler(sources ...MetricsSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w, sources...)
+	})
+}
+
+// ============================================================================
+// HTTP Metrics
+// ============================================================================
+
+// DefaultLatencyBuckets are the histogram bucket bounds, in seconds, that
+// HTTPMetrics uses unless given others.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HTTPMetrics records request counts by route, method and status, and
+// latency histograms by route and method, for export with WriteMetrics or
+// MetricsHandler. It is safe for concurrent use.
+type HTTPMetrics struct {
+	bounds   []float64
+	inFlight atomic.Int64
+
+	mu     sync.RWMutex
+	series map[routeKey]*routeSeries
+}
+
+type routeKey struct {
+	route, method string
+}
+
+type routeSeries struct {
+	mu       sync.Mutex
+	statuses map[int]uint64
+	buckets  []uint64 // per bound, not cumulative; the last is +Inf
+	count    uint64
+	sum      time.Duration
+}
+
+// NewHTTPMetrics creates HTTP metrics with the given latency bucket bounds
+// in seconds, or DefaultLatencyBuckets if there are none.
+func NewHTTPMetrics(buckets ...float64) *HTTPMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	bounds := slices.Clone(buckets)
+	slices.Sort(bounds)
+	return &HTTPMetrics{bounds: slices.Compact(bounds), series: make(map[routeKey]*routeSeries)}
+}
+
+// Middleware records every request. The route label is the pattern mux
+// routes the request to, such as "GET /items/{id}", or "unmatched", so paths
+// with IDs in them do not each get their own series. Because the route is
+// looked up before the request is served, this middleware can sit outermost
+// and count requests that other middleware turns away. With a nil mux the
+// pattern the ServeMux sets on the request is used instead; then no
+// middleware between this one and the ServeMux may replace the request.
+//
+// A request whose handler panics is recorded as 500 unless a status was
+// already sent, and the panic carries on up the stack.
+func (m *HTTPMetrics) Middleware(mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			m.inFlight.Add(1)
+			defer m.inFlight.Add(-1)
+
+			var route string
+			if mux != nil {
+				_, route = mux.Handler(r)
+			}
+			rw := &responseWriter{ResponseWriter: w}
+			done := false
+			defer func() {
+				if route == "" {
+					route = cmp.Or(r.Pattern, "unmatched")
+				}
+				status := rw.status
+				switch {
+				case status != 0:
+				case done:
+					status = http.StatusOK
+				default:
+					status = http.StatusInternalServerError
+				}
+				m.observe(routeKey{route: route, method: methodLabel(r.Method)}, status, time.Since(start))
+			}()
+			next.ServeHTTP(rw, r)
+			done = true
+		})
+	}
+}
+
+// methodLabel keeps arbitrary methods from creating new series.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+func (m *HTTPMetrics) observe(key routeKey, status int, d time.Duration) {
+	m.mu.RLock()
+	s, ok := m.series[key]
+	m.mu.RUnlock()
+	if !ok {
+		m.mu.Lock()
+		if s, ok = m.series[key]; !ok {
+			s = &routeSeries{statuses: make(map[int]uint64), buckets: make([]uint64, len(m.bounds)+1)}
+			m.series[key] = s
+		}
+		m.mu.Unlock()
+	}
+
+	i, _ := slices.BinarySearch(m.bounds, d.Seconds())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[status]++
+	s.buckets[i]++
+	s.count++
+	s.sum += d
+}
+
+// Metrics implements MetricsSource.
+func (m *HTTPMetrics) Metrics() []Metric {
+	m.mu.RLock()
+	keys := slices.SortedFunc(maps.Keys(m.series), func(a, b routeKey) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method))
+	})
+	series := make([]*routeSeries, len(keys))
+	for i, key := range keys {
+		series[i] = m.series[key]
+	}
+	m.mu.RUnlock()
+
+	var requests, durations []Metric
+	for i, key := range keys {
+		s := series[i]
+		s.mu.Lock()
+		for _, status := range slices.Sorted(maps.Keys(s.statuses)) {
+			requests = append(requests, Metric{
+				Name: "http_server_requests_total", Help: "HTTP requests by route, method and status.", Kind: Counter,
+				Labels: map[string]string{"route": key.route, "method": key.method, "code": strconv.Itoa(status)},
+				Value:  float64(s.statuses[status]),
+			})
+		}
+		buckets := make([]Bucket, len(m.bounds))
+		var cumulative uint64
+		for j, bound := range m.bounds {
+			cumulative += s.buckets[j]
+			buckets[j] = Bucket{UpperBound: bound, Count: cumulative}
+		}
+		durations = append(durations, Metric{
+			Name: "http_server_request_duration_seconds", Help: "HTTP request latency by route and method.", Kind: Histogram,
+			Labels:  map[string]string{"route": key.route, "method": key.method},
+			Value:   s.sum.Seconds(),
+			Buckets: buckets,
+			Count:   s.count,
+		})
+		s.mu.Unlock()
+	}
+
+	inFlight := Metric{
+		Name: "http_server_requests_in_flight", Help: "HTTP requests being served.", Kind: Gauge,
+		Value: float64(m.inFlight.Load()),
+	}
+	return append(append(requests, durations...), inFlight)
+}
@@ -0,0 +1,95 @@
+package decorator
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Metrics Export Tests
+// ============================================================================
+
+func TestWriteMetrics(t *testing.T) {
+	users := NewCachingDecorator(NewSimpleProcessor("Users"), WithCacheName("users"))
+	orders := NewCachingDecorator(NewSimpleProcessor("Orders"), WithCacheName(`or"ders`))
+	users.Process("a")
+	users.Process("a")
+	orders.Process("b")
+
+	metrics := &RequestMetrics{Name: "api"}
+	metrics.IncrementRequests()
+	metrics.IncrementErrors()
+	metrics.RecordDuration(1500 * time.Millisecond)
+
+	var out strings.Builder
+	if err := WriteMetrics(&out, users, orders, metrics); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+
+	for _, want := range []string{
+		"# HELP decorator_cache_hits_total Lookups answered from the cache.\n" +
+			"# TYPE decorator_cache_hits_total counter\n" +
+			"decorator_cache_hits_total{cache=\"users\"} 1\n" +
+			"decorator_cache_hits_total{cache=\"or\\\"ders\"} 0\n",
+		"# TYPE decorator_cache_entries gauge\n",
+		`decorator_cache_misses_total{cache="users"} 1`,
+		`decorator_http_requests_total{handler="api"} 1`,
+		`decorator_http_errors_total{handler="api"} 1`,
+		`decorator_http_request_duration_seconds_total{handler="api"} 1.5`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "# TYPE decorator_cache_hits_total"); n != 1 {
+		t.Errorf("Expected one TYPE line per metric, got %d", n)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	metrics := &RequestMetrics{Name: "api"}
+	handler := MetricsMiddleware(metrics)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Record from many goroutines while scraping.
+	server := httptest.NewServer(MetricsHandler(metrics))
+	defer server.Close()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 50 {
+				handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+			}
+		})
+	}
+	wg.Go(func() {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	})
+	wg.Wait()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text/plain, got %q", ct)
+	}
+	if !strings.Contains(string(body), `decorator_http_requests_total{handler="api"} 400`) {
+		t.Errorf("Expected 400 requests, got:\n%s", body)
+	}
+}
@@ -0,0 +1,414 @@
+package decorator
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Standard Library Middleware
+// ============================================================================
+
+// Middleware decorates a standard http.Handler. Unlike HTTPMiddleware it
+// works with any router, and with http.Server directly.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps handler with middlewares so that they run in the order given:
+// the first one sees the request first and the response last.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Adapt lets an HTTPMiddleware take part in a Chain.
+func Adapt(m HTTPMiddleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(m(next.ServeHTTP))
+	}
+}
+
+// responseWriter records the status a handler sends. It unwraps for
+// http.ResponseController and passes Flush through.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status == 0 && code >= 200 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	return rw.ResponseWriter.Write(p)
+}
+
+func (rw *responseWriter) Flush() {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+// ============================================================================
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID gives every request an ID: the caller's RequestIDHeader if it
+// sent a usable one, or a new random one. The ID is echoed in the response
+// and stored in the request's context for RequestIDFromContext and
+// RequestIDTransport.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+				r.Header.Set(RequestIDHeader, id)
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// validRequestID accepts up to 128 printable ASCII characters, so an ID from
+// the caller is safe to log and to send on.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestIDFromContext returns the ID RequestID gave the request, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDTransport sends the request ID from each outgoing request's
+// context on to the next service. base defaults to http.DefaultTransport.
+func RequestIDTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if id := RequestIDFromContext(r.Context()); id != "" && r.Header.Get(RequestIDHeader) == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set(RequestIDHeader, id)
+		}
+		return base.RoundTrip(r)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// ============================================================================
+
+// Recover turns a panicking handler into a 500 response and logs the panic
+// with its stack and request ID. If the handler had already started its
+// response, the connection is aborted instead, so the client cannot mistake
+// a partial response for a complete one. http.ErrAbortHandler is passed on
+// untouched.
+func Recover(logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logger.Printf("panic serving %s %s (request %s): %v\n%s",
+					r.Method, r.URL.Path, RequestIDFromContext(r.Context()), p, debug.Stack())
+				if rw.status != 0 {
+					panic(http.ErrAbortHandler)
+				}
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// ============================================================================
+
+// BodyLimit rejects request bodies larger than n bytes with 413 Request
+// Entity Too Large. Bodies without a declared length are cut off at n, and
+// the handler's read fails with an *http.MaxBytesError.
+func BodyLimit(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Timeout cancels the request's context after d and answers 503 Service
+// Unavailable if the handler has not finished by then. It buffers the
+// response, so it does not suit streaming handlers.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, "Service Unavailable: request timed out")
+	}
+}
+
+// ============================================================================
+
+// CORSConfig configures CORS.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin
+	// requests, such as "https://app.example.com". "*" allows any origin,
+	// and "https://*.example.com" any subdomain.
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders lists request headers a preflight may ask for.
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers the browser may show scripts.
+	ExposedHeaders []string
+	// AllowCredentials lets requests carry cookies and HTTP auth. The
+	// allowed origin is then echoed, since browsers refuse "*". It cannot
+	// be combined with a "*" origin, which would hand any site the user's
+	// credentials.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		scheme, domain, ok := strings.Cut(allowed, "://*.")
+		if ok && len(origin) > len(scheme)+3+len(domain) &&
+			strings.EqualFold(origin[:len(scheme)+3], scheme+"://") &&
+			strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS answers preflight requests and adds CORS headers to responses for
+// the configured origins. Preflights from other origins get 403 Forbidden;
+// other requests from them are served without CORS headers, so browsers
+// withhold the response from the calling script. It panics if config
+// allows credentials from any origin.
+func CORS(config CORSConfig) Middleware {
+	if config.AllowCredentials && slices.Contains(config.AllowedOrigins, "*") {
+		panic(`decorator: CORS: AllowCredentials cannot be used with the "*" origin`)
+	}
+	methods := config.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposedHeaders, ", ")
+	anyOrigin := slices.Contains(config.AllowedOrigins, "*")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			w.Header().Add("Vary", "Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !config.allowsOrigin(origin) {
+				if preflight {
+					http.Error(w, "Forbidden", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			if anyOrigin {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if config.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if exposeHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			h.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+			}
+			if config.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// ============================================================================
+
+// Gzip compresses responses for clients that accept gzip. Responses that
+// already have a Content-Encoding, and responses without a body, are sent
+// as they are. level is the gzip compression level (1-9, or -1 for
+// default); Gzip panics if it is invalid.
+func Gzip(level int) Middleware {
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic(fmt.Sprintf("decorator: Gzip: %v", err))
+	}
+	pool := &sync.Pool{New: func() any {
+		zw, _ := gzip.NewWriterLevel(nil, level)
+		return zw
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipResponseWriter{ResponseWriter: w, pool: pool}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for part := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.TrimSpace(coding)
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		if v, err := strconv.ParseFloat(q, 64); err == nil && v > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter decides whether to compress when the handler starts
+// its response.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	zw          *gzip.Writer
+	wroteHeader bool
+}
+
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	if gw.wroteHeader || code < 200 {
+		gw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	gw.wroteHeader = true
+	h := gw.Header()
+	if code != http.StatusNoContent && code != http.StatusNotModified && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		gw.zw = gw.pool.Get().(*gzip.Writer)
+		gw.zw.Reset(gw.ResponseWriter)
+	}
+	gw.ResponseWriter.WriteHeader(code)
+}
+
+func (gw *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !gw.wroteHeader {
+		// Sniff the type from the uncompressed body, as net/http would.
+		if gw.Header().Get("Content-Type") == "" {
+			gw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		gw.WriteHeader(http.StatusOK)
+	}
+	if gw.zw == nil {
+		return gw.ResponseWriter.Write(p)
+	}
+	return gw.zw.Write(p)
+}
+
+func (gw *gzipResponseWriter) Flush() {
+	if gw.zw != nil {
+		gw.zw.Flush()
+	}
+	http.NewResponseController(gw.ResponseWriter).Flush()
+}
+
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter { return gw.ResponseWriter }
+
+func (gw *gzipResponseWriter) close() {
+	if gw.zw == nil {
+		return
+	}
+	gw.zw.Close()
+	gw.zw.Reset(nil)
+	gw.pool.Put(gw.zw)
+	gw.zw = nil
+}
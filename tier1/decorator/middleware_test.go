@@ -0,0 +1,336 @@
+package decorator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Standard Library Middleware Tests
+// ============================================================================
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	legacy := Adapt(func(next HTTPHandler) HTTPHandler {
+		return func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "legacy")
+			next(w, r)
+		}
+	})
+
+	h := Chain(http.NotFoundHandler(), mark("first"), legacy, mark("last"))
+	serve(h, httptest.NewRequest("GET", "/", nil))
+	if got := strings.Join(order, ","); got != "first,legacy,last" {
+		t.Errorf("Expected first,legacy,last, got %s", got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	w := serve(h, httptest.NewRequest("GET", "/", nil))
+	if len(seen) != 32 || w.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("Expected a generated id echoed in the response, got %q and %q", seen, w.Header().Get(RequestIDHeader))
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestIDHeader, "upstream-42")
+	serve(h, r)
+	if seen != "upstream-42" {
+		t.Errorf("Expected the caller's id to be kept, got %q", seen)
+	}
+
+	r.Header.Set(RequestIDHeader, "bad id\n")
+	serve(h, r)
+	if seen == "bad id\n" || len(seen) != 32 {
+		t.Errorf("Expected an unsafe id to be replaced, got %q", seen)
+	}
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var received string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(RequestIDHeader)
+	}))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: RequestIDTransport(nil)}
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestIDHeader, "trace-me")
+	serve(h, r)
+	if received != "trace-me" {
+		t.Errorf("Expected the id to reach the downstream service, got %q", received)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var logBuf bytes.Buffer
+	logger := log.New(&logBuf, "", 0)
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), RequestID(), Recover(logger))
+	r := httptest.NewRequest("GET", "/explode", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	w := serve(h, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", w.Code)
+	}
+	if logged := logBuf.String(); !strings.Contains(logged, "panic serving GET /explode (request req-1): boom") ||
+		!strings.Contains(logged, "goroutine") {
+		t.Errorf("Expected the panic, request id and stack to be logged, got:\n%s", logged)
+	}
+
+	// After the response has started, the connection is aborted instead.
+	partial := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("late")
+	}))
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler, got %v", p)
+		}
+	}()
+	serve(partial, httptest.NewRequest("GET", "/", nil))
+}
+
+func TestBodyLimit(t *testing.T) {
+	h := BodyLimit(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if !errors.As(err, &tooLarge) {
+				t.Errorf("Expected *http.MaxBytesError, got %v", err)
+			}
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	if w := serve(h, httptest.NewRequest("POST", "/", strings.NewReader("small"))); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a small body, got %d", w.Code)
+	}
+	if w := serve(h, httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat("x", 11)))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a declared large body, got %d", w.Code)
+	}
+	// Without a declared length the body is cut off while reading.
+	r := httptest.NewRequest("POST", "/", io.MultiReader(strings.NewReader(strings.Repeat("x", 20))))
+	r.ContentLength = -1
+	if w := serve(h, r); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a streamed large body, got %d", w.Code)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			w.Write([]byte("too late"))
+		}
+	}))
+	w := serve(h, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", w.Code)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	h := CORS(CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"Authorization"},
+		ExposedHeaders:   []string{RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	request := func(method, origin, requestMethod string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set("Origin", origin)
+		if requestMethod != "" {
+			r.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		return serve(h, r)
+	}
+
+	w := request("OPTIONS", "https://app.example.com", "PUT")
+	if w.Code != http.StatusNoContent ||
+		w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		w.Header().Get("Access-Control-Allow-Methods") != "GET, PUT" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "Authorization" ||
+		w.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+		w.Header().Get("Access-Control-Max-Age") != "3600" {
+		t.Errorf("Unexpected preflight response %d %v", w.Code, w.Header())
+	}
+
+	w = request("GET", "https://api.example.org", "")
+	if w.Body.String() != "ok" || w.Header().Get("Access-Control-Allow-Origin") != "https://api.example.org" ||
+		w.Header().Get("Access-Control-Expose-Headers") != RequestIDHeader {
+		t.Errorf("Expected a subdomain to be allowed, got %v", w.Header())
+	}
+
+	for _, tt := range []struct{ origin, method string }{
+		{"https://evil.example.com", "GET"},
+		{"https://example.org", "GET"},
+		{"http://api.example.org", "GET"},
+		{"https://app.example.com", "DELETE"},
+	} {
+		if w := request("OPTIONS", tt.origin, tt.method); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected preflight to be refused, got %d", tt.origin, tt.method, w.Code)
+		}
+	}
+	w = request("GET", "https://evil.example.com", "")
+	if w.Body.String() != "ok" || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers for a disallowed origin, got %v", w.Header())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error(`Expected CORS to panic on "*" with credentials`)
+		}
+	}()
+	CORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat("compress me ", 100)
+	h := Gzip(gzip.BestSpeed)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "br")
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		return serve(h, r)
+	}
+
+	w := request("/", "br;q=1.0, gzip;q=0.8")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Expected a gzip response, got %v", w.Header())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected the type of the uncompressed body, got %q", ct)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != body {
+		t.Error("Decompressed body does not match")
+	}
+
+	for path, accept := range map[string]string{"/": "gzip;q=0", "/encoded": "gzip"} {
+		if w := request(path, accept); w.Header().Get("Content-Encoding") == "gzip" || w.Body.String() != body {
+			t.Errorf("%s with %q: expected an uncompressed response", path, accept)
+		}
+	}
+	if w := request("/empty", "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Errorf("Expected 204 to be left alone, got %v", w.Header())
+	}
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	metrics := NewHTTPMetrics(0.01, 0.1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "slow" {
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+	mux.HandleFunc("GET /admin", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/admin" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	// Outermost, in front of middleware that rejects requests and replaces
+	// them with r.WithContext.
+	h := Chain(mux, metrics.Middleware(mux), RequestID(), auth)
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/slow", "/missing", "/admin"} {
+		serve(h, httptest.NewRequest("GET", path, nil))
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic to carry on past the metrics")
+			}
+		}()
+		serve(h, httptest.NewRequest("GET", "/panic", nil))
+	}()
+
+	var out strings.Builder
+	WriteMetrics(&out, metrics)
+	for _, want := range []string{
+		`http_server_requests_total{code="200",method="GET",route="GET /items/{id}"} 3`,
+		`http_server_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`http_server_requests_total{code="401",method="GET",route="GET /admin"} 1`,
+		`http_server_requests_total{code="500",method="GET",route="GET /panic"} 1`,
+		`http_server_request_duration_seconds_bucket{method="GET",route="GET /items/{id}",le="0.01"} 2`,
+		`http_server_request_duration_seconds_bucket{method="GET",route="GET /items/{id}",le="0.1"} 3`,
+		`http_server_request_duration_seconds_bucket{method="GET",route="GET /items/{id}",le="+Inf"} 3`,
+		`http_server_request_duration_seconds_count{method="GET",route="GET /items/{id}"} 3`,
+		"# TYPE http_server_request_duration_seconds histogram\n",
+		"http_server_requests_in_flight 0\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	// Without a mux, the pattern comes from the request the ServeMux saw.
+	inner := NewHTTPMetrics()
+	serve(Chain(mux, inner.Middleware(nil)), httptest.NewRequest("GET", "/items/3", nil))
+	out.Reset()
+	WriteMetrics(&out, inner)
+	if want := `http_server_requests_total{code="200",method="GET",route="GET /items/{id}"} 1`; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+	}
+}
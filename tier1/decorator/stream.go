@@ -0,0 +1,614 @@
+package decorator
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// ============================================================================
+// Streaming Decorators
+// ============================================================================
+
+// ErrCorruptStream is returned when decoding data that was not produced by
+// the matching Encode, or that was truncated or tampered with.
+var ErrCorruptStream = errors.New("corrupt stream")
+
+// StreamStage is a reversible processing step on byte streams. Each
+// direction decorates an io.Writer or io.Reader, so stages stack like the
+// DataProcessor decorators but never hold a whole payload in memory.
+type StreamStage interface {
+	// Encode returns a writer that encodes everything written to it into w.
+	// Closing it flushes the encoding; it does not close w.
+	Encode(w io.Writer) (io.WriteCloser, error)
+	// Decode returns a reader of the data that Encode was given, read from
+	// r. Closing it does not close r.
+	Decode(r io.Reader) (io.ReadCloser, error)
+}
+
+// StreamChain applies stages in order when encoding and in reverse order
+// when decoding, so Decode undoes Encode.
+type StreamChain []StreamStage
+
+// Encode returns a writer that passes data through every stage into w.
+// Close must be called to flush all stages.
+func (c StreamChain) Encode(w io.Writer) (io.WriteCloser, error) {
+	writers := make([]io.WriteCloser, len(c))
+	for i := len(c) - 1; i >= 0; i-- {
+		wc, err := c[i].Encode(w)
+		if err != nil {
+			return nil, err
+		}
+		writers[i], w = wc, wc
+	}
+	return &chainWriter{Writer: w, writers: writers}, nil
+}
+
+// Decode returns a reader that undoes every stage, reading from r.
+func (c StreamChain) Decode(r io.Reader) (io.ReadCloser, error) {
+	readers := make([]io.ReadCloser, 0, len(c))
+	for i := len(c) - 1; i >= 0; i-- {
+		rc, err := c[i].Decode(r)
+		if err != nil {
+			closeAll(readers)
+			return nil, err
+		}
+		readers = append(readers, rc)
+		r = rc
+	}
+	return &chainReader{Reader: r, readers: readers}, nil
+}
+
+// EncodeAll encodes everything from src into dst.
+func (c StreamChain) EncodeAll(dst io.Writer, src io.Reader) (int64, error) {
+	w, err := c.Encode(dst)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, src)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// DecodeAll decodes everything from src into dst.
+func (c StreamChain) DecodeAll(dst io.Writer, src io.Reader) (int64, error) {
+	r, err := c.Decode(src)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(dst, r)
+	if closeErr := r.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// chainWriter writes to the first stage and closes the stages outermost
+// first, so each flushes into the next before that one is closed.
+type chainWriter struct {
+	io.Writer
+	writers []io.WriteCloser
+}
+
+func (w *chainWriter) Close() error {
+	var errs []error
+	for _, wc := range w.writers {
+		errs = append(errs, wc.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type chainReader struct {
+	io.Reader
+	readers []io.ReadCloser
+}
+
+func (r *chainReader) Close() error { return closeAll(r.readers) }
+
+func closeAll(readers []io.ReadCloser) error {
+	var errs []error
+	for _, rc := range readers {
+		errs = append(errs, rc.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// ============================================================================
+
+// GzipStage compresses with gzip.
+type GzipStage struct {
+	level int
+}
+
+// NewGzipStage creates a gzip stage. level is the gzip compression level
+// (1-9, or -1 for default).
+func NewGzipStage(level int) (*GzipStage, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, fmt.Errorf("gzip stage: invalid level %d", level)
+	}
+	return &GzipStage{level: level}, nil
+}
+
+// Encode implements StreamStage.
+func (s *GzipStage) Encode(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, s.level)
+}
+
+// Decode implements StreamStage.
+func (s *GzipStage) Decode(r io.Reader) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip stage: %w: %w", ErrCorruptStream, err)
+	}
+	return gzipReader{zr}, nil
+}
+
+// gzipReader reports damage found while reading, such as a bad checksum or
+// a stream cut short, as ErrCorruptStream. Other errors, including those
+// from the underlying reader, are passed through.
+type gzipReader struct {
+	*gzip.Reader
+}
+
+func (r gzipReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	var flateErr flate.CorruptInputError
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, gzip.ErrChecksum) ||
+		errors.Is(err, gzip.ErrHeader) || errors.As(err, &flateErr) {
+		if !errors.Is(err, ErrCorruptStream) {
+			err = fmt.Errorf("gzip stage: %w: %w", ErrCorruptStream, err)
+		}
+	}
+	return n, err
+}
+
+// ============================================================================
+
+// FrameStage compresses in a framed format modelled on zstd's: the stream
+// is cut into independent blocks, each stored raw when compressing it does
+// not help, and each carrying a checksum of its content. The standard
+// library has no zstd codec, so blocks are compressed with DEFLATE; the
+// framing is what bounds memory to one block in each direction.
+//
+// The frame is a magic number and the block size, then blocks, each with a
+// header of its payload length (top bit set for raw blocks) and the CRC-32C
+// of its content, then a zero-length end block.
+type FrameStage struct {
+	level     int
+	blockSize int
+}
+
+const (
+	frameMagic      = "DPF1"
+	frameRawFlag    = 1 << 31
+	maxFrameBlock   = 4 << 20
+	defaultFrameBlk = 128 << 10
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// NewFrameStage creates a framed compression stage. level is the DEFLATE
+// level (1-9, or -1 for default); blockSize is the size of each
+// independently compressed block, zero for 128 KiB, at most 4 MiB.
+func NewFrameStage(level, blockSize int) (*FrameStage, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, fmt.Errorf("frame stage: invalid level %d", level)
+	}
+	if blockSize == 0 {
+		blockSize = defaultFrameBlk
+	}
+	if blockSize < 0 || blockSize > maxFrameBlock {
+		return nil, fmt.Errorf("frame stage: block size %d out of range", blockSize)
+	}
+	return &FrameStage{level: level, blockSize: blockSize}, nil
+}
+
+// Encode implements StreamStage.
+func (s *FrameStage) Encode(w io.Writer) (io.WriteCloser, error) {
+	fw, err := flate.NewWriter(nil, s.level)
+	if err != nil {
+		return nil, err
+	}
+	return &frameWriter{w: w, block: make([]byte, 0, s.blockSize), flate: fw}, nil
+}
+
+// Decode implements StreamStage.
+func (s *FrameStage) Decode(r io.Reader) (io.ReadCloser, error) {
+	return &frameReader{r: r, flate: flate.NewReader(bytes.NewReader(nil))}, nil
+}
+
+type frameWriter struct {
+	w       io.Writer
+	block   []byte
+	flate   *flate.Writer
+	packed  bytes.Buffer
+	started bool
+	err     error
+}
+
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && fw.err == nil {
+		if len(fw.block) == cap(fw.block) {
+			fw.err = fw.flush()
+			continue
+		}
+		k := copy(fw.block[len(fw.block):cap(fw.block)], p)
+		fw.block = fw.block[:len(fw.block)+k]
+		p, n = p[k:], n+k
+	}
+	return n, fw.err
+}
+
+// flush writes the buffered block, compressed if that makes it smaller.
+func (fw *frameWriter) flush() error {
+	if !fw.started {
+		var header [8]byte
+		copy(header[:4], frameMagic)
+		binary.BigEndian.PutUint32(header[4:], uint32(cap(fw.block)))
+		if _, err := fw.w.Write(header[:]); err != nil {
+			return err
+		}
+		fw.started = true
+	}
+	if len(fw.block) == 0 {
+		return nil
+	}
+
+	fw.packed.Reset()
+	fw.flate.Reset(&fw.packed)
+	if _, err := fw.flate.Write(fw.block); err != nil {
+		return err
+	}
+	if err := fw.flate.Close(); err != nil {
+		return err
+	}
+	payload, length := fw.packed.Bytes(), uint32(fw.packed.Len())
+	if len(payload) >= len(fw.block) {
+		payload, length = fw.block, uint32(len(fw.block))|frameRawFlag
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], length)
+	binary.BigEndian.PutUint32(header[4:], crc32.Checksum(fw.block, crc32c))
+	if _, err := fw.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := fw.w.Write(payload); err != nil {
+		return err
+	}
+	fw.block = fw.block[:0]
+	return nil
+}
+
+func (fw *frameWriter) Close() error {
+	if fw.err != nil {
+		return fw.err
+	}
+	if fw.err = fw.flush(); fw.err != nil {
+		return fw.err
+	}
+	_, fw.err = fw.w.Write(make([]byte, 8)) // end block
+	if fw.err == nil {
+		fw.err = errors.New("frame stage: write after close")
+		return nil
+	}
+	return fw.err
+}
+
+type frameReader struct {
+	r         io.Reader
+	blockSize int
+	flate     io.ReadCloser
+	payload   []byte
+	block     []byte
+	pending   []byte
+	done      bool
+}
+
+func (fr *frameReader) Read(p []byte) (int, error) {
+	for len(fr.pending) == 0 {
+		if fr.done {
+			return 0, io.EOF
+		}
+		if err := fr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, fr.pending)
+	fr.pending = fr.pending[n:]
+	return n, nil
+}
+
+func (fr *frameReader) corrupt(reason string) error {
+	return fmt.Errorf("frame stage: %w: %s", ErrCorruptStream, reason)
+}
+
+// next reads and checks the next block into pending.
+func (fr *frameReader) next() error {
+	var header [8]byte
+	if fr.block == nil {
+		if _, err := io.ReadFull(fr.r, header[:]); err != nil {
+			return fr.corrupt("missing frame header")
+		}
+		size := binary.BigEndian.Uint32(header[4:])
+		if string(header[:4]) != frameMagic || size == 0 || size > maxFrameBlock {
+			return fr.corrupt("bad frame header")
+		}
+		fr.blockSize = int(size)
+		fr.block = make([]byte, size)
+	}
+
+	if _, err := io.ReadFull(fr.r, header[:]); err != nil {
+		return fr.corrupt("truncated")
+	}
+	length, sum := binary.BigEndian.Uint32(header[:4]), binary.BigEndian.Uint32(header[4:])
+	if length == 0 {
+		fr.done = true
+		return nil
+	}
+	raw := length&frameRawFlag != 0
+	length &^= frameRawFlag
+	// Blocks are only stored compressed when that makes them smaller.
+	if int(length) > fr.blockSize {
+		return fr.corrupt("block too large")
+	}
+	if cap(fr.payload) < int(length) {
+		fr.payload = make([]byte, length)
+	}
+	payload := fr.payload[:length]
+	if _, err := io.ReadFull(fr.r, payload); err != nil {
+		return fr.corrupt("truncated")
+	}
+
+	content := payload
+	if !raw {
+		fr.flate.(flate.Resetter).Reset(bytes.NewReader(payload), nil)
+		n, err := io.ReadFull(fr.flate, fr.block)
+		if err != io.ErrUnexpectedEOF && err != nil {
+			return fr.corrupt(err.Error())
+		}
+		if extra, _ := fr.flate.Read(make([]byte, 1)); extra != 0 {
+			return fr.corrupt("block too large")
+		}
+		content = fr.block[:n]
+	}
+	if crc32.Checksum(content, crc32c) != sum {
+		return fr.corrupt("checksum mismatch")
+	}
+	fr.pending = content
+	return nil
+}
+
+func (fr *frameReader) Close() error { return fr.flate.Close() }
+
+// ============================================================================
+
+// AESGCMStage encrypts with AES-GCM in fixed-size segments, so neither
+// direction holds more than one segment. Each segment's nonce is a random
+// per-stream prefix, the segment number and a flag marking the last
+// segment, so reordered, dropped or truncated segments fail to decrypt.
+type AESGCMStage struct {
+	aead cipher.AEAD
+}
+
+const (
+	gcmSegmentSize = 64 << 10
+	gcmPrefixSize  = 7
+	gcmVersion     = 1
+)
+
+// NewAESGCMStage creates an encryption stage. The key must be 16, 24, or 32
+// bytes for AES-128, AES-192, or AES-256.
+func NewAESGCMStage(key []byte) (*AESGCMStage, error) {
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("invalid key size: must be 16, 24, or 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCMStage{aead: aead}, nil
+}
+
+// nonce builds the nonce for segment i of a stream.
+func gcmNonce(prefix []byte, i uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[gcmPrefixSize:], i)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// Encode implements StreamStage.
+func (s *AESGCMStage) Encode(w io.Writer) (io.WriteCloser, error) {
+	header := make([]byte, 1+gcmPrefixSize)
+	header[0] = gcmVersion
+	if _, err := io.ReadFull(rand.Reader, header[1:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &gcmWriter{
+		aead:   s.aead,
+		w:      w,
+		prefix: header[1:],
+		buf:    make([]byte, 0, gcmSegmentSize),
+		sealed: make([]byte, 0, gcmSegmentSize+s.aead.Overhead()),
+	}, nil
+}
+
+// Decode implements StreamStage.
+func (s *AESGCMStage) Decode(r io.Reader) (io.ReadCloser, error) {
+	header := make([]byte, 1+gcmPrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil || header[0] != gcmVersion {
+		return nil, fmt.Errorf("aes-gcm stage: %w: bad header", ErrCorruptStream)
+	}
+	return &gcmReader{
+		aead:   s.aead,
+		r:      bufio.NewReader(r),
+		prefix: header[1:],
+		sealed: make([]byte, gcmSegmentSize+s.aead.Overhead()),
+	}, nil
+}
+
+type gcmWriter struct {
+	aead    cipher.AEAD
+	w       io.Writer
+	prefix  []byte
+	segment uint32
+	buf     []byte
+	sealed  []byte
+	err     error
+}
+
+// Write buffers a full segment and seals it only once more data arrives,
+// since the last segment must be sealed as last.
+func (gw *gcmWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && gw.err == nil {
+		if len(gw.buf) == gcmSegmentSize {
+			gw.err = gw.seal(false)
+			continue
+		}
+		k := copy(gw.buf[len(gw.buf):gcmSegmentSize], p)
+		gw.buf = gw.buf[:len(gw.buf)+k]
+		p, n = p[k:], n+k
+	}
+	return n, gw.err
+}
+
+func (gw *gcmWriter) seal(last bool) error {
+	if gw.segment == ^uint32(0) {
+		return errors.New("aes-gcm stage: stream too long")
+	}
+	gw.sealed = gw.aead.Seal(gw.sealed[:0], gcmNonce(gw.prefix, gw.segment, last), gw.buf, nil)
+	if _, err := gw.w.Write(gw.sealed); err != nil {
+		return err
+	}
+	gw.segment++
+	gw.buf = gw.buf[:0]
+	return nil
+}
+
+func (gw *gcmWriter) Close() error {
+	if gw.err != nil {
+		return gw.err
+	}
+	if gw.err = gw.seal(true); gw.err == nil {
+		gw.err = errors.New("aes-gcm stage: write after close")
+		return nil
+	}
+	return gw.err
+}
+
+type gcmReader struct {
+	aead    cipher.AEAD
+	r       *bufio.Reader
+	prefix  []byte
+	segment uint32
+	sealed  []byte
+	pending []byte
+	done    bool
+}
+
+func (gr *gcmReader) Read(p []byte) (int, error) {
+	for len(gr.pending) == 0 {
+		if gr.done {
+			return 0, io.EOF
+		}
+		if err := gr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, gr.pending)
+	gr.pending = gr.pending[n:]
+	return n, nil
+}
+
+// next reads and opens one segment. A segment is the last one if the
+// stream ends after it.
+func (gr *gcmReader) next() error {
+	n, err := io.ReadFull(gr.r, gr.sealed)
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		gr.done = true
+	case err != nil:
+		return err
+	default:
+		_, peekErr := gr.r.Peek(1)
+		gr.done = peekErr == io.EOF
+	}
+	plain, err := gr.aead.Open(gr.sealed[:0], gcmNonce(gr.prefix, gr.segment, gr.done), gr.sealed[:n], nil)
+	if err != nil {
+		return fmt.Errorf("aes-gcm stage: %w: segment %d: %w", ErrCorruptStream, gr.segment, err)
+	}
+	gr.segment++
+	gr.pending = plain
+	return nil
+}
+
+func (gr *gcmReader) Close() error { return nil }
+
+// ============================================================================
+
+// StreamDecorator adapts a StreamChain to DataProcessor. Like the other
+// decorators it base64-encodes its output, and unlike them it can undo it
+// with Reverse.
+type StreamDecorator struct {
+	processor DataProcessor
+	chain     StreamChain
+}
+
+// NewStreamDecorator wraps a processor with chain.
+func NewStreamDecorator(processor DataProcessor, chain StreamChain) *StreamDecorator {
+	return &StreamDecorator{processor: processor, chain: chain}
+}
+
+// Process encodes the wrapped processor's output through the chain.
+func (sd *StreamDecorator) Process(data string) (string, error) {
+	result, err := sd.processor.Process(data)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	enc := base64.NewEncoder(base64.StdEncoding, &buf)
+	if _, err := sd.chain.EncodeAll(enc, strings.NewReader(result)); err != nil {
+		return "", fmt.Errorf("stream encode error: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Reverse decodes output of Process back to the wrapped processor's output.
+func (sd *StreamDecorator) Reverse(data string) (string, error) {
+	var buf strings.Builder
+	dec := base64.NewDecoder(base64.StdEncoding, strings.NewReader(data))
+	if _, err := sd.chain.DecodeAll(&buf, dec); err != nil {
+		return "", fmt.Errorf("stream decode error: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,203 @@
+package decorator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// ============================================================================
+// Streaming Decorator Tests
+// ============================================================================
+
+func testStages(t *testing.T) map[string]StreamChain {
+	t.Helper()
+	gz, err := NewGzipStage(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := NewFrameStage(6, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesgcm, err := NewAESGCMStage([]byte("12345678901234567890123456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]StreamChain{
+		"empty":  {},
+		"gzip":   {gz},
+		"frame":  {frame},
+		"aesgcm": {aesgcm},
+		"chain":  {frame, gz, aesgcm},
+	}
+}
+
+// testInputs covers empty input, sizes on and around the frame and segment
+// boundaries, and data that does not compress.
+func testInputs() map[string][]byte {
+	random := make([]byte, 3*gcmSegmentSize+17)
+	rand.New(rand.NewSource(1)).Read(random)
+	return map[string][]byte{
+		"empty":      nil,
+		"short":      []byte("hello, stream"),
+		"one block":  bytes.Repeat([]byte("a"), 4096),
+		"segment":    bytes.Repeat([]byte("abcdefgh"), gcmSegmentSize/8),
+		"random":     random,
+		"repetitive": []byte(strings.Repeat("the quick brown fox ", 20000)),
+	}
+}
+
+func TestStreamChain_RoundTrip(t *testing.T) {
+	for name, chain := range testStages(t) {
+		for input, data := range testInputs() {
+			var encoded, decoded bytes.Buffer
+			if _, err := chain.EncodeAll(&encoded, bytes.NewReader(data)); err != nil {
+				t.Fatalf("%s/%s: encode: %v", name, input, err)
+			}
+			if _, err := chain.DecodeAll(&decoded, &encoded); err != nil {
+				t.Fatalf("%s/%s: decode: %v", name, input, err)
+			}
+			if !bytes.Equal(decoded.Bytes(), data) {
+				t.Errorf("%s/%s: round trip changed the data", name, input)
+			}
+		}
+	}
+}
+
+func TestFrameStage_StoresIncompressibleBlocksRaw(t *testing.T) {
+	frame, _ := NewFrameStage(9, 4096)
+	data := testInputs()["random"]
+
+	var encoded bytes.Buffer
+	if _, err := (StreamChain{frame}).EncodeAll(&encoded, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	blocks := (len(data) + 4095) / 4096
+	if want := len(data) + 8 + 8*blocks + 8; encoded.Len() != want {
+		t.Errorf("Expected %d encoded bytes for raw blocks, got %d", want, encoded.Len())
+	}
+}
+
+func TestStreamChain_DetectsCorruption(t *testing.T) {
+	data := testInputs()["random"]
+	for _, name := range []string{"gzip", "frame", "aesgcm", "chain"} {
+		chain := testStages(t)[name]
+		var encoded bytes.Buffer
+		if _, err := chain.EncodeAll(&encoded, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		valid := encoded.Bytes()
+
+		tampered := bytes.Clone(valid)
+		tampered[len(tampered)/2] ^= 0x01
+		truncated := valid[:len(valid)-1]
+		// Cut at a segment boundary, so every remaining segment is intact.
+		segmentCut := valid[:1+gcmPrefixSize+gcmSegmentSize+16]
+
+		for what, corrupt := range map[string][]byte{
+			"tampered": tampered, "truncated": truncated, "segment cut": segmentCut,
+		} {
+			if (name == "gzip" || name == "frame") && what == "segment cut" {
+				continue
+			}
+			_, err := chain.DecodeAll(io.Discard, bytes.NewReader(corrupt))
+			if !errors.Is(err, ErrCorruptStream) {
+				t.Errorf("%s/%s: expected ErrCorruptStream, got %v", name, what, err)
+			}
+		}
+	}
+}
+
+// TestGzipStage_CorruptCompressedData tests damage inside compressed
+// blocks, which the random input in TestStreamChain_DetectsCorruption stores
+// uncompressed.
+func TestGzipStage_CorruptCompressedData(t *testing.T) {
+	gz := testStages(t)["gzip"]
+	var encoded bytes.Buffer
+	if _, err := gz.EncodeAll(&encoded, bytes.NewReader(testInputs()["repetitive"])); err != nil {
+		t.Fatal(err)
+	}
+	valid := encoded.Bytes()
+
+	for what, corrupt := range map[string][]byte{
+		"bad block type": append(bytes.Clone(valid[:10]), 0xff, 0xff, 0xff, 0xff),
+		"truncated":      valid[:len(valid)/2],
+		"bad checksum":   append(bytes.Clone(valid[:len(valid)-8]), 0, 0, 0, 0, 0, 0, 0, 0),
+	} {
+		_, err := gz.DecodeAll(io.Discard, bytes.NewReader(corrupt))
+		if !errors.Is(err, ErrCorruptStream) {
+			t.Errorf("%s: expected ErrCorruptStream, got %v", what, err)
+		}
+	}
+}
+
+func TestAESGCMStage_WrongKey(t *testing.T) {
+	enc, _ := NewAESGCMStage([]byte("1234567890123456"))
+	dec, _ := NewAESGCMStage([]byte("6543210987654321"))
+
+	var encoded bytes.Buffer
+	if _, err := (StreamChain{enc}).EncodeAll(&encoded, strings.NewReader("secret")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (StreamChain{dec}).DecodeAll(io.Discard, &encoded); !errors.Is(err, ErrCorruptStream) {
+		t.Errorf("Expected ErrCorruptStream, got %v", err)
+	}
+	if _, err := NewAESGCMStage([]byte("short")); err == nil {
+		t.Error("Expected error for invalid key size")
+	}
+}
+
+// TestStreamChain_Large streams data through an encoder and decoder joined by
+// a pipe, so neither side ever holds the whole payload.
+func TestStreamChain_Large(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large stream in short mode")
+	}
+	chain := testStages(t)["chain"]
+	const size = 64 << 20
+
+	src := io.LimitReader(rand.New(rand.NewSource(2)), size)
+	want := sha256.New()
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := chain.EncodeAll(pw, io.TeeReader(src, want))
+		pw.CloseWithError(err)
+	}()
+
+	got := sha256.New()
+	n, err := chain.DecodeAll(got, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != size {
+		t.Errorf("Expected %d bytes, got %d", size, n)
+	}
+	if !bytes.Equal(got.Sum(nil), want.Sum(nil)) {
+		t.Error("Decoded stream does not match the input")
+	}
+}
+
+func TestStreamDecorator(t *testing.T) {
+	processor := NewStreamDecorator(NewSimpleProcessor("Test"), testStages(t)["chain"])
+
+	encoded, err := processor.Process("test data")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !isBase64(encoded) {
+		t.Errorf("Expected base64 output, got %q", encoded)
+	}
+
+	decoded, err := processor.Reverse(encoded)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded != "[Test] test data" {
+		t.Errorf("Expected %q, got %q", "[Test] test data", decoded)
+	}
+}
@@ -1,6 +1,10 @@
 package factory
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
 
 // Example1_BasicFactoryUsage demonstrates the basic usage of the factory pattern.
 // This shows how the factory function abstracts away the creation logic, allowing
@@ -25,7 +29,7 @@ func Example1_BasicFactoryUsage() {
 	fmt.Printf("Created: %s\n", processor.GetProcessorName())
 
 	// Process a payment - notice we're using the interface, not the concrete type
-	transactionID, err := processor.ProcessPayment(99.99)
+	transactionID, err := processor.ProcessPayment(payment.MustParseMoney("99.99", "USD"))
 	if err != nil {
 		fmt.Printf("Payment failed: %v\n", err)
 		return
@@ -60,7 +64,7 @@ func Example2_MultiplePaymentMethods() {
 		},
 	}
 
-	amount := 150.00
+	amount := payment.MustParseMoney("150.00", "USD")
 
 	// Process payment with each method - same code works for all types!
 	for i, config := range configs {
@@ -188,7 +192,7 @@ func Example5_RealWorldScenario() {
 	// Simulate an e-commerce checkout process
 	type Order struct {
 		OrderID       string
-		Amount        float64
+		Amount        payment.Money
 		PaymentMethod string
 		UserData      map[string]string
 	}
@@ -196,7 +200,7 @@ func Example5_RealWorldScenario() {
 	orders := []Order{
 		{
 			OrderID:       "ORD-001",
-			Amount:        299.99,
+			Amount:        payment.MustParseMoney("299.99", "USD"),
 			PaymentMethod: "creditcard",
 			UserData: map[string]string{
 				"cardNumber": "4532015112830366",
@@ -206,7 +210,7 @@ func Example5_RealWorldScenario() {
 		},
 		{
 			OrderID:       "ORD-002",
-			Amount:        149.50,
+			Amount:        payment.MustParseMoney("149.50", "USD"),
 			PaymentMethod: "paypal",
 			UserData: map[string]string{
 				"email":    "customer@email.com",
@@ -215,7 +219,7 @@ func Example5_RealWorldScenario() {
 		},
 		{
 			OrderID:       "ORD-003",
-			Amount:        599.00,
+			Amount:        payment.MustParseMoney("599.00", "USD"),
 			PaymentMethod: "bitcoin",
 			UserData: map[string]string{
 				"walletAddress": "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
@@ -227,7 +231,7 @@ func Example5_RealWorldScenario() {
 	// Process each order
 	for _, order := range orders {
 		fmt.Printf("Processing Order: %s\n", order.OrderID)
-		fmt.Printf("Amount: $%.2f\n", order.Amount)
+		fmt.Printf("Amount: %s\n", order.Amount.Format())
 		fmt.Printf("Payment Method: %s\n", order.PaymentMethod)
 
 		// Build configuration from order data
@@ -319,7 +323,7 @@ func Example6_FactoryWithDependencyInjection() {
 	}
 
 	fmt.Printf("Created processor: %s\n", processor.GetProcessorName())
-	transactionID, err := processor.ProcessPayment(499.99)
+	transactionID, err := processor.ProcessPayment(payment.MustParseMoney("499.99", "USD"))
 	if err != nil {
 		fmt.Printf("Payment failed: %v\n", err)
 		return
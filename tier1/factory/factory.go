@@ -9,6 +9,9 @@ package factory
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
 // PaymentMethod represents the type of payment method to use
@@ -24,11 +27,20 @@ const (
 // This abstraction allows clients to work with any payment processor without
 // knowing the concrete implementation details.
 type PaymentProcessor interface {
-	ProcessPayment(amount float64) (string, error)
+	ProcessPayment(amount payment.Money) (string, error)
 	ValidateAccount() error
 	GetProcessorName() string
 }
 
+// transactionSeq makes simulated transaction IDs unique, so repeated charges
+// of the same amount can be told apart (and refunded) separately.
+var transactionSeq atomic.Int64
+
+// Refunder is implemented by processors that can reverse a payment.
+type Refunder interface {
+	Refund(transactionID string, amount payment.Money) error
+}
+
 // CreditCardProcessor handles credit card payments
 type CreditCardProcessor struct {
 	cardNumber string
@@ -37,13 +49,13 @@ type CreditCardProcessor struct {
 }
 
 // ProcessPayment processes a credit card payment
-func (c *CreditCardProcessor) ProcessPayment(amount float64) (string, error) {
-	if amount <= 0 {
-		return "", fmt.Errorf("invalid amount: %.2f", amount)
+func (c *CreditCardProcessor) ProcessPayment(amount payment.Money) (string, error) {
+	if !amount.IsPositive() {
+		return "", fmt.Errorf("invalid amount: %s", amount)
 	}
 
 	// Simulate payment processing
-	transactionID := fmt.Sprintf("CC-%s-%.2f", c.cardNumber[len(c.cardNumber)-4:], amount)
+	transactionID := fmt.Sprintf("CC-%s-%s-%d", c.cardNumber[len(c.cardNumber)-4:], amount.Decimal(), transactionSeq.Add(1))
 	return transactionID, nil
 }
 
@@ -58,6 +70,15 @@ func (c *CreditCardProcessor) ValidateAccount() error {
 	return nil
 }
 
+// Refund reverses a credit card payment
+func (c *CreditCardProcessor) Refund(transactionID string, amount payment.Money) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("invalid amount: %s", amount)
+	}
+	// Simulate refund processing
+	return nil
+}
+
 // GetProcessorName returns the name of this payment processor
 func (c *CreditCardProcessor) GetProcessorName() string {
 	return "Credit Card Processor"
@@ -70,13 +91,13 @@ type PayPalProcessor struct {
 }
 
 // ProcessPayment processes a PayPal payment
-func (p *PayPalProcessor) ProcessPayment(amount float64) (string, error) {
-	if amount <= 0 {
-		return "", fmt.Errorf("invalid amount: %.2f", amount)
+func (p *PayPalProcessor) ProcessPayment(amount payment.Money) (string, error) {
+	if !amount.IsPositive() {
+		return "", fmt.Errorf("invalid amount: %s", amount)
 	}
 
 	// Simulate payment processing
-	transactionID := fmt.Sprintf("PP-%s-%.2f", p.email, amount)
+	transactionID := fmt.Sprintf("PP-%s-%s-%d", p.email, amount.Decimal(), transactionSeq.Add(1))
 	return transactionID, nil
 }
 
@@ -91,6 +112,15 @@ func (p *PayPalProcessor) ValidateAccount() error {
 	return nil
 }
 
+// Refund reverses a PayPal payment
+func (p *PayPalProcessor) Refund(transactionID string, amount payment.Money) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("invalid amount: %s", amount)
+	}
+	// Simulate refund processing
+	return nil
+}
+
 // GetProcessorName returns the name of this payment processor
 func (p *PayPalProcessor) GetProcessorName() string {
 	return "PayPal Processor"
@@ -103,13 +133,13 @@ type BitcoinProcessor struct {
 }
 
 // ProcessPayment processes a Bitcoin payment
-func (b *BitcoinProcessor) ProcessPayment(amount float64) (string, error) {
-	if amount <= 0 {
-		return "", fmt.Errorf("invalid amount: %.2f", amount)
+func (b *BitcoinProcessor) ProcessPayment(amount payment.Money) (string, error) {
+	if !amount.IsPositive() {
+		return "", fmt.Errorf("invalid amount: %s", amount)
 	}
 
 	// Simulate payment processing
-	transactionID := fmt.Sprintf("BTC-%s-%s-%.8f", b.network, b.walletAddress[:8], amount)
+	transactionID := fmt.Sprintf("BTC-%s-%s-%s-%d", b.network, b.walletAddress[:8], amount.Decimal(), transactionSeq.Add(1))
 	return transactionID, nil
 }
 
@@ -124,6 +154,15 @@ func (b *BitcoinProcessor) ValidateAccount() error {
 	return nil
 }
 
+// Refund sends a reverse Bitcoin transaction
+func (b *BitcoinProcessor) Refund(transactionID string, amount payment.Money) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("invalid amount: %s", amount)
+	}
+	// Simulate refund processing
+	return nil
+}
+
 // GetProcessorName returns the name of this payment processor
 func (b *BitcoinProcessor) GetProcessorName() string {
 	return fmt.Sprintf("Bitcoin Processor (%s)", b.network)
@@ -145,6 +184,10 @@ type PaymentConfig struct {
 	// Bitcoin fields
 	WalletAddress string
 	Network       string
+
+	// Options holds settings for registered providers that have no
+	// dedicated field above.
+	Options map[string]string
 }
 
 // NewPaymentProcessor is the factory function that creates and returns the appropriate
@@ -152,44 +195,40 @@ type PaymentConfig struct {
 //
 // This is the core of the Factory Pattern - it encapsulates the creation logic
 // and returns an interface type, allowing clients to work with any payment processor
-// without knowing the concrete implementation.
+// without knowing the concrete implementation. Providers are looked up in the
+// default registry, so new methods can be added with Register instead of
+// editing this function.
 func NewPaymentProcessor(config PaymentConfig) (PaymentProcessor, error) {
-	var processor PaymentProcessor
+	return defaultRegistry.New(config)
+}
 
-	switch config.Method {
-	case CreditCard:
-		processor = &CreditCardProcessor{
+// Register the built-in providers.
+func init() {
+	Register(CreditCard, func(config PaymentConfig) (PaymentProcessor, error) {
+		return &CreditCardProcessor{
 			cardNumber: config.CardNumber,
 			cvv:        config.CVV,
 			expiryDate: config.ExpiryDate,
-		}
+		}, nil
+	}, WithConfigSchema(ConfigSchema{Required: []string{"CardNumber", "CVV"}}))
 
-	case PayPal:
-		processor = &PayPalProcessor{
+	Register(PayPal, func(config PaymentConfig) (PaymentProcessor, error) {
+		return &PayPalProcessor{
 			email:    config.Email,
 			apiToken: config.APIToken,
-		}
+		}, nil
+	}, WithConfigSchema(ConfigSchema{Required: []string{"Email"}}))
 
-	case Bitcoin:
+	Register(Bitcoin, func(config PaymentConfig) (PaymentProcessor, error) {
 		network := config.Network
 		if network == "" {
 			network = "mainnet" // default to mainnet
 		}
-		processor = &BitcoinProcessor{
+		return &BitcoinProcessor{
 			walletAddress: config.WalletAddress,
 			network:       network,
-		}
-
-	default:
-		return nil, fmt.Errorf("unsupported payment method: %s", config.Method)
-	}
-
-	// Validate the created processor before returning
-	if err := processor.ValidateAccount(); err != nil {
-		return nil, fmt.Errorf("validation failed for %s: %w", processor.GetProcessorName(), err)
-	}
-
-	return processor, nil
+		}, nil
+	}, WithConfigSchema(ConfigSchema{Required: []string{"WalletAddress"}}))
 }
 
 // NewCreditCardProcessor is a specialized factory function for creating credit card processors.
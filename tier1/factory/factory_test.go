@@ -3,6 +3,8 @@ package factory
 import (
 	"strings"
 	"testing"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
 // TestNewPaymentProcessor_CreditCard tests the factory creates credit card processors correctly
@@ -260,7 +262,7 @@ func TestProcessPayment(t *testing.T) {
 	tests := []struct {
 		name   string
 		config PaymentConfig
-		amount float64
+		amount payment.Money
 		valid  bool
 	}{
 		{
@@ -271,7 +273,7 @@ func TestProcessPayment(t *testing.T) {
 				CVV:        "123",
 				ExpiryDate: "12/25",
 			},
-			amount: 99.99,
+			amount: payment.MustParseMoney("99.99", "USD"),
 			valid:  true,
 		},
 		{
@@ -281,7 +283,7 @@ func TestProcessPayment(t *testing.T) {
 				Email:    "user@example.com",
 				APIToken: "token123",
 			},
-			amount: 150.00,
+			amount: payment.MustParseMoney("150.00", "USD"),
 			valid:  true,
 		},
 		{
@@ -291,7 +293,7 @@ func TestProcessPayment(t *testing.T) {
 				WalletAddress: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
 				Network:       "mainnet",
 			},
-			amount: 0.01,
+			amount: payment.MustParseMoney("0.01", "BTC"),
 			valid:  true,
 		},
 		{
@@ -302,7 +304,7 @@ func TestProcessPayment(t *testing.T) {
 				CVV:        "123",
 				ExpiryDate: "12/25",
 			},
-			amount: 0,
+			amount: payment.MustParseMoney("0", "USD"),
 			valid:  false,
 		},
 		{
@@ -313,7 +315,7 @@ func TestProcessPayment(t *testing.T) {
 				CVV:        "123",
 				ExpiryDate: "12/25",
 			},
-			amount: -50.00,
+			amount: payment.MustParseMoney("-50.00", "USD"),
 			valid:  false,
 		},
 	}
@@ -390,7 +392,7 @@ func TestPaymentProcessor_InterfaceCompliance(t *testing.T) {
 			}
 
 			// Test ProcessPayment
-			transactionID, err := tt.processor.ProcessPayment(100.00)
+			transactionID, err := tt.processor.ProcessPayment(payment.MustParseMoney("100.00", "USD"))
 			if err != nil {
 				t.Errorf("ProcessPayment() error = %v", err)
 			}
@@ -508,10 +510,11 @@ func BenchmarkProcessPayment(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to create processor: %v", err)
 	}
+	amount := payment.MustParseMoney("99.99", "USD")
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := processor.ProcessPayment(99.99)
+		_, err := processor.ProcessPayment(amount)
 		if err != nil {
 			b.Fatalf("Unexpected error: %v", err)
 		}
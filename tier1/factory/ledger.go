@@ -0,0 +1,58 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
+
+// LedgerProcessor wraps a PaymentProcessor so charges and refunds go through
+// a payment.Ledger: retries with the same idempotency key return the original
+// transaction instead of charging twice, refunds are checked against the
+// original amount, and every attempt is written to the audit log.
+type LedgerProcessor struct {
+	PaymentProcessor
+	ledger *payment.Ledger
+}
+
+// NewLedgerProcessor wraps processor with ledger. A nil ledger gets a new
+// in-memory one.
+func NewLedgerProcessor(processor PaymentProcessor, ledger *payment.Ledger) *LedgerProcessor {
+	if ledger == nil {
+		ledger = payment.NewLedger()
+	}
+	return &LedgerProcessor{PaymentProcessor: processor, ledger: ledger}
+}
+
+// Ledger returns the ledger backing this processor.
+func (p *LedgerProcessor) Ledger() *payment.Ledger {
+	return p.ledger
+}
+
+// ProcessPayment charges amount without an idempotency key.
+func (p *LedgerProcessor) ProcessPayment(amount payment.Money) (string, error) {
+	return p.ProcessPaymentWithKey("", amount)
+}
+
+// ProcessPaymentWithKey charges amount at most once for key.
+func (p *LedgerProcessor) ProcessPaymentWithKey(key string, amount payment.Money) (string, error) {
+	return p.ledger.Charge(key, p.GetProcessorName(), amount, func() (string, error) {
+		return p.PaymentProcessor.ProcessPayment(amount)
+	})
+}
+
+// Refund refunds part or all of a transaction without an idempotency key.
+func (p *LedgerProcessor) Refund(transactionID string, amount payment.Money) error {
+	return p.RefundWithKey("", transactionID, amount)
+}
+
+// RefundWithKey refunds part or all of a transaction at most once for key.
+func (p *LedgerProcessor) RefundWithKey(key, transactionID string, amount payment.Money) error {
+	refunder, ok := p.PaymentProcessor.(Refunder)
+	if !ok {
+		return fmt.Errorf("%s does not support refunds", p.GetProcessorName())
+	}
+	return p.ledger.Refund(key, transactionID, amount, func() error {
+		return refunder.Refund(transactionID, amount)
+	})
+}
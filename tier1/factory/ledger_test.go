@@ -0,0 +1,45 @@
+package factory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
+
+// TestLedgerProcessor tests idempotent charges and validated refunds.
+func TestLedgerProcessor(t *testing.T) {
+	base, err := NewCreditCardProcessor("4532015112830366", "123", "12/25")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	audit := payment.NewMemoryAuditLog()
+	processor := NewLedgerProcessor(base, payment.NewLedger(payment.WithAuditLog(audit)))
+
+	first, err := processor.ProcessPaymentWithKey("order-42", payment.MustParseMoney("25.00", "USD"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	retry, err := processor.ProcessPaymentWithKey("order-42", payment.MustParseMoney("25.00", "USD"))
+	if err != nil || retry != first {
+		t.Errorf("Expected retry to return %s, got %s (%v)", first, retry, err)
+	}
+	other, _ := processor.ProcessPayment(payment.MustParseMoney("25.00", "USD"))
+	if other == first {
+		t.Error("Expected a new charge without a key to get a new transaction ID")
+	}
+
+	if err := processor.Refund(first, payment.MustParseMoney("25.00", "USD")); err != nil {
+		t.Fatalf("Expected refund to succeed, got: %v", err)
+	}
+	if err := processor.Refund(first, payment.MustParseMoney("25.00", "USD")); !errors.Is(err, payment.ErrAlreadyRefunded) {
+		t.Errorf("Expected double refund to be rejected, got: %v", err)
+	}
+	if err := processor.Refund(other, payment.MustParseMoney("30.00", "USD")); !errors.Is(err, payment.ErrOverRefund) {
+		t.Errorf("Expected over-refund to be rejected, got: %v", err)
+	}
+
+	if got := len(audit.Entries()); got != 6 {
+		t.Errorf("Expected 6 audit entries, got %d", got)
+	}
+}
@@ -0,0 +1,165 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+var (
+	// ErrDuplicateMethod is returned when a payment method is registered twice.
+	ErrDuplicateMethod = errors.New("payment method already registered")
+	// ErrUnsupportedMethod is returned for payment methods with no registered provider.
+	ErrUnsupportedMethod = errors.New("unsupported payment method")
+)
+
+// ProcessorConstructor builds a PaymentProcessor from its configuration.
+// The registry validates the config against the provider's schema first
+// and calls ValidateAccount on the result.
+type ProcessorConstructor func(config PaymentConfig) (PaymentProcessor, error)
+
+// ConfigSchema describes the configuration a provider accepts.
+//
+// Names refer to PaymentConfig fields (e.g. "CardNumber") or, for settings
+// PaymentConfig has no field for, keys in PaymentConfig.Options.
+type ConfigSchema struct {
+	// Required fields or options must be non-empty.
+	Required []string
+	// Optional lists the other Options keys the provider understands.
+	// Options keys that are neither required nor optional are rejected.
+	Optional []string
+}
+
+// Validate checks config against the schema.
+func (s ConfigSchema) Validate(config PaymentConfig) error {
+	for _, name := range s.Required {
+		if configValue(config, name) == "" {
+			return fmt.Errorf("missing required config field %s", name)
+		}
+	}
+	for key := range config.Options {
+		if !slices.Contains(s.Required, key) && !slices.Contains(s.Optional, key) {
+			return fmt.Errorf("unknown config option %q", key)
+		}
+	}
+	return nil
+}
+
+// configValue returns the named PaymentConfig string field, or the Options
+// entry of that name when there is no such field.
+func configValue(config PaymentConfig, name string) string {
+	field := reflect.ValueOf(config).FieldByName(name)
+	if field.IsValid() && field.Kind() == reflect.String {
+		return field.String()
+	}
+	return config.Options[name]
+}
+
+// RegisterOption configures a provider registration.
+type RegisterOption func(*provider)
+
+// WithConfigSchema validates configs against schema before construction.
+func WithConfigSchema(schema ConfigSchema) RegisterOption {
+	return func(p *provider) {
+		p.schema = schema
+	}
+}
+
+type provider struct {
+	constructor ProcessorConstructor
+	schema      ConfigSchema
+}
+
+// Registry maps payment methods to the providers that construct them.
+// It is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[PaymentMethod]provider
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[PaymentMethod]provider)}
+}
+
+// Register adds a provider for method. It returns ErrDuplicateMethod if the
+// method is already registered.
+func (r *Registry) Register(method PaymentMethod, constructor ProcessorConstructor, opts ...RegisterOption) error {
+	if method == "" {
+		return errors.New("payment method cannot be empty")
+	}
+	if constructor == nil {
+		return fmt.Errorf("nil constructor for payment method %s", method)
+	}
+
+	p := provider{constructor: constructor}
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.providers[method]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateMethod, method)
+	}
+	r.providers[method] = p
+	return nil
+}
+
+// Methods returns the registered payment methods in sorted order.
+func (r *Registry) Methods() []PaymentMethod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	methods := make([]PaymentMethod, 0, len(r.providers))
+	for method := range r.providers {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// New validates config against the provider's schema, constructs the
+// processor and validates its account.
+func (r *Registry) New(config PaymentConfig) (PaymentProcessor, error) {
+	r.mu.RLock()
+	p, ok := r.providers[config.Method]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMethod, config.Method)
+	}
+
+	if err := p.schema.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid config for %s: %w", config.Method, err)
+	}
+
+	processor, err := p.constructor(config)
+	if err != nil {
+		return nil, fmt.Errorf("create %s processor: %w", config.Method, err)
+	}
+
+	// Validate the created processor before returning
+	if err := processor.ValidateAccount(); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", processor.GetProcessorName(), err)
+	}
+	return processor, nil
+}
+
+// defaultRegistry backs NewPaymentProcessor and the package-level Register.
+var defaultRegistry = NewRegistry()
+
+// Register adds a provider to the default registry, typically from an init
+// function. Like database/sql.Register it panics if the method is already
+// registered, so conflicting providers fail at startup.
+func Register(method PaymentMethod, constructor ProcessorConstructor, opts ...RegisterOption) {
+	if err := defaultRegistry.Register(method, constructor, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// RegisteredMethods lists the payment methods in the default registry.
+func RegisteredMethods() []PaymentMethod {
+	return defaultRegistry.Methods()
+}
@@ -0,0 +1,129 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
+
+// giftCardProcessor is a provider defined outside the built-in set.
+type giftCardProcessor struct {
+	code string
+}
+
+func (g *giftCardProcessor) ProcessPayment(amount payment.Money) (string, error) {
+	return fmt.Sprintf("GC-%s-%s", g.code, amount.Decimal()), nil
+}
+
+func (g *giftCardProcessor) ValidateAccount() error {
+	if len(g.code) != 8 {
+		return errors.New("gift card code must be 8 characters")
+	}
+	return nil
+}
+
+func (g *giftCardProcessor) GetProcessorName() string {
+	return "Gift Card Processor"
+}
+
+func newGiftCardProcessor(config PaymentConfig) (PaymentProcessor, error) {
+	return &giftCardProcessor{code: config.Options["code"]}, nil
+}
+
+// TestRegistry_RegisterAndCreate tests registering and constructing a custom provider.
+func TestRegistry_RegisterAndCreate(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("giftcard", newGiftCardProcessor,
+		WithConfigSchema(ConfigSchema{Required: []string{"code"}, Optional: []string{"pin"}}))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	processor, err := registry.New(PaymentConfig{Method: "giftcard", Options: map[string]string{"code": "ABCD1234"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if processor.GetProcessorName() != "Gift Card Processor" {
+		t.Errorf("Unexpected processor: %s", processor.GetProcessorName())
+	}
+
+	tests := []struct {
+		name        string
+		options     map[string]string
+		expectedErr string
+	}{
+		{"missing required option", nil, "missing required config field code"},
+		{"unknown option", map[string]string{"code": "ABCD1234", "colour": "red"}, `unknown config option "colour"`},
+		{"account validation", map[string]string{"code": "short"}, "gift card code must be 8 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.New(PaymentConfig{Method: "giftcard", Options: tt.options})
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestRegistry_DuplicateRegistration tests that a method can only be registered once.
+func TestRegistry_DuplicateRegistration(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register("giftcard", newGiftCardProcessor); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := registry.Register("giftcard", newGiftCardProcessor); !errors.Is(err, ErrDuplicateMethod) {
+		t.Errorf("Expected ErrDuplicateMethod, got: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected package-level Register to panic on a built-in method")
+		}
+	}()
+	Register(CreditCard, newGiftCardProcessor)
+}
+
+// TestRegistry_Methods tests listing registered methods.
+func TestRegistry_Methods(t *testing.T) {
+	want := []PaymentMethod{Bitcoin, CreditCard, PayPal}
+	if got := RegisteredMethods(); !slices.Equal(got, want) {
+		t.Errorf("Expected built-in methods %v, got %v", want, got)
+	}
+
+	registry := NewRegistry()
+	if len(registry.Methods()) != 0 {
+		t.Error("Expected new registry to be empty")
+	}
+	if _, err := registry.New(PaymentConfig{Method: CreditCard}); !errors.Is(err, ErrUnsupportedMethod) {
+		t.Errorf("Expected ErrUnsupportedMethod, got: %v", err)
+	}
+}
+
+// TestRegistry_ConcurrentUse tests concurrent registration and lookup.
+func TestRegistry_ConcurrentUse(t *testing.T) {
+	registry := NewRegistry()
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			registry.Register(PaymentMethod(fmt.Sprintf("method-%d", i)), newGiftCardProcessor)
+		}()
+		go func() {
+			defer wg.Done()
+			registry.Methods()
+			registry.New(PaymentConfig{Method: "method-0", Options: map[string]string{"code": "ABCD1234"}})
+		}()
+	}
+	wg.Wait()
+
+	if got := len(registry.Methods()); got != 20 {
+		t.Errorf("Expected 20 registered methods, got %d", got)
+	}
+}
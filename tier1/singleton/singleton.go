@@ -14,6 +14,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/cache"
 )
 
 // ============================================================================
@@ -209,10 +211,13 @@ func ResetForTesting() {
 // Approach 4: Thread-Safe Cache Singleton
 // ============================================================================
 
+// cacheMaxEntries bounds the singleton cache; the least recently used
+// entries are evicted beyond it.
+const cacheMaxEntries = 10000
+
 // Cache represents a thread-safe in-memory cache singleton
 type Cache struct {
-	data map[string]interface{}
-	mu   sync.RWMutex
+	data *cache.Cache[string, interface{}]
 }
 
 var (
@@ -225,7 +230,7 @@ func GetCache() *Cache {
 	cacheOnce.Do(func() {
 		fmt.Println("Initializing cache...")
 		cacheInstance = &Cache{
-			data: make(map[string]interface{}),
+			data: cache.New[string, interface{}](cache.WithMaxEntries(cacheMaxEntries)),
 		}
 	})
 	return cacheInstance
@@ -233,36 +238,30 @@ func GetCache() *Cache {
 
 // Set stores a value in the cache
 func (c *Cache) Set(key string, value interface{}) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.data[key] = value
+	c.data.Set(key, value)
 }
 
 // Get retrieves a value from the cache
 func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	value, exists := c.data[key]
-	return value, exists
+	return c.data.Get(key)
 }
 
 // Delete removes a value from the cache
 func (c *Cache) Delete(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	delete(c.data, key)
+	c.data.Delete(key)
 }
 
 // Size returns the number of items in the cache
 func (c *Cache) Size() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return len(c.data)
+	return c.data.Len()
 }
 
 // Clear removes all items from the cache
 func (c *Cache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.data = make(map[string]interface{})
+	c.data.Clear()
+}
+
+// Stats returns the cache's hit, miss and eviction counts
+func (c *Cache) Stats() cache.Stats {
+	return c.data.Stats()
 }
\ No newline at end of file
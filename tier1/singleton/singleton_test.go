@@ -1,6 +1,7 @@
 package singleton
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -272,6 +273,27 @@ func TestCacheOperations(t *testing.T) {
 	}
 }
 
+// TestCacheBounded tests that the cache evicts the least recently used
+// entries beyond cacheMaxEntries
+func TestCacheBounded(t *testing.T) {
+	cache := GetCache()
+	cache.Clear()
+
+	for i := 0; i <= cacheMaxEntries; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	if cache.Size() != cacheMaxEntries {
+		t.Errorf("Expected cache size %d, got %d", cacheMaxEntries, cache.Size())
+	}
+	if _, exists := cache.Get("key0"); exists {
+		t.Error("Expected the oldest key to be evicted")
+	}
+	if _, exists := cache.Get(fmt.Sprintf("key%d", cacheMaxEntries)); !exists {
+		t.Error("Expected the newest key to be kept")
+	}
+	cache.Clear()
+}
+
 // TestCacheConcurrentAccess tests thread-safe cache operations
 func TestCacheConcurrentAccess(t *testing.T) {
 	cache := GetCache()
@@ -1,6 +1,10 @@
 package strategy
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
 
 // Example1_BasicPaymentProcessing demonstrates the fundamental use of the Strategy pattern.
 // This shows how to select different payment methods at runtime without conditional logic.
@@ -28,7 +32,7 @@ func Example1_BasicPaymentProcessing() {
 
 	// Process a payment
 	fmt.Printf("Using: %s\n", processor.GetCurrentStrategyName())
-	txID, err := processor.Process(99.99)
+	txID, err := processor.Process(payment.MustParseMoney("99.99", "USD"))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
@@ -36,7 +40,7 @@ func Example1_BasicPaymentProcessing() {
 	fmt.Printf("Transaction ID: %s\n", txID)
 
 	// Refund the payment
-	err = processor.Refund(txID, 99.99)
+	err = processor.Refund(txID, payment.MustParseMoney("99.99", "USD"))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	}
@@ -57,7 +61,7 @@ func Example2_SwitchingStrategiesAtRuntime() {
 	processor.SetDetails(PaymentDetails{CardNumber: "4532123456789010"})
 
 	fmt.Printf("Using: %s\n", processor.GetCurrentStrategyName())
-	txID, err := processor.Process(50.00)
+	txID, err := processor.Process(payment.MustParseMoney("50.00", "USD"))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
@@ -71,7 +75,7 @@ func Example2_SwitchingStrategiesAtRuntime() {
 	processor.SetDetails(PaymentDetails{Email: "bob@example.com"})
 
 	fmt.Printf("Using: %s\n", processor.GetCurrentStrategyName())
-	txID, err = processor.Process(75.50)
+	txID, err = processor.Process(payment.MustParseMoney("75.50", "USD"))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
@@ -85,7 +89,7 @@ func Example2_SwitchingStrategiesAtRuntime() {
 	processor.SetDetails(PaymentDetails{WalletAddr: "1A1z7agoat2GPFH7q05VtEWEB97YMQP5Z9"})
 
 	fmt.Printf("Using: %s\n", processor.GetCurrentStrategyName())
-	txID, err = processor.Process(0.025)
+	txID, err = processor.Process(payment.MustParseMoney("0.025", "BTC"))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
@@ -107,7 +111,7 @@ func Example3_ErrorHandlingPerStrategy() {
 	processor.SetStrategy(invalidCC)
 	processor.SetDetails(PaymentDetails{})
 
-	_, err := processor.Process(100.00)
+	_, err := processor.Process(payment.MustParseMoney("100.00", "USD"))
 	if err != nil {
 		fmt.Printf("Caught error: %v\n", err)
 	}
@@ -118,7 +122,7 @@ func Example3_ErrorHandlingPerStrategy() {
 	processor.SetStrategy(invalidPayPal)
 	processor.SetDetails(PaymentDetails{})
 
-	_, err = processor.Process(100.00)
+	_, err = processor.Process(payment.MustParseMoney("100.00", "USD"))
 	if err != nil {
 		fmt.Printf("Caught error: %v\n", err)
 	}
@@ -129,7 +133,7 @@ func Example3_ErrorHandlingPerStrategy() {
 	processor.SetStrategy(invalidCrypto)
 	processor.SetDetails(PaymentDetails{})
 
-	_, err = processor.Process(100.00)
+	_, err = processor.Process(payment.MustParseMoney("100.00", "USD"))
 	if err != nil {
 		fmt.Printf("Caught error: %v\n", err)
 	}
@@ -146,25 +150,25 @@ func Example4_MultiplePaymentsWithDifferentMethods() {
 		name     string
 		strategy PaymentStrategy
 		details  PaymentDetails
-		amount   float64
+		amount   payment.Money
 	}{
 		{
 			name:     "Alice (Credit Card)",
 			strategy: NewCreditCardStrategy("4532123456789010", "12/25", "123", "Alice"),
 			details:  PaymentDetails{CardNumber: "4532123456789010"},
-			amount:   99.99,
+			amount:   payment.MustParseMoney("99.99", "USD"),
 		},
 		{
 			name:     "Bob (PayPal)",
 			strategy: NewPayPalStrategy("bob@example.com"),
 			details:  PaymentDetails{Email: "bob@example.com"},
-			amount:   149.99,
+			amount:   payment.MustParseMoney("149.99", "USD"),
 		},
 		{
 			name:     "Charlie (Bitcoin)",
 			strategy: NewCryptoStrategy("1A1z7agoat2GPFH7q05VtEWEB97YMQP5Z9", "BTC"),
 			details:  PaymentDetails{WalletAddr: "1A1z7agoat2GPFH7q05VtEWEB97YMQP5Z9"},
-			amount:   0.05,
+			amount:   payment.MustParseMoney("0.05", "BTC"),
 		},
 	}
 
@@ -179,7 +183,7 @@ func Example4_MultiplePaymentsWithDifferentMethods() {
 
 		// Process payment
 		fmt.Printf("Payment Method: %s\n", processor.GetCurrentStrategyName())
-		fmt.Printf("Amount: %.2f\n", customer.amount)
+		fmt.Printf("Amount: %s\n", customer.amount.Format())
 
 		txID, err := processor.Process(customer.amount)
 		if err != nil {
@@ -230,7 +234,7 @@ func Example5_DynamicStrategySelection() {
 		processor.SetDetails(PaymentDetails{})
 
 		fmt.Printf("Using: %s\n", processor.GetCurrentStrategyName())
-		txID, err := processor.Process(100.00)
+		txID, err := processor.Process(payment.MustParseMoney("100.00", "USD"))
 		if err != nil {
 			fmt.Printf("Error: %v\n\n", err)
 			continue
@@ -262,10 +266,10 @@ func processPaymentOld(method string, amount float64) {
 	fmt.Println("--- WITH Strategy Pattern (Good) ---")
 	fmt.Println(`
 type PaymentStrategy interface {
-    Process(amount float64) (txID string, err error)
+    Process(amount payment.Money) (txID string, err error)
 }
 
-func processPaymentNew(strategy PaymentStrategy, amount float64) {
+func processPaymentNew(strategy PaymentStrategy, amount payment.Money) {
     txID, err := strategy.Process(amount)
     // Clean, testable, extensible - add new methods without changing this code
 }`)
@@ -285,7 +289,7 @@ func processPaymentNew(strategy PaymentStrategy, amount float64) {
 	for _, strategy := range strategies {
 		processor.SetStrategy(strategy)
 		fmt.Printf("\nUsing %s\n", processor.GetCurrentStrategyName())
-		txID, _ := processor.Process(50.00)
+		txID, _ := processor.Process(payment.MustParseMoney("50.00", "USD"))
 		fmt.Printf("Result: %s\n", txID)
 	}
 
@@ -1,7 +1,10 @@
 package strategy
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
 // TestCreditCardStrategyValidation tests credit card validation logic
@@ -80,32 +83,32 @@ func TestCreditCardStrategyProcess(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		amount  float64
+		amount  payment.Money
 		wantErr bool
 	}{
 		{
 			name:    "Valid amount",
-			amount:  99.99,
+			amount:  payment.MustParseMoney("99.99", "USD"),
 			wantErr: false,
 		},
 		{
 			name:    "Zero amount",
-			amount:  0.0,
+			amount:  payment.MustParseMoney("0.0", "USD"),
 			wantErr: true,
 		},
 		{
 			name:    "Negative amount",
-			amount:  -50.00,
+			amount:  payment.MustParseMoney("-50.00", "USD"),
 			wantErr: true,
 		},
 		{
 			name:    "Large amount",
-			amount:  999999.99,
+			amount:  payment.MustParseMoney("999999.99", "USD"),
 			wantErr: false,
 		},
 		{
 			name:    "Small positive amount",
-			amount:  0.01,
+			amount:  payment.MustParseMoney("0.01", "USD"),
 			wantErr: false,
 		},
 	}
@@ -177,22 +180,22 @@ func TestPayPalStrategyProcess(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		amount  float64
+		amount  payment.Money
 		wantErr bool
 	}{
 		{
 			name:    "Valid amount",
-			amount:  75.50,
+			amount:  payment.MustParseMoney("75.50", "USD"),
 			wantErr: false,
 		},
 		{
 			name:    "Zero amount",
-			amount:  0.0,
+			amount:  payment.MustParseMoney("0.0", "USD"),
 			wantErr: true,
 		},
 		{
 			name:    "Negative amount",
-			amount:  -25.00,
+			amount:  payment.MustParseMoney("-25.00", "USD"),
 			wantErr: true,
 		},
 	}
@@ -261,22 +264,22 @@ func TestCryptoStrategyProcess(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		amount  float64
+		amount  payment.Money
 		wantErr bool
 	}{
 		{
 			name:    "Valid amount",
-			amount:  0.05,
+			amount:  payment.MustParseMoney("0.05", "BTC"),
 			wantErr: false,
 		},
 		{
 			name:    "Zero amount",
-			amount:  0.0,
+			amount:  payment.MustParseMoney("0.0", "BTC"),
 			wantErr: true,
 		},
 		{
 			name:    "Negative amount",
-			amount:  -0.01,
+			amount:  payment.MustParseMoney("-0.01", "BTC"),
 			wantErr: true,
 		},
 	}
@@ -329,7 +332,7 @@ func TestPaymentProcessorValidation(t *testing.T) {
 	processor.SetDetails(PaymentDetails{})
 
 	// Test with no strategy set
-	_, err := processor.Process(100.0)
+	_, err := processor.Process(payment.MustParseMoney("100.0", "USD"))
 	if err == nil {
 		t.Error("Process() should error when no strategy is set")
 	}
@@ -338,7 +341,7 @@ func TestPaymentProcessorValidation(t *testing.T) {
 	invalidCC := NewCreditCardStrategy("123", "12/25", "123", "John")
 	processor.SetStrategy(invalidCC)
 
-	_, err = processor.Process(100.0)
+	_, err = processor.Process(payment.MustParseMoney("100.0", "USD"))
 	if err == nil {
 		t.Error("Process() should error with invalid card")
 	}
@@ -352,7 +355,7 @@ func TestPaymentProcessorProcessing(t *testing.T) {
 	processor := NewPaymentProcessor(NewCreditCardStrategy("4532123456789010", "12/25", "123", "John"))
 	processor.SetDetails(PaymentDetails{CardNumber: "4532123456789010"})
 
-	txID, err := processor.Process(99.99)
+	txID, err := processor.Process(payment.MustParseMoney("99.99", "USD"))
 	if err != nil {
 		t.Errorf("Process() unexpected error: %v", err)
 	}
@@ -368,14 +371,14 @@ func TestPaymentProcessorProcessing(t *testing.T) {
 func TestPaymentProcessorRefund(t *testing.T) {
 	processor := NewPaymentProcessor(NewCreditCardStrategy("4532123456789010", "12/25", "123", "John"))
 
-	err := processor.Refund("CC-12345-99.99", 99.99)
+	err := processor.Refund("CC-12345-99.99", payment.MustParseMoney("99.99", "USD"))
 	if err != nil {
 		t.Errorf("Refund() unexpected error: %v", err)
 	}
 
 	// Test with no strategy
 	processor2 := NewPaymentProcessor(nil)
-	err = processor2.Refund("TX-12345", 50.00)
+	err = processor2.Refund("TX-12345", payment.MustParseMoney("50.00", "USD"))
 	if err == nil {
 		t.Error("Refund() should error when no strategy is set")
 	}
@@ -390,7 +393,7 @@ func TestMultipleStrategiesWithSameProcessor(t *testing.T) {
 		name     string
 		strategy PaymentStrategy
 		setup    func(PaymentDetails) PaymentDetails
-		amount   float64
+		amount   payment.Money
 	}{
 		{
 			name:     "Credit Card",
@@ -399,7 +402,7 @@ func TestMultipleStrategiesWithSameProcessor(t *testing.T) {
 				p.CardNumber = "4532123456789010"
 				return p
 			},
-			amount: 50.0,
+			amount: payment.MustParseMoney("50.0", "USD"),
 		},
 		{
 			name:     "PayPal",
@@ -408,7 +411,7 @@ func TestMultipleStrategiesWithSameProcessor(t *testing.T) {
 				p.Email = "john@example.com"
 				return p
 			},
-			amount: 75.0,
+			amount: payment.MustParseMoney("75.0", "USD"),
 		},
 		{
 			name:     "Crypto",
@@ -417,7 +420,7 @@ func TestMultipleStrategiesWithSameProcessor(t *testing.T) {
 				p.WalletAddr = "1A1z7agoat2GPFH7q05VtEWEB97YMQP5Z9"
 				return p
 			},
-			amount: 0.05,
+			amount: payment.MustParseMoney("0.05", "BTC"),
 		},
 	}
 
@@ -482,7 +485,7 @@ func BenchmarkCreditCardProcess(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		card.Process(99.99)
+		card.Process(payment.MustParseMoney("99.99", "USD"))
 	}
 }
 
@@ -492,7 +495,7 @@ func BenchmarkPayPalProcess(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		pp.Process(99.99)
+		pp.Process(payment.MustParseMoney("99.99", "USD"))
 	}
 }
 
@@ -502,7 +505,7 @@ func BenchmarkCryptoProcess(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		crypto.Process(0.05)
+		crypto.Process(payment.MustParseMoney("0.05", "BTC"))
 	}
 }
 
@@ -513,7 +516,7 @@ func BenchmarkPaymentProcessorProcess(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		processor.Process(99.99)
+		processor.Process(payment.MustParseMoney("99.99", "USD"))
 	}
 }
 
@@ -537,3 +540,35 @@ func BenchmarkStrategySwitch(b *testing.B) {
 func contains(s, substr string) bool {
 	return len(s) > 0 && len(substr) > 0 && s != "" && substr != ""
 }
+
+// TestPaymentProcessorLedger tests idempotency keys and refund validation
+func TestPaymentProcessorLedger(t *testing.T) {
+	processor := NewPaymentProcessor(NewPayPalStrategy("user@example.com"))
+	ledger := payment.NewLedger()
+	processor.SetLedger(ledger)
+
+	txID, err := processor.ProcessWithKey("cart-7", payment.MustParseMoney("40.00", "USD"))
+	if err != nil {
+		t.Fatalf("ProcessWithKey() unexpected error: %v", err)
+	}
+	retry, err := processor.ProcessWithKey("cart-7", payment.MustParseMoney("40.00", "USD"))
+	if err != nil || retry != txID {
+		t.Errorf("ProcessWithKey() retry = %s, %v; want %s", retry, err, txID)
+	}
+
+	if err := processor.RefundWithKey("refund-7", txID, payment.MustParseMoney("15.00", "USD")); err != nil {
+		t.Fatalf("RefundWithKey() unexpected error: %v", err)
+	}
+	if err := processor.RefundWithKey("refund-7", txID, payment.MustParseMoney("15.00", "USD")); err != nil {
+		t.Errorf("RefundWithKey() retry should be a no-op, got: %v", err)
+	}
+	if remaining, _ := ledger.Refundable(txID); !remaining.Equal(payment.MustParseMoney("25.00", "USD")) {
+		t.Errorf("Refundable() = %s, want 25.00 USD", remaining)
+	}
+	if err := processor.Refund(txID, payment.MustParseMoney("30.00", "USD")); !errors.Is(err, payment.ErrOverRefund) {
+		t.Errorf("Refund() over-refund error = %v", err)
+	}
+	if err := processor.Refund("PP-unknown", payment.MustParseMoney("1.00", "USD")); !errors.Is(err, payment.ErrUnknownTransaction) {
+		t.Errorf("Refund() unknown transaction error = %v", err)
+	}
+}
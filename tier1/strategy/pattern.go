@@ -16,6 +16,9 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync/atomic"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
 // PaymentStrategy defines the interface for different payment methods.
@@ -27,10 +30,10 @@ type PaymentStrategy interface {
 
 	// Process executes the payment using this strategy.
 	// Returns a transaction ID on success or an error on failure.
-	Process(amount float64) (transactionID string, err error)
+	Process(amount payment.Money) (transactionID string, err error)
 
 	// Refund reverses a previous payment.
-	Refund(transactionID string, amount float64) error
+	Refund(transactionID string, amount payment.Money) error
 
 	// GetName returns a human-readable name for this strategy.
 	GetName() string
@@ -100,21 +103,21 @@ func (c *CreditCardStrategy) Validate(details PaymentDetails) error {
 }
 
 // Process executes a credit card payment.
-func (c *CreditCardStrategy) Process(amount float64) (string, error) {
-	if amount <= 0 {
+func (c *CreditCardStrategy) Process(amount payment.Money) (string, error) {
+	if !amount.IsPositive() {
 		return "", fmt.Errorf("amount must be positive")
 	}
 
 	// In a real system, this would call a payment gateway API
 	// For demonstration, we simulate success
-	transactionID := fmt.Sprintf("CC-%d-%.2f", hashCard(c.cardNumber), amount)
+	transactionID := fmt.Sprintf("CC-%d-%s-%d", hashCard(c.cardNumber), amount.Decimal(), transactionSeq.Add(1))
 	fmt.Printf("Processing credit card payment for %s\n", c.cardHolder)
 	return transactionID, nil
 }
 
 // Refund reverses a credit card payment.
-func (c *CreditCardStrategy) Refund(transactionID string, amount float64) error {
-	fmt.Printf("Refunding %.2f to credit card ending in %s\n", amount, c.cardNumber[len(c.cardNumber)-4:])
+func (c *CreditCardStrategy) Refund(transactionID string, amount payment.Money) error {
+	fmt.Printf("Refunding %s to credit card ending in %s\n", amount, c.cardNumber[len(c.cardNumber)-4:])
 	return nil
 }
 
@@ -148,21 +151,21 @@ func (p *PayPalStrategy) Validate(details PaymentDetails) error {
 
 // Process executes a PayPal payment.
 // In a real system, this would redirect to PayPal for authentication.
-func (p *PayPalStrategy) Process(amount float64) (string, error) {
-	if amount <= 0 {
+func (p *PayPalStrategy) Process(amount payment.Money) (string, error) {
+	if !amount.IsPositive() {
 		return "", fmt.Errorf("amount must be positive")
 	}
 
 	// Simulate PayPal OAuth flow and payment
 	fmt.Printf("Redirecting to PayPal for user %s\n", p.email)
-	transactionID := fmt.Sprintf("PP-%d-%.2f", hashEmail(p.email), amount)
+	transactionID := fmt.Sprintf("PP-%d-%s-%d", hashEmail(p.email), amount.Decimal(), transactionSeq.Add(1))
 	fmt.Printf("PayPal payment confirmed via OAuth\n")
 	return transactionID, nil
 }
 
 // Refund reverses a PayPal payment.
-func (p *PayPalStrategy) Refund(transactionID string, amount float64) error {
-	fmt.Printf("Issuing refund of %.2f to PayPal account %s\n", amount, p.email)
+func (p *PayPalStrategy) Refund(transactionID string, amount payment.Money) error {
+	fmt.Printf("Issuing refund of %s to PayPal account %s\n", amount, p.email)
 	return nil
 }
 
@@ -205,21 +208,21 @@ func (c *CryptoStrategy) Validate(details PaymentDetails) error {
 
 // Process executes a cryptocurrency payment.
 // This would involve blockchain monitoring in a real system.
-func (c *CryptoStrategy) Process(amount float64) (string, error) {
-	if amount <= 0 {
+func (c *CryptoStrategy) Process(amount payment.Money) (string, error) {
+	if !amount.IsPositive() {
 		return "", fmt.Errorf("amount must be positive")
 	}
 
 	fmt.Printf("Generating blockchain transaction for %s wallet\n", c.cryptoType)
 	fmt.Printf("Monitoring blockchain for confirmation...\n")
-	transactionID := fmt.Sprintf("CRYPTO-%s-%d", c.cryptoType, hashAddress(c.walletAddress))
+	transactionID := fmt.Sprintf("CRYPTO-%s-%d-%d", c.cryptoType, hashAddress(c.walletAddress), transactionSeq.Add(1))
 	fmt.Printf("Blockchain transaction confirmed\n")
 	return transactionID, nil
 }
 
 // Refund reverses a cryptocurrency payment.
 // Note: In reality, crypto transactions are typically irreversible.
-func (c *CryptoStrategy) Refund(transactionID string, amount float64) error {
+func (c *CryptoStrategy) Refund(transactionID string, amount payment.Money) error {
 	fmt.Printf("Initiating reverse transaction to %s wallet\n", c.walletAddress)
 	return nil
 }
@@ -239,6 +242,7 @@ func (c *CryptoStrategy) GetName() string {
 type PaymentProcessor struct {
 	strategy PaymentStrategy
 	details  PaymentDetails
+	ledger   *payment.Ledger
 }
 
 // NewPaymentProcessor creates a new payment processor with the given strategy.
@@ -259,13 +263,36 @@ func (p *PaymentProcessor) SetDetails(details PaymentDetails) {
 	p.details = details
 }
 
+// SetLedger routes payments and refunds through ledger, which deduplicates
+// idempotency keys, validates refunds against the original charge and keeps
+// an audit log. A nil ledger turns this off.
+func (p *PaymentProcessor) SetLedger(ledger *payment.Ledger) {
+	p.ledger = ledger
+}
+
 // Process executes the payment using the current strategy.
 // It handles the common workflow: validation, processing, and error handling.
-func (p *PaymentProcessor) Process(amount float64) (string, error) {
+func (p *PaymentProcessor) Process(amount payment.Money) (string, error) {
+	return p.ProcessWithKey("", amount)
+}
+
+// ProcessWithKey executes the payment at most once per idempotency key:
+// with a ledger set, retrying a key that already succeeded returns the
+// original transaction ID instead of charging again. An empty key, or no
+// ledger, processes the payment every time.
+func (p *PaymentProcessor) ProcessWithKey(key string, amount payment.Money) (string, error) {
 	if p.strategy == nil {
 		return "", fmt.Errorf("no payment strategy set")
 	}
+	if p.ledger == nil {
+		return p.process(amount)
+	}
+	return p.ledger.Charge(key, p.strategy.GetName(), amount, func() (string, error) {
+		return p.process(amount)
+	})
+}
 
+func (p *PaymentProcessor) process(amount payment.Money) (string, error) {
 	// Step 1: Validate the payment method
 	if err := p.strategy.Validate(p.details); err != nil {
 		return "", fmt.Errorf("validation failed: %w", err)
@@ -282,12 +309,23 @@ func (p *PaymentProcessor) Process(amount float64) (string, error) {
 }
 
 // Refund refunds a previous payment using the current strategy.
-func (p *PaymentProcessor) Refund(transactionID string, amount float64) error {
+func (p *PaymentProcessor) Refund(transactionID string, amount payment.Money) error {
+	return p.RefundWithKey("", transactionID, amount)
+}
+
+// RefundWithKey refunds a previous payment. With a ledger set the refund is
+// rejected if the transaction is unknown or the amount exceeds what is left
+// to refund, and retrying a key that already succeeded is a no-op.
+func (p *PaymentProcessor) RefundWithKey(key, transactionID string, amount payment.Money) error {
 	if p.strategy == nil {
 		return fmt.Errorf("no payment strategy set")
 	}
-
-	return p.strategy.Refund(transactionID, amount)
+	if p.ledger == nil {
+		return p.strategy.Refund(transactionID, amount)
+	}
+	return p.ledger.Refund(key, transactionID, amount, func() error {
+		return p.strategy.Refund(transactionID, amount)
+	})
 }
 
 // GetCurrentStrategyName returns the name of the current payment strategy.
@@ -302,6 +340,10 @@ func (p *PaymentProcessor) GetCurrentStrategyName() string {
 // Helper Functions
 // ============================================================================
 
+// transactionSeq keeps simulated transaction IDs distinct between otherwise
+// identical payments.
+var transactionSeq atomic.Int64
+
 // hashCard generates a simple hash of the card number for transaction IDs.
 func hashCard(cardNumber string) int {
 	hash := 0
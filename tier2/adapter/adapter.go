@@ -11,7 +11,13 @@ package adapter
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync/atomic"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
 // =============================================================================
@@ -19,10 +25,10 @@ import (
 // =============================================================================
 
 // Payment represents a standardized payment request across all gateways.
+// The currency travels with Amount.
 type Payment struct {
-	CustomerID string
-	Amount     float64
-	Currency   string
+	CustomerID  string
+	Amount      payment.Money
 	Description string
 }
 
@@ -31,26 +37,72 @@ type Receipt struct {
 	TransactionID string
 	Status        string
 	ProcessedAt   time.Time
-	Amount        float64
+	// Amount is what the gateway charged, in the gateway's currency.
+	Amount payment.Money
+	// Provider names the gateway that took the charge when a router chose
+	// among several.
+	Provider string
+
+	// Set by CurrencyRouter. RequestedAmount is the amount the customer was
+	// quoted, ExchangeRate the rate applied to reach Amount (nil when no
+	// conversion was needed) and Fee the estimated gateway fee in the
+	// requested currency.
+	RequestedAmount payment.Money
+	ExchangeRate    *payment.ExchangeRate
+	Fee             payment.Money
+}
+
+// gatewayError wraps err with ErrGatewayUnavailable when the gateway
+// cannot have taken the request: the connection was never made, or the
+// gateway answered with a rate limit or a server error. Anything else,
+// including a timeout after the request was sent, is left as is, because
+// the charge may have gone through.
+func gatewayError(err error, statusCode int) error {
+	var opErr *net.OpError
+	if statusCode >= 500 || statusCode == http.StatusTooManyRequests ||
+		errors.As(err, &opErr) && opErr.Op == "dial" && !opErr.Timeout() {
+		return fmt.Errorf("%w: %w", ErrGatewayUnavailable, err)
+	}
+	return err
 }
 
 // PaymentProcessor is the target interface that our application expects.
 // This is the stable internal interface that insulates us from external changes.
 type PaymentProcessor interface {
 	Process(payment Payment) (*Receipt, error)
-	Refund(transactionID string, amount float64) error
+	Refund(transactionID string, amount payment.Money) error
 }
 
 // --- Stripe External Library (Adaptee) ---
 
+// gatewaySeq keeps simulated gateway IDs unique within the same second, so
+// code that tracks charges by ID (such as CurrencyRouter) can tell them apart.
+var gatewaySeq atomic.Int64
+
 // StripePaymentGateway represents a third-party Stripe library with its own interface.
 // In reality, this would be an external package we don't control.
 type StripePaymentGateway struct {
 	APIKey string
+	// Fault, when set, is returned by every call in place of a response,
+	// to simulate network failures and *StripeError responses.
+	Fault error
+}
+
+// StripeError is an error response from the Stripe API.
+type StripeError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StripeError) Error() string {
+	return fmt.Sprintf("stripe: %d %s", e.StatusCode, e.Message)
 }
 
 // CreateCharge is Stripe's method for processing payments (different from our interface).
-func (s *StripePaymentGateway) CreateCharge(customerToken string, amountInCents int, currency string) (string, error) {
+func (s *StripePaymentGateway) CreateCharge(customerToken string, amountInCents int64, currency string) (string, error) {
+	if s.Fault != nil {
+		return "", s.Fault
+	}
 	if s.APIKey == "" {
 		return "", errors.New("stripe: API key required")
 	}
@@ -58,12 +110,15 @@ func (s *StripePaymentGateway) CreateCharge(customerToken string, amountInCents
 		return "", errors.New("stripe: amount must be positive")
 	}
 	// Simulate Stripe API call
-	chargeID := fmt.Sprintf("ch_stripe_%d", time.Now().Unix())
+	chargeID := fmt.Sprintf("ch_stripe_%d_%d", time.Now().Unix(), gatewaySeq.Add(1))
 	return chargeID, nil
 }
 
 // RefundCharge is Stripe's method for refunding (different signature).
-func (s *StripePaymentGateway) RefundCharge(chargeID string, amountInCents int) error {
+func (s *StripePaymentGateway) RefundCharge(chargeID string, amountInCents int64) error {
+	if s.Fault != nil {
+		return s.Fault
+	}
 	if chargeID == "" {
 		return errors.New("stripe: charge ID required")
 	}
@@ -78,20 +133,24 @@ type StripeAdapter struct {
 
 // NewStripeAdapter creates a new adapter for Stripe.
 func NewStripeAdapter(apiKey string) *StripeAdapter {
-	return &StripeAdapter{
-		gateway: &StripePaymentGateway{APIKey: apiKey},
-	}
+	return NewStripeAdapterFor(&StripePaymentGateway{APIKey: apiKey})
+}
+
+// NewStripeAdapterFor creates an adapter over an existing Stripe client.
+func NewStripeAdapterFor(gateway *StripePaymentGateway) *StripeAdapter {
+	return &StripeAdapter{gateway: gateway}
 }
 
 // Process adapts our Payment format to Stripe's CreateCharge format.
 func (a *StripeAdapter) Process(payment Payment) (*Receipt, error) {
-	// Convert dollars to cents (Stripe uses cents)
-	amountInCents := int(payment.Amount * 100)
+	// Stripe takes the amount in minor units (cents for USD), which is
+	// exactly what Money stores, so there is no float conversion to round.
+	amount := payment.Amount
 
 	// Call Stripe's API with adapted parameters
-	chargeID, err := a.gateway.CreateCharge(payment.CustomerID, amountInCents, payment.Currency)
+	chargeID, err := a.gateway.CreateCharge(payment.CustomerID, amount.Amount(), amount.Currency().Code)
 	if err != nil {
-		return nil, fmt.Errorf("stripe adapter: %w", err)
+		return nil, fmt.Errorf("stripe adapter: %w", stripeError(err))
 	}
 
 	// Convert Stripe's response to our Receipt format
@@ -104,9 +163,19 @@ func (a *StripeAdapter) Process(payment Payment) (*Receipt, error) {
 }
 
 // Refund adapts our refund interface to Stripe's format.
-func (a *StripeAdapter) Refund(transactionID string, amount float64) error {
-	amountInCents := int(amount * 100)
-	return a.gateway.RefundCharge(transactionID, amountInCents)
+func (a *StripeAdapter) Refund(transactionID string, amount payment.Money) error {
+	if err := a.gateway.RefundCharge(transactionID, amount.Amount()); err != nil {
+		return fmt.Errorf("stripe adapter: %w", stripeError(err))
+	}
+	return nil
+}
+
+func stripeError(err error) error {
+	var apiErr *StripeError
+	if errors.As(err, &apiErr) {
+		return gatewayError(err, apiErr.StatusCode)
+	}
+	return gatewayError(err, 0)
 }
 
 // --- PayPal External Library (Adaptee) ---
@@ -115,10 +184,26 @@ func (a *StripeAdapter) Refund(transactionID string, amount float64) error {
 type PayPalPaymentService struct {
 	ClientID     string
 	ClientSecret string
+	// Fault, when set, is returned by every call in place of a response,
+	// to simulate network failures and *PayPalError responses.
+	Fault error
+}
+
+// PayPalError is an error response from the PayPal API.
+type PayPalError struct {
+	StatusCode int
+	Name       string
+}
+
+func (e *PayPalError) Error() string {
+	return fmt.Sprintf("paypal: %d %s", e.StatusCode, e.Name)
 }
 
 // ExecutePayment is PayPal's method (completely different from Stripe and our interface).
 func (p *PayPalPaymentService) ExecutePayment(accountEmail string, paymentAmount float64, currencyCode string, memo string) (*PayPalTransaction, error) {
+	if p.Fault != nil {
+		return nil, p.Fault
+	}
 	if p.ClientID == "" || p.ClientSecret == "" {
 		return nil, errors.New("paypal: credentials required")
 	}
@@ -127,7 +212,7 @@ func (p *PayPalPaymentService) ExecutePayment(accountEmail string, paymentAmount
 	}
 	// Simulate PayPal API call
 	return &PayPalTransaction{
-		ID:        fmt.Sprintf("PAYPAL-%d", time.Now().Unix()),
+		ID:        fmt.Sprintf("PAYPAL-%d-%d", time.Now().Unix(), gatewaySeq.Add(1)),
 		Status:    "COMPLETED",
 		Timestamp: time.Now(),
 	}, nil
@@ -135,6 +220,9 @@ func (p *PayPalPaymentService) ExecutePayment(accountEmail string, paymentAmount
 
 // ReversePayment is PayPal's refund method.
 func (p *PayPalPaymentService) ReversePayment(txnID string, refundAmount float64) error {
+	if p.Fault != nil {
+		return p.Fault
+	}
 	if txnID == "" {
 		return errors.New("paypal: transaction ID required")
 	}
@@ -155,12 +243,15 @@ type PayPalAdapter struct {
 
 // NewPayPalAdapter creates a new adapter for PayPal.
 func NewPayPalAdapter(clientID, clientSecret string) *PayPalAdapter {
-	return &PayPalAdapter{
-		service: &PayPalPaymentService{
-			ClientID:     clientID,
-			ClientSecret: clientSecret,
-		},
-	}
+	return NewPayPalAdapterFor(&PayPalPaymentService{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+}
+
+// NewPayPalAdapterFor creates an adapter over an existing PayPal client.
+func NewPayPalAdapterFor(service *PayPalPaymentService) *PayPalAdapter {
+	return &PayPalAdapter{service: service}
 }
 
 // Process adapts our Payment format to PayPal's ExecutePayment format.
@@ -168,12 +259,12 @@ func (a *PayPalAdapter) Process(payment Payment) (*Receipt, error) {
 	// Call PayPal's API with adapted parameters
 	txn, err := a.service.ExecutePayment(
 		payment.CustomerID,
-		payment.Amount,
-		payment.Currency,
+		payment.Amount.Float64(),
+		payment.Amount.Currency().Code,
 		payment.Description,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("paypal adapter: %w", err)
+		return nil, fmt.Errorf("paypal adapter: %w", paypalError(err))
 	}
 
 	// Convert PayPal's response to our Receipt format
@@ -186,8 +277,19 @@ func (a *PayPalAdapter) Process(payment Payment) (*Receipt, error) {
 }
 
 // Refund adapts our refund interface to PayPal's format.
-func (a *PayPalAdapter) Refund(transactionID string, amount float64) error {
-	return a.service.ReversePayment(transactionID, amount)
+func (a *PayPalAdapter) Refund(transactionID string, amount payment.Money) error {
+	if err := a.service.ReversePayment(transactionID, amount.Float64()); err != nil {
+		return fmt.Errorf("paypal adapter: %w", paypalError(err))
+	}
+	return nil
+}
+
+func paypalError(err error) error {
+	var apiErr *PayPalError
+	if errors.As(err, &apiErr) {
+		return gatewayError(err, apiErr.StatusCode)
+	}
+	return gatewayError(err, 0)
 }
 
 // =============================================================================
@@ -198,6 +300,21 @@ func (a *PayPalAdapter) Refund(transactionID string, amount float64) error {
 type QueryResult struct {
 	Rows     []map[string]interface{}
 	RowCount int
+	// Columns describes the result columns in select order. Only adapters
+	// whose driver reports metadata, such as SQLAdapter, fill it in.
+	Columns []Column
+}
+
+// Column describes one result column.
+type Column struct {
+	Name string
+	// DatabaseType is the driver's type name, such as "VARCHAR" or "INT8".
+	// It is empty when the driver does not report one.
+	DatabaseType string
+	// Nullable is true when the driver reports that the column may be NULL.
+	Nullable bool
+	// ScanType is the Go type the driver scans the column into, or nil.
+	ScanType reflect.Type
 }
 
 // Database is the target interface that our application expects.
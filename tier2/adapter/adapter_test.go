@@ -3,16 +3,21 @@ package adapter
 import (
 	"errors"
 	"testing"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
+func usd(amount string) payment.Money {
+	return payment.MustParseMoney(amount, "USD")
+}
+
 // TestStripeAdapter_Process tests Stripe payment processing.
 func TestStripeAdapter_Process(t *testing.T) {
 	adapter := NewStripeAdapter("test_api_key")
 
 	payment := Payment{
 		CustomerID:  "cus_123",
-		Amount:      100.50,
-		Currency:    "USD",
+		Amount:      usd("100.50"),
 		Description: "Test payment",
 	}
 
@@ -26,8 +31,8 @@ func TestStripeAdapter_Process(t *testing.T) {
 		t.Fatal("Expected receipt, got nil")
 	}
 
-	if receipt.Amount != payment.Amount {
-		t.Errorf("Expected amount %.2f, got %.2f", payment.Amount, receipt.Amount)
+	if !receipt.Amount.Equal(payment.Amount) {
+		t.Errorf("Expected amount %s, got %s", payment.Amount, receipt.Amount)
 	}
 
 	if receipt.Status != "completed" {
@@ -45,8 +50,7 @@ func TestStripeAdapter_ProcessInvalidAmount(t *testing.T) {
 
 	payment := Payment{
 		CustomerID: "cus_123",
-		Amount:     0, // Invalid amount
-		Currency:   "USD",
+		Amount:     usd("0"), // Invalid amount
 	}
 
 	_, err := adapter.Process(payment)
@@ -60,7 +64,7 @@ func TestStripeAdapter_ProcessInvalidAmount(t *testing.T) {
 func TestStripeAdapter_Refund(t *testing.T) {
 	adapter := NewStripeAdapter("test_api_key")
 
-	err := adapter.Refund("ch_test_123", 50.00)
+	err := adapter.Refund("ch_test_123", usd("50.00"))
 
 	if err != nil {
 		t.Errorf("Expected successful refund, got error: %v", err)
@@ -73,8 +77,7 @@ func TestPayPalAdapter_Process(t *testing.T) {
 
 	payment := Payment{
 		CustomerID:  "user@example.com",
-		Amount:      75.25,
-		Currency:    "USD",
+		Amount:      usd("75.25"),
 		Description: "Test payment",
 	}
 
@@ -88,8 +91,8 @@ func TestPayPalAdapter_Process(t *testing.T) {
 		t.Fatal("Expected receipt, got nil")
 	}
 
-	if receipt.Amount != payment.Amount {
-		t.Errorf("Expected amount %.2f, got %.2f", payment.Amount, receipt.Amount)
+	if !receipt.Amount.Equal(payment.Amount) {
+		t.Errorf("Expected amount %s, got %s", payment.Amount, receipt.Amount)
 	}
 
 	if receipt.TransactionID == "" {
@@ -103,8 +106,7 @@ func TestPayPalAdapter_ProcessMissingCredentials(t *testing.T) {
 
 	payment := Payment{
 		CustomerID: "user@example.com",
-		Amount:     100.00,
-		Currency:   "USD",
+		Amount:     usd("100.00"),
 	}
 
 	_, err := adapter.Process(payment)
@@ -118,8 +120,7 @@ func TestPayPalAdapter_ProcessMissingCredentials(t *testing.T) {
 func TestPaymentProcessorPolymorphism(t *testing.T) {
 	payment := Payment{
 		CustomerID: "test_customer",
-		Amount:     50.00,
-		Currency:   "USD",
+		Amount:     usd("50.00"),
 	}
 
 	processors := []struct {
@@ -318,8 +319,7 @@ func TestAdapterIsolatesExternalChanges(t *testing.T) {
 	adapter := NewStripeAdapter("test_key")
 	payment := Payment{
 		CustomerID: "cus_123",
-		Amount:     100.00,
-		Currency:   "USD",
+		Amount:     usd("100.00"),
 	}
 
 	// Client code remains unchanged even if Stripe's internal API changes
@@ -339,8 +339,7 @@ func BenchmarkStripeAdapter(b *testing.B) {
 	adapter := NewStripeAdapter("test_key")
 	payment := Payment{
 		CustomerID: "cus_bench",
-		Amount:     50.00,
-		Currency:   "USD",
+		Amount:     usd("50.00"),
 	}
 
 	for i := 0; i < b.N; i++ {
@@ -353,8 +352,7 @@ func BenchmarkPayPalAdapter(b *testing.B) {
 	adapter := NewPayPalAdapter("client_id", "secret")
 	payment := Payment{
 		CustomerID: "user@example.com",
-		Amount:     50.00,
-		Currency:   "USD",
+		Amount:     usd("50.00"),
 	}
 
 	for i := 0; i < b.N; i++ {
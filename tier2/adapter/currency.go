@@ -0,0 +1,268 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/jumaniyozov/design_patterns/cache"
+	"github.com/jumaniyozov/design_patterns/payment"
+)
+
+// =============================================================================
+// Multi-currency routing
+// =============================================================================
+
+// ErrNoRoute is returned when no gateway can take a payment in its currency,
+// directly or after conversion.
+var ErrNoRoute = errors.New("no payment route for currency")
+
+// FeeSchedule is a gateway's price per charge: a percentage in basis points
+// (290 = 2.9%) plus a fixed fee in the gateway's own currency.
+type FeeSchedule struct {
+	BasisPoints int64
+	Fixed       payment.Money
+}
+
+// Gateway is a PaymentProcessor that publishes the currencies it can charge
+// in and what it costs. The first currency is the one it settles in, used
+// when a payment has to be converted.
+type Gateway interface {
+	PaymentProcessor
+	Currencies() []string
+	Fees() FeeSchedule
+}
+
+// Currencies returns the currencies Stripe charges in.
+func (a *StripeAdapter) Currencies() []string {
+	return []string{"USD", "EUR", "GBP", "CAD", "AUD", "JPY", "CHF", "SEK", "MXN"}
+}
+
+// Fees returns Stripe's standard card pricing.
+func (a *StripeAdapter) Fees() FeeSchedule {
+	return FeeSchedule{BasisPoints: 290, Fixed: payment.MustParseMoney("0.30", "USD")}
+}
+
+// Currencies returns the currencies PayPal charges in.
+func (a *PayPalAdapter) Currencies() []string {
+	return []string{"USD", "EUR", "GBP", "CAD", "AUD", "JPY", "MXN", "SEK", "INR"}
+}
+
+// Fees returns PayPal's standard checkout pricing.
+func (a *PayPalAdapter) Fees() FeeSchedule {
+	return FeeSchedule{BasisPoints: 349, Fixed: payment.MustParseMoney("0.49", "USD")}
+}
+
+// Route is a gateway the router may send payments to.
+type Route struct {
+	Name       string
+	Processor  PaymentProcessor
+	Currencies []string
+	Fees       FeeSchedule
+}
+
+// NewRoute builds a route from a gateway's published currencies and fees.
+func NewRoute(name string, gateway Gateway) Route {
+	return Route{Name: name, Processor: gateway, Currencies: gateway.Currencies(), Fees: gateway.Fees()}
+}
+
+// supports reports whether the route charges in currency.
+func (r *Route) supports(currency string) bool {
+	return slices.Contains(r.Currencies, currency)
+}
+
+// Quote is what a route would charge for a payment.
+type Quote struct {
+	Route string
+	// Amount is the amount charged, in the gateway's currency.
+	Amount payment.Money
+	// Rate converts the requested currency to Amount's currency.
+	Rate payment.ExchangeRate
+	// Fee is the estimated gateway fee in the requested currency.
+	Fee payment.Money
+
+	route *Route
+}
+
+// routedCharge remembers where a charge went so its refund can follow.
+type routedCharge struct {
+	route *Route
+	rate  payment.ExchangeRate
+}
+
+// CurrencyRouter is a PaymentProcessor that sends each payment to the
+// cheapest route that charges in the payment's currency. When no route does,
+// the payment is converted to the cheapest route's settlement currency.
+// Refunds go back to the route that took the charge, at the rate applied to it.
+type CurrencyRouter struct {
+	rates   payment.RateProvider
+	routes  []*Route
+	charges *cache.Cache[string, routedCharge]
+}
+
+// NewCurrencyRouter creates a router over routes, in order of preference
+// when quotes tie. It remembers the last DefaultChargeHistory charges for
+// Refund.
+func NewCurrencyRouter(rates payment.RateProvider, routes ...Route) *CurrencyRouter {
+	r := &CurrencyRouter{
+		rates:   rates,
+		charges: cache.New[string, routedCharge](cache.WithMaxEntries(DefaultChargeHistory)),
+	}
+	for _, route := range routes {
+		r.routes = append(r.routes, &route)
+	}
+	return r
+}
+
+// Quotes returns a quote from every route able to take amount. Routes that
+// charge in amount's currency come first, since conversion costs the customer
+// a spread; within each group the cheapest route comes first.
+func (r *CurrencyRouter) Quotes(amount payment.Money) ([]Quote, error) {
+	var quotes []Quote
+	for _, route := range r.routes {
+		quote, err := r.quote(route, amount)
+		if errors.Is(err, payment.ErrRateUnavailable) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("quote %s: %w", route.Name, err)
+		}
+		quotes = append(quotes, quote)
+	}
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("%w %s", ErrNoRoute, amount.Currency().Code)
+	}
+
+	slices.SortStableFunc(quotes, func(a, b Quote) int {
+		switch {
+		case a.Rate.IsIdentity() && !b.Rate.IsIdentity():
+			return -1
+		case b.Rate.IsIdentity() && !a.Rate.IsIdentity():
+			return 1
+		}
+		c, _ := a.Fee.Cmp(b.Fee)
+		return c
+	})
+	return quotes, nil
+}
+
+func (r *CurrencyRouter) quote(route *Route, amount payment.Money) (Quote, error) {
+	requested := amount.Currency().Code
+	target := requested
+	if !route.supports(requested) {
+		if len(route.Currencies) == 0 {
+			return Quote{}, payment.ErrRateUnavailable
+		}
+		target = route.Currencies[0]
+	}
+
+	rate, err := r.rates.Rate(requested, target)
+	if err != nil {
+		return Quote{}, err
+	}
+	charged, err := rate.Convert(amount)
+	if err != nil {
+		return Quote{}, err
+	}
+	fee, err := r.fee(route.Fees, amount)
+	if err != nil {
+		return Quote{}, err
+	}
+	return Quote{Route: route.Name, Amount: charged, Rate: rate, Fee: fee, route: route}, nil
+}
+
+// fee estimates the cost of charging amount in amount's own currency. The
+// percentage does not depend on the currency; the fixed part is converted.
+func (r *CurrencyRouter) fee(schedule FeeSchedule, amount payment.Money) (payment.Money, error) {
+	fee, err := amount.Scale(big.NewRat(schedule.BasisPoints, 10000))
+	if err != nil {
+		return payment.Money{}, err
+	}
+	if schedule.Fixed.IsZero() {
+		return fee, nil
+	}
+
+	rate, err := r.rates.Rate(schedule.Fixed.Currency().Code, amount.Currency().Code)
+	if err != nil {
+		return payment.Money{}, err
+	}
+	fixed, err := rate.Convert(schedule.Fixed)
+	if err != nil {
+		return payment.Money{}, err
+	}
+	return fee.Add(fixed)
+}
+
+// Process charges the payment through the cheapest route and records the
+// route, requested amount, applied rate and fee on the receipt.
+func (r *CurrencyRouter) Process(p Payment) (*Receipt, error) {
+	quotes, err := r.Quotes(p.Amount)
+	if err != nil {
+		return nil, err
+	}
+	best := quotes[0]
+
+	charged := p
+	charged.Amount = best.Amount
+	receipt, err := best.route.Processor.Process(charged)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", best.Route, err)
+	}
+
+	receipt.Amount = best.Amount
+	receipt.Provider = best.Route
+	receipt.RequestedAmount = p.Amount
+	receipt.Fee = best.Fee
+	if !best.Rate.IsIdentity() {
+		rate := best.Rate
+		receipt.ExchangeRate = &rate
+	}
+
+	r.charges.Set(receipt.TransactionID, routedCharge{route: best.route, rate: best.Rate})
+	return receipt, nil
+}
+
+// Refund sends the refund to the route that took the charge. amount may be
+// in the requested currency, in which case it is converted at the rate
+// applied to the charge, or in the charged currency. Only recent charges
+// can be found; use RefundReceipt for older ones.
+func (r *CurrencyRouter) Refund(transactionID string, amount payment.Money) error {
+	charge, ok := r.charges.Get(transactionID)
+	if !ok {
+		return fmt.Errorf("%w: %s", payment.ErrUnknownTransaction, transactionID)
+	}
+	return r.refund(transactionID, charge, amount)
+}
+
+// RefundReceipt refunds the charge on receipt through the route named by
+// receipt.Provider, at receipt.ExchangeRate. It works for charges the router
+// no longer remembers.
+func (r *CurrencyRouter) RefundReceipt(receipt *Receipt, amount payment.Money) error {
+	i := slices.IndexFunc(r.routes, func(route *Route) bool { return route.Name == receipt.Provider })
+	if i < 0 {
+		return fmt.Errorf("refund %s: unknown route %q", receipt.TransactionID, receipt.Provider)
+	}
+	charged := receipt.Amount.Currency()
+	rate := payment.ExchangeRate{From: charged, To: charged, Rate: big.NewRat(1, 1)}
+	if receipt.ExchangeRate != nil {
+		rate = *receipt.ExchangeRate
+	}
+	return r.refund(receipt.TransactionID, routedCharge{route: r.routes[i], rate: rate}, amount)
+}
+
+// refund converts amount to the charged currency if needed and sends it to
+// the charge's route.
+func (r *CurrencyRouter) refund(transactionID string, charge routedCharge, amount payment.Money) error {
+	if amount.Currency().Code != charge.rate.To.Code {
+		converted, err := charge.rate.Convert(amount)
+		if err != nil {
+			return err
+		}
+		amount = converted
+	}
+	if err := charge.route.Processor.Refund(transactionID, amount); err != nil {
+		return fmt.Errorf("%s: %w", charge.route.Name, err)
+	}
+	return nil
+}
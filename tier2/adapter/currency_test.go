@@ -0,0 +1,182 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
+
+// recordingGateway is a Gateway that remembers what it was asked to charge
+// and refund.
+type recordingGateway struct {
+	name       string
+	currencies []string
+	fees       FeeSchedule
+
+	charged  []payment.Money
+	refunded []payment.Money
+}
+
+func (g *recordingGateway) Process(p Payment) (*Receipt, error) {
+	g.charged = append(g.charged, p.Amount)
+	return &Receipt{TransactionID: fmt.Sprintf("%s-%d", g.name, len(g.charged)), Status: "completed", Amount: p.Amount}, nil
+}
+
+func (g *recordingGateway) Refund(transactionID string, amount payment.Money) error {
+	g.refunded = append(g.refunded, amount)
+	return nil
+}
+
+func (g *recordingGateway) Currencies() []string { return g.currencies }
+func (g *recordingGateway) Fees() FeeSchedule    { return g.fees }
+
+func testRates(t *testing.T) payment.RateProvider {
+	t.Helper()
+	rates, err := payment.NewStaticRates("USD", map[string]string{"EUR": "0.90", "GBP": "0.80", "KRW": "1300"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rates
+}
+
+// TestCurrencyRouter_PicksCheapestSupportingRoute tests routing by currency support, then fee.
+func TestCurrencyRouter_PicksCheapestSupportingRoute(t *testing.T) {
+	cheap := &recordingGateway{name: "cheap", currencies: []string{"USD", "EUR"}, fees: FeeSchedule{BasisPoints: 200}}
+	pricey := &recordingGateway{name: "pricey", currencies: []string{"GBP", "EUR", "USD"}, fees: FeeSchedule{BasisPoints: 300}}
+	router := NewCurrencyRouter(testRates(t), NewRoute("pricey", pricey), NewRoute("cheap", cheap))
+
+	tests := []struct {
+		amount    payment.Money
+		wantRoute string
+		wantFee   string
+	}{
+		{payment.MustParseMoney("100.00", "EUR"), "cheap", "2.00 EUR"},
+		{payment.MustParseMoney("100.00", "GBP"), "pricey", "3.00 GBP"},
+		// Neither route takes KRW, so both convert and the lower fee wins.
+		{payment.MustParseMoney("100000", "KRW"), "cheap", "2000 KRW"},
+	}
+	for _, tt := range tests {
+		receipt, err := router.Process(Payment{CustomerID: "cus_1", Amount: tt.amount})
+		if err != nil {
+			t.Fatalf("Process(%s): %v", tt.amount, err)
+		}
+		if got := receipt.TransactionID[:len(tt.wantRoute)]; got != tt.wantRoute {
+			t.Errorf("Process(%s) routed to %s, want %s", tt.amount, receipt.TransactionID, tt.wantRoute)
+		}
+		if receipt.Fee.String() != tt.wantFee {
+			t.Errorf("Process(%s) fee = %s, want %s", tt.amount, receipt.Fee, tt.wantFee)
+		}
+		if !receipt.RequestedAmount.Equal(tt.amount) {
+			t.Errorf("RequestedAmount = %s, want %s", receipt.RequestedAmount, tt.amount)
+		}
+	}
+}
+
+// TestCurrencyRouter_ConvertsAndRefundsAtChargeRate tests conversion and refunds.
+func TestCurrencyRouter_ConvertsAndRefundsAtChargeRate(t *testing.T) {
+	gateway := &recordingGateway{name: "usd-only", currencies: []string{"USD"}}
+	router := NewCurrencyRouter(testRates(t), NewRoute("usd-only", gateway))
+
+	receipt, err := router.Process(Payment{Amount: payment.MustParseMoney("45.00", "EUR")})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if receipt.Amount.String() != "50.00 USD" {
+		t.Errorf("Amount = %s, want 50.00 USD", receipt.Amount)
+	}
+	if receipt.ExchangeRate == nil || receipt.ExchangeRate.From.Code != "EUR" || receipt.ExchangeRate.To.Code != "USD" {
+		t.Fatalf("ExchangeRate = %v, want EUR to USD", receipt.ExchangeRate)
+	}
+
+	if err := router.Refund(receipt.TransactionID, payment.MustParseMoney("9.00", "EUR")); err != nil {
+		t.Fatalf("Refund in requested currency: %v", err)
+	}
+	if err := router.Refund(receipt.TransactionID, payment.MustParseMoney("5.00", "USD")); err != nil {
+		t.Fatalf("Refund in charged currency: %v", err)
+	}
+	if fmt.Sprint(gateway.refunded) != "[10.00 USD 5.00 USD]" {
+		t.Errorf("refunds reaching gateway = %v, want [10.00 USD 5.00 USD]", gateway.refunded)
+	}
+
+	if err := router.Refund("unknown", payment.MustParseMoney("1.00", "USD")); !errors.Is(err, payment.ErrUnknownTransaction) {
+		t.Errorf("expected ErrUnknownTransaction, got %v", err)
+	}
+	if err := router.Refund(receipt.TransactionID, payment.MustParseMoney("1.00", "GBP")); !errors.Is(err, payment.ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+	}
+
+	// Same-currency payments carry no rate.
+	receipt, _ = router.Process(Payment{Amount: payment.MustParseMoney("5.00", "USD")})
+	if receipt.ExchangeRate != nil {
+		t.Errorf("expected no exchange rate for a USD payment, got %s", receipt.ExchangeRate)
+	}
+}
+
+// TestCurrencyRouter_RefundReceipt tests refunds of charges the router does
+// not remember, in both currencies.
+func TestCurrencyRouter_RefundReceipt(t *testing.T) {
+	gateway := &recordingGateway{name: "usd-only", currencies: []string{"USD"}}
+	charger := NewCurrencyRouter(testRates(t), NewRoute("usd-only", gateway))
+	converted, err := charger.Process(Payment{Amount: payment.MustParseMoney("45.00", "EUR")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct, err := charger.Process(Payment{Amount: payment.MustParseMoney("5.00", "USD")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh router, as after a restart, has only the receipts.
+	router := NewCurrencyRouter(testRates(t), NewRoute("usd-only", gateway))
+	if err := router.Refund(converted.TransactionID, payment.MustParseMoney("9.00", "EUR")); !errors.Is(err, payment.ErrUnknownTransaction) {
+		t.Fatalf("expected ErrUnknownTransaction, got %v", err)
+	}
+	if err := router.RefundReceipt(converted, payment.MustParseMoney("9.00", "EUR")); err != nil {
+		t.Fatalf("RefundReceipt in requested currency: %v", err)
+	}
+	if err := router.RefundReceipt(direct, payment.MustParseMoney("2.00", "USD")); err != nil {
+		t.Fatalf("RefundReceipt of a direct charge: %v", err)
+	}
+	if fmt.Sprint(gateway.refunded) != "[10.00 USD 2.00 USD]" {
+		t.Errorf("refunds reaching gateway = %v, want [10.00 USD 2.00 USD]", gateway.refunded)
+	}
+	if err := router.RefundReceipt(&Receipt{Provider: "adyen"}, payment.MustParseMoney("1.00", "USD")); err == nil {
+		t.Error("expected an error for an unknown route")
+	}
+}
+
+// TestCurrencyRouter_NoRoute tests currencies that cannot be charged or converted.
+func TestCurrencyRouter_NoRoute(t *testing.T) {
+	router := NewCurrencyRouter(testRates(t), NewRoute("usd-only", &recordingGateway{currencies: []string{"USD"}}))
+
+	if _, err := router.Process(Payment{Amount: payment.MustParseMoney("10.000", "BHD")}); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("expected ErrNoRoute, got %v", err)
+	}
+}
+
+// TestCurrencyRouter_RealAdapters tests routing across the Stripe and PayPal adapters.
+func TestCurrencyRouter_RealAdapters(t *testing.T) {
+	router := NewCurrencyRouter(testRates(t),
+		NewRoute("paypal", NewPayPalAdapter("id", "secret")),
+		NewRoute("stripe", NewStripeAdapter("sk_test")),
+	)
+
+	quotes, err := router.Quotes(usd("100.00"))
+	if err != nil {
+		t.Fatalf("Quotes: %v", err)
+	}
+	if len(quotes) != 2 || quotes[0].Route != "stripe" || quotes[0].Fee.String() != "3.20 USD" {
+		t.Errorf("quotes = %+v, want stripe first at 3.20 USD", quotes)
+	}
+
+	var processor PaymentProcessor = router
+	receipt, err := processor.Process(Payment{CustomerID: "cus_1", Amount: usd("100.00")})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if err := processor.Refund(receipt.TransactionID, usd("100.00")); err != nil {
+		t.Errorf("Refund: %v", err)
+	}
+}
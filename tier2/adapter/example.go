@@ -3,6 +3,9 @@ package adapter
 import (
 	"errors"
 	"fmt"
+	"net/http"
+
+	"github.com/jumaniyozov/design_patterns/payment"
 )
 
 // Example1_PaymentProcessorWithoutAdapter demonstrates the problem: incompatible interfaces.
@@ -32,14 +35,13 @@ func Example2_PaymentProcessorWithAdapter() {
 	// Create payment object (same format regardless of gateway)
 	payment := Payment{
 		CustomerID:  "cus_12345",
-		Amount:      99.99,
-		Currency:    "USD",
+		Amount:      payment.MustParseMoney("99.99", "USD"),
 		Description: "Premium subscription",
 	}
 
 	fmt.Println("Payment Request:")
 	fmt.Printf("  Customer: %s\n", payment.CustomerID)
-	fmt.Printf("  Amount:   $%.2f %s\n", payment.Amount, payment.Currency)
+	fmt.Printf("  Amount:   %s\n", payment.Amount)
 	fmt.Printf("  Desc:     %s\n\n", payment.Description)
 
 	// Process with Stripe (using adapter)
@@ -50,7 +52,7 @@ func Example2_PaymentProcessorWithAdapter() {
 		fmt.Printf("Error: %v\n", err)
 	} else {
 		fmt.Printf("✓ Success! Transaction ID: %s\n", receipt1.TransactionID)
-		fmt.Printf("  Status: %s, Amount: $%.2f\n", receipt1.Status, receipt1.Amount)
+		fmt.Printf("  Status: %s, Amount: %s\n", receipt1.Status, receipt1.Amount.Format())
 	}
 
 	fmt.Println()
@@ -63,7 +65,7 @@ func Example2_PaymentProcessorWithAdapter() {
 		fmt.Printf("Error: %v\n", err)
 	} else {
 		fmt.Printf("✓ Success! Transaction ID: %s\n", receipt2.TransactionID)
-		fmt.Printf("  Status: %s, Amount: $%.2f\n", receipt2.Status, receipt2.Amount)
+		fmt.Printf("  Status: %s, Amount: %s\n", receipt2.Status, receipt2.Amount.Format())
 	}
 
 	fmt.Println()
@@ -89,8 +91,7 @@ func Example3_PolymorphicPaymentProcessing() {
 
 	payment := Payment{
 		CustomerID:  "cus_67890",
-		Amount:      149.99,
-		Currency:    "USD",
+		Amount:      payment.MustParseMoney("149.99", "USD"),
 		Description: "Annual subscription",
 	}
 
@@ -119,18 +120,17 @@ func Example4_RefundWithAdapters() {
 	// Process a payment first
 	payment := Payment{
 		CustomerID: "cus_99999",
-		Amount:     79.99,
-		Currency:   "USD",
+		Amount:     payment.MustParseMoney("79.99", "USD"),
 	}
 
 	processor := NewStripeAdapter("sk_test_key")
 	receipt, _ := processor.Process(payment)
 
-	fmt.Printf("Original charge: %s for $%.2f\n", receipt.TransactionID, receipt.Amount)
+	fmt.Printf("Original charge: %s for %s\n", receipt.TransactionID, receipt.Amount.Format())
 
 	// Refund the payment
-	refundAmount := 79.99
-	fmt.Printf("\nIssuing refund of $%.2f...\n", refundAmount)
+	refundAmount := receipt.Amount
+	fmt.Printf("\nIssuing refund of %s...\n", refundAmount.Format())
 	err := processor.Refund(receipt.TransactionID, refundAmount)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -326,3 +326,78 @@ func Example10_WhenNotToUseAdapter() {
 	fmt.Println("💡 Use adapters strategically when they provide clear value,")
 	fmt.Println("   not reflexively for every third-party integration.")
 }
+
+// Example11_MultiCurrencyRouting demonstrates one PaymentProcessor front door
+// for multi-currency checkout.
+func Example11_MultiCurrencyRouting() {
+	fmt.Println("\n=== Example 11: Multi-Currency Routing ===")
+	fmt.Println()
+
+	rates, err := payment.NewStaticRates("USD", map[string]string{"EUR": "0.92", "GBP": "0.79", "INR": "83.10", "KRW": "1330"})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	var checkout PaymentProcessor = NewCurrencyRouter(rates,
+		NewRoute("Stripe", NewStripeAdapter("sk_test_key")),
+		NewRoute("PayPal", NewPayPalAdapter("client_id", "secret")),
+	)
+
+	orders := []payment.Money{
+		payment.MustParseMoney("49.00", "EUR"),   // both gateways: cheapest fee wins
+		payment.MustParseMoney("2500.00", "INR"), // only PayPal charges INR
+		payment.MustParseMoney("65000", "KRW"),   // nobody charges KRW: converted to USD
+	}
+	for _, amount := range orders {
+		receipt, err := checkout.Process(Payment{CustomerID: "cus_intl", Amount: amount})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		fmt.Printf("✓ %s charged as %s (%s), fee ~%s\n",
+			receipt.RequestedAmount.Format(), receipt.Amount.Format(), receipt.TransactionID, receipt.Fee.Format())
+		if receipt.ExchangeRate != nil {
+			fmt.Printf("  Rate applied: %s\n", receipt.ExchangeRate)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("💡 The router is itself a PaymentProcessor: callers don't know")
+	fmt.Println("   which gateway took the charge or whether it was converted.")
+}
+
+// Example12_FailoverRouting demonstrates failing over between gateways.
+func Example12_FailoverRouting() {
+	fmt.Println("\n=== Example 12: Failover Routing ===")
+	fmt.Println()
+
+	// Stripe is having an outage: it answers every request with a 503,
+	// so the charge cannot have been taken and PayPal is tried instead.
+	stripe := &StripePaymentGateway{
+		APIKey: "sk_test_key",
+		Fault:  &StripeError{StatusCode: http.StatusServiceUnavailable, Message: "service unavailable"},
+	}
+	checkout := NewFailoverProcessor([]Provider{
+		{Name: "Stripe", Processor: NewStripeAdapterFor(stripe)},
+		{Name: "PayPal", Processor: NewPayPalAdapter("client_id", "secret")},
+	})
+
+	receipt, err := checkout.Process(Payment{CustomerID: "cus_42", Amount: payment.MustParseMoney("30.00", "USD")})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Charged %s via %s (%s)\n", receipt.Amount.Format(), receipt.Provider, receipt.TransactionID)
+
+	if err := checkout.Refund(receipt.TransactionID, receipt.Amount); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Refund sent back to %s\n", receipt.Provider)
+	fmt.Printf("  Provider health: %v\n", checkout.Health())
+
+	fmt.Println()
+	fmt.Println("💡 Each provider sits behind a circuit breaker, so an unhealthy")
+	fmt.Println("   gateway is skipped instead of slowing down every checkout.")
+}
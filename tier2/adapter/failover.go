@@ -0,0 +1,258 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jumaniyozov/design_patterns/cache"
+	"github.com/jumaniyozov/design_patterns/payment"
+	"github.com/jumaniyozov/design_patterns/tier4/circuitbreaker"
+)
+
+// =============================================================================
+// Failover routing
+// =============================================================================
+
+var (
+	// ErrGatewayUnavailable marks a gateway error that happened before the
+	// gateway took the payment (connection refused, rate limit, server
+	// error), so another gateway may be tried without charging twice.
+	// Adapters wrap it with %w.
+	ErrGatewayUnavailable = errors.New("payment gateway unavailable")
+	// ErrNoProviderAvailable is returned when every provider was skipped or
+	// failed with a retryable error.
+	ErrNoProviderAvailable = errors.New("no payment provider available")
+	// ErrNoReceipt is returned when a provider reports success without a
+	// receipt. Whether it charged is unknown, so it is not failed over.
+	ErrNoReceipt = errors.New("payment provider returned no receipt")
+)
+
+// Provider is one gateway behind a FailoverProcessor.
+type Provider struct {
+	Name      string
+	Processor PaymentProcessor
+	// Weight is the provider's share of traffic with WithWeightedSelection.
+	// Zero means 1.
+	Weight int
+	// Breaker tracks the provider's health. Nil gets a breaker built from
+	// the processor's breaker config.
+	Breaker *circuitbreaker.CircuitBreaker
+}
+
+// DefaultChargeHistory is how many recent charges a processor remembers so
+// Refund can find them by transaction ID.
+const DefaultChargeHistory = 10000
+
+// FailoverOption configures a FailoverProcessor.
+type FailoverOption func(*FailoverProcessor)
+
+// WithWeightedSelection spreads charges across providers in proportion to
+// their weights instead of always starting with the first. Providers not
+// picked first remain available for failover. src may be nil.
+func WithWeightedSelection(src rand.Source) FailoverOption {
+	return func(f *FailoverProcessor) {
+		if src == nil {
+			src = rand.NewPCG(uint64(time.Now().UnixNano()), 0)
+		}
+		f.rand = rand.New(src)
+	}
+}
+
+// WithRetryable sets the test for errors that move on to the next provider.
+// The default is IsRetryable.
+func WithRetryable(retryable func(error) bool) FailoverOption {
+	return func(f *FailoverProcessor) {
+		f.retryable = retryable
+	}
+}
+
+// WithBreakerConfig sets the circuit breaker config for providers without a
+// Breaker. The default trips after 3 consecutive failures and probes again
+// after 30 seconds.
+func WithBreakerConfig(config circuitbreaker.Config) FailoverOption {
+	return func(f *FailoverProcessor) {
+		f.breakerConfig = config
+	}
+}
+
+// WithChargeHistory sets how many recent charges Refund can find by
+// transaction ID; the oldest are forgotten first. Older charges are refunded
+// with RefundReceipt. The default is DefaultChargeHistory; zero means no
+// limit.
+func WithChargeHistory(n int) FailoverOption {
+	return func(f *FailoverProcessor) {
+		f.chargeHistory = n
+	}
+}
+
+// IsRetryable reports whether err is worth retrying on another gateway,
+// which is only safe for ErrGatewayUnavailable. A declined card would fail
+// the same way anywhere, and after a timeout the first gateway may already
+// have charged the card.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrGatewayUnavailable)
+}
+
+// isTimeout reports whether err is a deadline or network timeout. Timeouts
+// count against a provider's breaker but do not fail over.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// FailoverProcessor is a PaymentProcessor over several providers. It tries
+// them in priority order (or weighted order), skips providers whose circuit
+// breaker is open, and moves on when a provider fails with a retryable
+// error. Any other error, a timeout included, is returned without trying
+// another provider, so a charge whose outcome is unknown is not repeated.
+// The receipt names the provider that took the charge, and Refund sends
+// refunds back to it.
+type FailoverProcessor struct {
+	providers     []*Provider
+	retryable     func(error) bool
+	breakerConfig circuitbreaker.Config
+	chargeHistory int
+	charges       *cache.Cache[string, *Provider]
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewFailoverProcessor creates a processor trying providers in the given order.
+func NewFailoverProcessor(providers []Provider, opts ...FailoverOption) *FailoverProcessor {
+	f := &FailoverProcessor{
+		retryable: IsRetryable,
+		breakerConfig: circuitbreaker.Config{
+			Timeout:     30 * time.Second,
+			ReadyToTrip: func(counts circuitbreaker.Counts) bool { return counts.ConsecutiveFailures >= 3 },
+		},
+		chargeHistory: DefaultChargeHistory,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.charges = cache.New[string, *Provider](cache.WithMaxEntries(f.chargeHistory))
+
+	for _, provider := range providers {
+		if provider.Weight <= 0 {
+			provider.Weight = 1
+		}
+		if provider.Breaker == nil {
+			provider.Breaker = circuitbreaker.New(f.breakerConfig)
+		}
+		f.providers = append(f.providers, &provider)
+	}
+	return f
+}
+
+// Process charges the payment through the first healthy provider, failing
+// over on retryable errors. A non-retryable error is returned immediately.
+func (f *FailoverProcessor) Process(p Payment) (*Receipt, error) {
+	var errs []error
+	for _, provider := range f.order() {
+		var receipt *Receipt
+		var chargeErr error
+		err := provider.Breaker.Execute(func() error {
+			receipt, chargeErr = provider.Processor.Process(p)
+			if chargeErr == nil && receipt == nil {
+				chargeErr = ErrNoReceipt
+			}
+			// Only failures that say the provider is unhealthy count
+			// against its breaker.
+			if chargeErr != nil && (f.retryable(chargeErr) || isTimeout(chargeErr)) {
+				return chargeErr
+			}
+			return nil
+		})
+
+		if chargeErr != nil && !f.retryable(chargeErr) {
+			return nil, fmt.Errorf("%s: %w", provider.Name, chargeErr)
+		}
+		if err != nil {
+			// Either the breaker is open (ErrCircuitOpen, ErrTooManyRequests)
+			// or the provider failed with a retryable error.
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+			continue
+		}
+
+		receipt.Provider = provider.Name
+		f.charges.Set(receipt.TransactionID, provider)
+		return receipt, nil
+	}
+	return nil, fmt.Errorf("%w: %w", ErrNoProviderAvailable, errors.Join(errs...))
+}
+
+// Refund sends the refund to the provider that took the charge. Refunds do
+// not fail over: no other provider knows the transaction. Only the last
+// WithChargeHistory charges can be found; use RefundReceipt for older ones.
+func (f *FailoverProcessor) Refund(transactionID string, amount payment.Money) error {
+	provider, ok := f.charges.Get(transactionID)
+	if !ok {
+		return fmt.Errorf("%w: %s", payment.ErrUnknownTransaction, transactionID)
+	}
+
+	if err := provider.Processor.Refund(transactionID, amount); err != nil {
+		return fmt.Errorf("%s: %w", provider.Name, err)
+	}
+	return nil
+}
+
+// RefundReceipt refunds the charge on receipt through receipt.Provider. It
+// works for receipts kept from an earlier process or older than the charge
+// history, which Refund cannot look up.
+func (f *FailoverProcessor) RefundReceipt(receipt *Receipt, amount payment.Money) error {
+	for _, provider := range f.providers {
+		if provider.Name == receipt.Provider {
+			if err := provider.Processor.Refund(receipt.TransactionID, amount); err != nil {
+				return fmt.Errorf("%s: %w", provider.Name, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("refund %s: unknown provider %q", receipt.TransactionID, receipt.Provider)
+}
+
+// Health returns each provider's circuit breaker state.
+func (f *FailoverProcessor) Health() map[string]circuitbreaker.State {
+	health := make(map[string]circuitbreaker.State, len(f.providers))
+	for _, provider := range f.providers {
+		health[provider.Name] = provider.Breaker.State()
+	}
+	return health
+}
+
+// order returns the providers in the order to try them. With weighted
+// selection each position is drawn from the remaining providers in
+// proportion to their weights.
+func (f *FailoverProcessor) order() []*Provider {
+	if f.rand == nil {
+		return f.providers
+	}
+
+	remaining := append([]*Provider(nil), f.providers...)
+	order := make([]*Provider, 0, len(remaining))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(remaining) > 0 {
+		total := 0
+		for _, provider := range remaining {
+			total += provider.Weight
+		}
+		pick := f.rand.IntN(total)
+		for i, provider := range remaining {
+			if pick < provider.Weight {
+				order = append(order, provider)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+			pick -= provider.Weight
+		}
+	}
+	return order
+}
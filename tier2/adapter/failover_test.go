@@ -0,0 +1,304 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+	"github.com/jumaniyozov/design_patterns/tier4/circuitbreaker"
+)
+
+// scriptedGateway returns the queued errors in order, then succeeds.
+type scriptedGateway struct {
+	name    string
+	errs    []error
+	calls   int
+	refunds []string
+}
+
+func (g *scriptedGateway) Process(p Payment) (*Receipt, error) {
+	g.calls++
+	if len(g.errs) > 0 {
+		err := g.errs[0]
+		g.errs = g.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Receipt{TransactionID: fmt.Sprintf("%s-%d", g.name, g.calls), Status: "completed", Amount: p.Amount}, nil
+}
+
+func (g *scriptedGateway) Refund(transactionID string, amount payment.Money) error {
+	g.refunds = append(g.refunds, transactionID)
+	return nil
+}
+
+var errOutage = fmt.Errorf("503 from upstream: %w", ErrGatewayUnavailable)
+
+// TestFailoverProcessor_FailsOverOnRetryableErrors tests priority order and failover.
+func TestFailoverProcessor_FailsOverOnRetryableErrors(t *testing.T) {
+	primary := &scriptedGateway{name: "primary", errs: []error{errOutage}}
+	backup := &scriptedGateway{name: "backup"}
+	processor := NewFailoverProcessor([]Provider{
+		{Name: "primary", Processor: primary},
+		{Name: "backup", Processor: backup},
+	})
+
+	receipt, err := processor.Process(Payment{Amount: usd("10.00")})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if receipt.Provider != "backup" {
+		t.Errorf("Provider = %q, want backup", receipt.Provider)
+	}
+
+	// The primary recovered, so it takes the next charge again.
+	receipt, err = processor.Process(Payment{Amount: usd("10.00")})
+	if err != nil || receipt.Provider != "primary" {
+		t.Fatalf("expected primary to take the second charge, got %v, %v", receipt, err)
+	}
+
+	// Refunds follow the charge to its provider.
+	if err := processor.Refund(receipt.TransactionID, usd("10.00")); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if len(primary.refunds) != 1 || len(backup.refunds) != 0 {
+		t.Errorf("refunds: primary %v, backup %v", primary.refunds, backup.refunds)
+	}
+	if err := processor.Refund("unknown", usd("1.00")); !errors.Is(err, payment.ErrUnknownTransaction) {
+		t.Errorf("expected ErrUnknownTransaction, got %v", err)
+	}
+}
+
+// TestFailoverProcessor_NonRetryableErrorStops tests that declines are not retried elsewhere.
+func TestFailoverProcessor_NonRetryableErrorStops(t *testing.T) {
+	declined := errors.New("card declined")
+	primary := &scriptedGateway{name: "primary", errs: []error{declined, declined, declined, declined}}
+	backup := &scriptedGateway{name: "backup"}
+	processor := NewFailoverProcessor([]Provider{
+		{Name: "primary", Processor: primary},
+		{Name: "backup", Processor: backup},
+	})
+
+	for range 4 {
+		if _, err := processor.Process(Payment{Amount: usd("10.00")}); !errors.Is(err, declined) {
+			t.Fatalf("expected the decline to be returned, got %v", err)
+		}
+	}
+	if backup.calls != 0 {
+		t.Errorf("expected backup not to be tried, got %d calls", backup.calls)
+	}
+	if state := processor.Health()["primary"]; state != circuitbreaker.StateClosed {
+		t.Errorf("declines must not trip the breaker, got %s", state)
+	}
+}
+
+// TestFailoverProcessor_TimeoutDoesNotFailOver tests that a charge with an
+// unknown outcome is not repeated elsewhere, while still counting against
+// the provider's health.
+func TestFailoverProcessor_TimeoutDoesNotFailOver(t *testing.T) {
+	primary := &scriptedGateway{name: "primary", errs: []error{context.DeadlineExceeded, context.DeadlineExceeded}}
+	backup := &scriptedGateway{name: "backup"}
+	processor := NewFailoverProcessor([]Provider{
+		{Name: "primary", Processor: primary},
+		{Name: "backup", Processor: backup},
+	}, WithBreakerConfig(circuitbreaker.Config{
+		Timeout:     time.Hour,
+		ReadyToTrip: func(counts circuitbreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	}))
+
+	for range 2 {
+		if _, err := processor.Process(Payment{Amount: usd("10.00")}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the timeout to be returned, got %v", err)
+		}
+	}
+	if backup.calls != 0 {
+		t.Errorf("expected backup not to be tried after a timeout, got %d calls", backup.calls)
+	}
+	if state := processor.Health()["primary"]; state != circuitbreaker.StateOpen {
+		t.Errorf("expected timeouts to trip the breaker, got %s", state)
+	}
+}
+
+// nilReceiptGateway reports success without a receipt.
+type nilReceiptGateway struct{ scriptedGateway }
+
+func (g *nilReceiptGateway) Process(Payment) (*Receipt, error) {
+	g.calls++
+	return nil, nil
+}
+
+// TestFailoverProcessor_NilReceipt tests that a provider returning neither
+// a receipt nor an error fails the charge instead of panicking.
+func TestFailoverProcessor_NilReceipt(t *testing.T) {
+	primary := &nilReceiptGateway{}
+	backup := &scriptedGateway{name: "backup"}
+	processor := NewFailoverProcessor([]Provider{
+		{Name: "primary", Processor: primary},
+		{Name: "backup", Processor: backup},
+	})
+
+	if _, err := processor.Process(Payment{Amount: usd("10.00")}); !errors.Is(err, ErrNoReceipt) {
+		t.Errorf("expected ErrNoReceipt, got %v", err)
+	}
+	if backup.calls != 0 {
+		t.Errorf("expected backup not to be tried, got %d calls", backup.calls)
+	}
+}
+
+// TestFailoverProcessor_Adapters tests failover through the real adapters
+// and the errors they mark as safe to retry.
+func TestFailoverProcessor_Adapters(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name     string
+		fault    error
+		failover bool
+	}{
+		{"server error", &StripeError{StatusCode: http.StatusServiceUnavailable, Message: "unavailable"}, true},
+		{"rate limited", &StripeError{StatusCode: http.StatusTooManyRequests, Message: "slow down"}, true},
+		{"connection refused", dialErr, true},
+		{"card declined", &StripeError{StatusCode: http.StatusPaymentRequired, Message: "card_declined"}, false},
+		{"timeout", context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		stripe := &StripePaymentGateway{APIKey: "sk_test", Fault: tt.fault}
+		processor := NewFailoverProcessor([]Provider{
+			{Name: "stripe", Processor: NewStripeAdapterFor(stripe)},
+			{Name: "paypal", Processor: NewPayPalAdapter("id", "secret")},
+		})
+
+		receipt, err := processor.Process(Payment{Amount: usd("10.00")})
+		if tt.failover {
+			if err != nil || receipt.Provider != "paypal" {
+				t.Errorf("%s: expected paypal to take the charge, got %v, %v", tt.name, receipt, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.fault) || errors.Is(err, ErrGatewayUnavailable) {
+			t.Errorf("%s: expected the error to be returned as is, got %v", tt.name, err)
+		}
+	}
+
+	paypal := NewPayPalAdapterFor(&PayPalPaymentService{
+		ClientID: "id", ClientSecret: "secret",
+		Fault: &PayPalError{StatusCode: http.StatusBadGateway, Name: "INTERNAL_SERVICE_ERROR"},
+	})
+	if _, err := paypal.Process(Payment{Amount: usd("10.00")}); !errors.Is(err, ErrGatewayUnavailable) {
+		t.Errorf("expected a PayPal 502 to be ErrGatewayUnavailable, got %v", err)
+	}
+}
+
+// TestFailoverProcessor_SkipsOpenCircuit tests that unhealthy providers are skipped.
+func TestFailoverProcessor_SkipsOpenCircuit(t *testing.T) {
+	primary := &scriptedGateway{name: "primary", errs: []error{errOutage, errOutage}}
+	backup := &scriptedGateway{name: "backup"}
+	processor := NewFailoverProcessor([]Provider{
+		{Name: "primary", Processor: primary},
+		{Name: "backup", Processor: backup},
+	}, WithBreakerConfig(circuitbreaker.Config{
+		Timeout:     time.Hour,
+		ReadyToTrip: func(counts circuitbreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	}))
+
+	for range 5 {
+		receipt, err := processor.Process(Payment{Amount: usd("10.00")})
+		if err != nil || receipt.Provider != "backup" {
+			t.Fatalf("expected backup to take the charge, got %v, %v", receipt, err)
+		}
+	}
+	if primary.calls != 2 {
+		t.Errorf("expected the open circuit to stop calls to primary after 2, got %d", primary.calls)
+	}
+	if state := processor.Health()["primary"]; state != circuitbreaker.StateOpen {
+		t.Errorf("primary breaker = %s, want Open", state)
+	}
+}
+
+// TestFailoverProcessor_AllProvidersDown tests the error when nothing can take the charge.
+func TestFailoverProcessor_AllProvidersDown(t *testing.T) {
+	processor := NewFailoverProcessor([]Provider{
+		{Name: "a", Processor: &scriptedGateway{errs: []error{errOutage}}},
+		{Name: "b", Processor: &scriptedGateway{errs: []error{errOutage}}},
+	})
+
+	_, err := processor.Process(Payment{Amount: usd("10.00")})
+	if !errors.Is(err, ErrNoProviderAvailable) || !errors.Is(err, ErrGatewayUnavailable) {
+		t.Fatalf("expected ErrNoProviderAvailable wrapping the outages, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "a:") || !strings.Contains(err.Error(), "b:") {
+		t.Errorf("expected both providers in the error, got %v", err)
+	}
+}
+
+// TestFailoverProcessor_WeightedSelection tests that traffic follows weights.
+func TestFailoverProcessor_WeightedSelection(t *testing.T) {
+	heavy := &scriptedGateway{name: "heavy"}
+	light := &scriptedGateway{name: "light"}
+	processor := NewFailoverProcessor([]Provider{
+		{Name: "light", Processor: light, Weight: 1},
+		{Name: "heavy", Processor: heavy, Weight: 3},
+	}, WithWeightedSelection(rand.NewPCG(1, 2)))
+
+	counts := map[string]int{}
+	for range 1000 {
+		receipt, err := processor.Process(Payment{Amount: usd("1.00")})
+		if err != nil {
+			t.Fatalf("Process: %v", err)
+		}
+		counts[receipt.Provider]++
+	}
+	if counts["heavy"] < 700 || counts["heavy"] > 800 {
+		t.Errorf("expected about 750 charges on heavy, got %v", counts)
+	}
+}
+
+// TestFailoverProcessor_RefundReceipt tests refunding from a stored receipt.
+// TestFailoverProcessor_ChargeHistory tests that Refund remembers only the
+// most recent charges.
+func TestFailoverProcessor_ChargeHistory(t *testing.T) {
+	stripe := &scriptedGateway{name: "stripe"}
+	processor := NewFailoverProcessor([]Provider{{Name: "stripe", Processor: stripe}}, WithChargeHistory(2))
+
+	var receipts []*Receipt
+	for range 3 {
+		receipt, err := processor.Process(Payment{Amount: usd("10.00")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	if err := processor.Refund(receipts[0].TransactionID, usd("1.00")); !errors.Is(err, payment.ErrUnknownTransaction) {
+		t.Errorf("expected the oldest charge to be forgotten, got %v", err)
+	}
+	if err := processor.RefundReceipt(receipts[0], usd("1.00")); err != nil {
+		t.Errorf("RefundReceipt of a forgotten charge: %v", err)
+	}
+	if err := processor.Refund(receipts[2].TransactionID, usd("1.00")); err != nil {
+		t.Errorf("Refund of a recent charge: %v", err)
+	}
+}
+
+func TestFailoverProcessor_RefundReceipt(t *testing.T) {
+	stripe := &scriptedGateway{name: "stripe"}
+	processor := NewFailoverProcessor([]Provider{{Name: "stripe", Processor: stripe}})
+
+	// A receipt saved by an earlier process: this processor never saw it.
+	receipt := &Receipt{TransactionID: "stripe-old", Provider: "stripe"}
+	if err := processor.RefundReceipt(receipt, usd("5.00")); err != nil {
+		t.Fatalf("RefundReceipt: %v", err)
+	}
+	if len(stripe.refunds) != 1 || stripe.refunds[0] != "stripe-old" {
+		t.Errorf("refunds = %v", stripe.refunds)
+	}
+	if err := processor.RefundReceipt(&Receipt{Provider: "adyen"}, usd("5.00")); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
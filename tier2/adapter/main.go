@@ -23,6 +23,8 @@ func RunAllExamples() {
 	Example8_PolymorphicLogging()
 	Example9_AdapterBenefits()
 	Example10_WhenNotToUseAdapter()
+	Example11_MultiCurrencyRouting()
+	Example12_FailoverRouting()
 
 	fmt.Println("\n╔═══════════════════════════════════════════════════════════╗")
 	fmt.Println("║                    EXAMPLES COMPLETED SUCCESSFULLY        ║")
@@ -0,0 +1,244 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// database/sql adapter
+// =============================================================================
+
+// queryer is the part of *sql.DB and *sql.Tx the adapters use.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// SQLAdapter adapts a database/sql handle to our Database interface, so any
+// registered driver can stand in for the simulated SQLite and Postgres
+// libraries. Arguments are passed to the driver as query parameters.
+type SQLAdapter struct {
+	db *sql.DB
+}
+
+// NewSQLAdapter wraps an open database handle. Close closes the handle.
+func NewSQLAdapter(db *sql.DB) *SQLAdapter {
+	return &SQLAdapter{db: db}
+}
+
+// OpenSQLAdapter opens a database with a registered driver and checks that
+// it is reachable.
+func OpenSQLAdapter(driverName, dataSourceName string) (*SQLAdapter, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("sql adapter: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sql adapter: %w", err)
+	}
+	return NewSQLAdapter(db), nil
+}
+
+// Query runs a query and returns all rows.
+func (a *SQLAdapter) Query(query string, args ...interface{}) (*QueryResult, error) {
+	return a.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is Query with a context for cancellation and deadlines.
+func (a *SQLAdapter) QueryContext(ctx context.Context, query string, args ...interface{}) (*QueryResult, error) {
+	return runQuery(ctx, a.db, query, args)
+}
+
+// Execute runs a statement and returns the number of affected rows.
+func (a *SQLAdapter) Execute(query string, args ...interface{}) (int64, error) {
+	return a.ExecuteContext(context.Background(), query, args...)
+}
+
+// ExecuteContext is Execute with a context for cancellation and deadlines.
+func (a *SQLAdapter) ExecuteContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return runExec(ctx, a.db, query, args)
+}
+
+// Begin starts a transaction.
+func (a *SQLAdapter) Begin() (*SQLTx, error) {
+	return a.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction. If ctx is cancelled before Commit, the
+// driver rolls the transaction back.
+func (a *SQLAdapter) BeginTx(ctx context.Context, opts *sql.TxOptions) (*SQLTx, error) {
+	tx, err := a.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("sql adapter: %w", err)
+	}
+	return &SQLTx{tx: tx}, nil
+}
+
+// Close closes the underlying database handle.
+func (a *SQLAdapter) Close() error {
+	return a.db.Close()
+}
+
+// SQLTx is a transaction that also satisfies Database, so code written
+// against Database can run inside one unchanged.
+type SQLTx struct {
+	tx *sql.Tx
+}
+
+// Query runs a query inside the transaction.
+func (t *SQLTx) Query(query string, args ...interface{}) (*QueryResult, error) {
+	return t.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is Query with a context for cancellation and deadlines.
+func (t *SQLTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*QueryResult, error) {
+	return runQuery(ctx, t.tx, query, args)
+}
+
+// Execute runs a statement inside the transaction.
+func (t *SQLTx) Execute(query string, args ...interface{}) (int64, error) {
+	return t.ExecuteContext(context.Background(), query, args...)
+}
+
+// ExecuteContext is Execute with a context for cancellation and deadlines.
+func (t *SQLTx) ExecuteContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return runExec(ctx, t.tx, query, args)
+}
+
+// Commit commits the transaction.
+func (t *SQLTx) Commit() error {
+	if err := t.tx.Commit(); err != nil {
+		return fmt.Errorf("sql adapter: %w", err)
+	}
+	return nil
+}
+
+// Rollback aborts the transaction.
+func (t *SQLTx) Rollback() error {
+	if err := t.tx.Rollback(); err != nil {
+		return fmt.Errorf("sql adapter: %w", err)
+	}
+	return nil
+}
+
+// Close rolls the transaction back unless it has already been committed or
+// rolled back, which makes a deferred Close safe after Commit.
+func (t *SQLTx) Close() error {
+	if err := t.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return fmt.Errorf("sql adapter: %w", err)
+	}
+	return nil
+}
+
+func runQuery(ctx context.Context, q queryer, query string, args []interface{}) (*QueryResult, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql adapter: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := scanRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("sql adapter: %w", err)
+	}
+	return result, nil
+}
+
+func runExec(ctx context.Context, q queryer, query string, args []interface{}) (int64, error) {
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("sql adapter: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("sql adapter: %w", err)
+	}
+	return affected, nil
+}
+
+// scanRows reads every row into a QueryResult, keyed by column name.
+func scanRows(rows *sql.Rows) (*QueryResult, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{
+		Rows:    make([]map[string]interface{}, 0),
+		Columns: make([]Column, len(types)),
+	}
+	for i, ct := range types {
+		nullable, _ := ct.Nullable()
+		result.Columns[i] = Column{
+			Name:         ct.Name(),
+			DatabaseType: ct.DatabaseTypeName(),
+			Nullable:     nullable,
+			ScanType:     ct.ScanType(),
+		}
+	}
+
+	values := make([]any, len(types))
+	dest := make([]any, len(types))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(values))
+		for i, col := range result.Columns {
+			row[col.Name] = typedValue(col, values[i])
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.RowCount = len(result.Rows)
+	return result, nil
+}
+
+// typedValue turns the raw bytes some drivers return for every column into
+// the column's Go type. Binary columns stay []byte; other text becomes a
+// string, or a number or bool when the driver reports that scan type.
+func typedValue(col Column, v any) any {
+	b, ok := v.([]byte)
+	if !ok || isBinaryType(col.DatabaseType) {
+		return v
+	}
+	s := string(b)
+	if col.ScanType == nil {
+		return s
+	}
+	switch col.ScanType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(s); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+func isBinaryType(name string) bool {
+	name = strings.ToUpper(name)
+	return strings.Contains(name, "BLOB") || strings.Contains(name, "BINARY") || name == "BYTEA"
+}
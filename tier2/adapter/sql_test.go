@@ -0,0 +1,306 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memDB is an in-process database/sql driver with a single users table. It
+// understands just the statements the tests use and, like the text protocol
+// of many real drivers, returns name and balance as raw bytes.
+type memDB struct {
+	mu     sync.Mutex
+	users  [][]driver.Value
+	nextID int64
+}
+
+var userColumns = []struct {
+	name, dbType string
+	nullable     bool
+	scanType     reflect.Type
+}{
+	{"id", "INTEGER", false, reflect.TypeFor[int64]()},
+	{"name", "VARCHAR", false, reflect.TypeFor[string]()},
+	{"email", "VARCHAR", true, reflect.TypeFor[sql.NullString]()},
+	{"balance", "DECIMAL", false, reflect.TypeFor[float64]()},
+	{"active", "BOOLEAN", false, reflect.TypeFor[bool]()},
+	{"avatar", "BLOB", true, reflect.TypeFor[[]byte]()},
+	{"created", "TIMESTAMP", false, reflect.TypeFor[time.Time]()},
+}
+
+var userCreated = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newMemDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(&memDB{})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (m *memDB) Connect(context.Context) (driver.Conn, error) { return &memConn{db: m}, nil }
+func (m *memDB) Driver() driver.Driver                        { return m }
+func (m *memDB) Open(string) (driver.Conn, error)             { return &memConn{db: m}, nil }
+
+// exec runs one statement against the table.
+func (m *memDB) exec(query string, args []driver.Value) (*memRows, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO users"):
+		if len(args) != 4 {
+			return nil, 0, fmt.Errorf("memdb: insert wants 4 args, got %d", len(args))
+		}
+		m.nextID++
+		balance := []byte(fmt.Sprintf("%.2f", args[2]))
+		m.users = append(m.users, []driver.Value{m.nextID, []byte(args[0].(string)), args[1], balance, args[3], []byte{0xff, 0xd8}, userCreated})
+		return nil, 1, nil
+	case query == "SELECT * FROM users":
+		return &memRows{rows: clone(m.users)}, 0, nil
+	case query == "SELECT * FROM users WHERE id = ?":
+		var rows [][]driver.Value
+		for _, u := range m.users {
+			if u[0] == args[0] {
+				rows = append(rows, u)
+			}
+		}
+		return &memRows{rows: rows}, 0, nil
+	case query == "DELETE FROM users":
+		n := int64(len(m.users))
+		m.users = nil
+		return nil, n, nil
+	}
+	return nil, 0, fmt.Errorf("memdb: unsupported statement %q", query)
+}
+
+func clone(rows [][]driver.Value) [][]driver.Value {
+	out := make([][]driver.Value, len(rows))
+	copy(out, rows)
+	return out
+}
+
+type memConn struct {
+	db       *memDB
+	snapshot [][]driver.Value
+}
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	return &memStmt{conn: c, query: query}, nil
+}
+
+func (c *memConn) Close() error { return nil }
+
+func (c *memConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.snapshot = clone(c.db.users)
+	return c, nil
+}
+
+func (c *memConn) Commit() error {
+	c.snapshot = nil
+	return nil
+}
+
+func (c *memConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.users, c.snapshot = c.snapshot, nil
+	return nil
+}
+
+type memStmt struct {
+	conn  *memConn
+	query string
+}
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return -1 }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	_, n, err := s.conn.db.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, _, err := s.conn.db.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return nil, errors.New("memdb: statement returns no rows")
+	}
+	return rows, nil
+}
+
+type memRows struct {
+	rows [][]driver.Value
+}
+
+func (r *memRows) Columns() []string {
+	names := make([]string, len(userColumns))
+	for i, c := range userColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+func (r *memRows) Close() error { return nil }
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func (r *memRows) ColumnTypeDatabaseTypeName(i int) string { return userColumns[i].dbType }
+func (r *memRows) ColumnTypeNullable(i int) (bool, bool)   { return userColumns[i].nullable, true }
+func (r *memRows) ColumnTypeScanType(i int) reflect.Type   { return userColumns[i].scanType }
+
+// TestSQLAdapter_QueryMapsTypedRows tests column metadata and value conversion.
+func TestSQLAdapter_QueryMapsTypedRows(t *testing.T) {
+	db := NewSQLAdapter(newMemDB(t))
+
+	if n, err := db.Execute("INSERT INTO users (name, email, balance, active) VALUES (?, ?, ?, ?)",
+		"Ada", "ada@example.com", 12.5, true); err != nil || n != 1 {
+		t.Fatalf("Execute = %d, %v", n, err)
+	}
+	if _, err := db.Execute("INSERT INTO users (name, email, balance, active) VALUES (?, ?, ?, ?)",
+		"Grace", nil, 0.0, false); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	result, err := db.Query("SELECT * FROM users WHERE id = ?", 1)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if result.RowCount != 1 {
+		t.Fatalf("RowCount = %d, want 1", result.RowCount)
+	}
+
+	want := map[string]interface{}{
+		"id":      int64(1),
+		"name":    "Ada",
+		"email":   "ada@example.com",
+		"balance": 12.5,
+		"active":  true,
+		"avatar":  []byte{0xff, 0xd8},
+		"created": userCreated,
+	}
+	if !reflect.DeepEqual(result.Rows[0], want) {
+		t.Errorf("row = %#v\nwant  %#v", result.Rows[0], want)
+	}
+
+	if len(result.Columns) != len(userColumns) {
+		t.Fatalf("got %d columns, want %d", len(result.Columns), len(userColumns))
+	}
+	email := result.Columns[2]
+	if email.Name != "email" || email.DatabaseType != "VARCHAR" || !email.Nullable {
+		t.Errorf("email column = %+v", email)
+	}
+	if result.Columns[0].ScanType != reflect.TypeFor[int64]() {
+		t.Errorf("id scan type = %v", result.Columns[0].ScanType)
+	}
+
+	all, err := db.Query("SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if all.RowCount != 2 || all.Rows[1]["email"] != nil {
+		t.Errorf("expected a NULL email on the second row, got %v", all.Rows)
+	}
+}
+
+// TestSQLAdapter_Transactions tests commit, rollback and deferred Close.
+func TestSQLAdapter_Transactions(t *testing.T) {
+	db := NewSQLAdapter(newMemDB(t))
+	insert := func(d Database, name string) {
+		t.Helper()
+		if _, err := d.Execute("INSERT INTO users (name, email, balance, active) VALUES (?, ?, ?, ?)", name, nil, 1.0, true); err != nil {
+			t.Fatalf("insert %s: %v", name, err)
+		}
+	}
+	count := func() int {
+		t.Helper()
+		result, err := db.Query("SELECT * FROM users")
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		return result.RowCount
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	insert(tx, "kept")
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := tx.Close(); err != nil {
+		t.Errorf("Close after Commit: %v", err)
+	}
+
+	tx, err = db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	insert(tx, "discarded")
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if err := tx.Commit(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("Commit after Rollback: expected sql.ErrTxDone, got %v", err)
+	}
+
+	if n := count(); n != 1 {
+		t.Errorf("expected 1 committed row, got %d", n)
+	}
+}
+
+// TestSQLAdapter_Errors tests driver errors and cancelled contexts.
+func TestSQLAdapter_Errors(t *testing.T) {
+	db := NewSQLAdapter(newMemDB(t))
+
+	if _, err := db.Query("SELECT nonsense"); err == nil || !strings.HasPrefix(err.Error(), "sql adapter:") {
+		t.Errorf("expected a wrapped driver error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.QueryContext(ctx, "SELECT * FROM users"); !errors.Is(err, context.Canceled) {
+		t.Errorf("QueryContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := db.ExecuteContext(ctx, "DELETE FROM users"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecuteContext: expected context.Canceled, got %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := db.Query("SELECT * FROM users"); err == nil {
+		t.Error("expected an error after Close")
+	}
+}
+
+// TestSQLAdapter_OpenUnknownDriver tests opening through the driver registry.
+func TestSQLAdapter_OpenUnknownDriver(t *testing.T) {
+	if _, err := OpenSQLAdapter("no-such-driver", ""); err == nil {
+		t.Error("expected an error for an unregistered driver")
+	}
+}
@@ -0,0 +1,444 @@
+package adapter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jumaniyozov/design_patterns/payment"
+)
+
+// =============================================================================
+// Webhook ingestion
+// =============================================================================
+
+var (
+	// ErrInvalidSignature is returned when a webhook's signature does not match.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrTimestampOutOfRange is returned for webhooks signed too far from now,
+	// which is how replayed requests are caught.
+	ErrTimestampOutOfRange = errors.New("webhook: timestamp outside tolerance")
+	// ErrMissingSecret is returned by Verify when the source has no secret.
+	// An HMAC with an empty key is one anybody can compute, so such a source
+	// accepts nothing.
+	ErrMissingSecret = errors.New("webhook: no signing secret configured")
+)
+
+// EventType is a provider-independent payment event type.
+type EventType string
+
+const (
+	EventChargeSucceeded EventType = "charge.succeeded"
+	EventChargeFailed    EventType = "charge.failed"
+	EventChargeRefunded  EventType = "charge.refunded"
+	// EventOther is any provider event without a common equivalent;
+	// PaymentEvent.ProviderType holds the original type.
+	EventOther EventType = "other"
+)
+
+// PaymentEvent is a provider webhook mapped to a common shape.
+type PaymentEvent struct {
+	ID            string
+	Provider      string
+	Type          EventType
+	ProviderType  string
+	TransactionID string
+	Amount        payment.Money
+	OccurredAt    time.Time
+}
+
+// WebhookSource is one provider's webhook format: how requests are signed
+// and how payloads map to PaymentEvent. Sign exists so tests and local tools
+// can produce requests the source will accept.
+type WebhookSource interface {
+	Name() string
+	// Verify checks the signature and returns the time the request was signed.
+	Verify(header http.Header, body []byte) (time.Time, error)
+	Parse(body []byte) (PaymentEvent, error)
+	Sign(body []byte, at time.Time) http.Header
+}
+
+// WebhookOption configures a WebhookHub.
+type WebhookOption func(*WebhookHub)
+
+// WithTolerance sets how far a webhook's signing time may be from now, in
+// either direction. Defaults to 5 minutes.
+func WithTolerance(tolerance time.Duration) WebhookOption {
+	return func(h *WebhookHub) {
+		h.tolerance = tolerance
+	}
+}
+
+// WithDedupWindow sets how long event IDs are remembered. It should exceed
+// the providers' retry period. Defaults to 72 hours.
+func WithDedupWindow(window time.Duration) WebhookOption {
+	return func(h *WebhookHub) {
+		h.dedupWindow = window
+	}
+}
+
+// WithClock sets the time source, for tests.
+func WithClock(now func() time.Time) WebhookOption {
+	return func(h *WebhookHub) {
+		h.now = now
+	}
+}
+
+// maxWebhookBody caps the size of a webhook payload.
+const maxWebhookBody = 1 << 20
+
+// WebhookHub receives webhooks from any number of sources, verifies them,
+// drops events it has already seen and publishes the rest to subscribers.
+type WebhookHub struct {
+	tolerance   time.Duration
+	dedupWindow time.Duration
+	now         func() time.Time
+
+	mu          sync.Mutex
+	seen        map[string]struct{}
+	expiry      []seenEvent // oldest first, so expired keys are at the front
+	subscribers []subscriber
+	nextID      int
+}
+
+// seenEvent records when an event key was first delivered.
+type seenEvent struct {
+	key string
+	at  time.Time
+}
+
+type subscriber struct {
+	id int
+	fn func(PaymentEvent)
+}
+
+// NewWebhookHub creates a hub with no subscribers.
+func NewWebhookHub(opts ...WebhookOption) *WebhookHub {
+	h := &WebhookHub{
+		tolerance:   5 * time.Minute,
+		dedupWindow: 72 * time.Hour,
+		now:         time.Now,
+		seen:        make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Subscribe registers fn to receive every new event, in subscription order,
+// and returns a function that removes it. fn runs on the request goroutine;
+// slow work should be handed off so the provider is answered quickly.
+func (h *WebhookHub) Subscribe(fn func(PaymentEvent)) (unsubscribe func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := h.nextID
+	h.nextID++
+	h.subscribers = append(h.subscribers, subscriber{id: id, fn: fn})
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.subscribers = slices.DeleteFunc(h.subscribers, func(s subscriber) bool { return s.id == id })
+	}
+}
+
+// Handler returns the http.Handler for one source, e.g. mounted at
+// /webhooks/stripe. It answers 401 for bad signatures or timestamps, 400 for
+// payloads it cannot parse, and 200 for new and duplicate events alike so the
+// provider stops retrying. A source without a secret answers 500 to every
+// request, so the provider keeps retrying until it is configured.
+func (h *WebhookHub) Handler(source WebhookSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "cannot read body", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		signedAt, err := source.Verify(r.Header, body)
+		if errors.Is(err, ErrMissingSecret) {
+			http.Error(w, "webhook source not configured", http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if skew := h.now().Sub(signedAt).Abs(); skew > h.tolerance {
+			http.Error(w, ErrTimestampOutOfRange.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		event, err := source.Parse(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		event.Provider = source.Name()
+		h.deliver(event)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// deliver publishes event unless it was seen within the dedup window. Keys
+// are forgotten from the front of the expiry queue, so each call does
+// amortised constant work however many keys the window holds.
+func (h *WebhookHub) deliver(event PaymentEvent) {
+	key := event.Provider + ":" + event.ID
+	now := h.now()
+
+	h.mu.Lock()
+	for len(h.expiry) > 0 && now.Sub(h.expiry[0].at) > h.dedupWindow {
+		delete(h.seen, h.expiry[0].key)
+		h.expiry[0] = seenEvent{}
+		h.expiry = h.expiry[1:]
+	}
+	if _, dup := h.seen[key]; dup {
+		h.mu.Unlock()
+		return
+	}
+	h.seen[key] = struct{}{}
+	h.expiry = append(h.expiry, seenEvent{key: key, at: now})
+	subscribers := slices.Clone(h.subscribers)
+	h.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.fn(event)
+	}
+}
+
+// NewWebhookRequest builds a POST to url carrying body as source would sign
+// it at time at. Tests and local tools use it to replay fake provider events.
+func NewWebhookRequest(source WebhookSource, url string, body []byte, at time.Time) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range source.Sign(body, at) {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func hmacSHA256(secret string, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
+
+// --- Stripe-style webhooks ---
+
+// StripeWebhook verifies Stripe-style webhooks: a Stripe-Signature header of
+// the form "t=<unix>,v1=<hex HMAC-SHA256 of "t.body">". Several v1 entries
+// may be present while a secret is being rotated.
+type StripeWebhook struct {
+	Secret string
+}
+
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object struct {
+			ID       string `json:"id"`
+			Amount   int64  `json:"amount"`
+			Currency string `json:"currency"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+var stripeEventTypes = map[string]EventType{
+	"charge.succeeded": EventChargeSucceeded,
+	"charge.failed":    EventChargeFailed,
+	"charge.refunded":  EventChargeRefunded,
+}
+
+// Name implements WebhookSource.
+func (s StripeWebhook) Name() string { return "stripe" }
+
+// Verify implements WebhookSource.
+func (s StripeWebhook) Verify(header http.Header, body []byte) (time.Time, error) {
+	if s.Secret == "" {
+		return time.Time{}, ErrMissingSecret
+	}
+	var timestamp string
+	var signatures [][]byte
+	for _, field := range strings.Split(header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return time.Time{}, fmt.Errorf("%w: malformed Stripe-Signature header", ErrInvalidSignature)
+	}
+
+	expected := hmacSHA256(s.Secret, []byte(timestamp), []byte("."), body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return time.Unix(unix, 0), nil
+		}
+	}
+	return time.Time{}, ErrInvalidSignature
+}
+
+// Parse implements WebhookSource.
+func (s StripeWebhook) Parse(body []byte) (PaymentEvent, error) {
+	var e stripeEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return PaymentEvent{}, fmt.Errorf("stripe webhook: %w", err)
+	}
+	if e.ID == "" {
+		return PaymentEvent{}, errors.New("stripe webhook: missing event id")
+	}
+
+	event := PaymentEvent{
+		ID:            e.ID,
+		Type:          EventOther,
+		ProviderType:  e.Type,
+		TransactionID: e.Data.Object.ID,
+		OccurredAt:    time.Unix(e.Created, 0),
+	}
+	if t, ok := stripeEventTypes[e.Type]; ok {
+		event.Type = t
+	}
+	if e.Data.Object.Currency != "" {
+		amount, err := payment.NewMoney(e.Data.Object.Amount, e.Data.Object.Currency)
+		if err != nil {
+			return PaymentEvent{}, fmt.Errorf("stripe webhook: %w", err)
+		}
+		event.Amount = amount
+	}
+	return event, nil
+}
+
+// Sign implements WebhookSource.
+func (s StripeWebhook) Sign(body []byte, at time.Time) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	sig := hmacSHA256(s.Secret, []byte(timestamp), []byte("."), body)
+	header := http.Header{}
+	header.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(sig))
+	return header
+}
+
+// --- PayPal-style webhooks ---
+
+// PayPalWebhook verifies PayPal-style webhooks. PayPal signs with a
+// certificate; this model uses a shared secret over the same fields:
+// base64(HMAC-SHA256("<transmission id>|<transmission time>|<body>")).
+type PayPalWebhook struct {
+	Secret string
+}
+
+type paypalEvent struct {
+	ID         string    `json:"id"`
+	EventType  string    `json:"event_type"`
+	CreateTime time.Time `json:"create_time"`
+	Resource   struct {
+		ID     string `json:"id"`
+		Amount struct {
+			Value        string `json:"value"`
+			CurrencyCode string `json:"currency_code"`
+		} `json:"amount"`
+	} `json:"resource"`
+}
+
+var paypalEventTypes = map[string]EventType{
+	"PAYMENT.CAPTURE.COMPLETED": EventChargeSucceeded,
+	"PAYMENT.CAPTURE.DENIED":    EventChargeFailed,
+	"PAYMENT.CAPTURE.REFUNDED":  EventChargeRefunded,
+}
+
+// Name implements WebhookSource.
+func (p PayPalWebhook) Name() string { return "paypal" }
+
+// Verify implements WebhookSource.
+func (p PayPalWebhook) Verify(header http.Header, body []byte) (time.Time, error) {
+	if p.Secret == "" {
+		return time.Time{}, ErrMissingSecret
+	}
+	id := header.Get("Paypal-Transmission-Id")
+	at, err := time.Parse(time.RFC3339, header.Get("Paypal-Transmission-Time"))
+	sig, sigErr := base64.StdEncoding.DecodeString(header.Get("Paypal-Transmission-Sig"))
+	if id == "" || err != nil || sigErr != nil {
+		return time.Time{}, fmt.Errorf("%w: missing or malformed PayPal transmission headers", ErrInvalidSignature)
+	}
+
+	expected := hmacSHA256(p.Secret, []byte(id+"|"+header.Get("Paypal-Transmission-Time")+"|"), body)
+	if !hmac.Equal(sig, expected) {
+		return time.Time{}, ErrInvalidSignature
+	}
+	return at, nil
+}
+
+// Parse implements WebhookSource.
+func (p PayPalWebhook) Parse(body []byte) (PaymentEvent, error) {
+	var e paypalEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return PaymentEvent{}, fmt.Errorf("paypal webhook: %w", err)
+	}
+	if e.ID == "" {
+		return PaymentEvent{}, errors.New("paypal webhook: missing event id")
+	}
+
+	event := PaymentEvent{
+		ID:            e.ID,
+		Type:          EventOther,
+		ProviderType:  e.EventType,
+		TransactionID: e.Resource.ID,
+		OccurredAt:    e.CreateTime,
+	}
+	if t, ok := paypalEventTypes[e.EventType]; ok {
+		event.Type = t
+	}
+	if e.Resource.Amount.CurrencyCode != "" {
+		amount, err := payment.ParseMoney(e.Resource.Amount.Value, e.Resource.Amount.CurrencyCode)
+		if err != nil {
+			return PaymentEvent{}, fmt.Errorf("paypal webhook: %w", err)
+		}
+		event.Amount = amount
+	}
+	return event, nil
+}
+
+// Sign implements WebhookSource. The transmission ID is derived from the
+// signing time, which is unique enough for tests.
+func (p PayPalWebhook) Sign(body []byte, at time.Time) http.Header {
+	id := fmt.Sprintf("tx-%d", at.UnixNano())
+	transmitted := at.UTC().Format(time.RFC3339)
+	sig := hmacSHA256(p.Secret, []byte(id+"|"+transmitted+"|"), body)
+
+	header := http.Header{}
+	header.Set("Paypal-Transmission-Id", id)
+	header.Set("Paypal-Transmission-Time", transmitted)
+	header.Set("Paypal-Transmission-Sig", base64.StdEncoding.EncodeToString(sig))
+	return header
+}
@@ -0,0 +1,250 @@
+package adapter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const stripeChargeSucceeded = `{
+	"id": "evt_1",
+	"type": "charge.succeeded",
+	"created": 1700000000,
+	"data": {"object": {"id": "ch_123", "amount": 1999, "currency": "usd"}}
+}`
+
+const paypalCaptureRefunded = `{
+	"id": "WH-9",
+	"event_type": "PAYMENT.CAPTURE.REFUNDED",
+	"create_time": "2023-11-14T22:13:20Z",
+	"resource": {"id": "CAP-7", "amount": {"value": "12.50", "currency_code": "EUR"}}
+}`
+
+// webhookHarness mounts a hub on a test server and records published events.
+type webhookHarness struct {
+	hub    *WebhookHub
+	server *httptest.Server
+
+	mu     sync.Mutex
+	now    time.Time
+	events []PaymentEvent
+}
+
+func newWebhookHarness(t *testing.T, sources ...WebhookSource) *webhookHarness {
+	t.Helper()
+	h := &webhookHarness{now: time.Unix(1700000000, 0)}
+	h.hub = NewWebhookHub(WithClock(func() time.Time {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.now
+	}))
+	h.hub.Subscribe(func(e PaymentEvent) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.events = append(h.events, e)
+	})
+
+	mux := http.NewServeMux()
+	for _, source := range sources {
+		mux.Handle("/webhooks/"+source.Name(), h.hub.Handler(source))
+	}
+	h.server = httptest.NewServer(mux)
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+// post sends body to source's endpoint, signed by signer at time at.
+func (h *webhookHarness) post(t *testing.T, source, signer WebhookSource, body string, at time.Time) int {
+	t.Helper()
+	req, err := NewWebhookRequest(signer, h.server.URL+"/webhooks/"+source.Name(), []byte(body), at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := h.server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (h *webhookHarness) published() []PaymentEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]PaymentEvent(nil), h.events...)
+}
+
+// TestWebhookHub_MapsProviderEvents tests verification and mapping for both providers.
+func TestWebhookHub_MapsProviderEvents(t *testing.T) {
+	stripe, paypal := StripeWebhook{Secret: "whsec_test"}, PayPalWebhook{Secret: "pp_secret"}
+	h := newWebhookHarness(t, stripe, paypal)
+
+	if code := h.post(t, stripe, stripe, stripeChargeSucceeded, h.now); code != http.StatusOK {
+		t.Fatalf("stripe webhook: status %d", code)
+	}
+	if code := h.post(t, paypal, paypal, paypalCaptureRefunded, h.now); code != http.StatusOK {
+		t.Fatalf("paypal webhook: status %d", code)
+	}
+
+	events := h.published()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	tests := []struct {
+		got                PaymentEvent
+		provider, id, txID string
+		eventType          EventType
+		amount             string
+	}{
+		{events[0], "stripe", "evt_1", "ch_123", EventChargeSucceeded, "19.99 USD"},
+		{events[1], "paypal", "WH-9", "CAP-7", EventChargeRefunded, "12.50 EUR"},
+	}
+	for _, tt := range tests {
+		if tt.got.Provider != tt.provider || tt.got.ID != tt.id || tt.got.TransactionID != tt.txID ||
+			tt.got.Type != tt.eventType || tt.got.Amount.String() != tt.amount {
+			t.Errorf("event = %+v, want %s %s %s %s %s", tt.got, tt.provider, tt.id, tt.txID, tt.eventType, tt.amount)
+		}
+	}
+}
+
+// TestWebhookHub_RejectsBadSignatures tests signature and timestamp checks.
+func TestWebhookHub_RejectsBadSignatures(t *testing.T) {
+	stripe, paypal := StripeWebhook{Secret: "whsec_test"}, PayPalWebhook{Secret: "pp_secret"}
+	h := newWebhookHarness(t, stripe, paypal)
+
+	tests := []struct {
+		name   string
+		source WebhookSource
+		signer WebhookSource
+		at     time.Time
+	}{
+		{"stripe wrong secret", stripe, StripeWebhook{Secret: "other"}, h.now},
+		{"paypal wrong secret", paypal, PayPalWebhook{Secret: "other"}, h.now},
+		{"stripe too old", stripe, stripe, h.now.Add(-6 * time.Minute)},
+		{"paypal from the future", paypal, paypal, h.now.Add(6 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := stripeChargeSucceeded
+			if tt.source == WebhookSource(paypal) {
+				body = paypalCaptureRefunded
+			}
+			if code := h.post(t, tt.source, tt.signer, body, tt.at); code != http.StatusUnauthorized {
+				t.Errorf("expected 401, got %d", code)
+			}
+		})
+	}
+
+	// A body altered after signing no longer matches.
+	req, _ := NewWebhookRequest(stripe, h.server.URL+"/webhooks/stripe", []byte(stripeChargeSucceeded), h.now)
+	req.Body = http.NoBody
+	req.ContentLength = 0
+	resp, err := h.server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("tampered body: expected 401, got %d", resp.StatusCode)
+	}
+
+	if len(h.published()) != 0 {
+		t.Errorf("expected nothing published, got %v", h.published())
+	}
+	if _, err := stripe.Verify(http.Header{}, nil); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for a missing header, got %v", err)
+	}
+}
+
+// TestWebhookHub_RejectsEmptySecret tests that a source without a secret
+// refuses payloads signed with the empty key.
+func TestWebhookHub_RejectsEmptySecret(t *testing.T) {
+	stripe, paypal := StripeWebhook{}, PayPalWebhook{}
+	h := newWebhookHarness(t, stripe, paypal)
+
+	if code := h.post(t, stripe, stripe, stripeChargeSucceeded, h.now); code != http.StatusInternalServerError {
+		t.Errorf("stripe: expected 500, got %d", code)
+	}
+	if code := h.post(t, paypal, paypal, paypalCaptureRefunded, h.now); code != http.StatusInternalServerError {
+		t.Errorf("paypal: expected 500, got %d", code)
+	}
+	if len(h.published()) != 0 {
+		t.Errorf("expected nothing published, got %v", h.published())
+	}
+	for _, source := range []WebhookSource{stripe, paypal} {
+		body := []byte(stripeChargeSucceeded)
+		if _, err := source.Verify(source.Sign(body, h.now), body); !errors.Is(err, ErrMissingSecret) {
+			t.Errorf("%s: expected ErrMissingSecret, got %v", source.Name(), err)
+		}
+	}
+}
+
+// TestWebhookHub_DeduplicatesRetries tests that provider retries are published once.
+func TestWebhookHub_DeduplicatesRetries(t *testing.T) {
+	stripe := StripeWebhook{Secret: "whsec_test"}
+	h := newWebhookHarness(t, stripe)
+
+	for i := range 3 {
+		// Providers re-sign each retry, so only the event ID repeats.
+		if code := h.post(t, stripe, stripe, stripeChargeSucceeded, h.now.Add(time.Duration(i)*time.Second)); code != http.StatusOK {
+			t.Fatalf("attempt %d: status %d", i, code)
+		}
+	}
+	if n := len(h.published()); n != 1 {
+		t.Errorf("expected 1 published event, got %d", n)
+	}
+
+	// After the dedup window the ID is forgotten.
+	h.mu.Lock()
+	h.now = h.now.Add(73 * time.Hour)
+	h.mu.Unlock()
+	h.post(t, stripe, stripe, stripeChargeSucceeded, h.now)
+	if n := len(h.published()); n != 2 {
+		t.Errorf("expected the event again after the dedup window, got %d events", n)
+	}
+
+	// Expired keys are dropped as new events arrive.
+	h.post(t, stripe, stripe, strings.Replace(stripeChargeSucceeded, "evt_1", "evt_2", 1), h.now)
+	h.mu.Lock()
+	h.now = h.now.Add(73 * time.Hour)
+	h.mu.Unlock()
+	h.post(t, stripe, stripe, strings.Replace(stripeChargeSucceeded, "evt_1", "evt_3", 1), h.now)
+	h.hub.mu.Lock()
+	seen, queued := len(h.hub.seen), len(h.hub.expiry)
+	h.hub.mu.Unlock()
+	if seen != 1 || queued != 1 {
+		t.Errorf("expected only the newest key to be kept, got %d seen and %d queued", seen, queued)
+	}
+}
+
+// TestWebhookHub_Subscribers tests unsubscribing and malformed payloads.
+func TestWebhookHub_Subscribers(t *testing.T) {
+	stripe := StripeWebhook{Secret: "whsec_test"}
+	h := newWebhookHarness(t, stripe)
+
+	var extra int
+	unsubscribe := h.hub.Subscribe(func(PaymentEvent) { extra++ })
+	h.post(t, stripe, stripe, stripeChargeSucceeded, h.now)
+	unsubscribe()
+	h.post(t, stripe, stripe, strings.Replace(stripeChargeSucceeded, "evt_1", "evt_2", 1), h.now)
+
+	if extra != 1 || len(h.published()) != 2 {
+		t.Errorf("extra subscriber got %d events, recorder got %d; want 1 and 2", extra, len(h.published()))
+	}
+
+	if code := h.post(t, stripe, stripe, `{"type": "charge.succeeded"}`, h.now); code != http.StatusBadRequest {
+		t.Errorf("event without id: expected 400, got %d", code)
+	}
+	resp, err := h.server.Client().Get(h.server.URL + "/webhooks/stripe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: expected 405, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,379 @@
+// Command proxygen generates interceptable proxies for Go interfaces.
+//
+// For each named interface it writes a struct that implements the interface
+// by describing every call as an intercept.Invocation and running it through
+// an intercept.Chain before calling the wrapped implementation:
+//
+//	//go:generate go run ./cmd/proxygen -type DataService,PaymentService
+//
+// produces InterceptedDataService with NewInterceptedDataService(target,
+// interceptors...), and the same for PaymentService. Interfaces may embed
+// other interfaces declared in the same package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const interceptPath = "github.com/jumaniyozov/design_patterns/tier2/proxy/intercept"
+
+func main() {
+	var (
+		types  = flag.String("type", "", "comma-separated interface names (required)")
+		output = flag.String("output", "intercepted_gen.go", "output file name, relative to -dir")
+		dir    = flag.String("dir", ".", "package directory")
+		prefix = flag.String("prefix", "Intercepted", "prefix for generated type names")
+	)
+	flag.Parse()
+	if *types == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := generate(*dir, strings.Split(*types, ","), *prefix, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "proxygen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *output), src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "proxygen:", err)
+		os.Exit(1)
+	}
+}
+
+// method is one interface method ready to be rendered.
+type method struct {
+	name     string
+	params   []param
+	results  []string // result types, including a trailing error
+	hasError bool     // the last result is error
+	variadic bool
+}
+
+type param struct {
+	name, typ string
+}
+
+// pkg is the parsed package the interfaces come from.
+type pkg struct {
+	name   string
+	fset   *token.FileSet
+	ifaces map[string]*ast.InterfaceType
+	files  map[string]*ast.File // declaring file of each interface
+}
+
+// generate returns the formatted source for proxies of the named interfaces
+// in dir. The file named skip, the previous output, is not parsed.
+func generate(dir string, names []string, prefix, skip string) ([]byte, error) {
+	p, err := parsePackage(dir, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := map[string]string{} // path -> name used in signatures
+	var body bytes.Buffer
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		methods, err := p.methods(name, imports, nil)
+		if err != nil {
+			return nil, err
+		}
+		render(&body, prefix+name, name, methods)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by proxygen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", p.name)
+	paths := []string{interceptPath}
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		if name := imports[path]; name != "" && name != filepath.Base(path) {
+			fmt.Fprintf(&out, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func parsePackage(dir, skip string) (*pkg, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &pkg{
+		fset:   token.NewFileSet(),
+		ifaces: make(map[string]*ast.InterfaceType),
+		files:  make(map[string]*ast.File),
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == skip {
+			continue
+		}
+		file, err := parser.ParseFile(p.fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if p.name == "" {
+			p.name = file.Name.Name
+		} else if file.Name.Name != p.name {
+			continue
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if iface, ok := ts.Type.(*ast.InterfaceType); ok && ts.TypeParams == nil {
+					p.ifaces[ts.Name.Name] = iface
+					p.files[ts.Name.Name] = file
+				}
+			}
+		}
+	}
+	if p.name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return p, nil
+}
+
+// methods lists the methods of interface name, expanding embedded
+// interfaces, and records the imports their signatures need.
+func (p *pkg) methods(name string, imports map[string]string, seen []string) ([]method, error) {
+	iface, ok := p.ifaces[name]
+	if !ok {
+		return nil, fmt.Errorf("interface %s not found in package %s", name, p.name)
+	}
+	if slices.Contains(seen, name) {
+		return nil, fmt.Errorf("interface %s embeds itself", name)
+	}
+	seen = append(seen, name)
+	file := p.files[name]
+
+	var methods []method
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			ident, ok := field.Type.(*ast.Ident)
+			if !ok {
+				return nil, fmt.Errorf("%s: only interfaces from the same package can be embedded, found %s", name, p.expr(field.Type))
+			}
+			embedded, err := p.methods(ident.Name, imports, seen)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, embedded...)
+			continue
+		}
+		if err := p.collectImports(file, fn, imports); err != nil {
+			return nil, err
+		}
+
+		m := method{name: field.Names[0].Name}
+		i := 0
+		for _, f := range fn.Params.List {
+			typ := p.expr(f.Type)
+			if ellipsis, ok := f.Type.(*ast.Ellipsis); ok {
+				m.variadic = true
+				typ = "..." + p.expr(ellipsis.Elt)
+			}
+			if len(f.Names) == 0 {
+				m.params = append(m.params, param{name: paramName("", i), typ: typ})
+				i++
+			}
+			for _, n := range f.Names {
+				m.params = append(m.params, param{name: paramName(n.Name, i), typ: typ})
+				i++
+			}
+		}
+		if fn.Results != nil {
+			for _, f := range fn.Results.List {
+				for range max(len(f.Names), 1) {
+					m.results = append(m.results, p.expr(f.Type))
+				}
+			}
+		}
+		m.hasError = len(m.results) > 0 && m.results[len(m.results)-1] == "error"
+		methods = append(methods, m)
+	}
+
+	for i := range methods {
+		for j := range i {
+			if methods[i].name == methods[j].name {
+				return nil, fmt.Errorf("%s: duplicate method %s", name, methods[i].name)
+			}
+		}
+	}
+	return methods, nil
+}
+
+// paramName keeps the source's parameter name unless it is missing, blank
+// or would clash with a name the generated method uses.
+func paramName(name string, i int) string {
+	switch {
+	case name == "" || name == "_":
+		return fmt.Sprintf("a%d", i)
+	case name == "p" || name == "inv" || name == "err" || name == "intercept" ||
+		(len(name) > 1 && name[0] == 'r' && strings.Trim(name[1:], "0123456789") == ""):
+		return name + "_"
+	}
+	return name
+}
+
+// collectImports records the import of every package referenced in fn.
+func (p *pkg) collectImports(file *ast.File, fn *ast.FuncType, imports map[string]string) error {
+	var err error
+	ast.Inspect(fn, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		path, found := importPath(file, ident.Name)
+		if !found {
+			err = fmt.Errorf("no import for package %s", ident.Name)
+			return false
+		}
+		imports[path] = ident.Name
+		return false
+	})
+	return err
+}
+
+// importPath finds the import that file refers to as name. Unnamed imports
+// are matched on the last path element.
+func importPath(file *ast.File, name string) (string, bool) {
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			if spec.Name.Name == name {
+				return path, true
+			}
+			continue
+		}
+		if filepath.Base(path) == name {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func (p *pkg) expr(e ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, p.fset, e)
+	return buf.String()
+}
+
+// render writes the proxy type for one interface.
+func render(w *bytes.Buffer, typeName, iface string, methods []method) {
+	article := "a"
+	if strings.ContainsRune("AEIOU", rune(iface[0])) {
+		article = "an"
+	}
+	fmt.Fprintf(w, `
+// %[1]s is %[3]s %[2]s that runs every call through interceptors.
+type %[1]s struct {
+	target %[2]s
+	chain  intercept.Chain
+}
+
+// New%[1]s wraps target. Interceptors run in the order given.
+func New%[1]s(target %[2]s, interceptors ...intercept.Interceptor) *%[1]s {
+	return &%[1]s{target: target, chain: interceptors}
+}
+
+var _ %[2]s = (*%[1]s)(nil)
+`, typeName, iface, article)
+
+	for _, m := range methods {
+		var params, args, call []string
+		for i, prm := range m.params {
+			params = append(params, prm.name+" "+prm.typ)
+			args = append(args, prm.name)
+			if m.variadic && i == len(m.params)-1 {
+				call = append(call, prm.name+"...")
+			} else {
+				call = append(call, prm.name)
+			}
+		}
+
+		values := m.results
+		if m.hasError {
+			values = values[:len(values)-1]
+		}
+		var vars []string
+		for i := range values {
+			vars = append(vars, fmt.Sprintf("r%d", i))
+		}
+
+		results := strings.Join(m.results, ", ")
+		if len(m.results) > 1 {
+			results = "(" + results + ")"
+		}
+		fmt.Fprintf(w, "\n// %s calls the target through the interceptors.\n", m.name)
+		fmt.Fprintf(w, "func (p *%s) %s(%s) %s {\n", typeName, m.name, strings.Join(params, ", "), results)
+		if len(args) > 0 {
+			fmt.Fprintf(w, "\tinv := &intercept.Invocation{Method: %q, Args: []any{%s}}\n", m.name, strings.Join(args, ", "))
+		} else {
+			fmt.Fprintf(w, "\tinv := &intercept.Invocation{Method: %q}\n", m.name)
+		}
+		w.WriteString("\tp.chain.Invoke(inv, func() {\n")
+
+		assign := slices.Clone(vars)
+		if m.hasError {
+			assign = append(assign, "err")
+		}
+		targetCall := fmt.Sprintf("p.target.%s(%s)", m.name, strings.Join(call, ", "))
+		if len(assign) > 0 {
+			fmt.Fprintf(w, "\t\t%s := %s\n", strings.Join(assign, ", "), targetCall)
+		} else {
+			fmt.Fprintf(w, "\t\t%s\n", targetCall)
+		}
+		if len(vars) > 0 {
+			fmt.Fprintf(w, "\t\tinv.Results = []any{%s}\n", strings.Join(vars, ", "))
+		}
+		if m.hasError {
+			w.WriteString("\t\tinv.Err = err\n")
+		}
+		w.WriteString("\t})\n")
+
+		if len(m.results) > 0 {
+			var ret []string
+			for i, typ := range values {
+				ret = append(ret, fmt.Sprintf("intercept.Result[%s](inv, %d)", typ, i))
+			}
+			if m.hasError {
+				ret = append(ret, "inv.Err")
+			}
+			fmt.Fprintf(w, "\treturn %s\n", strings.Join(ret, ", "))
+		}
+		w.WriteString("}\n")
+	}
+}
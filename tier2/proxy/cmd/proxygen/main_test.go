@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerate_ProxyPackageUpToDate tests that the checked-in proxies in
+// tier2/proxy match the generator.
+func TestGenerate_ProxyPackageUpToDate(t *testing.T) {
+	const dir, output = "../..", "intercepted_gen.go"
+	want, err := os.ReadFile(filepath.Join(dir, output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(dir, []string{"Image", "Database", "DataService", "PaymentService", "APIService"}, "Intercepted", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; run go generate ./tier2/proxy", output)
+	}
+}
+
+const sampleSource = `package store
+
+import (
+	"context"
+	stdtime "time"
+)
+
+type Closer interface {
+	Close()
+}
+
+type Store interface {
+	Closer
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(context.Context, string, []byte, ...stdtime.Duration) error
+	Len() int
+}
+
+type Bad interface {
+	Get(key string) string
+	Get(key string) string
+}
+`
+
+// TestGenerate_Signatures tests embedded interfaces, imports, unnamed and
+// variadic parameters, and methods without results or errors.
+func TestGenerate_Signatures(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "store.go"), []byte(sampleSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(dir, []string{"Store"}, "Intercepted", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"package store",
+		`stdtime "time"`,
+		`"context"`,
+		"func NewInterceptedStore(target Store, interceptors ...intercept.Interceptor) *InterceptedStore",
+		"func (p *InterceptedStore) Close() {",
+		"\t\tp.target.Close()\n",
+		"func (p *InterceptedStore) Get(ctx context.Context, key string) ([]byte, bool, error) {",
+		"return intercept.Result[[]byte](inv, 0), intercept.Result[bool](inv, 1), inv.Err",
+		"func (p *InterceptedStore) Put(a0 context.Context, a1 string, a2 []byte, a3 ...stdtime.Duration) error {",
+		"err := p.target.Put(a0, a1, a2, a3...)",
+		"return inv.Err",
+		"func (p *InterceptedStore) Len() int {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+
+	for _, tt := range []struct{ name, errText string }{
+		{"Missing", "not found"},
+		{"Bad", "duplicate method Get"},
+	} {
+		if _, err := generate(dir, []string{tt.name}, "Intercepted", ""); err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("generate(%s): expected an error containing %q, got %v", tt.name, tt.errText, err)
+		}
+	}
+}
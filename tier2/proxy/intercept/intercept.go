@@ -0,0 +1,114 @@
+// Package intercept is the runtime half of the proxygen tool. Generated
+// proxies describe each call as an Invocation and pass it through a Chain of
+// Interceptors before and after calling the real object, so cross-cutting
+// behaviour such as logging, timing, caching and rate limiting is written
+// once and plugged into a proxy for any interface.
+package intercept
+
+import "time"
+
+// Invocation describes one method call on a generated proxy.
+type Invocation struct {
+	// Method is the interface method name, such as "GetData".
+	Method string
+	// Args holds the call's arguments in order. A variadic parameter is
+	// passed as a single slice.
+	Args []any
+	// Results holds the method's results, except a trailing error, once the
+	// target has been called or an interceptor has handled the call.
+	Results []any
+	// Err is the trailing error result, or the error an interceptor's
+	// Before returned. For a method without an error result, a rejected call
+	// returns zero values and the error is visible only to interceptors.
+	Err error
+	// Handled is set by an interceptor that filled in Results itself, such
+	// as a cache hit; the target is then not called.
+	Handled bool
+	// Start is when the call entered the chain.
+	Start time.Time
+	// Duration is how long the call took, set before After and OnError.
+	Duration time.Duration
+}
+
+// Result returns inv.Results[i] as a T, or T's zero value if it is missing
+// or nil. Generated proxies use it to return typed results.
+func Result[T any](inv *Invocation, i int) T {
+	var zero T
+	if i >= len(inv.Results) || inv.Results[i] == nil {
+		return zero
+	}
+	return inv.Results[i].(T)
+}
+
+// Interceptor hooks into calls on a generated proxy.
+type Interceptor interface {
+	// Before runs before the target is called. Returning an error rejects
+	// the call; setting inv.Handled skips the target and later interceptors.
+	Before(inv *Invocation) error
+	// After runs when the call succeeded.
+	After(inv *Invocation)
+	// OnError runs instead of After when inv.Err is set.
+	OnError(inv *Invocation)
+}
+
+// Funcs adapts plain functions to Interceptor. Nil fields are skipped.
+type Funcs struct {
+	BeforeFunc  func(inv *Invocation) error
+	AfterFunc   func(inv *Invocation)
+	OnErrorFunc func(inv *Invocation)
+}
+
+// Before calls f.BeforeFunc if set.
+func (f Funcs) Before(inv *Invocation) error {
+	if f.BeforeFunc == nil {
+		return nil
+	}
+	return f.BeforeFunc(inv)
+}
+
+// After calls f.AfterFunc if set.
+func (f Funcs) After(inv *Invocation) {
+	if f.AfterFunc != nil {
+		f.AfterFunc(inv)
+	}
+}
+
+// OnError calls f.OnErrorFunc if set.
+func (f Funcs) OnError(inv *Invocation) {
+	if f.OnErrorFunc != nil {
+		f.OnErrorFunc(inv)
+	}
+}
+
+// Chain runs interceptors in order around a call.
+type Chain []Interceptor
+
+// Invoke runs each interceptor's Before in order, then call unless the
+// invocation was rejected or handled, then After or OnError in reverse order
+// for every interceptor whose Before ran.
+func (c Chain) Invoke(inv *Invocation, call func()) {
+	inv.Start = time.Now()
+	ran := 0
+	for _, interceptor := range c {
+		ran++
+		if err := interceptor.Before(inv); err != nil {
+			inv.Err = err
+			break
+		}
+		if inv.Handled {
+			break
+		}
+	}
+	if inv.Err == nil && !inv.Handled {
+		call()
+	}
+	inv.Duration = time.Since(inv.Start)
+
+	for i := ran - 1; i >= 0; i-- {
+		if inv.Err != nil {
+			c[i].OnError(inv)
+		} else {
+			c[i].After(inv)
+		}
+	}
+}
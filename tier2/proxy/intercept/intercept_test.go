@@ -0,0 +1,149 @@
+package intercept
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/jumaniyozov/design_patterns/cache"
+	"github.com/jumaniyozov/design_patterns/ratelimit"
+)
+
+// recorder is an interceptor that appends its hooks to a shared trace.
+func recorder(name string, trace *[]string, before error) Interceptor {
+	return Funcs{
+		BeforeFunc: func(*Invocation) error {
+			*trace = append(*trace, name+".Before")
+			return before
+		},
+		AfterFunc:   func(*Invocation) { *trace = append(*trace, name+".After") },
+		OnErrorFunc: func(*Invocation) { *trace = append(*trace, name+".OnError") },
+	}
+}
+
+// TestChain_Invoke tests hook order, rejection and short-circuiting.
+func TestChain_Invoke(t *testing.T) {
+	rejected := errors.New("rejected")
+	tests := []struct {
+		name   string
+		chain  func(trace *[]string) Chain
+		called bool
+		want   string
+	}{
+		{
+			name: "success",
+			chain: func(trace *[]string) Chain {
+				return Chain{recorder("a", trace, nil), recorder("b", trace, nil)}
+			},
+			called: true,
+			want:   "a.Before b.Before call b.After a.After",
+		},
+		{
+			name: "rejected by the second interceptor",
+			chain: func(trace *[]string) Chain {
+				return Chain{recorder("a", trace, nil), recorder("b", trace, rejected), recorder("c", trace, nil)}
+			},
+			want: "a.Before b.Before b.OnError a.OnError",
+		},
+		{
+			name: "handled by the first interceptor",
+			chain: func(trace *[]string) Chain {
+				handler := Funcs{BeforeFunc: func(inv *Invocation) error {
+					*trace = append(*trace, "cache.Before")
+					inv.Results, inv.Handled = []any{"cached"}, true
+					return nil
+				}}
+				return Chain{handler, recorder("b", trace, nil)}
+			},
+			want: "cache.Before",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trace []string
+			inv := &Invocation{Method: "Get"}
+			called := false
+			tt.chain(&trace).Invoke(inv, func() {
+				called = true
+				trace = append(trace, "call")
+			})
+			if called != tt.called {
+				t.Errorf("called = %v, want %v", called, tt.called)
+			}
+			if got := strings.Join(trace, " "); got != tt.want {
+				t.Errorf("trace = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCaching tests that repeated calls are answered from the cache.
+func TestCaching(t *testing.T) {
+	chain := Chain{Caching(cache.New[string, []any](), "Get")}
+	calls := 0
+	invoke := func(method string, arg any) *Invocation {
+		inv := &Invocation{Method: method, Args: []any{arg}}
+		chain.Invoke(inv, func() {
+			calls++
+			inv.Results = []any{calls}
+		})
+		return inv
+	}
+
+	invoke("Get", "a")
+	if inv := invoke("Get", "a"); !inv.Handled || Result[int](inv, 0) != 1 {
+		t.Errorf("expected a cached result of 1, got %v (handled %v)", inv.Results, inv.Handled)
+	}
+	invoke("Get", "b")
+	invoke("Put", "a")
+	invoke("Put", "a")
+	if calls != 4 {
+		t.Errorf("expected 4 target calls, got %d", calls)
+	}
+}
+
+// TestRateLimit tests that calls beyond the limit are rejected, per method
+// with ByMethod.
+func TestRateLimit(t *testing.T) {
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerMinute(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := Chain{RateLimit(limiter, ByMethod)}
+	var errs int
+	for _, method := range []string{"Get", "Get", "Get", "Put"} {
+		inv := &Invocation{Method: method}
+		chain.Invoke(inv, func() {})
+		var exceeded *ratelimit.ExceededError
+		if errors.Is(inv.Err, ErrRateLimited) && errors.As(inv.Err, &exceeded) && exceeded.RetryAfter > 0 {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Errorf("expected 1 rejected call, got %d", errs)
+	}
+}
+
+// TestTimingsAndLogging tests the observing interceptors.
+func TestTimingsAndLogging(t *testing.T) {
+	var buf bytes.Buffer
+	timings := NewTimings()
+	chain := Chain{Logging(log.New(&buf, "", 0)), timings}
+
+	chain.Invoke(&Invocation{Method: "Get", Args: []any{"k", 1}}, func() {})
+	failed := &Invocation{Method: "Get"}
+	chain.Invoke(failed, func() { failed.Err = errors.New("boom") })
+
+	got := timings.Snapshot()["Get"]
+	if got.Calls != 2 || got.Errors != 1 {
+		t.Errorf("timings = %+v, want 2 calls and 1 error", got)
+	}
+	out := buf.String()
+	for _, want := range []string{`→ Get("k", 1)`, "← Get ok", "← Get failed", "boom"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package intercept
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jumaniyozov/design_patterns/cache"
+	"github.com/jumaniyozov/design_patterns/ratelimit"
+)
+
+// ErrRateLimited matches the errors of calls rejected by RateLimit.
+var ErrRateLimited = ratelimit.ErrLimited
+
+// Logging returns an interceptor that logs each call, its duration and any
+// error to logger.
+func Logging(logger *log.Logger) Interceptor {
+	return Funcs{
+		BeforeFunc: func(inv *Invocation) error {
+			logger.Printf("→ %s(%s)", inv.Method, formatArgs(inv.Args))
+			return nil
+		},
+		AfterFunc: func(inv *Invocation) {
+			logger.Printf("← %s ok in %v", inv.Method, inv.Duration)
+		},
+		OnErrorFunc: func(inv *Invocation) {
+			logger.Printf("← %s failed in %v: %v", inv.Method, inv.Duration, inv.Err)
+		},
+	}
+}
+
+// MethodTiming summarises the calls to one method.
+type MethodTiming struct {
+	Calls  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Average returns the mean call duration, or 0 before any call.
+func (m MethodTiming) Average() time.Duration {
+	if m.Calls == 0 {
+		return 0
+	}
+	return m.Total / time.Duration(m.Calls)
+}
+
+// Timings is an interceptor that records call counts and durations per method.
+type Timings struct {
+	mu      sync.Mutex
+	methods map[string]MethodTiming
+}
+
+// NewTimings creates an empty Timings.
+func NewTimings() *Timings {
+	return &Timings{methods: make(map[string]MethodTiming)}
+}
+
+// Before does nothing; the chain measures the duration.
+func (t *Timings) Before(*Invocation) error { return nil }
+
+// After records a successful call.
+func (t *Timings) After(inv *Invocation) { t.record(inv, false) }
+
+// OnError records a failed call.
+func (t *Timings) OnError(inv *Invocation) { t.record(inv, true) }
+
+func (t *Timings) record(inv *Invocation, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := t.methods[inv.Method]
+	m.Calls++
+	if failed {
+		m.Errors++
+	}
+	m.Total += inv.Duration
+	m.Max = max(m.Max, inv.Duration)
+	t.methods[inv.Method] = m
+}
+
+// Snapshot returns a copy of the timings by method name.
+func (t *Timings) Snapshot() map[string]MethodTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]MethodTiming, len(t.methods))
+	for name, m := range t.methods {
+		out[name] = m
+	}
+	return out
+}
+
+// Caching returns an interceptor that answers repeated calls from c. The key
+// is the method name and its formatted arguments, so arguments should print
+// distinctly. Only successful results are stored. If methods are given,
+// only those are cached.
+func Caching(c *cache.Cache[string, []any], methods ...string) Interceptor {
+	cacheable := func(inv *Invocation) bool {
+		return len(methods) == 0 || slices.Contains(methods, inv.Method)
+	}
+	key := func(inv *Invocation) string {
+		return inv.Method + "(" + formatArgs(inv.Args) + ")"
+	}
+	return Funcs{
+		BeforeFunc: func(inv *Invocation) error {
+			if !cacheable(inv) {
+				return nil
+			}
+			if results, ok := c.Get(key(inv)); ok {
+				inv.Results = slices.Clone(results)
+				inv.Handled = true
+			}
+			return nil
+		},
+		AfterFunc: func(inv *Invocation) {
+			if cacheable(inv) && !inv.Handled {
+				c.Set(key(inv), slices.Clone(inv.Results))
+			}
+		},
+	}
+}
+
+// RateLimit returns an interceptor that asks limiter for each call under
+// the key that key returns, rejecting denied calls with an error matching
+// ErrRateLimited that carries the limiter's RetryAfter. A nil key limits all
+// calls together.
+func RateLimit(limiter ratelimit.Limiter, key func(inv *Invocation) string) Interceptor {
+	if key == nil {
+		key = func(*Invocation) string { return "" }
+	}
+	return Funcs{
+		BeforeFunc: func(inv *Invocation) error {
+			d, err := ratelimit.Allow(context.Background(), limiter, key(inv))
+			if err != nil {
+				return fmt.Errorf("%s: %w", inv.Method, err)
+			}
+			if err := d.Err(); err != nil {
+				return fmt.Errorf("%s: %w", inv.Method, err)
+			}
+			return nil
+		},
+	}
+}
+
+// ByMethod is a RateLimit key giving each method its own limit.
+func ByMethod(inv *Invocation) string { return inv.Method }
+
+func formatArgs(args []any) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("%#v", arg)
+	}
+	return strings.Join(parts, ", ")
+}
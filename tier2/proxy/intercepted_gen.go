@@ -0,0 +1,158 @@
+// Code generated by proxygen; DO NOT EDIT.
+
+package proxy
+
+import (
+	"github.com/jumaniyozov/design_patterns/tier2/proxy/intercept"
+)
+
+// InterceptedImage is an Image that runs every call through interceptors.
+type InterceptedImage struct {
+	target Image
+	chain  intercept.Chain
+}
+
+// NewInterceptedImage wraps target. Interceptors run in the order given.
+func NewInterceptedImage(target Image, interceptors ...intercept.Interceptor) *InterceptedImage {
+	return &InterceptedImage{target: target, chain: interceptors}
+}
+
+var _ Image = (*InterceptedImage)(nil)
+
+// Display calls the target through the interceptors.
+func (p *InterceptedImage) Display() string {
+	inv := &intercept.Invocation{Method: "Display"}
+	p.chain.Invoke(inv, func() {
+		r0 := p.target.Display()
+		inv.Results = []any{r0}
+	})
+	return intercept.Result[string](inv, 0)
+}
+
+// GetSize calls the target through the interceptors.
+func (p *InterceptedImage) GetSize() int {
+	inv := &intercept.Invocation{Method: "GetSize"}
+	p.chain.Invoke(inv, func() {
+		r0 := p.target.GetSize()
+		inv.Results = []any{r0}
+	})
+	return intercept.Result[int](inv, 0)
+}
+
+// InterceptedDatabase is a Database that runs every call through interceptors.
+type InterceptedDatabase struct {
+	target Database
+	chain  intercept.Chain
+}
+
+// NewInterceptedDatabase wraps target. Interceptors run in the order given.
+func NewInterceptedDatabase(target Database, interceptors ...intercept.Interceptor) *InterceptedDatabase {
+	return &InterceptedDatabase{target: target, chain: interceptors}
+}
+
+var _ Database = (*InterceptedDatabase)(nil)
+
+// Query calls the target through the interceptors.
+func (p *InterceptedDatabase) Query(sql string) (string, error) {
+	inv := &intercept.Invocation{Method: "Query", Args: []any{sql}}
+	p.chain.Invoke(inv, func() {
+		r0, err := p.target.Query(sql)
+		inv.Results = []any{r0}
+		inv.Err = err
+	})
+	return intercept.Result[string](inv, 0), inv.Err
+}
+
+// Execute calls the target through the interceptors.
+func (p *InterceptedDatabase) Execute(sql string) (int, error) {
+	inv := &intercept.Invocation{Method: "Execute", Args: []any{sql}}
+	p.chain.Invoke(inv, func() {
+		r0, err := p.target.Execute(sql)
+		inv.Results = []any{r0}
+		inv.Err = err
+	})
+	return intercept.Result[int](inv, 0), inv.Err
+}
+
+// InterceptedDataService is a DataService that runs every call through interceptors.
+type InterceptedDataService struct {
+	target DataService
+	chain  intercept.Chain
+}
+
+// NewInterceptedDataService wraps target. Interceptors run in the order given.
+func NewInterceptedDataService(target DataService, interceptors ...intercept.Interceptor) *InterceptedDataService {
+	return &InterceptedDataService{target: target, chain: interceptors}
+}
+
+var _ DataService = (*InterceptedDataService)(nil)
+
+// GetData calls the target through the interceptors.
+func (p *InterceptedDataService) GetData(key string) (string, error) {
+	inv := &intercept.Invocation{Method: "GetData", Args: []any{key}}
+	p.chain.Invoke(inv, func() {
+		r0, err := p.target.GetData(key)
+		inv.Results = []any{r0}
+		inv.Err = err
+	})
+	return intercept.Result[string](inv, 0), inv.Err
+}
+
+// ComputeExpensive calls the target through the interceptors.
+func (p *InterceptedDataService) ComputeExpensive(input int) (int, error) {
+	inv := &intercept.Invocation{Method: "ComputeExpensive", Args: []any{input}}
+	p.chain.Invoke(inv, func() {
+		r0, err := p.target.ComputeExpensive(input)
+		inv.Results = []any{r0}
+		inv.Err = err
+	})
+	return intercept.Result[int](inv, 0), inv.Err
+}
+
+// InterceptedPaymentService is a PaymentService that runs every call through interceptors.
+type InterceptedPaymentService struct {
+	target PaymentService
+	chain  intercept.Chain
+}
+
+// NewInterceptedPaymentService wraps target. Interceptors run in the order given.
+func NewInterceptedPaymentService(target PaymentService, interceptors ...intercept.Interceptor) *InterceptedPaymentService {
+	return &InterceptedPaymentService{target: target, chain: interceptors}
+}
+
+var _ PaymentService = (*InterceptedPaymentService)(nil)
+
+// ProcessPayment calls the target through the interceptors.
+func (p *InterceptedPaymentService) ProcessPayment(amount float64, customer string) (string, error) {
+	inv := &intercept.Invocation{Method: "ProcessPayment", Args: []any{amount, customer}}
+	p.chain.Invoke(inv, func() {
+		r0, err := p.target.ProcessPayment(amount, customer)
+		inv.Results = []any{r0}
+		inv.Err = err
+	})
+	return intercept.Result[string](inv, 0), inv.Err
+}
+
+// InterceptedAPIService is an APIService that runs every call through interceptors.
+type InterceptedAPIService struct {
+	target APIService
+	chain  intercept.Chain
+}
+
+// NewInterceptedAPIService wraps target. Interceptors run in the order given.
+func NewInterceptedAPIService(target APIService, interceptors ...intercept.Interceptor) *InterceptedAPIService {
+	return &InterceptedAPIService{target: target, chain: interceptors}
+}
+
+var _ APIService = (*InterceptedAPIService)(nil)
+
+// MakeRequest calls the target through the interceptors.
+func (p *InterceptedAPIService) MakeRequest(endpoint string) (string, error) {
+	inv := &intercept.Invocation{Method: "MakeRequest", Args: []any{endpoint}}
+	p.chain.Invoke(inv, func() {
+		r0, err := p.target.MakeRequest(endpoint)
+		inv.Results = []any{r0}
+		inv.Err = err
+	})
+	return intercept.Result[string](inv, 0), inv.Err
+}
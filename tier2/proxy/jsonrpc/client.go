@@ -0,0 +1,95 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Client calls methods on a Server at one URL. It is safe for concurrent
+// use.
+type Client struct {
+	url    string
+	opts   options
+	nextID atomic.Uint64
+}
+
+// NewClient creates a client for the server at url.
+func NewClient(url string, opts ...Option) *Client {
+	return &Client{url: url, opts: newOptions(opts)}
+}
+
+// Call calls method with params and decodes its result into result, which
+// may be nil to discard it. Errors from the method are *Error values that
+// unwrap to the Go errors registered with WithErrors; transport failures
+// and ctx ending are returned as they are.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	if c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+
+	req := request{Version: Version, Method: method, ID: c.nextID.Add(1)}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("jsonrpc: %s: %w", method, err)
+		}
+		req.Params = raw
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: %s: %w", method, err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("jsonrpc: %s: %w", method, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if deadline, ok := ctx.Deadline(); ok {
+		// Round up so a short remaining time is not sent as zero, which
+		// would mean no limit.
+		ms := (time.Until(deadline) + time.Millisecond - 1) / time.Millisecond
+		httpReq.Header.Set(TimeoutHeader, strconv.FormatInt(int64(max(ms, 1)), 10))
+	}
+
+	httpResp, err := c.opts.httpClient.Do(httpReq)
+	if err != nil {
+		// Return ctx's own error, so callers can test for it with errors.Is.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("jsonrpc: %s: %w", method, ctxErr)
+		}
+		return fmt.Errorf("jsonrpc: %s: %w", method, err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(httpResp.Body, 512))
+		return fmt.Errorf("jsonrpc: %s: HTTP %s: %s", method, httpResp.Status, bytes.TrimSpace(msg))
+	}
+
+	var resp response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("jsonrpc: %s: decoding response: %w", method, err)
+	}
+	if resp.Error != nil {
+		return c.opts.fromError(resp.Error)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("jsonrpc: %s: response id %d does not match request id %d", method, resp.ID, req.ID)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("jsonrpc: %s: decoding result: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package jsonrpc is the transport for the remote proxies in tier2/proxy: a
+// small JSON-RPC 2.0 server that exposes Go functions over HTTP, and a
+// client that calls them. The client's deadline travels with each request,
+// so the server gives up when the caller does, and errors the server
+// returns are mapped back to the same Go errors on the client.
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Version is the JSON-RPC protocol version.
+const Version = "2.0"
+
+// TimeoutHeader carries the caller's remaining time to the server, in
+// milliseconds.
+const TimeoutHeader = "Rpc-Timeout"
+
+// Error codes defined by JSON-RPC 2.0, and two of this package's own for
+// calls cut short by their context.
+const (
+	CodeParseError       = -32700
+	CodeInvalidRequest   = -32600
+	CodeMethodNotFound   = -32601
+	CodeInvalidParams    = -32602
+	CodeInternalError    = -32603
+	CodeServerError      = -32000
+	CodeDeadlineExceeded = -32001
+	CodeCanceled         = -32002
+)
+
+// internalErrorMessage is all a client is told about an error the server
+// has no code for; the details go to the server's error log.
+const internalErrorMessage = "internal error"
+
+// ErrMethodNotFound matches errors for calls to methods the server does not
+// have.
+var ErrMethodNotFound = errors.New("method not found")
+
+type request struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      uint64          `json:"id"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      uint64          `json:"id"`
+}
+
+// Error is a JSON-RPC error object. On the client it unwraps to the Go
+// error its code was registered for with WithErrors, or to the context or
+// method-not-found errors for the codes this package defines.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (code %d)", e.Message, e.Code)
+}
+
+func (e *Error) Unwrap() error { return e.err }
+
+type options struct {
+	errors     map[int]error
+	timeout    time.Duration
+	httpClient *http.Client
+	errorLog   *log.Logger
+}
+
+// Option configures a Server or Client.
+type Option func(*options)
+
+// WithErrors maps application error codes to Go errors. A server sends a
+// method's error with the code of the registered error it matches under
+// errors.Is, or CodeServerError, so registered errors should not wrap one
+// another. A client turns the code back into an *Error that unwraps to the
+// registered error. Give both sides the same map. Codes from -32768 to
+// -32000 are reserved by JSON-RPC.
+func WithErrors(codes map[int]error) Option {
+	return func(o *options) {
+		o.errors = codes
+	}
+}
+
+// WithTimeout bounds each call. On a client it is the default for calls
+// whose context has no earlier deadline; on a server it is the longest a
+// call may run whatever the client asks for. Zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithHTTPClient sets the client's HTTP client; http.DefaultClient is used
+// otherwise. It has no effect on a server.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithErrorLog sets where a server logs the errors and panics it does not
+// send to clients; log.Default() is used otherwise. It has no effect on a
+// client.
+func WithErrorLog(l *log.Logger) Option {
+	return func(o *options) {
+		o.errorLog = l
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{httpClient: http.DefaultClient, errorLog: log.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// toError builds the error object a server sends for err from method. An
+// error without a registered code could carry internal detail, so the client
+// gets a generic message and the error is logged instead.
+func (o *options) toError(method string, err error) *Error {
+	var rpcErr *Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return &Error{Code: rpcErr.Code, Message: rpcErr.Message}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeDeadlineExceeded, Message: err.Error()}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled, Message: err.Error()}
+	}
+	for code, target := range o.errors {
+		if errors.Is(err, target) {
+			return &Error{Code: code, Message: err.Error()}
+		}
+	}
+	o.errorLog.Printf("jsonrpc: %s: %v", method, err)
+	return &Error{Code: CodeServerError, Message: internalErrorMessage}
+}
+
+// fromError attaches the Go error a client maps e's code to.
+func (o *options) fromError(e *Error) *Error {
+	switch e.Code {
+	case CodeDeadlineExceeded:
+		e.err = context.DeadlineExceeded
+	case CodeCanceled:
+		e.err = context.Canceled
+	case CodeMethodNotFound:
+		e.err = ErrMethodNotFound
+	default:
+		e.err = o.errors[e.Code]
+	}
+	return e
+}
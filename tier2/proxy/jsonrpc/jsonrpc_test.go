@@ -0,0 +1,198 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+type addParams struct {
+	A, B int
+}
+
+func newTestServer(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+	s := NewServer(append([]Option{WithErrorLog(log.New(io.Discard, "", 0))}, opts...)...)
+	Register(s, "add", func(_ context.Context, p addParams) (int, error) {
+		return p.A + p.B, nil
+	})
+	Register(s, "lookup", func(_ context.Context, key string) (string, error) {
+		return "", errors.Join(errors.New("looking up "+key), errNotFound)
+	})
+	Register(s, "wait", func(ctx context.Context, _ struct{}) (struct{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return struct{}{}, errors.New("no deadline")
+		}
+		<-ctx.Done()
+		return struct{}{}, ctx.Err()
+	})
+	Register(s, "panic", func(context.Context, struct{}) (struct{}, error) {
+		panic("boom")
+	})
+	Register(s, "fail", func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, errors.New("dial db.internal:5432: connection refused")
+	})
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestCall tests a round trip and each way a call can fail.
+func TestCall(t *testing.T) {
+	codes := map[int]error{404: errNotFound}
+	ts := newTestServer(t, WithErrors(codes))
+	client := NewClient(ts.URL, WithErrors(codes))
+	ctx := context.Background()
+
+	var sum int
+	if err := client.Call(ctx, "add", addParams{A: 2, B: 3}, &sum); err != nil || sum != 5 {
+		t.Errorf("add = %d, %v; want 5", sum, err)
+	}
+
+	tests := []struct {
+		method string
+		params any
+		is     error
+		code   int
+	}{
+		{"lookup", "k", errNotFound, 404},
+		{"missing", nil, ErrMethodNotFound, CodeMethodNotFound},
+		{"add", "not an object", nil, CodeInvalidParams},
+		{"panic", nil, nil, CodeInternalError},
+	}
+	for _, tt := range tests {
+		err := client.Call(ctx, tt.method, tt.params, nil)
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != tt.code {
+			t.Errorf("%s: expected an *Error with code %d, got %v", tt.method, tt.code, err)
+			continue
+		}
+		if tt.is != nil && !errors.Is(err, tt.is) {
+			t.Errorf("%s: expected %v to match %v", tt.method, err, tt.is)
+		}
+	}
+
+	// Unregistered errors and panics reach the client as a generic message.
+	for _, method := range []string{"panic", "fail"} {
+		err := client.Call(ctx, method, nil, nil)
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Message != internalErrorMessage {
+			t.Errorf("%s: expected a generic message, got %v", method, err)
+		}
+	}
+
+	// A client without the error map still sees the code and message.
+	err := NewClient(ts.URL).Call(ctx, "lookup", "k", nil)
+	if errors.Is(err, errNotFound) || !strings.Contains(err.Error(), "code 404") {
+		t.Errorf("unmapped error = %v", err)
+	}
+}
+
+// lockedBuffer is a log destination that is safe to read while handlers
+// write to it.
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// TestServer_ErrorLog tests that the details kept from clients are logged.
+func TestServer_ErrorLog(t *testing.T) {
+	var buf lockedBuffer
+	ts := newTestServer(t, WithErrorLog(log.New(&buf, "", 0)))
+	client := NewClient(ts.URL)
+	client.Call(context.Background(), "panic", nil, nil)
+	client.Call(context.Background(), "fail", nil, nil)
+
+	got := buf.String()
+	for _, want := range []string{"jsonrpc: panic: panic: boom", "runtime/debug.Stack", "jsonrpc: fail: dial db.internal:5432"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected the error log to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+// TestCall_Deadlines tests that the client's deadline reaches the server,
+// and that the server's own timeout caps it.
+func TestCall_Deadlines(t *testing.T) {
+	ts := newTestServer(t)
+	client := NewClient(ts.URL, WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	err := client.Call(context.Background(), "wait", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("call took %v, want about 50ms", elapsed)
+	}
+
+	// The server gives up first and reports the deadline as an *Error.
+	capped := newTestServer(t, WithTimeout(20*time.Millisecond))
+	err = NewClient(capped.URL).Call(context.Background(), "wait", nil, nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeDeadlineExceeded || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline *Error from the server, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.Call(ctx, "add", addParams{}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// TestServer_BadRequests tests requests that are not JSON-RPC calls.
+func TestServer_BadRequests(t *testing.T) {
+	ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", resp.StatusCode)
+	}
+
+	for body, want := range map[string]string{
+		"{":                        `"code":-32700`,
+		`{"jsonrpc":"1.0","id":1}`: `"code":-32600`,
+		`{"jsonrpc":"2.0","id":7}`: `"code":-32600`,
+		`{"jsonrpc":"2.0","method":"add","params":{"A":1,"B":1},"id":9}`: `"result":2`,
+	} {
+		resp, err := http.Post(ts.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf strings.Builder
+		_, err = io.Copy(&buf, resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s: response %s does not contain %s", body, buf.String(), want)
+		}
+	}
+}
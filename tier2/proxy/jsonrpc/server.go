@@ -0,0 +1,140 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRequestBytes caps the size of a request body.
+const maxRequestBytes = 1 << 20
+
+// Handler runs one method call. params is the request's raw params.
+type Handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server is an http.Handler that dispatches JSON-RPC calls to registered
+// methods. Each request carries one call; batches are not supported.
+type Server struct {
+	opts options
+
+	mu      sync.RWMutex
+	methods map[string]Handler
+}
+
+// NewServer creates a server with no methods.
+func NewServer(opts ...Option) *Server {
+	return &Server{opts: newOptions(opts), methods: make(map[string]Handler)}
+}
+
+// Handle registers h under method, replacing any previous handler.
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = h
+}
+
+// Register registers fn under method, decoding params into a P.
+func Register[P, R any](s *Server, method string, fn func(ctx context.Context, params P) (R, error)) {
+	s.Handle(method, func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var params P
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+			}
+		}
+		return fn(ctx, params)
+	})
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		s.reply(w, response{Error: &Error{Code: CodeParseError, Message: err.Error()}})
+		return
+	}
+	resp := response{ID: req.ID}
+	if req.Version != Version || req.Method == "" {
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "invalid request"}
+		s.reply(w, resp)
+		return
+	}
+	s.mu.RLock()
+	h, ok := s.methods[req.Method]
+	s.mu.RUnlock()
+	if !ok {
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+		s.reply(w, resp)
+		return
+	}
+
+	ctx, cancel := s.callContext(r)
+	defer cancel()
+	result, err := s.call(ctx, req.Method, h, req.Params)
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Error = s.opts.toError(req.Method, err)
+	}
+	s.reply(w, resp)
+}
+
+// callContext derives the call's context from the request's, bounded by the
+// client's TimeoutHeader and the server's own timeout.
+func (s *Server) callContext(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout := s.opts.timeout
+	if ms, err := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64); err == nil && ms > 0 {
+		if d := time.Duration(ms) * time.Millisecond; timeout == 0 || d < timeout {
+			timeout = d
+		}
+	}
+	if timeout == 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// call runs h, returning early if ctx ends first. A handler that ignores
+// ctx keeps running in the background, but its result is dropped. A panic
+// is logged with its stack and reported to the client as an internal error.
+func (s *Server) call(ctx context.Context, method string, h Handler, params json.RawMessage) (any, error) {
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				s.opts.errorLog.Printf("jsonrpc: %s: panic: %v\n%s", method, p, debug.Stack())
+				done <- outcome{err: &Error{Code: CodeInternalError, Message: internalErrorMessage}}
+			}
+		}()
+		result, err := h(ctx, params)
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Server) reply(w http.ResponseWriter, resp response) {
+	resp.Version = Version
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
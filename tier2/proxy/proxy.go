@@ -10,12 +10,23 @@
 package proxy
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/cache"
+	"github.com/jumaniyozov/design_patterns/policy"
+	"github.com/jumaniyozov/design_patterns/ratelimit"
+	"github.com/jumaniyozov/design_patterns/tier2/proxy/jsonrpc"
 )
 
+// Interceptable proxies for this package's interfaces; see cmd/proxygen.
+//go:generate go run ./cmd/proxygen -type Image,Database,DataService,PaymentService,APIService
+
 // =============================================================================
 // Example 1: Lazy Loading Image Proxy
 // =============================================================================
@@ -141,6 +152,8 @@ func (d *RealDatabase) Execute(sql string) (int, error) {
 type User struct {
 	Name        string
 	Permissions []string
+	// Roles are the user's roles in the proxy's policy.
+	Roles []string
 }
 
 // HasPermission checks if user has a specific permission.
@@ -153,19 +166,67 @@ func (u *User) HasPermission(permission string) bool {
 	return false
 }
 
+// DefaultDatabasePolicy has one role per legacy permission, so a User with
+// Permissions "read" and "write" keeps its access under a policy engine:
+// read allows SELECT on any table, and write allows changing rows in any
+// table.
+func DefaultDatabasePolicy() policy.Document {
+	return policy.Document{Roles: map[string]policy.RoleSpec{
+		"read": {Allow: []policy.RuleSpec{
+			{Actions: []string{"SELECT"}, Resources: []string{"*"}},
+		}},
+		"write": {Allow: []policy.RuleSpec{
+			{Actions: []string{"INSERT", "UPDATE", "DELETE"}, Resources: []string{"*"}},
+		}},
+	}}
+}
+
 // DatabaseProxy provides access control to the database.
 type DatabaseProxy struct {
-	user *User
-	db   *RealDatabase
-	mu   sync.Mutex
+	user   *User
+	db     *RealDatabase
+	policy *policy.Engine
+	mu     sync.Mutex
 }
 
-// NewDatabaseProxy creates a new database proxy with access control.
+// NewDatabaseProxy creates a new database proxy with access control under
+// DefaultDatabasePolicy.
 func NewDatabaseProxy(user *User, dbName string) *DatabaseProxy {
+	engine, err := policy.New(DefaultDatabasePolicy())
+	if err != nil {
+		panic(err)
+	}
+	return NewDatabaseProxyWithPolicy(user, dbName, engine)
+}
+
+// NewDatabaseProxyWithPolicy creates a database proxy that asks engine
+// whether the user may run each statement's verb on every table it names.
+// The user's Permissions and Roles are both treated as roles.
+func NewDatabaseProxyWithPolicy(user *User, dbName string, engine *policy.Engine) *DatabaseProxy {
 	return &DatabaseProxy{
-		user: user,
-		db:   NewRealDatabase(dbName),
+		user:   user,
+		db:     NewRealDatabase(dbName),
+		policy: engine,
+	}
+}
+
+// authorize checks every verb sql uses on every table it touches.
+func (p *DatabaseProxy) authorize(sql string) error {
+	accesses, err := parseStatement(sql)
+	if err != nil {
+		return fmt.Errorf("access denied: %w", err)
 	}
+	subject := policy.Subject{
+		Name:  p.user.Name,
+		Roles: append(slices.Clone(p.user.Permissions), p.user.Roles...),
+	}
+	for _, a := range accesses {
+		d := p.policy.Authorize(policy.Request{Subject: subject, Action: a.verb, Resource: a.table})
+		if err := d.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Query checks permissions before allowing query execution.
@@ -175,8 +236,8 @@ func (p *DatabaseProxy) Query(sql string) (string, error) {
 
 	fmt.Printf("🔒 Checking permissions for user '%s' to run query...\n", p.user.Name)
 
-	if !p.user.HasPermission("read") {
-		return "", fmt.Errorf("access denied: user '%s' lacks 'read' permission", p.user.Name)
+	if err := p.authorize(sql); err != nil {
+		return "", err
 	}
 
 	fmt.Printf("✓ Permission granted to user '%s'\n", p.user.Name)
@@ -190,14 +251,132 @@ func (p *DatabaseProxy) Execute(sql string) (int, error) {
 
 	fmt.Printf("🔒 Checking permissions for user '%s' to execute command...\n", p.user.Name)
 
-	if !p.user.HasPermission("write") {
-		return 0, fmt.Errorf("access denied: user '%s' lacks 'write' permission", p.user.Name)
+	if err := p.authorize(sql); err != nil {
+		return 0, err
 	}
 
 	fmt.Printf("✓ Permission granted to user '%s'\n", p.user.Name)
 	return p.db.Execute(sql)
 }
 
+// tableKeywords are the keywords a table name follows.
+var tableKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true,
+	"USING": true,
+}
+
+// verbKeywords start a statement or a subquery.
+var verbKeywords = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"REPLACE": true, "UPSERT": true, "CREATE": true, "ALTER": true, "DROP": true,
+	"TRUNCATE": true, "GRANT": true, "REVOKE": true, "CALL": true, "EXEC": true,
+	"EXECUTE": true, "COPY": true,
+}
+
+// clauseKeywords end a list of tables.
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "SET": true, "VALUES": true, "GROUP": true, "ORDER": true,
+	"HAVING": true, "LIMIT": true, "OFFSET": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true, "WINDOW": true, "RETURNING": true,
+}
+
+// tableAccess is one verb a statement applies to one table. table is empty
+// for a verb that names no table, such as SELECT 1.
+type tableAccess struct {
+	verb, table string
+}
+
+// parseStatement returns every verb a statement uses, each paired with the
+// tables it applies to. It reads keywords rather than parsing SQL, so it
+// errs towards naming too much: a table is paired with the innermost verb
+// around it, CTE names are reported as tables, and separators and comments
+// that could hide a second statement are refused outright. A real proxy
+// would use the database's own parser.
+func parseStatement(sql string) ([]tableAccess, error) {
+	if strings.Contains(sql, ";") {
+		return nil, errors.New("multiple statements are not allowed")
+	}
+	if strings.Contains(sql, "--") || strings.Contains(sql, "/*") || strings.Contains(sql, "#") {
+		return nil, errors.New("comments are not allowed")
+	}
+	fields := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ", ",", " , ").Replace(sql))
+	if len(fields) == 0 {
+		return nil, errors.New("empty statement")
+	}
+
+	var accesses []tableAccess
+	add := func(verb, table string) {
+		a := tableAccess{verb: verb, table: table}
+		if !slices.Contains(accesses, a) {
+			accesses = append(accesses, a)
+		}
+	}
+	// verbs[d] is the verb in force at parenthesis depth d, and lists[d]
+	// whether a comma there introduces another table.
+	verbs := []string{strings.ToUpper(fields[0])}
+	lists := []bool{false}
+	var used []string
+	use := func(verb string) {
+		if verb != "WITH" && !slices.Contains(used, verb) {
+			used = append(used, verb)
+		}
+	}
+	use(verbs[0])
+	isKeyword := func(field string) bool {
+		word := strings.ToUpper(field)
+		return tableKeywords[word] || verbKeywords[word] || clauseKeywords[word]
+	}
+	// table reads the table name at fields[i], if there is one.
+	table := func(i int) {
+		if i >= len(fields) || strings.ContainsAny(fields[i], "(),") || isKeyword(fields[i]) {
+			return // a subquery such as FROM (SELECT ...) names its own tables
+		}
+		add(verbs[len(verbs)-1], strings.ToLower(strings.Trim(fields[i], "`\"[]")))
+	}
+	for i := 0; i < len(fields); i++ {
+		word := strings.ToUpper(fields[i])
+		top := len(verbs) - 1
+		switch {
+		case word == "(":
+			verbs = append(verbs, verbs[top])
+			lists = append(lists, false)
+		case word == ")":
+			if top > 0 {
+				verbs, lists = verbs[:top], lists[:top]
+			}
+		case word == ",":
+			if lists[top] {
+				table(i + 1)
+			}
+		case verbKeywords[word]:
+			verbs[top], lists[top] = word, false
+			use(word)
+			if tableKeywords[word] || word == "TRUNCATE" {
+				lists[top] = true // UPDATE t, TRUNCATE t
+				table(i + 1)
+			}
+		case tableKeywords[word]:
+			lists[top] = true
+			for i+1 < len(fields) && (strings.EqualFold(fields[i+1], "IF") ||
+				strings.EqualFold(fields[i+1], "NOT") || strings.EqualFold(fields[i+1], "EXISTS")) {
+				i++ // DROP TABLE IF EXISTS t
+			}
+			table(i + 1)
+		case clauseKeywords[word]:
+			lists[top] = false
+		}
+	}
+	for _, verb := range used {
+		if !slices.ContainsFunc(accesses, func(a tableAccess) bool { return a.verb == verb }) {
+			add(verb, "")
+		}
+	}
+	if len(accesses) == 0 {
+		return nil, errors.New("no statement")
+	}
+	return accesses, nil
+}
+
 // =============================================================================
 // Example 3: Caching Proxy
 // =============================================================================
@@ -232,77 +411,176 @@ func (s *ExpensiveDataService) ComputeExpensive(input int) (int, error) {
 	return input * input * input, nil // Cubic calculation
 }
 
-// CachingProxy caches results to avoid repeated expensive operations.
-type CachingProxy struct {
-	service      *ExpensiveDataService
-	dataCache    map[string]string
-	computeCache map[int]int
-	mu           sync.RWMutex
+// cachingProxyMaxEntries bounds each of CachingProxy's caches; the least
+// recently used results are evicted beyond it.
+const cachingProxyMaxEntries = 1000
+
+// CachingOption configures a CachingProxy.
+type CachingOption func(*cachingPolicy)
+
+// cachingPolicy decides how long results stay fresh, stale and failed.
+type cachingPolicy struct {
+	freshFor   time.Duration
+	staleFor   time.Duration
+	errorTTL   time.Duration
+	now        func() time.Time
+	maxEntries int
 }
 
-// NewCachingProxy creates a new caching proxy.
-func NewCachingProxy(serviceName string) *CachingProxy {
-	return &CachingProxy{
-		service:      NewExpensiveDataService(serviceName),
-		dataCache:    make(map[string]string),
-		computeCache: make(map[int]int),
+// WithFreshFor sets how long a result is served without asking the service
+// again. Zero, the default, keeps results until they are evicted.
+func WithFreshFor(d time.Duration) CachingOption {
+	return func(p *cachingPolicy) {
+		p.freshFor = d
 	}
 }
 
-// GetData checks cache first, only calls real service on cache miss.
-func (p *CachingProxy) GetData(key string) (string, error) {
-	// Check cache (read lock)
-	p.mu.RLock()
-	if cached, exists := p.dataCache[key]; exists {
-		p.mu.RUnlock()
-		fmt.Printf("⚡ Cache HIT for key '%s' (no expensive operation!)\n", key)
-		return cached, nil
+// WithStaleWhileRevalidate lets a result be served for up to d after it
+// stops being fresh, while a background call refreshes it. Callers never
+// wait for the refresh; if it fails, the stale result is kept. It needs
+// WithFreshFor: without it results never stop being fresh, so there is
+// nothing to revalidate and d is ignored.
+func WithStaleWhileRevalidate(d time.Duration) CachingOption {
+	return func(p *cachingPolicy) {
+		p.staleFor = d
 	}
-	p.mu.RUnlock()
+}
 
-	// Cache miss - acquire write lock
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// WithErrorTTL caches failures for d, so a failing key is not retried by
+// every caller. Keep it shorter than the fresh period. Zero, the default,
+// does not cache errors.
+func WithErrorTTL(d time.Duration) CachingOption {
+	return func(p *cachingPolicy) {
+		p.errorTTL = d
+	}
+}
 
-	// Double-check in case another goroutine cached it
-	if cached, exists := p.dataCache[key]; exists {
-		fmt.Printf("⚡ Cache HIT on retry for key '%s'\n", key)
-		return cached, nil
+// WithCacheClock replaces time.Now, for tests.
+func WithCacheClock(now func() time.Time) CachingOption {
+	return func(p *cachingPolicy) {
+		p.now = now
 	}
+}
 
-	fmt.Printf("❌ Cache MISS for key '%s'\n", key)
-	result, err := p.service.GetData(key)
-	if err != nil {
-		return "", err
+// WithMaxCachedEntries bounds each cache. The default is 1000.
+func WithMaxCachedEntries(n int) CachingOption {
+	return func(p *cachingPolicy) {
+		p.maxEntries = n
+	}
+}
+
+// CachingProxy caches results to avoid repeated expensive operations.
+// Concurrent misses for one key share a single call to the service.
+type CachingProxy struct {
+	data    *cachedCalls[string, string]
+	compute *cachedCalls[int, int]
+}
+
+// NewCachingProxy creates a new caching proxy in front of an
+// ExpensiveDataService.
+func NewCachingProxy(serviceName string, opts ...CachingOption) *CachingProxy {
+	return NewCachingProxyFor(NewExpensiveDataService(serviceName), opts...)
+}
+
+// NewCachingProxyFor creates a caching proxy in front of any DataService.
+func NewCachingProxyFor(service DataService, opts ...CachingOption) *CachingProxy {
+	policy := &cachingPolicy{now: time.Now, maxEntries: cachingProxyMaxEntries}
+	for _, opt := range opts {
+		opt(policy)
 	}
+	return &CachingProxy{
+		data:    newCachedCalls(policy, "key '%v'", service.GetData),
+		compute: newCachedCalls(policy, "computation input %v", service.ComputeExpensive),
+	}
+}
 
-	p.dataCache[key] = result
-	fmt.Printf("📥 Cached result for key '%s'\n", key)
-	return result, nil
+// GetData checks cache first, only calls real service on cache miss.
+func (p *CachingProxy) GetData(key string) (string, error) {
+	return p.data.get(key)
 }
 
 // ComputeExpensive checks cache first for computation results.
 func (p *CachingProxy) ComputeExpensive(input int) (int, error) {
-	p.mu.RLock()
-	if cached, exists := p.computeCache[input]; exists {
-		p.mu.RUnlock()
-		fmt.Printf("⚡ Cache HIT for computation input %d (instant!)\n", input)
-		return cached, nil
+	return p.compute.get(input)
+}
+
+// Stats returns hit, miss and eviction counts for the data and computation caches.
+func (p *CachingProxy) Stats() (data, compute cache.Stats) {
+	return p.data.entries.Stats(), p.compute.entries.Stats()
+}
+
+// cachedResult is a service response and when it was fetched.
+type cachedResult[V any] struct {
+	value   V
+	err     error
+	fetched time.Time
+}
+
+// cachedCalls caches one DataService method.
+type cachedCalls[K comparable, V any] struct {
+	policy  *cachingPolicy
+	label   string // describes a key in log lines
+	fetch   func(K) (V, error)
+	entries *cache.Cache[K, cachedResult[V]]
+	flight  cache.Group[K, V]
+}
+
+func newCachedCalls[K comparable, V any](policy *cachingPolicy, label string, fetch func(K) (V, error)) *cachedCalls[K, V] {
+	return &cachedCalls[K, V]{
+		policy:  policy,
+		label:   label,
+		fetch:   fetch,
+		entries: cache.New[K, cachedResult[V]](cache.WithMaxEntries(policy.maxEntries), cache.WithClock(policy.now)),
+	}
+}
+
+func (c *cachedCalls[K, V]) get(key K) (V, error) {
+	what := fmt.Sprintf(c.label, key)
+	if cached, ok := c.entries.Get(key); ok {
+		if cached.err != nil {
+			fmt.Printf("⛔ Cached failure for %s\n", what)
+			var zero V
+			return zero, cached.err
+		}
+		if c.policy.freshFor > 0 && c.policy.now().Sub(cached.fetched) >= c.policy.freshFor {
+			fmt.Printf("♻️ Serving stale result for %s while refreshing\n", what)
+			if !c.flight.InFlight(key) {
+				go c.flight.Do(key, func() (V, error) { return c.load(key, true) })
+			}
+			return cached.value, nil
+		}
+		fmt.Printf("⚡ Cache HIT for %s (no expensive operation!)\n", what)
+		return cached.value, nil
 	}
-	p.mu.RUnlock()
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	value, err, shared := c.flight.Do(key, func() (V, error) {
+		fmt.Printf("❌ Cache MISS for %s\n", what)
+		return c.load(key, false)
+	})
+	if shared {
+		fmt.Printf("🤝 Shared in-flight result for %s\n", what)
+	}
+	return value, err
+}
 
-	fmt.Printf("❌ Cache MISS for computation input %d\n", input)
-	result, err := p.service.ComputeExpensive(input)
+// load calls the service and caches the outcome. A failed refresh leaves
+// the stale result in place rather than replacing it with the error.
+func (c *cachedCalls[K, V]) load(key K, refreshing bool) (V, error) {
+	value, err := c.fetch(key)
 	if err != nil {
-		return 0, err
+		if c.policy.errorTTL > 0 && !refreshing {
+			c.entries.SetWithTTL(key, cachedResult[V]{err: err}, c.policy.errorTTL)
+		}
+		return value, err
 	}
 
-	p.computeCache[input] = result
-	fmt.Printf("📥 Cached computation result for input %d\n", input)
-	return result, nil
+	var ttl time.Duration
+	if c.policy.freshFor > 0 {
+		ttl = c.policy.freshFor + c.policy.staleFor
+	}
+	c.entries.SetWithTTL(key, cachedResult[V]{value: value, fetched: c.policy.now()}, ttl)
+	fmt.Printf("📥 Cached result for %s\n", fmt.Sprintf(c.label, key))
+	return value, nil
 }
 
 // =============================================================================
@@ -322,10 +600,13 @@ func NewRealPaymentService() *RealPaymentService {
 	return &RealPaymentService{}
 }
 
+// ErrInvalidAmount is returned for payments of zero or less.
+var ErrInvalidAmount = errors.New("invalid amount")
+
 // ProcessPayment processes the payment.
 func (s *RealPaymentService) ProcessPayment(amount float64, customer string) (string, error) {
 	if amount <= 0 {
-		return "", errors.New("invalid amount")
+		return "", ErrInvalidAmount
 	}
 	txnID := fmt.Sprintf("TXN-%d", time.Now().Unix())
 	return txnID, nil
@@ -399,51 +680,170 @@ func (s *RealAPIService) MakeRequest(endpoint string) (string, error) {
 
 // RateLimitingProxy limits the rate of API calls.
 type RateLimitingProxy struct {
-	service       *RealAPIService
-	maxRequests   int
-	timeWindow    time.Duration
-	requests      []time.Time
-	mu            sync.Mutex
+	service *RealAPIService
+	limiter ratelimit.Limiter
+	key     func(endpoint string) string
 }
 
-// NewRateLimitingProxy creates a new rate-limiting proxy.
+// NewRateLimitingProxy creates a new rate-limiting proxy that allows a burst
+// of maxRequests calls across all endpoints, refilled at maxRequests per
+// window. It uses GCRA, which keeps one timestamp per key however high the
+// rate.
 func NewRateLimitingProxy(maxRequests int, window time.Duration) *RateLimitingProxy {
+	limiter, err := ratelimit.NewGCRA(ratelimit.Limit{Rate: maxRequests, Period: window})
+	if err != nil {
+		panic(err)
+	}
+	return NewRateLimitingProxyWith(limiter, nil)
+}
+
+// NewRateLimitingProxyWith creates a rate-limiting proxy around any limiter.
+// key maps an endpoint to the key it is limited under, so endpoints can
+// have separate limits; nil limits all endpoints together.
+func NewRateLimitingProxyWith(limiter ratelimit.Limiter, key func(endpoint string) string) *RateLimitingProxy {
+	if key == nil {
+		key = func(string) string { return "api" }
+	}
 	return &RateLimitingProxy{
-		service:     NewRealAPIService(),
-		maxRequests: maxRequests,
-		timeWindow:  window,
-		requests:    make([]time.Time, 0),
+		service: NewRealAPIService(),
+		limiter: limiter,
+		key:     key,
 	}
 }
 
 // MakeRequest enforces rate limiting before calling the real service.
 func (p *RateLimitingProxy) MakeRequest(endpoint string) (string, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	d, err := ratelimit.Allow(context.Background(), p.limiter, p.key(endpoint))
+	if err != nil {
+		return "", err
+	}
+	if !d.Allowed {
+		fmt.Printf("🚫 Rate limit exceeded! (retry after %v)\n", d.RetryAfter.Round(time.Millisecond))
+		return "", d.Err()
+	}
+	fmt.Printf("✓ Request allowed (%d/%d in current window)\n",
+		d.Limit-d.Remaining, d.Limit)
 
-	now := time.Now()
+	return p.service.MakeRequest(endpoint)
+}
 
-	// Remove old requests outside the time window
-	cutoff := now.Add(-p.timeWindow)
-	validRequests := make([]time.Time, 0)
-	for _, reqTime := range p.requests {
-		if reqTime.After(cutoff) {
-			validRequests = append(validRequests, reqTime)
+// =============================================================================
+// Example 6: Remote Proxy
+// =============================================================================
+
+// RemoteErrors are the application errors the remote proxies carry across
+// the wire; pass them to both sides with jsonrpc.WithErrors.
+var RemoteErrors = map[int]error{
+	1001: ErrInvalidAmount,
+}
+
+type getDataParams struct {
+	Key string `json:"key"`
+}
+
+type computeParams struct {
+	Input int `json:"input"`
+}
+
+type processPaymentParams struct {
+	Amount   float64 `json:"amount"`
+	Customer string  `json:"customer"`
+}
+
+// ServeDataService exposes service's methods on s as "DataService.GetData"
+// and "DataService.ComputeExpensive". DataService methods take no context,
+// so a call whose deadline has already passed is refused, but one that
+// starts in time runs to completion after the server has replied.
+func ServeDataService(s *jsonrpc.Server, service DataService) {
+	jsonrpc.Register(s, "DataService.GetData", func(ctx context.Context, p getDataParams) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
 		}
-	}
-	p.requests = validRequests
+		return service.GetData(p.Key)
+	})
+	jsonrpc.Register(s, "DataService.ComputeExpensive", func(ctx context.Context, p computeParams) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return service.ComputeExpensive(p.Input)
+	})
+}
+
+// ServePaymentService exposes service's method on s as
+// "PaymentService.ProcessPayment". A payment whose caller has already given
+// up is not started; one that has started cannot be stopped, since
+// ProcessPayment takes no context, so it completes even if the caller sees a
+// deadline error.
+func ServePaymentService(s *jsonrpc.Server, service PaymentService) {
+	jsonrpc.Register(s, "PaymentService.ProcessPayment", func(ctx context.Context, p processPaymentParams) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return service.ProcessPayment(p.Amount, p.Customer)
+	})
+}
 
-	// Check if rate limit exceeded
-	if len(p.requests) >= p.maxRequests {
-		fmt.Printf("🚫 Rate limit exceeded! (%d requests in %v window)\n",
-			len(p.requests), p.timeWindow)
-		return "", errors.New("rate limit exceeded")
-	}
+// RemoteDataService is a DataService whose calls run on a server set up
+// with ServeDataService. The interface methods use the client's default
+// timeout; the Context variants also take a caller's context.
+type RemoteDataService struct {
+	client *jsonrpc.Client
+}
 
-	// Record this request
-	p.requests = append(p.requests, now)
-	fmt.Printf("✓ Request allowed (%d/%d in current window)\n",
-		len(p.requests), p.maxRequests)
+var _ DataService = (*RemoteDataService)(nil)
 
-	return p.service.MakeRequest(endpoint)
+// NewRemoteDataService creates a remote proxy that calls through client.
+func NewRemoteDataService(client *jsonrpc.Client) *RemoteDataService {
+	return &RemoteDataService{client: client}
+}
+
+// GetData implements DataService.
+func (p *RemoteDataService) GetData(key string) (string, error) {
+	return p.GetDataContext(context.Background(), key)
+}
+
+// GetDataContext calls GetData on the server, giving up when ctx ends.
+func (p *RemoteDataService) GetDataContext(ctx context.Context, key string) (string, error) {
+	var data string
+	err := p.client.Call(ctx, "DataService.GetData", getDataParams{Key: key}, &data)
+	return data, err
+}
+
+// ComputeExpensive implements DataService.
+func (p *RemoteDataService) ComputeExpensive(input int) (int, error) {
+	return p.ComputeExpensiveContext(context.Background(), input)
+}
+
+// ComputeExpensiveContext calls ComputeExpensive on the server, giving up
+// when ctx ends.
+func (p *RemoteDataService) ComputeExpensiveContext(ctx context.Context, input int) (int, error) {
+	var result int
+	err := p.client.Call(ctx, "DataService.ComputeExpensive", computeParams{Input: input}, &result)
+	return result, err
+}
+
+// RemotePaymentService is a PaymentService whose calls run on a server set
+// up with ServePaymentService.
+type RemotePaymentService struct {
+	client *jsonrpc.Client
+}
+
+var _ PaymentService = (*RemotePaymentService)(nil)
+
+// NewRemotePaymentService creates a remote proxy that calls through client.
+func NewRemotePaymentService(client *jsonrpc.Client) *RemotePaymentService {
+	return &RemotePaymentService{client: client}
+}
+
+// ProcessPayment implements PaymentService.
+func (p *RemotePaymentService) ProcessPayment(amount float64, customer string) (string, error) {
+	return p.ProcessPaymentContext(context.Background(), amount, customer)
+}
+
+// ProcessPaymentContext calls ProcessPayment on the server, giving up when
+// ctx ends.
+func (p *RemotePaymentService) ProcessPaymentContext(ctx context.Context, amount float64, customer string) (string, error) {
+	var txnID string
+	err := p.client.Call(ctx, "PaymentService.ProcessPayment", processPaymentParams{Amount: amount, Customer: customer}, &txnID)
+	return txnID, err
 }
@@ -1,10 +1,21 @@
 package proxy
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/cache"
+	"github.com/jumaniyozov/design_patterns/policy"
+	"github.com/jumaniyozov/design_patterns/ratelimit"
+	"github.com/jumaniyozov/design_patterns/tier2/proxy/intercept"
+	"github.com/jumaniyozov/design_patterns/tier2/proxy/jsonrpc"
 )
 
 // TestImageProxy_LazyLoading tests that image is not loaded until accessed.
@@ -146,6 +157,144 @@ func TestDatabaseProxy_UnauthorizedExecute(t *testing.T) {
 	}
 }
 
+// TestDatabaseProxy_Policy tests rules by verb and table, with roles
+// inherited from the policy document.
+func TestDatabaseProxy_Policy(t *testing.T) {
+	doc, err := policy.Parse([]byte(`
+roles:
+  analyst:
+    allow:
+      - actions: [select]
+        resources: ["*"]
+    deny:
+      - actions: [select]
+        resources: [salaries]
+  clerk:
+    inherits: [analyst]
+    allow:
+      - actions: [insert, update]
+        resources: [orders, order_items]
+`), policy.YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := policy.NewMemoryAuditLog()
+	engine, err := policy.New(doc, policy.WithAuditLog(audit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewDatabaseProxyWithPolicy(&User{Name: "carol", Roles: []string{"clerk"}}, "testdb", engine)
+
+	allowed := []string{
+		"SELECT * FROM users",
+		"INSERT INTO orders (id) VALUES (1)",
+		"UPDATE orders SET total = 2 WHERE id IN (SELECT order_id FROM order_items)",
+	}
+	denied := []string{
+		"SELECT name FROM users JOIN salaries ON users.id = salaries.user_id",
+		"DELETE FROM orders",
+		"UPDATE users SET name = 'x'",
+		"",
+	}
+	for _, sql := range allowed {
+		if _, err := db.Execute(sql); err != nil {
+			t.Errorf("%q: %v", sql, err)
+		}
+	}
+	for _, sql := range denied {
+		if _, err := db.Query(sql); err == nil || !strings.Contains(err.Error(), "access denied") {
+			t.Errorf("%q: expected access denied, got %v", sql, err)
+		}
+	}
+	if _, err := db.Query("SELECT * FROM salaries"); !errors.Is(err, policy.ErrDenied) {
+		t.Errorf("expected policy.ErrDenied, got %v", err)
+	}
+	if got := len(audit.Entries()); got < len(allowed)+len(denied) {
+		t.Errorf("expected every check to be audited, got %d entries", got)
+	}
+}
+
+// TestParseStatement tests verb and table extraction.
+func TestParseStatement(t *testing.T) {
+	type access = tableAccess
+	tests := []struct {
+		sql  string
+		want []access
+	}{
+		{"select * from Users where id = 1", []access{{"SELECT", "users"}}},
+		{"INSERT INTO `orders`(id) VALUES (1)", []access{{"INSERT", "orders"}}},
+		{"UPDATE a SET x = (SELECT y FROM b JOIN c ON b.id = c.id)",
+			[]access{{"UPDATE", "a"}, {"SELECT", "b"}, {"SELECT", "c"}}},
+		{"DROP TABLE IF EXISTS audit_log", []access{{"DROP", "audit_log"}}},
+		{"TRUNCATE sessions", []access{{"TRUNCATE", "sessions"}}},
+		{"SELECT 1", []access{{"SELECT", ""}}},
+		{"SELECT a, b FROM public p, secrets AS s WHERE p.id = s.id",
+			[]access{{"SELECT", "public"}, {"SELECT", "secrets"}}},
+		{"SELECT * FROM a JOIN b ON a.id = b.id, c",
+			[]access{{"SELECT", "a"}, {"SELECT", "b"}, {"SELECT", "c"}}},
+		{"WITH x AS (SELECT * FROM secrets) SELECT * FROM x",
+			[]access{{"SELECT", "secrets"}, {"SELECT", "x"}}},
+		{"WITH t AS (SELECT id FROM public) DELETE FROM public USING t",
+			[]access{{"SELECT", "public"}, {"DELETE", "public"}, {"DELETE", "t"}}},
+	}
+	for _, tt := range tests {
+		got, err := parseStatement(tt.sql)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("parseStatement(%q) = %v, %v; want %v", tt.sql, got, err, tt.want)
+		}
+	}
+
+	for _, sql := range []string{
+		"  ",
+		"SELECT * FROM public;",
+		"SELECT * FROM public; DELETE FROM public",
+		"SELECT * FROM public -- comment",
+		"SELECT * FROM public /* comment */",
+	} {
+		if got, err := parseStatement(sql); err == nil {
+			t.Errorf("parseStatement(%q) = %v; want an error", sql, got)
+		}
+	}
+}
+
+// TestDatabaseProxy_PolicyBypass tests statements that name a table or verb
+// beyond the first one.
+func TestDatabaseProxy_PolicyBypass(t *testing.T) {
+	doc, err := policy.Parse([]byte(`
+roles:
+  reader:
+    allow:
+      - actions: [select]
+        resources: [public]
+`), policy.YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := policy.New(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewDatabaseProxyWithPolicy(&User{Name: "dave", Roles: []string{"reader"}}, "testdb", engine)
+
+	if _, err := db.Query("SELECT * FROM public p WHERE p.id IN (SELECT id FROM public)"); err != nil {
+		t.Errorf("Expected the allowed table to be readable, got %v", err)
+	}
+	for _, sql := range []string{
+		"SELECT * FROM public, secrets",
+		"SELECT * FROM public p, secrets s WHERE p.id = s.id",
+		"SELECT * FROM public JOIN public q ON public.id = q.id, secrets",
+		"SELECT * FROM public; DELETE FROM public",
+		"SELECT * FROM public;DELETE FROM public",
+		"WITH x AS (SELECT * FROM secrets) SELECT * FROM public",
+		"WITH t AS (SELECT * FROM public) DELETE FROM public",
+		"SELECT * FROM public UNION SELECT * FROM secrets",
+	} {
+		if _, err := db.Query(sql); err == nil || !strings.Contains(err.Error(), "access denied") {
+			t.Errorf("%q: expected access denied, got %v", sql, err)
+		}
+	}
+}
+
 // TestCachingProxy_CacheMiss tests first access (cache miss).
 func TestCachingProxy_CacheMiss(t *testing.T) {
 	proxy := NewCachingProxy("test_service")
@@ -245,6 +394,275 @@ func TestCachingProxy_DifferentKeys(t *testing.T) {
 	}
 }
 
+// countingService is a DataService whose GetData blocks until release is
+// closed, then returns the current version or err.
+type countingService struct {
+	mu      sync.Mutex
+	calls   int
+	version int
+	err     error
+	panics  bool
+	release chan struct{}
+}
+
+func (s *countingService) GetData(key string) (string, error) {
+	s.mu.Lock()
+	s.calls++
+	version, err, panics, release := s.version, s.err, s.panics, s.release
+	s.mu.Unlock()
+	if release != nil {
+		<-release
+	}
+	if panics {
+		panic("backend bug")
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s v%d", key, version), nil
+}
+
+func (s *countingService) ComputeExpensive(input int) (int, error) {
+	return input * input * input, nil
+}
+
+func (s *countingService) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// testClock is a settable clock safe for use from background refreshes.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// TestCachingProxy_CoalescesConcurrentMisses tests that a burst of misses makes one call.
+func TestCachingProxy_CoalescesConcurrentMisses(t *testing.T) {
+	service := &countingService{release: make(chan struct{})}
+	proxy := NewCachingProxyFor(service)
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = proxy.GetData("hot")
+		}()
+	}
+	// Callers that arrive after the first are either waiting on it or, once
+	// it has finished, served from the cache.
+	for !proxy.data.flight.InFlight("hot") {
+		time.Sleep(time.Millisecond)
+	}
+	close(service.release)
+	wg.Wait()
+
+	if n := service.callCount(); n != 1 {
+		t.Errorf("expected 1 backend call, got %d", n)
+	}
+	for _, r := range results {
+		if r != "hot v0" {
+			t.Fatalf("expected every caller to get %q, got %q", "hot v0", r)
+		}
+	}
+}
+
+// TestCachingProxy_StaleWhileRevalidate tests serving stale results during a refresh.
+func TestCachingProxy_StaleWhileRevalidate(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	service := &countingService{}
+	proxy := NewCachingProxyFor(service,
+		WithFreshFor(time.Minute), WithStaleWhileRevalidate(time.Hour), WithCacheClock(clock.Now))
+
+	proxy.GetData("profile")
+	service.mu.Lock()
+	service.version = 1
+	service.mu.Unlock()
+
+	clock.Advance(2 * time.Minute)
+	if got, _ := proxy.GetData("profile"); got != "profile v0" {
+		t.Errorf("expected the stale result, got %q", got)
+	}
+
+	// The refresh runs in the background.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if got, _ := proxy.GetData("profile"); got == "profile v1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refreshed result never arrived")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Past the stale window the caller waits for a fresh result.
+	clock.Advance(2 * time.Hour)
+	service.mu.Lock()
+	service.version = 2
+	service.mu.Unlock()
+	if got, _ := proxy.GetData("profile"); got != "profile v2" {
+		t.Errorf("expected a fresh result after the stale window, got %q", got)
+	}
+}
+
+// TestCachingProxy_StaleWithoutFreshFor tests that WithStaleWhileRevalidate
+// on its own leaves results cached until eviction, with no refreshes.
+func TestCachingProxy_StaleWithoutFreshFor(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	service := &countingService{}
+	proxy := NewCachingProxyFor(service, WithStaleWhileRevalidate(time.Minute), WithCacheClock(clock.Now))
+
+	proxy.GetData("profile")
+	service.mu.Lock()
+	service.version = 1
+	service.mu.Unlock()
+
+	clock.Advance(time.Hour)
+	if got, _ := proxy.GetData("profile"); got != "profile v0" {
+		t.Errorf("expected the cached result, got %q", got)
+	}
+	if proxy.data.flight.InFlight("profile") {
+		t.Error("expected no background refresh")
+	}
+	if n := service.callCount(); n != 1 {
+		t.Errorf("expected 1 backend call, got %d", n)
+	}
+}
+
+// TestCachingProxy_Bounded tests that the caches evict beyond their limit.
+func TestCachingProxy_Bounded(t *testing.T) {
+	service := &countingService{}
+	proxy := NewCachingProxyFor(service, WithMaxCachedEntries(2))
+
+	for _, key := range []string{"a", "b", "c", "a"} {
+		proxy.GetData(key)
+	}
+	if n := service.callCount(); n != 4 {
+		t.Errorf("expected a to be evicted and fetched again, got %d calls", n)
+	}
+	if data, _ := proxy.Stats(); data.Evictions != 2 {
+		t.Errorf("expected 2 evictions, got %+v", data)
+	}
+
+	// The default bound applies without the option.
+	proxy = NewCachingProxyFor(service)
+	for i := range cachingProxyMaxEntries + 1 {
+		proxy.ComputeExpensive(i)
+	}
+	if _, compute := proxy.Stats(); compute.Evictions != 1 {
+		t.Errorf("expected 1 eviction past the default bound, got %+v", compute)
+	}
+}
+
+// TestCachingProxy_RefreshPanics tests that a panicking service fails the
+// call instead of the process, including during a background refresh.
+func TestCachingProxy_RefreshPanics(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	service := &countingService{}
+	proxy := NewCachingProxyFor(service,
+		WithFreshFor(time.Minute), WithStaleWhileRevalidate(time.Hour), WithCacheClock(clock.Now))
+
+	proxy.GetData("profile")
+	service.mu.Lock()
+	service.panics = true
+	service.mu.Unlock()
+
+	clock.Advance(2 * time.Minute)
+	if got, _ := proxy.GetData("profile"); got != "profile v0" {
+		t.Errorf("expected the stale result, got %q", got)
+	}
+	for service.callCount() < 2 || proxy.data.flight.InFlight("profile") {
+		time.Sleep(time.Millisecond)
+	}
+	if got, err := proxy.GetData("profile"); got != "profile v0" || err != nil {
+		t.Errorf("expected the stale result to survive the failed refresh, got %q, %v", got, err)
+	}
+
+	var panicErr *cache.PanicError
+	if _, err := proxy.GetData("other"); !errors.As(err, &panicErr) {
+		t.Errorf("expected a *cache.PanicError, got %v", err)
+	}
+}
+
+// TestCachingProxy_NegativeCaching tests that failures are cached briefly.
+func TestCachingProxy_NegativeCaching(t *testing.T) {
+	clock := &testClock{now: time.Unix(0, 0)}
+	service := &countingService{err: errors.New("backend down")}
+	proxy := NewCachingProxyFor(service,
+		WithFreshFor(time.Minute), WithErrorTTL(5*time.Second), WithCacheClock(clock.Now))
+
+	for range 3 {
+		if _, err := proxy.GetData("broken"); err == nil || err.Error() != "backend down" {
+			t.Fatalf("expected the backend error, got %v", err)
+		}
+	}
+	if n := service.callCount(); n != 1 {
+		t.Errorf("expected the failure to be cached, got %d calls", n)
+	}
+
+	clock.Advance(6 * time.Second)
+	service.mu.Lock()
+	service.err = nil
+	service.mu.Unlock()
+	if got, err := proxy.GetData("broken"); err != nil || got != "broken v0" {
+		t.Errorf("expected a retry after the error TTL, got %q, %v", got, err)
+	}
+
+	data, _ := proxy.Stats()
+	if data.Hits != 2 || data.Misses != 2 {
+		t.Errorf("data cache stats = %+v, want 2 hits and 2 misses", data)
+	}
+}
+
+// TestInterceptedDataService tests a generated proxy with ready-made interceptors.
+func TestInterceptedDataService(t *testing.T) {
+	service := &countingService{}
+	timings := intercept.NewTimings()
+	proxy := NewInterceptedDataService(service,
+		timings,
+		intercept.Caching(cache.New[string, []any](cache.WithMaxEntries(100)), "GetData"),
+	)
+
+	for range 3 {
+		if got, err := proxy.GetData("user"); err != nil || got != "user v0" {
+			t.Fatalf("GetData = %q, %v", got, err)
+		}
+	}
+	if got, _ := proxy.ComputeExpensive(3); got != 27 {
+		t.Errorf("ComputeExpensive(3) = %d, want 27", got)
+	}
+
+	if n := service.callCount(); n != 1 {
+		t.Errorf("expected 1 backend call, got %d", n)
+	}
+	if calls := timings.Snapshot()["GetData"].Calls; calls != 3 {
+		t.Errorf("expected 3 timed GetData calls, got %d", calls)
+	}
+
+	service.mu.Lock()
+	service.err = errors.New("backend down")
+	service.mu.Unlock()
+	if _, err := proxy.GetData("other"); err == nil || err.Error() != "backend down" {
+		t.Errorf("expected the backend error through the proxy, got %v", err)
+	}
+}
+
 // TestLoggingPaymentProxy_Success tests logging of successful payments.
 func TestLoggingPaymentProxy_Success(t *testing.T) {
 	proxy := NewLoggingPaymentProxy()
@@ -360,6 +778,79 @@ func TestRateLimitingProxy_WindowReset(t *testing.T) {
 	}
 }
 
+// TestRateLimitingProxy_PerEndpoint tests limits keyed by endpoint, and the
+// RetryAfter carried by the error.
+func TestRateLimitingProxy_PerEndpoint(t *testing.T) {
+	limiter, err := ratelimit.NewGCRA(ratelimit.PerMinute(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := NewRateLimitingProxyWith(limiter, func(endpoint string) string { return endpoint })
+
+	for _, endpoint := range []string{"/api/users", "/api/posts"} {
+		if _, err := proxy.MakeRequest(endpoint); err != nil {
+			t.Errorf("first request to %s failed: %v", endpoint, err)
+		}
+	}
+	_, err = proxy.MakeRequest("/api/users")
+	var exceeded *ratelimit.ExceededError
+	if !errors.As(err, &exceeded) || exceeded.RetryAfter <= 0 || exceeded.RetryAfter > time.Minute {
+		t.Errorf("expected an ExceededError with a RetryAfter of up to a minute, got %v", err)
+	}
+}
+
+// TestRemoteDataService tests the remote proxy against a real service on
+// loopback, including a call that outlives its context.
+func TestRemoteDataService(t *testing.T) {
+	service := &countingService{}
+	server := jsonrpc.NewServer()
+	ServeDataService(server, service)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	var remote DataService = NewRemoteDataService(jsonrpc.NewClient(ts.URL))
+	if got, err := remote.GetData("user:1"); err != nil || got != "user:1 v0" {
+		t.Errorf("GetData = %q, %v", got, err)
+	}
+	if got, err := remote.ComputeExpensive(3); err != nil || got != 27 {
+		t.Errorf("ComputeExpensive = %d, %v", got, err)
+	}
+
+	service.mu.Lock()
+	service.release = make(chan struct{})
+	service.mu.Unlock()
+	defer close(service.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := remote.(*RemoteDataService).GetDataContext(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestRemotePaymentService tests that service errors arrive as the same Go
+// errors.
+func TestRemotePaymentService(t *testing.T) {
+	server := jsonrpc.NewServer(jsonrpc.WithErrors(RemoteErrors))
+	ServePaymentService(server, NewRealPaymentService())
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	remote := NewRemotePaymentService(jsonrpc.NewClient(ts.URL, jsonrpc.WithErrors(RemoteErrors), jsonrpc.WithTimeout(time.Second)))
+	if txnID, err := remote.ProcessPayment(10, "alice"); err != nil || !strings.HasPrefix(txnID, "TXN-") {
+		t.Errorf("ProcessPayment = %q, %v", txnID, err)
+	}
+	if _, err := remote.ProcessPayment(-1, "alice"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+
+	// Remote proxies compose with the local ones.
+	intercepted := NewInterceptedPaymentService(remote)
+	if _, err := intercepted.ProcessPayment(0, "bob"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount through the interceptor chain, got %v", err)
+	}
+}
+
 // BenchmarkImageProxy_LazyLoading benchmarks lazy loading performance.
 func BenchmarkImageProxy_LazyLoading(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -18,6 +18,9 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/policy"
+	"github.com/jumaniyozov/design_patterns/ratelimit"
 )
 
 // Handler defines the interface for handling requests.
@@ -105,6 +108,7 @@ func (h *AuthenticationHandler) Handle(ctx context.Context, request interface{})
 type AuthorizationHandler struct {
 	BaseHandler
 	requiredRole string
+	policy       *policy.Engine
 }
 
 // NewAuthorizationHandler creates a new authorization handler.
@@ -114,6 +118,12 @@ func NewAuthorizationHandler(requiredRole string) *AuthorizationHandler {
 	}
 }
 
+// NewAuthorizationHandlerWith creates an authorization handler that asks
+// engine whether the request's role may use its method on its path.
+func NewAuthorizationHandlerWith(engine *policy.Engine) *AuthorizationHandler {
+	return &AuthorizationHandler{policy: engine}
+}
+
 // Handle processes authorization.
 func (h *AuthorizationHandler) Handle(ctx context.Context, request interface{}) error {
 	req, ok := request.(*HTTPRequest)
@@ -130,7 +140,18 @@ func (h *AuthorizationHandler) Handle(ctx context.Context, request interface{})
 		return fmt.Errorf("authorization failed: requires %s role, got %s", h.requiredRole, req.Role)
 	}
 
-	fmt.Printf("[Authz]  Authorized role: %s\n", req.Role)
+	if h.policy != nil {
+		d := h.policy.Authorize(policy.Request{
+			Subject:  policy.Subject{Name: req.User, Roles: []string{req.Role}},
+			Action:   req.Method,
+			Resource: req.Path,
+		})
+		if err := d.Err(); err != nil {
+			return fmt.Errorf("authorization failed: %w", err)
+		}
+	}
+
+	fmt.Printf("[Authz]  Authorized role: %s\n", req.Role)
 
 	// Pass to next handler
 	return h.CallNext(ctx, request)
@@ -178,21 +199,26 @@ func (h *ValidationHandler) Handle(ctx context.Context, request interface{}) err
 	return h.CallNext(ctx, request)
 }
 
-// RateLimitHandler implements rate limiting.
+// RateLimitHandler implements per-user rate limiting.
 type RateLimitHandler struct {
 	BaseHandler
-	maxRequests int
-	window      time.Duration
-	requests    map[string][]time.Time
+	limiter ratelimit.Limiter
 }
 
-// NewRateLimitHandler creates a new rate limit handler.
+// NewRateLimitHandler creates a rate limit handler that allows each user a
+// burst of maxRequests requests, refilled at maxRequests per window.
 func NewRateLimitHandler(maxRequests int, window time.Duration) *RateLimitHandler {
-	return &RateLimitHandler{
-		maxRequests: maxRequests,
-		window:      window,
-		requests:    make(map[string][]time.Time),
+	limiter, err := ratelimit.NewGCRA(ratelimit.Limit{Rate: maxRequests, Period: window})
+	if err != nil {
+		panic(err)
 	}
+	return NewRateLimitHandlerWith(limiter)
+}
+
+// NewRateLimitHandlerWith creates a rate limit handler that asks limiter,
+// keyed by user, whether to pass each request on.
+func NewRateLimitHandlerWith(limiter ratelimit.Limiter) *RateLimitHandler {
+	return &RateLimitHandler{limiter: limiter}
 }
 
 // Handle processes rate limiting.
@@ -202,28 +228,15 @@ func (h *RateLimitHandler) Handle(ctx context.Context, request interface{}) erro
 		return errors.New("invalid request type")
 	}
 
-	// Get user's request history
-	now := time.Now()
-	userRequests := h.requests[req.User]
-
-	// Filter requests within window
-	var validRequests []time.Time
-	for _, t := range userRequests {
-		if now.Sub(t) < h.window {
-			validRequests = append(validRequests, t)
-		}
+	d, err := ratelimit.Allow(ctx, h.limiter, req.User)
+	if err != nil {
+		return err
 	}
-
-	// Check rate limit
-	if len(validRequests) >= h.maxRequests {
-		return fmt.Errorf("rate limit exceeded: %d requests in %v", len(validRequests), h.window)
+	if err := d.Err(); err != nil {
+		return err
 	}
 
-	// Add current request
-	validRequests = append(validRequests, now)
-	h.requests[req.User] = validRequests
-
-	fmt.Printf("[RateLimit]  Request allowed: %d/%d in %v\n", len(validRequests), h.maxRequests, h.window)
+	fmt.Printf("[RateLimit]  Request allowed: %d/%d\n", d.Limit-d.Remaining, d.Limit)
 
 	// Pass to next handler
 	return h.CallNext(ctx, request)
@@ -2,8 +2,12 @@ package chainofresponsibility
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/jumaniyozov/design_patterns/policy"
+	"github.com/jumaniyozov/design_patterns/ratelimit"
 )
 
 func TestAuthenticationHandler(t *testing.T) {
@@ -111,6 +115,44 @@ func TestChainedHandlers(t *testing.T) {
 	}
 }
 
+func TestAuthorizationHandler_Policy(t *testing.T) {
+	engine, err := policy.New(policy.Document{Roles: map[string]policy.RoleSpec{
+		"viewer": {Allow: []policy.RuleSpec{{Actions: []string{"GET"}, Resources: []string{"/api/*"}}}},
+		"editor": {
+			Inherits: []string{"viewer"},
+			Allow:    []policy.RuleSpec{{Actions: []string{"POST", "PUT"}, Resources: []string{"/api/*"}}},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewAuthorizationHandlerWith(engine)
+	ctx := context.Background()
+
+	tests := []struct {
+		role, method, path string
+		allowed            bool
+	}{
+		{"viewer", "GET", "/api/users", true},
+		{"viewer", "POST", "/api/users", false},
+		{"editor", "GET", "/api/users", true},
+		{"editor", "PUT", "/api/users", true},
+		{"editor", "DELETE", "/api/users", false},
+		{"editor", "GET", "/admin", false},
+	}
+	for _, tt := range tests {
+		request := NewHTTPRequest(tt.method, tt.path)
+		request.User, request.Role = "dana", tt.role
+		err := handler.Handle(ctx, request)
+		if tt.allowed && err != nil {
+			t.Errorf("%s %s %s: %v", tt.role, tt.method, tt.path, err)
+		}
+		if !tt.allowed && !errors.Is(err, policy.ErrDenied) {
+			t.Errorf("%s %s %s: expected policy.ErrDenied, got %v", tt.role, tt.method, tt.path, err)
+		}
+	}
+}
+
 func TestRateLimitHandler(t *testing.T) {
 	handler := NewRateLimitHandler(2, 100*time.Millisecond)
 	ctx := context.Background()
@@ -146,6 +188,29 @@ func TestRateLimitHandler(t *testing.T) {
 	}
 }
 
+func TestRateLimitHandler_PerUser(t *testing.T) {
+	limiter, err := ratelimit.NewGCRA(ratelimit.PerMinute(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewRateLimitHandlerWith(limiter)
+	ctx := context.Background()
+
+	for _, user := range []string{"alice", "bob"} {
+		request := NewHTTPRequest("GET", "/test")
+		request.User = user
+		if err := handler.Handle(ctx, request); err != nil {
+			t.Errorf("first request for %s failed: %v", user, err)
+		}
+	}
+
+	request := NewHTTPRequest("GET", "/test")
+	request.User = "alice"
+	if err := handler.Handle(ctx, request); !errors.Is(err, ratelimit.ErrLimited) {
+		t.Errorf("expected ratelimit.ErrLimited, got %v", err)
+	}
+}
+
 func TestManagerApproval(t *testing.T) {
 	handler := NewManagerApproval(1000.00)
 	ctx := context.Background()
@@ -0,0 +1,234 @@
+package options
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPError is returned by the JSON helpers when the server responds with a
+// non-2xx status code.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("http error: %s", e.Status)
+	}
+	return fmt.Sprintf("http error: %s: %s", e.Status, bytes.TrimSpace(e.Body))
+}
+
+// Get sends a GET request to path and decodes the JSON response into out.
+// A nil out discards the response body.
+func (c *Client) Get(ctx context.Context, path string, out any) error {
+	return c.DoJSON(ctx, http.MethodGet, path, nil, out)
+}
+
+// Post encodes in as JSON, sends it to path and decodes the JSON response into out.
+func (c *Client) Post(ctx context.Context, path string, in, out any) error {
+	return c.DoJSON(ctx, http.MethodPost, path, in, out)
+}
+
+// DoJSON sends a request with an optional JSON body and decodes a JSON response.
+func (c *Client) DoJSON(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := c.NewRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: data}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// NewRequest creates a request for path relative to BaseURL.
+// Absolute URLs are used as given.
+func (c *Client) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, c.resolve(path), body)
+}
+
+// Do sends req with the client's headers, bearer token and user agent applied.
+// Idempotent requests are retried with exponential backoff on network errors
+// and retryable status codes, honouring any Retry-After header up to
+// MaxBackoff.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	c.applyHeaders(req)
+
+	attempts := 1
+	if isIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil) {
+		attempts += max(c.MaxRetries, 0)
+	}
+
+	httpClient := c.httpClient()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := httpClient.Do(req)
+		last := attempt == attempts-1
+		if last || !shouldRetry(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := c.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = retryAfter
+				if c.MaxBackoff > 0 {
+					delay = min(delay, c.MaxBackoff)
+				}
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		if err := c.wait(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// applyHeaders injects the configured headers into req without overriding
+// headers the caller already set.
+func (c *Client) applyHeaders(req *http.Request) {
+	for key, value := range c.Headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	}
+	if c.BearerToken != "" && req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+	if c.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: c.Timeout}
+}
+
+func (c *Client) resolve(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	if path == "" {
+		return c.BaseURL
+	}
+	return strings.TrimRight(c.BaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// backoff returns the exponential delay for the given attempt, capped at MaxBackoff.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.RetryBackoff
+	for i := 0; i < attempt && (c.MaxBackoff <= 0 || delay < c.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if c.MaxBackoff > 0 && delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+	return delay
+}
+
+func (c *Client) wait(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isIdempotent reports whether a request with the given method is safe to retry.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// shouldRetry reports whether the outcome of an attempt is transient.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return max(time.Until(when), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,211 @@
+package options
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordSleeps replaces the client's retry wait with one that records delays.
+func recordSleeps(c *Client) *[]time.Duration {
+	var delays []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return &delays
+}
+
+func TestClient_GetDecodesJSONAndInjectsHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/users/1" {
+			t.Errorf("Expected path /api/users/1, got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", got)
+		}
+		if got := r.Header.Get("X-Tenant"); got != "acme" {
+			t.Errorf("Expected X-Tenant header, got %q", got)
+		}
+		if got := r.Header.Get("User-Agent"); got != "test-agent" {
+			t.Errorf("Expected user agent, got %q", got)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"name": "Alice"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/",
+		WithBearerToken("secret"),
+		WithHeader("X-Tenant", "acme"),
+		WithUserAgent("test-agent"),
+	)
+
+	var user struct{ Name string }
+	if err := client.Get(context.Background(), "/users/1", &user); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if user.Name != "Alice" {
+		t.Errorf("Expected Alice, got %q", user.Name)
+	}
+}
+
+func TestClient_PostEncodesJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON content type, got %q", r.Header.Get("Content-Type"))
+		}
+		var in map[string]int
+		json.NewDecoder(r.Body).Decode(&in)
+		json.NewEncoder(w).Encode(map[string]int{"sum": in["a"] + in["b"]})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	var out map[string]int
+	if err := client.Post(context.Background(), "sum", map[string]int{"a": 2, "b": 3}, &out); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	if out["sum"] != 5 {
+		t.Errorf("Expected sum 5, got %d", out["sum"])
+	}
+}
+
+func TestClient_RetriesIdempotentWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithMaxRetries(3), WithRetryBackoff(10*time.Millisecond, time.Second))
+	delays := recordSleeps(client)
+
+	var out struct{ OK bool }
+	if err := client.Get(context.Background(), "/", &out); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !out.OK {
+		t.Error("Expected ok response")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls.Load())
+	}
+
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}
+	if len(*delays) != len(expected) {
+		t.Fatalf("Expected %d waits, got %v", len(expected), *delays)
+	}
+	for i, d := range expected {
+		if (*delays)[i] != d {
+			t.Errorf("Expected wait %v at %d, got %v", d, i, (*delays)[i])
+		}
+	}
+}
+
+func TestClient_HonoursRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		maxBackoff time.Duration
+		want       time.Duration
+	}{
+		{10 * time.Second, 7 * time.Second},
+		{2 * time.Second, 2 * time.Second},
+		{0, 7 * time.Second},
+	}
+	for _, tt := range tests {
+		calls.Store(0)
+		client := NewClient(server.URL, WithRetryBackoff(100*time.Millisecond, tt.maxBackoff))
+		delays := recordSleeps(client)
+
+		if err := client.Get(context.Background(), "/", nil); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if len(*delays) != 1 || (*delays)[0] != tt.want {
+			t.Errorf("MaxBackoff %v: expected a single %v wait, got %v", tt.maxBackoff, tt.want, *delays)
+		}
+	}
+}
+
+func TestClient_DoesNotRetryPost(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithMaxRetries(5))
+	recordSleeps(client)
+
+	err := client.Post(context.Background(), "/", map[string]string{}, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected HTTPError 503, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected POST to be sent once, got %d", calls.Load())
+	}
+}
+
+func TestClient_RetriesReplayBody(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"v":1}` {
+			t.Errorf("Attempt %d: expected replayed body, got %q", calls.Load()+1, body)
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	recordSleeps(client)
+
+	if err := client.DoJSON(context.Background(), http.MethodPut, "/", map[string]int{"v": 1}, nil); err != nil {
+		t.Fatalf("PUT failed: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("3"); !ok || d != 3*time.Second {
+		t.Errorf("Expected 3s, got %v %v", d, ok)
+	}
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(future); !ok || d <= 0 || d > time.Minute {
+		t.Errorf("Expected ~1m from HTTP date, got %v %v", d, ok)
+	}
+
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("Expected invalid Retry-After to be rejected")
+	}
+}
@@ -0,0 +1,248 @@
+package options
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logLevels maps the level names accepted by WithLevel to slog levels.
+var logLevels = map[string]slog.Level{
+	"debug":   slog.LevelDebug,
+	"info":    slog.LevelInfo,
+	"warn":    slog.LevelWarn,
+	"warning": slog.LevelWarn,
+	"error":   slog.LevelError,
+}
+
+// parseLevel converts a level name to a slog.Level, defaulting to info.
+func parseLevel(level string) slog.Level {
+	if lvl, ok := logLevels[strings.ToLower(level)]; ok {
+		return lvl
+	}
+	return slog.LevelInfo
+}
+
+// buildHandler creates the slog.Handler described by the logger's fields.
+func (l *Logger) buildHandler() slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:     parseLevel(l.Level),
+		AddSource: l.EnableCaller,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey && l.TimeFormat != "" {
+				return slog.String(slog.TimeKey, a.Value.Time().Format(l.TimeFormat))
+			}
+			return a
+		},
+	}
+
+	out := l.openOutput()
+	if strings.ToLower(l.Format) == "text" {
+		return slog.NewTextHandler(out, opts)
+	}
+	return slog.NewJSONHandler(out, opts)
+}
+
+// openOutput resolves Writer or Output to an io.Writer.
+func (l *Logger) openOutput() io.Writer {
+	if l.Writer != nil {
+		return l.Writer
+	}
+
+	switch l.Output {
+	case "", "stdout":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	}
+
+	path := l.Output
+	if path == "file" {
+		path = l.FilePath
+	}
+	file := &rotatingFile{path: path, maxSize: l.MaxSize, maxBackups: l.MaxBackups}
+	l.closer = file
+	return file
+}
+
+// Handler returns the underlying slog.Handler.
+func (l *Logger) Handler() slog.Handler {
+	return l.handler
+}
+
+// Slog returns a *slog.Logger that shares this logger's handler.
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(l.handler)
+}
+
+// With returns a logger that adds the given key-value pairs to every record.
+// The child shares the parent's output but does not own it: closing the
+// child is a no-op, and the parent's Close closes the file for both.
+func (l *Logger) With(args ...any) *Logger {
+	child := *l
+	child.handler = l.handler.WithAttrs(argsToAttrs(args))
+	child.closer = nil
+	return &child
+}
+
+// Enabled reports whether records at level would be written.
+func (l *Logger) Enabled(level slog.Level) bool {
+	return l.handler.Enabled(context.Background(), level)
+}
+
+// Debug logs a message at debug level.
+func (l *Logger) Debug(msg string) {
+	l.log(context.Background(), slog.LevelDebug, msg)
+}
+
+// Info logs a message at info level.
+func (l *Logger) Info(msg string) {
+	l.log(context.Background(), slog.LevelInfo, msg)
+}
+
+// Warn logs a message at warn level.
+func (l *Logger) Warn(msg string) {
+	l.log(context.Background(), slog.LevelWarn, msg)
+}
+
+// Error logs a message at error level.
+func (l *Logger) Error(msg string) {
+	l.log(context.Background(), slog.LevelError, msg)
+}
+
+// Log logs a message with structured key-value pairs at the given level.
+func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l.log(ctx, level, msg, args...)
+}
+
+// Close closes the log file, if this logger opened one. Loggers returned
+// by With leave it open.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// log builds and handles a record. It must be called directly by the exported
+// logging methods so the caller is reported correctly.
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+
+	var pc uintptr
+	if l.EnableCaller {
+		var pcs [1]uintptr
+		// Skip runtime.Callers, log and the exported method.
+		runtime.Callers(3, pcs[:])
+		pc = pcs[0]
+	}
+
+	record := slog.NewRecord(time.Now(), level, l.Prefix+msg, pc)
+	record.Add(args...)
+	_ = l.handler.Handle(ctx, record)
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var record slog.Record
+	record.Add(args...)
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// rotatingFile is an io.WriteCloser that rotates the file once it grows past
+// maxSize bytes. Rotated files are named path.1 (newest) to path.N (oldest).
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if r.maxBackups > 0 {
+		os.Remove(r.backupName(r.maxBackups))
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			os.Rename(r.backupName(i), r.backupName(i+1))
+		}
+		if err := os.Rename(r.path, r.backupName(1)); err != nil {
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+
+	return r.open()
+}
+
+func (r *rotatingFile) backupName(n int) string {
+	return fmt.Sprintf("%s.%d", r.path, n)
+}
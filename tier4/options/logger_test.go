@@ -0,0 +1,149 @@
+package options
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jumaniyozov/design_patterns/others/dependencyinjection"
+	"github.com/jumaniyozov/design_patterns/others/nullobject"
+)
+
+// The options logger can be injected wherever these packages expect a Logger.
+var (
+	_ dependencyinjection.Logger = (*Logger)(nil)
+	_ nullobject.Logger          = (*Logger)(nil)
+)
+
+func TestLogger_JSONFormatAndLevelFilter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(WithWriter(&buf), WithLevel("info"), WithFormat("json"))
+
+	logger.Debug("hidden")
+	logger.Info("visible")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 line, got %d: %q", len(lines), buf.String())
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", lines[0], err)
+	}
+	if entry["msg"] != "visible" || entry["level"] != "INFO" {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+}
+
+func TestLogger_TextFormatWithPrefixAndAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(WithWriter(&buf), WithFormat("text"), WithPrefix("[api] "))
+
+	logger.With("request_id", "abc").Log(context.Background(), slog.LevelWarn, "slow", "ms", 250)
+
+	output := buf.String()
+	for _, want := range []string{`msg="[api] slow"`, "level=WARN", "request_id=abc", "ms=250"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in %q", want, output)
+		}
+	}
+}
+
+func TestLogger_ReportsCaller(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(WithWriter(&buf), WithCaller())
+
+	logger.Info("where")
+
+	var entry struct {
+		Source struct {
+			File string `json:"file"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if filepath.Base(entry.Source.File) != "logger_test.go" {
+		t.Errorf("Expected caller in logger_test.go, got %q", entry.Source.File)
+	}
+}
+
+func TestLogger_DevelopmentPreset(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(DevelopmentLogger(), WithWriter(&buf))
+
+	if !logger.Enabled(slog.LevelDebug) {
+		t.Error("Expected development logger to enable debug")
+	}
+	logger.Debug("details")
+	if !strings.Contains(buf.String(), "level=DEBUG") {
+		t.Errorf("Expected text debug output, got %q", buf.String())
+	}
+}
+
+func TestLogger_FileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger := NewLogger(WithFile(path), WithRotation(200, 2))
+	defer logger.Close()
+
+	for i := 0; i < 20; i++ {
+		logger.Info("a reasonably long log message to fill the file")
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+			continue
+		}
+		if info.Size() > 200 {
+			t.Errorf("Expected %s to be at most 200 bytes, got %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected at most 2 backups")
+	}
+}
+
+func TestLogger_ChildDoesNotCloseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger := NewLogger(WithFile(path))
+
+	child := logger.With("request_id", "abc")
+	if err := child.Close(); err != nil {
+		t.Fatalf("Expected closing a child to succeed, got %v", err)
+	}
+	logger.Info("after child close")
+	child.Info("child still writes")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Expected the root logger to close its file, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"after child close", "child still writes"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in the log file, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestLogger_InjectedIntoServices(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(WithWriter(&buf))
+
+	service := nullobject.NewService(logger, nil)
+	service.DoWork("42")
+
+	if buf.Len() == 0 {
+		t.Error("Expected service to log through the options logger")
+	}
+}
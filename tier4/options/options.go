@@ -16,7 +16,14 @@
 package options
 
 import (
+	"context"
 	"crypto/tls"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
 	"time"
 )
 
@@ -42,6 +49,15 @@ type Server struct {
 	EnableMetrics   bool
 	ShutdownTimeout time.Duration
 	MiddlewareChain []string
+	Handler         http.Handler
+	AccessLog       *log.Logger
+	ErrorLog        *log.Logger
+
+	mu         sync.Mutex
+	httpServer *http.Server
+	listener   net.Listener
+	ready      chan struct{}
+	metrics    serverMetrics
 }
 
 // ServerOption is an option for configuring a Server.
@@ -59,6 +75,9 @@ func NewServer(options ...ServerOption) *Server {
 		EnableMetrics:   false,
 		ShutdownTimeout: 10 * time.Second,
 		MiddlewareChain: []string{},
+		Handler:         http.NotFoundHandler(),
+		AccessLog:       log.Default(),
+		ErrorLog:        log.Default(),
 	}
 
 	// Apply options
@@ -126,25 +145,48 @@ func WithShutdownTimeout(timeout time.Duration) ServerOption {
 }
 
 // WithMiddleware adds middleware to the chain.
+// The name is resolved against the middleware registry when the server starts.
 func WithMiddleware(middleware string) ServerOption {
 	return func(s *Server) {
 		s.MiddlewareChain = append(s.MiddlewareChain, middleware)
 	}
 }
 
+// WithHandler sets the handler that serves requests.
+func WithHandler(handler http.Handler) ServerOption {
+	return func(s *Server) {
+		s.Handler = handler
+	}
+}
+
+// WithAccessLog sets the logger used by the logging middleware.
+func WithAccessLog(logger *log.Logger) ServerOption {
+	return func(s *Server) {
+		s.AccessLog = logger
+	}
+}
+
+// WithErrorLog sets the logger for errors accepting connections and
+// serving requests, such as TLS handshake failures and handler panics.
+func WithErrorLog(logger *log.Logger) ServerOption {
+	return func(s *Server) {
+		s.ErrorLog = logger
+	}
+}
+
 // DatabaseConfig demonstrates options with validation.
 type DatabaseConfig struct {
-	Host            string
-	Port            int
-	Username        string
-	Password        string
-	Database        string
-	MaxConnections  int
-	ConnectTimeout  time.Duration
-	QueryTimeout    time.Duration
-	SSLMode         string
-	RetryAttempts   int
-	RetryDelay      time.Duration
+	Host           string
+	Port           int
+	Username       string
+	Password       string
+	Database       string
+	MaxConnections int
+	ConnectTimeout time.Duration
+	QueryTimeout   time.Duration
+	SSLMode        string
+	RetryAttempts  int
+	RetryDelay     time.Duration
 }
 
 // DatabaseOption is an option for configuring a database connection.
@@ -247,12 +289,18 @@ func WithRetry(attempts int, delay time.Duration) DatabaseOption {
 
 // Client demonstrates options with builder-like behavior.
 type Client struct {
-	BaseURL     string
-	Timeout     time.Duration
-	MaxRetries  int
-	Headers     map[string]string
-	BearerToken string
-	UserAgent   string
+	BaseURL      string
+	Timeout      time.Duration
+	MaxRetries   int
+	Headers      map[string]string
+	BearerToken  string
+	UserAgent    string
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	HTTPClient   *http.Client
+
+	// sleep waits between retries; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
 }
 
 // ClientOption is an option for configuring a client.
@@ -261,11 +309,13 @@ type ClientOption func(*Client)
 // NewClient creates a new HTTP client with options.
 func NewClient(baseURL string, options ...ClientOption) *Client {
 	client := &Client{
-		BaseURL:    baseURL,
-		Timeout:    30 * time.Second,
-		MaxRetries: 3,
-		Headers:    make(map[string]string),
-		UserAgent:  "Go-Client/1.0",
+		BaseURL:      baseURL,
+		Timeout:      30 * time.Second,
+		MaxRetries:   3,
+		Headers:      make(map[string]string),
+		UserAgent:    "Go-Client/1.0",
+		RetryBackoff: 100 * time.Millisecond,
+		MaxBackoff:   5 * time.Second,
 	}
 
 	for _, option := range options {
@@ -311,14 +361,37 @@ func WithUserAgent(agent string) ClientOption {
 	}
 }
 
+// WithRetryBackoff sets the initial and maximum delay between retries.
+func WithRetryBackoff(initial, max time.Duration) ClientOption {
+	return func(c *Client) {
+		c.RetryBackoff = initial
+		c.MaxBackoff = max
+	}
+}
+
+// WithHTTPClient sets the underlying http.Client used to send requests.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = httpClient
+	}
+}
+
 // Logger demonstrates options with different configuration levels.
+// The handler is built once in NewLogger; changing fields afterwards has no effect.
 type Logger struct {
-	Level      string
-	Output     string
-	Format     string
-	TimeFormat string
-	Prefix     string
+	Level        string
+	Output       string
+	Format       string
+	TimeFormat   string
+	Prefix       string
 	EnableCaller bool
+	FilePath     string
+	MaxSize      int64
+	MaxBackups   int
+	Writer       io.Writer
+
+	handler slog.Handler
+	closer  io.Closer
 }
 
 // LoggerOption is an option for configuring a logger.
@@ -327,17 +400,22 @@ type LoggerOption func(*Logger)
 // NewLogger creates a new logger with options.
 func NewLogger(options ...LoggerOption) *Logger {
 	logger := &Logger{
-		Level:      "info",
-		Output:     "stdout",
-		Format:     "json",
-		TimeFormat: time.RFC3339,
+		Level:        "info",
+		Output:       "stdout",
+		Format:       "json",
+		TimeFormat:   time.RFC3339,
 		EnableCaller: false,
+		FilePath:     "app.log",
+		MaxSize:      100 << 20,
+		MaxBackups:   3,
 	}
 
 	for _, option := range options {
 		option(logger)
 	}
 
+	logger.handler = logger.buildHandler()
+
 	return logger
 }
 
@@ -348,7 +426,8 @@ func WithLevel(level string) LoggerOption {
 	}
 }
 
-// WithOutput sets the output destination.
+// WithOutput sets the output destination: "stdout", "stderr", "file"
+// (which writes to FilePath) or a file path.
 func WithOutput(output string) LoggerOption {
 	return func(l *Logger) {
 		l.Output = output
@@ -383,6 +462,30 @@ func WithCaller() LoggerOption {
 	}
 }
 
+// WithFile writes logs to the file at path.
+func WithFile(path string) LoggerOption {
+	return func(l *Logger) {
+		l.Output = "file"
+		l.FilePath = path
+	}
+}
+
+// WithRotation rotates the log file once it exceeds maxSize bytes,
+// keeping at most maxBackups old files.
+func WithRotation(maxSize int64, maxBackups int) LoggerOption {
+	return func(l *Logger) {
+		l.MaxSize = maxSize
+		l.MaxBackups = maxBackups
+	}
+}
+
+// WithWriter sends logs to w, overriding Output.
+func WithWriter(w io.Writer) LoggerOption {
+	return func(l *Logger) {
+		l.Writer = w
+	}
+}
+
 // Combining options example
 func DevelopmentLogger() LoggerOption {
 	return func(l *Logger) {
@@ -0,0 +1,359 @@
+package options
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrServerRunning is returned when Start is called on a running server.
+	ErrServerRunning = errors.New("server is already running")
+
+	// ErrServerNotStarted is returned when Shutdown is called before Start.
+	ErrServerNotStarted = errors.New("server is not started")
+
+	// ErrUnknownMiddleware is returned when a middleware name is not registered.
+	ErrUnknownMiddleware = errors.New("unknown middleware")
+
+	// ErrDuplicateMiddleware is returned when a middleware name is registered twice.
+	ErrDuplicateMiddleware = errors.New("middleware already registered")
+)
+
+// Middleware wraps an http.Handler with additional behavior.
+type Middleware func(next http.Handler) http.Handler
+
+// MiddlewareFactory builds a Middleware for a specific server.
+// The server is passed so middleware can use its logger and metrics.
+type MiddlewareFactory func(s *Server) Middleware
+
+// Names of the built-in middleware.
+const (
+	MiddlewareLogging = "logging"
+	MiddlewareMetrics = "metrics"
+)
+
+var (
+	middlewareMu       sync.RWMutex
+	middlewareRegistry = map[string]MiddlewareFactory{
+		MiddlewareLogging: loggingMiddleware,
+		MiddlewareMetrics: metricsMiddleware,
+	}
+)
+
+// RegisterMiddleware makes a middleware available to WithMiddleware by name.
+func RegisterMiddleware(name string, factory MiddlewareFactory) error {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+
+	if _, exists := middlewareRegistry[name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateMiddleware, name)
+	}
+	middlewareRegistry[name] = factory
+	return nil
+}
+
+// lookupMiddleware returns the factory registered under name.
+func lookupMiddleware(name string) (MiddlewareFactory, error) {
+	middlewareMu.RLock()
+	defer middlewareMu.RUnlock()
+
+	factory, ok := middlewareRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMiddleware, name)
+	}
+	return factory, nil
+}
+
+// ServerMetrics is a snapshot of the request counters kept by the metrics middleware.
+type ServerMetrics struct {
+	Requests      int64
+	Errors        int64
+	InFlight      int64
+	TotalDuration time.Duration
+}
+
+// serverMetrics holds the live counters behind ServerMetrics.
+type serverMetrics struct {
+	requests  atomic.Int64
+	errors    atomic.Int64
+	inFlight  atomic.Int64
+	totalNano atomic.Int64
+}
+
+// Metrics returns a snapshot of the server's request metrics.
+func (s *Server) Metrics() ServerMetrics {
+	return ServerMetrics{
+		Requests:      s.metrics.requests.Load(),
+		Errors:        s.metrics.errors.Load(),
+		InFlight:      s.metrics.inFlight.Load(),
+		TotalDuration: time.Duration(s.metrics.totalNano.Load()),
+	}
+}
+
+// Addr returns the address the server is listening on, or nil if not started.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Ready returns a channel that is closed once the server is accepting connections.
+func (s *Server) Ready() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readyChan()
+}
+
+// readyChan lazily creates the ready channel. Callers must hold s.mu.
+func (s *Server) readyChan() chan struct{} {
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+	return s.ready
+}
+
+// Start listens on Host:Port and serves requests until ctx is cancelled or
+// Shutdown is called. It returns nil after a graceful shutdown, after which
+// the server can be started again.
+func (s *Server) Start(ctx context.Context) error {
+	handler, err := s.buildHandler()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.httpServer != nil {
+		s.mu.Unlock()
+		return ErrServerRunning
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)))
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("listen: %w", err)
+	}
+	if s.MaxConnections > 0 {
+		listener = newLimitListener(listener, s.MaxConnections)
+	}
+	if s.TLSConfig != nil {
+		listener = tls.NewListener(listener, s.TLSConfig)
+	}
+
+	server := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  s.Timeout,
+		WriteTimeout: s.Timeout,
+		IdleTimeout:  s.Timeout,
+		ErrorLog:     s.ErrorLog,
+	}
+	s.httpServer = server
+	s.listener = listener
+	close(s.readyChan())
+	s.mu.Unlock()
+
+	defer s.stopped(server)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		if err := s.Shutdown(); err != nil {
+			return err
+		}
+		<-serveErr
+		return nil
+	}
+}
+
+// stopped clears the state Start set up for server once it has stopped
+// serving, so Start can run again and Addr and Ready reflect the stop.
+func (s *Server) stopped(server *http.Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpServer == server {
+		s.httpServer = nil
+		s.listener = nil
+		s.ready = nil
+	}
+}
+
+// Shutdown gracefully stops the server, waiting at most ShutdownTimeout for
+// in-flight requests to complete before closing remaining connections.
+func (s *Server) Shutdown() error {
+	s.mu.Lock()
+	server := s.httpServer
+	s.mu.Unlock()
+
+	if server == nil {
+		return ErrServerNotStarted
+	}
+
+	ctx := context.Background()
+	if s.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.ShutdownTimeout)
+		defer cancel()
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		// Timeout expired: force-close whatever is still open.
+		server.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
+}
+
+// buildHandler wraps Handler with the configured middleware chain.
+// The first middleware in the chain is the outermost one.
+func (s *Server) buildHandler() (http.Handler, error) {
+	names := make([]string, 0, len(s.MiddlewareChain)+2)
+	if s.EnableLogging && !containsString(s.MiddlewareChain, MiddlewareLogging) {
+		names = append(names, MiddlewareLogging)
+	}
+	if s.EnableMetrics && !containsString(s.MiddlewareChain, MiddlewareMetrics) {
+		names = append(names, MiddlewareMetrics)
+	}
+	names = append(names, s.MiddlewareChain...)
+
+	handler := s.Handler
+	if handler == nil {
+		handler = http.NotFoundHandler()
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		factory, err := lookupMiddleware(names[i])
+		if err != nil {
+			return nil, err
+		}
+		handler = factory(s)(handler)
+	}
+	return handler, nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// loggingMiddleware logs method, path, status and duration for each request.
+func loggingMiddleware(s *Server) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if s.AccessLog != nil {
+				s.AccessLog.Printf("%s %s %d %v", r.Method, r.URL.Path, rec.status, time.Since(start))
+			}
+		})
+	}
+}
+
+// metricsMiddleware records request counts, 5xx errors and latency.
+func metricsMiddleware(s *Server) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			s.metrics.inFlight.Add(1)
+			defer s.metrics.inFlight.Add(-1)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			s.metrics.requests.Add(1)
+			s.metrics.totalNano.Add(int64(time.Since(start)))
+			if rec.status >= http.StatusInternalServerError {
+				s.metrics.errors.Add(1)
+			}
+		})
+	}
+}
+
+// limitListener caps the number of simultaneously open connections.
+// Accept blocks while the limit is reached.
+type limitListener struct {
+	net.Listener
+	sem  chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newLimitListener(l net.Listener, n int) *limitListener {
+	return &limitListener{
+		Listener: l,
+		sem:      make(chan struct{}, n),
+		done:     make(chan struct{}),
+	}
+}
+
+// Accept waits for a free slot, then accepts the next connection.
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+// Close closes the listener and unblocks any pending Accept.
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() { close(l.done) })
+	return err
+}
+
+// limitConn releases its listener slot exactly once when closed.
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
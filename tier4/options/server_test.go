@@ -0,0 +1,313 @@
+package options
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startTestServer(t *testing.T, opts ...ServerOption) (*Server, string, context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	opts = append([]ServerOption{WithHost("127.0.0.1"), WithPort(0)}, opts...)
+	server := NewServer(opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx)
+	}()
+
+	select {
+	case <-server.Ready():
+	case err := <-done:
+		cancel()
+		t.Fatalf("Server failed to start: %v", err)
+	case <-time.After(2 * time.Second):
+		cancel()
+		t.Fatal("Server did not become ready")
+	}
+
+	return server, "http://" + server.Addr().String(), cancel, done
+}
+
+func TestServer_ServesHandler(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	})
+	_, url, cancel, done := startTestServer(t, WithHandler(handler))
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "hello" {
+		t.Errorf("Expected body 'hello', got %q", body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestServer_LoggingAndMetricsMiddleware(t *testing.T) {
+	var logs strings.Builder
+	var mu sync.Mutex
+	logger := log.New(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return logs.Write(p)
+	}), "", 0)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server, url, cancel, done := startTestServer(t,
+		WithHandler(handler),
+		WithAccessLog(logger),
+		WithMiddleware(MiddlewareLogging),
+		WithMetrics(),
+	)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, path := range []string{"/ok", "/fail", "/ok"} {
+		resp, err := http.Get(url + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	metrics := server.Metrics()
+	if metrics.Requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", metrics.Requests)
+	}
+	if metrics.Errors != 1 {
+		t.Errorf("Expected 1 error, got %d", metrics.Errors)
+	}
+
+	mu.Lock()
+	output := logs.String()
+	mu.Unlock()
+	if !strings.Contains(output, "GET /fail 500") {
+		t.Errorf("Expected access log for /fail, got %q", output)
+	}
+}
+
+func TestServer_UnknownMiddleware(t *testing.T) {
+	server := NewServer(WithHost("127.0.0.1"), WithPort(0), WithMiddleware("does-not-exist"))
+
+	err := server.Start(context.Background())
+	if !errors.Is(err, ErrUnknownMiddleware) {
+		t.Errorf("Expected ErrUnknownMiddleware, got %v", err)
+	}
+}
+
+func TestRegisterMiddleware(t *testing.T) {
+	err := RegisterMiddleware("test-header", func(s *Server) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Test", "yes")
+				next.ServeHTTP(w, r)
+			})
+		}
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	if err := RegisterMiddleware(MiddlewareLogging, loggingMiddleware); !errors.Is(err, ErrDuplicateMiddleware) {
+		t.Errorf("Expected ErrDuplicateMiddleware, got %v", err)
+	}
+
+	_, url, cancel, done := startTestServer(t, WithMiddleware("test-header"))
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.Header.Get("X-Test") != "yes" {
+		t.Error("Expected registered middleware to set X-Test header")
+	}
+}
+
+func TestServer_MaxConnections(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	})
+
+	_, url, cancel, done := startTestServer(t, WithHandler(handler), WithMaxConnections(1))
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	transport := &http.Transport{DisableKeepAlives: true}
+	client := &http.Client{Transport: transport}
+	defer transport.CloseIdleConnections()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(url)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+
+	<-entered
+	select {
+	case <-entered:
+		t.Error("Expected second connection to wait for a free slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-r.Context().Done()
+	})
+
+	server, url, cancel, done := startTestServer(t,
+		WithHandler(handler),
+		WithShutdownTimeout(50*time.Millisecond),
+	)
+	defer cancel()
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	start := time.Now()
+	err := server.Shutdown()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown took %v, expected it to honour the timeout", elapsed)
+	}
+	<-done
+}
+
+func TestServer_Restart(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	})
+	server := NewServer(WithHost("127.0.0.1"), WithPort(0), WithHandler(handler))
+
+	for i := range 2 {
+		done := make(chan error, 1)
+		go func() {
+			done <- server.Start(context.Background())
+		}()
+		select {
+		case <-server.Ready():
+		case err := <-done:
+			t.Fatalf("Start %d failed: %v", i+1, err)
+		}
+
+		resp, err := http.Get("http://" + server.Addr().String())
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i+1, err)
+		}
+		resp.Body.Close()
+
+		if err := server.Shutdown(); err != nil {
+			t.Fatalf("Shutdown %d failed: %v", i+1, err)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("Expected Start %d to return nil, got %v", i+1, err)
+		}
+		if addr := server.Addr(); addr != nil {
+			t.Errorf("Expected no address after shutdown, got %v", addr)
+		}
+	}
+	if err := server.Shutdown(); !errors.Is(err, ErrServerNotStarted) {
+		t.Errorf("Expected ErrServerNotStarted after the server stopped, got %v", err)
+	}
+}
+
+func TestServer_ErrorLog(t *testing.T) {
+	var accessLog, errorLog strings.Builder
+	var mu sync.Mutex
+	logTo := func(b *strings.Builder) *log.Logger {
+		return log.New(writerFunc(func(p []byte) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return b.Write(p)
+		}), "", 0)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler bug")
+	})
+	_, url, cancel, done := startTestServer(t,
+		WithHandler(handler),
+		WithAccessLog(logTo(&accessLog)),
+		WithErrorLog(logTo(&errorLog)),
+	)
+
+	if resp, err := http.Get(url); err == nil {
+		resp.Body.Close()
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(errorLog.String(), "handler bug") {
+		t.Errorf("Expected the panic in the error log, got %q", errorLog.String())
+	}
+	if strings.Contains(accessLog.String(), "handler bug") {
+		t.Errorf("Expected the access log to stay clean, got %q", accessLog.String())
+	}
+}
+
+func TestServer_ShutdownBeforeStart(t *testing.T) {
+	server := NewServer()
+	if err := server.Shutdown(); !errors.Is(err, ErrServerNotStarted) {
+		t.Errorf("Expected ErrServerNotStarted, got %v", err)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }